@@ -1,29 +1,93 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"path/filepath"
-	"strconv"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/backup"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/config"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 	"github.com/spf13/cobra"
 )
 
+var (
+	cloudProfile    string
+	restoreDryRun   bool
+	newPasswordFile string
+	listSince       string
+	listGroupBy     []string
+	listLatest      int
+	allowRoot       bool
+	pruneYes        bool
+)
+
+var (
+	errRunningAsRoot     = errors.New("refusing to run the backup as root")
+	errPruneNotConfirmed = errors.New("refusing to prune without confirmation, use --yes when not running from a terminal")
+)
+
 func init() {
 	rootCmd.AddCommand(backupCmd)
 	backupCmd.AddCommand(backupLocalCmd)
+	backupLocalCmd.AddCommand(backupLocalEstimateCmd)
 	backupCmd.AddCommand(backupCloudCmd)
 
 	// Add cloud backup subcommands
 	backupCloudCmd.AddCommand(backupCloudInitCmd)
 	backupCloudCmd.AddCommand(backupCloudCheckCmd)
 	backupCloudCmd.AddCommand(backupCloudListCmd)
+	backupCloudCmd.AddCommand(backupCloudTagsCmd)
 	backupCloudCmd.AddCommand(backupCloudPruneCmd)
 	backupCloudCmd.AddCommand(backupCloudRestoreCmd)
 	backupCloudCmd.AddCommand(backupCloudListFilesCmd)
+	backupCloudCmd.AddCommand(backupCloudPasswdCmd)
+	backupCloudCmd.AddCommand(backupCloudCopyCmd)
+
+	backupCmd.PersistentFlags().BoolVar(
+		&allowRoot, "allow-root", false,
+		"Run the backup even if the current user is root, in which case the backed up files may belong to root",
+	)
+	backupCloudCmd.PersistentFlags().StringVar(
+		&cloudProfile, "profile", "",
+		"Name of the backup profile to use. Reads HOMELAB_BACKUP_<PROFILE>_* variables instead of HOMELAB_BACKUP_*",
+	)
+	backupCloudRestoreCmd.Flags().BoolVar(
+		&restoreDryRun, "dry-run", false,
+		"Show which files would be restored without writing anything",
+	)
+	backupCloudListCmd.Flags().StringVar(
+		&listSince, "since", "",
+		"Only list the snapshots taken within this duration, such as 7d, 2w or 36h",
+	)
+	backupCloudListCmd.Flags().StringSliceVar(
+		&listGroupBy, "group-by", []string{},
+		"Group the snapshots by host, paths and/or tags, such as --group-by host,tags. Can't be used with --since",
+	)
+	backupCloudListCmd.Flags().IntVar(
+		&listLatest, "latest", 0,
+		"Only list the latest N snapshots of each host and path. Can't be used with --since",
+	)
+	backupCloudPruneCmd.Flags().BoolVar(
+		&pruneYes, "yes", false,
+		"Prune without asking for confirmation",
+	)
+	backupCloudPasswdCmd.Flags().StringVar(
+		&newPasswordFile, "new-password-file", "",
+		"File that contains the new repository password",
+	)
+	_ = backupCloudPasswdCmd.MarkFlagRequired("new-password-file")
 }
 
 var backupCmd = &cobra.Command{
@@ -35,14 +99,27 @@ var backupCmd = &cobra.Command{
 var backupLocalCmd = &cobra.Command{
 	Use:   "local",
 	Short: "Creates a local backup of all services' data",
-	Long:  "Creates a local backup of all services' data into a single directory. Running this command will start up all services first. The backup operations run concurrently. It is important that backups are performed in periods of low service usage: for example, we would not want to backup a database that's in the process of updating a large number of records",
+	Long:  "Creates a local backup of all services' data into a single directory. Running this command will start up all services first, except the ones that must be stopped while their data is copied, which are started again once the backup finishes. The backup operations of different services run concurrently, and the ones of the same service one after the other. It is important that backups are performed in periods of low service usage: for example, we would not want to backup a database that's in the process of updating a large number of records",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureNotRoot(os.Getuid, allowRoot); err != nil {
+			return err
+		}
 		files := system.NewDefaultFilesHandler()
 		env := system.NewDefaultEnv()
-		if err := startAllContainers(); err != nil {
+		runLog, err := startRunLog(env, files, system.NewDefaultTime(), "backup-local", localBackupPathEnvVar)
+		if err != nil {
 			return err
 		}
-		return runBackupLocal(files, env)
+		return runLog.Finish(runFullBackupLocal(files, env))
+	},
+}
+
+var backupLocalEstimateCmd = &cobra.Command{
+	Use:   "estimate",
+	Short: "Estimates the size of the local backup",
+	Long:  "Sums the size of the files under the source directory of each directory backup, to estimate how much data the local backup copies. Nothing is copied and no container is started. Database dumps are not included, because their size is only known once they are made",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return estimateLocalBackup(os.Stdout, system.NewDefaultFilesHandler(), system.NewDefaultEnv())
 	},
 }
 
@@ -51,13 +128,17 @@ var backupCloudCmd = &cobra.Command{
 	Short: "Manage cloud backups using restic and Backblaze B2",
 	Long:  "Commands to manage cloud backups. Run without subcommands to perform a full backup (init, backup, prune).",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureNotRoot(os.Getuid, allowRoot); err != nil {
+			return err
+		}
 		env := system.NewDefaultEnv()
-		config, err := getCloudBackupConfig(env)
+		runLog, err := startRunLog(env, system.NewDefaultFilesHandler(), system.NewDefaultTime(), "backup-cloud", backup.BackupPathEnvVar(cloudProfile))
 		if err != nil {
 			return err
 		}
-		cloudBackup := backup.NewCloudBackup(config)
-		return cloudBackup.RunFullBackup()
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		return runLog.Finish(runFullBackupCloud(ctx, env))
 	},
 }
 
@@ -86,15 +167,53 @@ var backupCloudCheckCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cloudBackup := backup.NewCloudBackup(config)
-		return cloudBackup.Check()
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		cloudBackup := backup.NewInterruptibleCloudBackup(ctx, config)
+		return cloudBackup.RunInterruptible(cloudBackup.Check)
 	},
 }
 
 var backupCloudListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all cloud backup snapshots",
-	Long:  "Lists all snapshots in the cloud backup repository.",
+	Long:  "Lists all snapshots in the cloud backup repository. With --since, only the snapshots taken within that duration are listed. With --group-by, restic groups the snapshots. With --latest N, only the latest N snapshots are listed.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if listSince != "" && len(listGroupBy) > 0 {
+			return errors.New("--group-by can't be used with --since")
+		}
+		if cmd.Flags().Changed("latest") {
+			if listLatest <= 0 {
+				return fmt.Errorf("--latest must be greater than zero, got %d", listLatest)
+			}
+			if listSince != "" {
+				return errors.New("--latest can't be used with --since")
+			}
+		}
+		var since time.Duration
+		if listSince != "" {
+			var err error
+			if since, err = backup.ParseSince(listSince); err != nil {
+				return err
+			}
+		}
+		env := system.NewDefaultEnv()
+		config, err := getCloudBackupConfig(env)
+		if err != nil {
+			return err
+		}
+		cloudBackup := backup.NewCloudBackup(config)
+		if since > 0 {
+			return cloudBackup.ListSnapshotsSince(since)
+		}
+		return cloudBackup.ListSnapshots(listGroupBy, listLatest)
+	},
+}
+
+var backupCloudTagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List the tags of the automatic cloud backup snapshots",
+	Long:  "Lists the unique automatic tags (automatic-*, or the prefix set in HOMELAB_BACKUP_TAG_PREFIX) of the snapshots in the cloud backup repository, one per line and from oldest to newest, so that they can be used by other tools.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		env := system.NewDefaultEnv()
 		config, err := getCloudBackupConfig(env)
@@ -102,25 +221,81 @@ var backupCloudListCmd = &cobra.Command{
 			return err
 		}
 		cloudBackup := backup.NewCloudBackup(config)
-		return cloudBackup.ListSnapshots()
+		tags, err := cloudBackup.ListAutomaticTags()
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			fmt.Fprintln(os.Stdout, tag)
+		}
+		return nil
 	},
 }
 
 var backupCloudPruneCmd = &cobra.Command{
 	Use:   "prune",
 	Short: "Remove old cloud backup snapshots",
-	Long:  "Removes old snapshots according to the configured retention policy.",
+	Long:  "Removes old snapshots according to the configured retention policy. The retention is shown and must be confirmed first, unless --yes is given, because the removed snapshots can't be recovered.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		env := system.NewDefaultEnv()
 		config, err := getCloudBackupConfig(env)
 		if err != nil {
 			return err
 		}
-		cloudBackup := backup.NewCloudBackup(config)
-		return cloudBackup.Prune()
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		cloudBackup := backup.NewInterruptibleCloudBackup(ctx, config)
+		return runCloudPrune(terminalPrompter(), config, pruneYes, func() error {
+			return cloudBackup.RunInterruptible(cloudBackup.Prune)
+		})
 	},
 }
 
+// confirmationPrompter asks the user a question and returns the answer
+type confirmationPrompter interface {
+	Prompt(message string) (string, error)
+}
+
+// terminalPrompter returns a prompter that reads from the standard input, or nil if it is not a terminal
+func terminalPrompter() config.Prompter {
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		return config.NewConsolePrompter()
+	}
+	return nil
+}
+
+// runCloudPrune runs prune once the user confirms the retention of resticConfig, because the snapshots that are removed
+// can't be recovered. If yes is true, the user is not asked. If prompter is nil, there is no user to ask and pruning
+// fails unless yes is true
+func runCloudPrune(prompter confirmationPrompter, resticConfig backup.ResticConfig, yes bool, prune func() error) error {
+	if !yes {
+		if prompter == nil {
+			return errPruneNotConfirmed
+		}
+		question := fmt.Sprintf(
+			"Permanently remove the snapshots of %s, %s? [y/N]: ",
+			resticConfig.RepositoryURL, describeRetention(resticConfig),
+		)
+		answer, err := prompter.Prompt(question)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains([]string{"y", "yes"}, strings.ToLower(strings.TrimSpace(answer))) {
+			slog.Info("Not pruning the cloud backup")
+			return nil
+		}
+	}
+	return prune()
+}
+
+// describeRetention describes which snapshots restic forget keeps with the retention of resticConfig
+func describeRetention(resticConfig backup.ResticConfig) string {
+	if resticConfig.KeepLast > 0 {
+		return fmt.Sprintf("keeping the snapshots within %s and the latest %d", resticConfig.Retention, resticConfig.KeepLast)
+	}
+	return fmt.Sprintf("keeping the snapshots within %s", resticConfig.Retention)
+}
+
 var backupCloudRestoreCmd = &cobra.Command{
 	Use:   "restore [target-directory]",
 	Short: "Restore the latest cloud backup snapshot",
@@ -132,9 +307,13 @@ var backupCloudRestoreCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cloudBackup := backup.NewCloudBackup(config)
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		cloudBackup := backup.NewInterruptibleCloudBackup(ctx, config)
 		targetDir := args[0]
-		return cloudBackup.Restore(targetDir)
+		return cloudBackup.RunInterruptible(func() error {
+			return cloudBackup.Restore(targetDir, restoreDryRun)
+		})
 	},
 }
 
@@ -155,181 +334,495 @@ var backupCloudListFilesCmd = &cobra.Command{
 	},
 }
 
-// startAllContainers starts all containers. Note that some containers (e.g., databases) need to be running in
-// order to perform the backup, because we need to run commands on them (e.g., exporting the database)
-func startAllContainers() error {
-	dockerRunner := docker.NewSystemRunner()
-	if err := dockerRunner.ComposeStart([]string{}); err != nil {
-		return fmt.Errorf("failed to start all containers: %w", err)
+var backupCloudPasswdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change the password of the cloud backup repository",
+	Long:  "Changes the password of the cloud backup repository to the content of --new-password-file, with \"restic key passwd\". The RESTIC_PASSWORD variable of the .env file must be updated afterwards.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := system.NewDefaultEnv()
+		config, err := getCloudBackupConfig(env)
+		if err != nil {
+			return err
+		}
+		cloudBackup := backup.NewCloudBackup(config)
+		return cloudBackup.ChangePassword(newPasswordFile)
+	},
+}
+
+var backupCloudCopyCmd = &cobra.Command{
+	Use:   "copy",
+	Short: "Copy the cloud backup snapshots into a secondary repository",
+	Long:  "Copies the snapshots of the cloud backup repository that are missing from the secondary repository (HOMELAB_BACKUP_COPY_RESTIC_REPOSITORY and HOMELAB_BACKUP_COPY_RESTIC_PASSWORD) into it, with \"restic copy\". The secondary repository must already be initialized.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := system.NewDefaultEnv()
+		config, err := getCloudBackupConfig(env)
+		if err != nil {
+			return err
+		}
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		cloudBackup := backup.NewInterruptibleCloudBackup(ctx, config)
+		return cloudBackup.RunInterruptible(cloudBackup.Copy)
+	},
+}
+
+// ensureNotRoot refuses to run a backup when getuid returns the root user, unless allowRoot is true. When run as root,
+// the UID and GID injected into Docker Compose are 0, so the containers and the backup create files that the
+// containers' user can't read
+func ensureNotRoot(getuid func() int, allowRoot bool) error {
+	if getuid() != 0 {
+		return nil
+	}
+	if allowRoot {
+		slog.Warn("Running the backup as root because of --allow-root. The backed up files may belong to root")
+		return nil
+	}
+	return fmt.Errorf("%w: run it as the user of the containers, or pass --allow-root", errRunningAsRoot)
+}
+
+// runFullBackupLocal starts the containers, except the disabled services, and runs the local backup between the
+// backup hooks
+func runFullBackupLocal(files system.FilesHandler, env system.Env) error {
+	disabledServices, err := localBackupDisabledServices(env)
+	if err != nil {
+		return err
+	}
+	dockerRunner := newDockerRunner()
+	if err := startContainersForBackup(dockerRunner, disabledServices); err != nil {
+		return err
+	}
+	backupErr := backup.NewBackupHooks().RunAround(func() error {
+		return runBackupLocal(files, env)
+	})
+	return errors.Join(backupErr, restartDisabledServices(dockerRunner, disabledServices))
+}
+
+// runFullBackupCloud runs the full cloud backup (init, backup, prune) between the backup hooks. When ctx is done, restic
+// is interrupted and the repository is unlocked. Its result is notified to the configured webhook, if any
+func runFullBackupCloud(ctx context.Context, env system.Env) (err error) {
+	defer func() { notifyBackupResult(env, "cloud", err) }()
+
+	config, err := getCloudBackupConfig(env)
+	if err != nil {
+		return err
+	}
+	cloudBackup := backup.NewInterruptibleCloudBackup(ctx, config)
+	return backup.NewBackupHooks().RunAround(func() error {
+		return cloudBackup.RunInterruptible(cloudBackup.RunFullBackup)
+	})
+}
+
+// interruptContext returns a context that is done when the process receives SIGINT (Ctrl-C) or SIGTERM, so that the
+// restic commands are interrupted cleanly instead of the process exiting and leaving the repository locked
+func interruptContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+}
+
+// startContainersForBackup starts all containers except the disabled services, which are stopped if they are running.
+// Note that some containers (e.g., databases) need to be running in order to perform the backup, because we need to
+// run commands on them (e.g., exporting the database), while others must not be running, because they write to the
+// directories that are copied
+func startContainersForBackup(dockerRunner docker.Runner, disabledServices []string) error {
+	if len(disabledServices) == 0 {
+		if err := dockerRunner.ComposeStart([]string{}); err != nil {
+			return fmt.Errorf("failed to start all containers: %w", err)
+		}
+		return nil
+	}
+
+	services, err := dockerRunner.ComposeServices()
+	if err != nil {
+		return fmt.Errorf("failed to list services: %w", err)
+	}
+	var enabledServices []string
+	for _, service := range services {
+		if !slices.Contains(disabledServices, service) {
+			enabledServices = append(enabledServices, service)
+		}
+	}
+
+	slog.Info("Stopping the services that must be disabled during the backup", "services", disabledServices)
+	if err := dockerRunner.ComposeStop(disabledServices); err != nil {
+		return fmt.Errorf("failed to stop services %v: %w", disabledServices, err)
+	}
+	if len(enabledServices) == 0 {
+		return nil
+	}
+	if err := dockerRunner.ComposeStart(enabledServices); err != nil {
+		return fmt.Errorf("failed to start containers: %w", err)
 	}
 	return nil
 }
 
-func runBackupLocal(files system.FilesHandler, env system.Env) error {
+// restartDisabledServices starts again the services that were disabled during the backup, whether it succeeded or not
+func restartDisabledServices(dockerRunner docker.Runner, disabledServices []string) error {
+	if len(disabledServices) == 0 {
+		return nil
+	}
+	slog.Info("Starting the services that were disabled during the backup", "services", disabledServices)
+	if err := dockerRunner.ComposeStart(disabledServices); err != nil {
+		return fmt.Errorf("failed to start services %v: %w", disabledServices, err)
+	}
+	return nil
+}
+
+// localBackupDisabledServices returns the services that must be stopped during the local backup
+func localBackupDisabledServices(env system.Env) ([]string, error) {
+	if err := ensureLocalBackupRequirements(env); err != nil {
+		return nil, fmt.Errorf("missing configuration for the local backup: %w", err)
+	}
+	mainBackupDir, err := env.GetRequiredEnv(localBackupPathEnvVar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup path: %w", err)
+	}
+	localBackupList, err := buildLocalBackupList(mainBackupDir, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup operations: %w", err)
+	}
+	return localBackupList.ServicesDisabled(), nil
+}
+
+// runBackupLocal runs the local backup of all services. Its result is notified to the configured webhook, if any
+func runBackupLocal(files system.FilesHandler, env system.Env) (err error) {
+	defer func() { notifyBackupResult(env, "local", err) }()
+
 	slog.Info("Creating local backup...")
 
+	// Check the configuration before anything is emptied, so that a misconfiguration doesn't delete the last backup
+	if err := ensureLocalBackupRequirements(env); err != nil {
+		return fmt.Errorf("missing configuration for the local backup: %w", err)
+	}
+
 	// Get the main backup directory path
-	mainBackupDir, err := env.GetRequiredEnv("HOMELAB_BACKUP_PATH")
+	mainBackupDir, err := env.GetRequiredEnv(localBackupPathEnvVar)
 	if err != nil {
 		return fmt.Errorf("failed to get backup path: %w", err)
 	}
 
-	// Prepare the main backup directory
-	if err := files.EmptyDir(mainBackupDir); err != nil {
-		return fmt.Errorf("failed to prepare backup directory: %w", err)
+	archivePruner, err := backup.LoadLocalArchivePruner(env)
+	if err != nil {
+		return err
+	}
+	options := loadLocalBackupOptions(env)
+	if archivePruner != nil && !options.incremental {
+		slog.Warn("Only the overridden destinations are pruned, because the backup path is emptied when the backup is not incremental",
+			"path", mainBackupDir)
 	}
 
-	// Define backup operations
+	// Define backup operations. This is done before emptying the main backup directory, so that the previous backup
+	// is kept if the operations can't be built
 	localBackupList, err := buildLocalBackupList(mainBackupDir, env)
 	if err != nil {
 		return fmt.Errorf("failed to create backup operations: %w", err)
 	}
+	if err := localBackupList.Validate(); err != nil {
+		return fmt.Errorf("invalid backup operations: %w", err)
+	}
+	if err := localBackupList.ValidateSourcesOutside(mainBackupDir); err != nil {
+		return fmt.Errorf("invalid backup operations: %w", err)
+	}
+
+	if err := prepareLocalBackupDir(files, mainBackupDir, options); err != nil {
+		return err
+	}
+
+	if err := localBackupList.WaitUntilReady(); err != nil {
+		return fmt.Errorf("failed waiting for containers to be ready: %w", err)
+	}
 
 	if err := localBackupList.RunAll(); err != nil {
 		return fmt.Errorf("failed running backup operations: %w", err)
 	}
 
+	// Archives are only pruned after a successful backup, so that the last good ones are never deleted
+	if archivePruner != nil {
+		if err := archivePruner.Prune(localBackupList.DstPaths()); err != nil {
+			return fmt.Errorf("failed pruning expired local archives: %w", err)
+		}
+	}
+
 	slog.Info("Local backup completed successfully")
 	return nil
 }
 
-func buildLocalBackupList(mainBackupDir string, env system.Env) (*backup.LocalBackupList, error) {
-	localBackupList := backup.NewLocalBackupList()
+// prepareLocalBackupDir empties the main backup directory, unless the backup is incremental, in which case the previous
+// backup is kept so that only the files that changed are copied
+func prepareLocalBackupDir(files system.FilesHandler, mainBackupDir string, options localBackupOptions) error {
+	if options.incremental {
+		slog.Info("Keeping the previous local backup, because it is incremental", "path", mainBackupDir)
+		return nil
+	}
+	if err := files.EmptyDir(mainBackupDir); err != nil {
+		return fmt.Errorf("failed to prepare backup directory: %w", err)
+	}
+	return nil
+}
 
-	calibreLibraryPath, err := env.GetRequiredEnv("HOMELAB_CALIBRE_LIBRARY_PATH")
+// estimateLocalBackup prints the size of the source directory of each directory backup, and their total
+func estimateLocalBackup(out io.Writer, files system.FilesHandler, env system.Env) error {
+	mainBackupDir, err := env.GetRequiredEnv(localBackupPathEnvVar)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to get backup path: %w", err)
 	}
-	localBackupList.Add(backup.NewDirectoryLocalBackup(
-		calibreLibraryPath,
-		filepath.Join(mainBackupDir, "calibre-web-automated-calibre-library"),
-		"",
-	))
-
-	calibreConfPath, err := env.GetRequiredEnv("HOMELAB_CALIBRE_CONF_PATH")
+	localBackupList, err := buildLocalBackupList(mainBackupDir, env)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to create backup operations: %w", err)
 	}
-	localBackupList.Add(backup.NewDirectoryLocalBackup(
-		calibreConfPath,
-		filepath.Join(mainBackupDir, "calibre-web-automated-config"),
-		"",
-	))
 
-	paperlessExportPath, err := env.GetRequiredEnv("HOMELAB_PAPERLESS_WEB_EXPORT_PATH")
-	if err != nil {
-		return nil, err
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	var total int64
+	for _, srcPath := range localBackupList.SrcPaths() {
+		size, err := files.DirSize(srcPath)
+		if err != nil {
+			return err
+		}
+		total += size
+		fmt.Fprintf(w, "%s\t%s\n", srcPath, format.FormatSize(size))
 	}
-	localBackupList.Add(backup.NewDirectoryLocalBackup(
-		paperlessExportPath,
-		filepath.Join(mainBackupDir, "paperless-ngx-webserver-export"),
-		docker.BuildDockerComposeCommandStr("exec -T paperless document_exporter -d ../export"),
-	))
+	fmt.Fprintf(w, "Total\t%s\n", format.FormatSize(total))
+	return w.Flush()
+}
 
-	immichDBContainer, err := env.GetRequiredEnv("HOMELAB_IMMICH_DB_CONTAINER_NAME")
-	if err != nil {
-		return nil, err
+// localBackupService is the local backup of a service. All of its environment variables are required, and they are
+// only listed here, so that the requirements check and the backup operations always read the same variables
+type localBackupService struct {
+	Name    string
+	EnvVars []string
+	// build creates the backup operations of the service. values has the value of each of EnvVars
+	build func(env system.Env, mainBackupDir string, values map[string]string) ([]backup.LocalBackup, error)
+}
+
+// localBackupServices are the services that are backed up locally, in the order their operations are added
+var localBackupServices = []localBackupService{
+	{
+		Name:    "calibre",
+		EnvVars: []string{"HOMELAB_CALIBRE_LIBRARY_PATH", "HOMELAB_CALIBRE_CONF_PATH"},
+		build:   buildCalibreLocalBackups,
+	},
+	{
+		Name:    "paperless",
+		EnvVars: []string{"HOMELAB_PAPERLESS_WEB_EXPORT_PATH"},
+		build:   buildPaperlessLocalBackups,
+	},
+	{
+		Name: "immich",
+		EnvVars: []string{
+			"HOMELAB_IMMICH_DB_CONTAINER_NAME",
+			"HOMELAB_IMMICH_DB_DATABASE",
+			"HOMELAB_IMMICH_DB_USER",
+			"HOMELAB_IMMICH_DB_PASSWORD",
+			"HOMELAB_IMMICH_WEB_UPLOAD_PATH",
+		},
+		build: buildImmichLocalBackups,
+	},
+	{
+		Name: "firefly",
+		EnvVars: []string{
+			"HOMELAB_FIREFLY_DB_CONTAINER_NAME",
+			"HOMELAB_FIREFLY_DB_DATABASE",
+			"HOMELAB_FIREFLY_DB_USER",
+			"HOMELAB_FIREFLY_DB_PASSWORD",
+		},
+		build: buildFireflyLocalBackups,
+	},
+}
+
+// localBackupOptions change how every directory of the local backup is copied
+type localBackupOptions struct {
+	// incremental keeps the previous backup and copies only the files that changed, with rsync
+	incremental bool
+	// skipUnreadable skips and logs the files that can't be read, instead of failing the copy
+	skipUnreadable bool
+	// followSymlinks copies the files that symbolic links point to, instead of the links
+	followSymlinks bool
+}
+
+// loadLocalBackupOptions loads the options of the local backup from HOMELAB_LOCAL_INCREMENTAL,
+// HOMELAB_LOCAL_SKIP_UNREADABLE and HOMELAB_LOCAL_FOLLOW_SYMLINKS. They are all disabled by default
+func loadLocalBackupOptions(env system.Env) localBackupOptions {
+	incremental, _ := env.GetBoolEnv("HOMELAB_LOCAL_INCREMENTAL")
+	skipUnreadable, _ := env.GetBoolEnv("HOMELAB_LOCAL_SKIP_UNREADABLE")
+	followSymlinks, _ := env.GetBoolEnv("HOMELAB_LOCAL_FOLLOW_SYMLINKS")
+	return localBackupOptions{incremental: incremental, skipUnreadable: skipUnreadable, followSymlinks: followSymlinks}
+}
+
+// applyTo sets the options on a directory backup
+func (o localBackupOptions) applyTo(directory *backup.DirectoryLocalBackup) {
+	if o.incremental {
+		directory.WithIncremental()
+	}
+	if o.skipUnreadable {
+		directory.WithSkipUnreadable()
+	}
+	if o.followSymlinks {
+		directory.WithFollowSymlinks()
+	}
+}
+
+func buildLocalBackupList(mainBackupDir string, env system.Env) (*backup.LocalBackupList, error) {
+	localBackupList := backup.NewLocalBackupList()
+	options := loadLocalBackupOptions(env)
+	for _, service := range localBackupServices {
+		operations, err := buildLocalBackupServiceOperations(env, mainBackupDir, service, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, operation := range operations {
+			localBackupList.Add(operation)
+		}
 	}
-	immichDBName, err := env.GetRequiredEnv("HOMELAB_IMMICH_DB_DATABASE")
+	return localBackupList, nil
+}
+
+// buildLocalBackupServiceOperations reads the environment variables of a service and creates its backup operations.
+// The operations of a service run one after the other, so that a service that is stopped, exported or dumped by one
+// of them is not read by another one at the same time. The options are set on every directory backup
+func buildLocalBackupServiceOperations(
+	env system.Env,
+	mainBackupDir string,
+	service localBackupService,
+	options localBackupOptions,
+) ([]backup.LocalBackup, error) {
+	values := make(map[string]string, len(service.EnvVars))
+	for _, envVar := range service.EnvVars {
+		value, err := env.GetRequiredEnv(envVar)
+		if err != nil {
+			return nil, err
+		}
+		values[envVar] = value
+	}
+	operations, err := service.build(env, mainBackupDir, values)
 	if err != nil {
 		return nil, err
 	}
-	immichDBUser, err := env.GetRequiredEnv("HOMELAB_IMMICH_DB_USER")
+	for _, operation := range operations {
+		if grouper, ok := operation.(backup.ExclusionGrouper); ok {
+			grouper.SetExclusionGroup(service.Name)
+		}
+		if directory, ok := operation.(*backup.DirectoryLocalBackup); ok {
+			options.applyTo(directory)
+		}
+	}
+	return operations, nil
+}
+
+func buildCalibreLocalBackups(env system.Env, mainBackupDir string, values map[string]string) ([]backup.LocalBackup, error) {
+	calibreLibraryDst, err := backup.LocalBackupDst(env, mainBackupDir, "calibre-web-automated-calibre-library")
 	if err != nil {
 		return nil, err
 	}
-	immichDBPassword, err := env.GetRequiredEnv("HOMELAB_IMMICH_DB_PASSWORD")
+	calibreConfDst, err := backup.LocalBackupDst(env, mainBackupDir, "calibre-web-automated-config")
 	if err != nil {
 		return nil, err
 	}
-	localBackupList.Add(backup.NewPostgreSQLLocalBackup(
-		immichDBContainer,
-		immichDBName,
-		immichDBUser,
-		immichDBPassword,
-		filepath.Join(mainBackupDir, "immich-db"),
-	))
+	return []backup.LocalBackup{
+		// Calibre writes to the metadata.db database of the library, which could be copied half-written
+		backup.NewDirectoryLocalBackup(
+			values["HOMELAB_CALIBRE_LIBRARY_PATH"],
+			calibreLibraryDst,
+			"",
+		).WithServicesDisabled("calibre"),
+		backup.NewDirectoryLocalBackup(
+			values["HOMELAB_CALIBRE_CONF_PATH"],
+			calibreConfDst,
+			"",
+		),
+	}, nil
+}
 
-	immichUploadPath, err := env.GetRequiredEnv("HOMELAB_IMMICH_WEB_UPLOAD_PATH")
+func buildPaperlessLocalBackups(env system.Env, mainBackupDir string, values map[string]string) ([]backup.LocalBackup, error) {
+	paperlessExportDst, err := backup.LocalBackupDst(env, mainBackupDir, "paperless-ngx-webserver-export")
 	if err != nil {
 		return nil, err
 	}
-	localBackupList.Add(backup.NewDirectoryLocalBackup(
-		immichUploadPath,
-		filepath.Join(mainBackupDir, "immich-library"),
-		"",
-	))
+	return []backup.LocalBackup{
+		backup.NewDirectoryLocalBackup(
+			values["HOMELAB_PAPERLESS_WEB_EXPORT_PATH"],
+			paperlessExportDst,
+			docker.BuildDockerComposeCommandStr("exec -T paperless document_exporter -d ../export"),
+		),
+	}, nil
+}
 
-	fireflyDBContainer, err := env.GetRequiredEnv("HOMELAB_FIREFLY_DB_CONTAINER_NAME")
+func buildImmichLocalBackups(env system.Env, mainBackupDir string, values map[string]string) ([]backup.LocalBackup, error) {
+	immichDBDst, err := backup.LocalBackupDst(env, mainBackupDir, "immich-db")
 	if err != nil {
 		return nil, err
 	}
-	fireflyDBName, err := env.GetRequiredEnv("HOMELAB_FIREFLY_DB_DATABASE")
+	immichDBReadyTimeout, err := backup.LoadReadinessTimeout(env, "HOMELAB_IMMICH_DB_READY_TIMEOUT")
 	if err != nil {
 		return nil, err
 	}
-	fireflyDBUser, err := env.GetRequiredEnv("HOMELAB_FIREFLY_DB_USER")
+	immichDBDumpFilename, err := backup.LoadDumpFilenameTemplate(env, "HOMELAB_IMMICH_DB_DUMP_FILENAME", values["HOMELAB_IMMICH_DB_DATABASE"])
 	if err != nil {
 		return nil, err
 	}
-	fireflyDBPassword, err := env.GetRequiredEnv("HOMELAB_FIREFLY_DB_PASSWORD")
+	immichUploadDst, err := backup.LocalBackupDst(env, mainBackupDir, "immich-library")
 	if err != nil {
 		return nil, err
 	}
-	localBackupList.Add(backup.NewMariaDBLocalBackup(
-		fireflyDBContainer,
-		fireflyDBName,
-		fireflyDBUser,
-		fireflyDBPassword,
-		filepath.Join(mainBackupDir, "firefly-db"),
-	))
-
-	return localBackupList, nil
+	return []backup.LocalBackup{
+		backup.NewPostgreSQLLocalBackup(
+			values["HOMELAB_IMMICH_DB_CONTAINER_NAME"],
+			values["HOMELAB_IMMICH_DB_DATABASE"],
+			values["HOMELAB_IMMICH_DB_USER"],
+			values["HOMELAB_IMMICH_DB_PASSWORD"],
+			immichDBDst,
+		).WithReadinessTimeout(immichDBReadyTimeout).WithFilenameTemplate(immichDBDumpFilename),
+		backup.NewDirectoryLocalBackup(
+			values["HOMELAB_IMMICH_WEB_UPLOAD_PATH"],
+			immichUploadDst,
+			"",
+		),
+	}, nil
 }
 
-// getCloudBackupConfig loads cloud backup configuration from environment variables
-func getCloudBackupConfig(env system.Env) (backup.ResticConfig, error) {
-	repositoryURL, err := env.GetRequiredEnv("HOMELAB_BACKUP_RESTIC_REPOSITORY")
-	if err != nil {
-		return backup.ResticConfig{}, err
-	}
-
-	b2KeyID, err := env.GetRequiredEnv("HOMELAB_BACKUP_B2_KEY_ID")
+func buildFireflyLocalBackups(env system.Env, mainBackupDir string, values map[string]string) ([]backup.LocalBackup, error) {
+	fireflyDBDst, err := backup.LocalBackupDst(env, mainBackupDir, "firefly-db")
 	if err != nil {
-		return backup.ResticConfig{}, err
+		return nil, err
 	}
-
-	b2ApplicationKey, err := env.GetRequiredEnv("HOMELAB_BACKUP_B2_APPLICATION_KEY")
+	fireflyDBReadyTimeout, err := backup.LoadReadinessTimeout(env, "HOMELAB_FIREFLY_DB_READY_TIMEOUT")
 	if err != nil {
-		return backup.ResticConfig{}, err
+		return nil, err
 	}
-
-	resticPassword, err := env.GetRequiredEnv("HOMELAB_BACKUP_RESTIC_PASSWORD")
+	fireflyDBDumpFilename, err := backup.LoadDumpFilenameTemplate(env, "HOMELAB_FIREFLY_DB_DUMP_FILENAME", values["HOMELAB_FIREFLY_DB_DATABASE"])
 	if err != nil {
-		return backup.ResticConfig{}, err
+		return nil, err
 	}
+	return []backup.LocalBackup{
+		backup.NewMariaDBLocalBackup(
+			values["HOMELAB_FIREFLY_DB_CONTAINER_NAME"],
+			values["HOMELAB_FIREFLY_DB_DATABASE"],
+			values["HOMELAB_FIREFLY_DB_USER"],
+			values["HOMELAB_FIREFLY_DB_PASSWORD"],
+			fireflyDBDst,
+		).WithReadinessTimeout(fireflyDBReadyTimeout).WithFilenameTemplate(fireflyDBDumpFilename),
+	}, nil
+}
 
-	backupPath, err := env.GetRequiredEnv("HOMELAB_BACKUP_PATH")
+// getCloudBackupConfig loads cloud backup configuration of the selected profile from environment variables. When the
+// restic password is not configured and the command is run from a terminal, the password is asked to the user
+func getCloudBackupConfig(env system.Env) (backup.ResticConfig, error) {
+	config, err := loadCloudBackupConfig(env, terminalPrompter())
 	if err != nil {
 		return backup.ResticConfig{}, err
 	}
-
-	retentionDaysStr, err := env.GetRequiredEnv("HOMELAB_BACKUP_RETENTION_DAYS")
-	if err != nil {
+	if err := backup.RequireResticBinary(system.NewDefaultCommands(), config); err != nil {
 		return backup.ResticConfig{}, err
 	}
+	return config, nil
+}
 
-	retentionDays, err := strconv.Atoi(retentionDaysStr)
-	if err != nil {
-		return backup.ResticConfig{}, fmt.Errorf("invalid retention days value: %w", err)
+// loadCloudBackupConfig loads cloud backup configuration of the selected profile. If prompter is not nil, it is used to
+// ask for the restic password when it is not configured
+func loadCloudBackupConfig(env system.Env, prompter backup.SecretPrompter) (backup.ResticConfig, error) {
+	if prompter != nil {
+		var err error
+		env, err = backup.WithPromptedResticPassword(env, cloudProfile, prompter)
+		if err != nil {
+			return backup.ResticConfig{}, err
+		}
 	}
-
-	return backup.ResticConfig{
-		RepositoryURL:    repositoryURL,
-		B2KeyID:          b2KeyID,
-		B2ApplicationKey: b2ApplicationKey,
-		ResticPassword:   resticPassword,
-		BackupPath:       backupPath,
-		RetentionDays:    retentionDays,
-	}, nil
+	return backup.LoadResticConfig(env, cloudProfile)
 }
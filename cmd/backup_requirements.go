@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/spf13/cobra"
+)
+
+var errUnknownBackupService = errors.New("no local backup for service")
+
+func init() {
+	backupCmd.AddCommand(backupRequirementsCmd)
+}
+
+var backupRequirementsCmd = &cobra.Command{
+	Use:   "requirements [service]",
+	Short: "List the environment variables that the local backup needs",
+	Long:  "Lists the environment variables that the local backup of a service (or all services if none specified) needs, and whether each of them is currently set. This helps fixing the configuration before running the backup.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := system.NewDefaultEnv()
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		statuses, err := buildBackupRequirementStatuses(env, service)
+		if err != nil {
+			return err
+		}
+		printBackupRequirementStatuses(os.Stdout, statuses)
+		return nil
+	},
+}
+
+// localBackupPathEnvVar is the environment variable with the directory where the local backups are stored. The local
+// backup of every service needs it
+const localBackupPathEnvVar = "HOMELAB_BACKUP_PATH"
+
+// backupRequirementStatus tells whether an environment variable needed by the local backup of a service is set. A
+// variable that still has a placeholder value, such as CHANGEME, is not set
+type backupRequirementStatus struct {
+	Service string
+	EnvVar  string
+	IsSet   bool
+}
+
+// buildBackupRequirementStatuses returns whether each environment variable needed by the local backup of a service
+// is set. If the service is empty, the variables of all services are returned
+func buildBackupRequirementStatuses(env system.Env, service string) ([]backupRequirementStatus, error) {
+	var statuses []backupRequirementStatus
+	for _, backupService := range localBackupServices {
+		if service != "" && backupService.Name != service {
+			continue
+		}
+		envVars := append([]string{localBackupPathEnvVar}, backupService.EnvVars...)
+		for _, envVar := range envVars {
+			// The variables are read like the backup reads them, so that a variable reported as set can't be missing
+			_, err := env.GetRequiredEnv(envVar)
+			statuses = append(statuses, backupRequirementStatus{Service: backupService.Name, EnvVar: envVar, IsSet: err == nil})
+		}
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("%w %q", errUnknownBackupService, service)
+	}
+	return statuses, nil
+}
+
+// ensureLocalBackupRequirements checks that all the environment variables needed by the local backups are set. Every
+// missing variable is reported in the error, so that they can all be fixed at once
+func ensureLocalBackupRequirements(env system.Env) error {
+	statuses, err := buildBackupRequirementStatuses(env, "")
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, status := range statuses {
+		if !status.IsSet && !slices.Contains(missing, status.EnvVar) {
+			missing = append(missing, status.EnvVar)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", system.ErrRequiredEnvNotFound, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// printBackupRequirementStatuses prints the environment variables needed by the local backups in a table
+func printBackupRequirementStatuses(out io.Writer, statuses []backupRequirementStatus) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tVARIABLE\tSTATUS")
+	for _, status := range statuses {
+		state := "unset"
+		if status.IsSet {
+			state = "set"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", status.Service, status.EnvVar, state)
+	}
+	w.Flush()
+}
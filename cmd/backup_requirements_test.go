@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildBackupRequirementStatuses_SingleService(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{
+		"HOMELAB_BACKUP_PATH":              "/backup",
+		"HOMELAB_IMMICH_DB_CONTAINER_NAME": "immich-db",
+		"HOMELAB_IMMICH_DB_USER":           "immich",
+	}}
+
+	statuses, err := buildBackupRequirementStatuses(env, "immich")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := []backupRequirementStatus{
+		{Service: "immich", EnvVar: "HOMELAB_BACKUP_PATH", IsSet: true},
+		{Service: "immich", EnvVar: "HOMELAB_IMMICH_DB_CONTAINER_NAME", IsSet: true},
+		{Service: "immich", EnvVar: "HOMELAB_IMMICH_DB_DATABASE", IsSet: false},
+		{Service: "immich", EnvVar: "HOMELAB_IMMICH_DB_USER", IsSet: true},
+		{Service: "immich", EnvVar: "HOMELAB_IMMICH_DB_PASSWORD", IsSet: false},
+		{Service: "immich", EnvVar: "HOMELAB_IMMICH_WEB_UPLOAD_PATH", IsSet: false},
+	}
+	if diff := cmp.Diff(expected, statuses); diff != "" {
+		t.Errorf("statuses mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildBackupRequirementStatuses_AllServices(t *testing.T) {
+	env := &mockEnv{}
+
+	statuses, err := buildBackupRequirementStatuses(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var services []string
+	for _, status := range statuses {
+		if status.IsSet {
+			t.Errorf("expected %q to be unset", status.EnvVar)
+		}
+		if len(services) == 0 || services[len(services)-1] != status.Service {
+			services = append(services, status.Service)
+		}
+	}
+	if diff := cmp.Diff([]string{"calibre", "paperless", "immich", "firefly"}, services); diff != "" {
+		t.Errorf("services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildBackupRequirementStatuses_UnknownService(t *testing.T) {
+	_, err := buildBackupRequirementStatuses(&mockEnv{}, "navidrome")
+
+	if !errors.Is(err, errUnknownBackupService) {
+		t.Errorf("expected errUnknownBackupService, got: %v", err)
+	}
+}
+
+func TestPrintBackupRequirementStatuses(t *testing.T) {
+	statuses := []backupRequirementStatus{
+		{Service: "paperless", EnvVar: "HOMELAB_BACKUP_PATH", IsSet: true},
+		{Service: "paperless", EnvVar: "HOMELAB_PAPERLESS_WEB_EXPORT_PATH", IsSet: false},
+	}
+	out := &bytes.Buffer{}
+
+	printBackupRequirementStatuses(out, statuses)
+
+	expected := "SERVICE    VARIABLE                           STATUS\n" +
+		"paperless  HOMELAB_BACKUP_PATH                set\n" +
+		"paperless  HOMELAB_PAPERLESS_WEB_EXPORT_PATH  unset\n"
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,610 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/backup"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/google/go-cmp/cmp"
+)
+
+// localBackupTestVars are all the variables needed by the local backups
+var localBackupTestVars = map[string]string{
+	"HOMELAB_BACKUP_PATH":               "/backup",
+	"HOMELAB_CALIBRE_LIBRARY_PATH":      "/calibre/library",
+	"HOMELAB_CALIBRE_CONF_PATH":         "/calibre/conf",
+	"HOMELAB_PAPERLESS_WEB_EXPORT_PATH": "/paperless/export",
+	"HOMELAB_IMMICH_DB_CONTAINER_NAME":  "immich-db",
+	"HOMELAB_IMMICH_DB_DATABASE":        "immich",
+	"HOMELAB_IMMICH_DB_USER":            "immich",
+	"HOMELAB_IMMICH_DB_PASSWORD":        "password",
+	"HOMELAB_IMMICH_WEB_UPLOAD_PATH":    "/immich/upload",
+	"HOMELAB_FIREFLY_DB_CONTAINER_NAME": "firefly-db",
+	"HOMELAB_FIREFLY_DB_DATABASE":       "firefly",
+	"HOMELAB_FIREFLY_DB_USER":           "firefly",
+	"HOMELAB_FIREFLY_DB_PASSWORD":       "password",
+}
+
+func TestRunBackupLocal_MissingVarsDoNotEmptyDir(t *testing.T) {
+	emptyDirCalled := false
+	files := &mockFiles{
+		emptyDir: func(path string) error {
+			emptyDirCalled = true
+			return nil
+		},
+	}
+	env := &mockEnv{vars: map[string]string{
+		"HOMELAB_BACKUP_PATH":          "/backup",
+		"HOMELAB_CALIBRE_LIBRARY_PATH": "/calibre/library",
+	}}
+
+	err := runBackupLocal(files, env)
+
+	if !errors.Is(err, system.ErrRequiredEnvNotFound) {
+		t.Fatalf("expected ErrRequiredEnvNotFound, got: %v", err)
+	}
+	if emptyDirCalled {
+		t.Error("expected the backup directory not to be emptied")
+	}
+}
+
+func TestRunBackupLocal_ListsAllMissingVars(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{
+		"HOMELAB_CALIBRE_LIBRARY_PATH": "/calibre/library",
+	}}
+
+	err := runBackupLocal(&mockFiles{}, env)
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	missing := []string{
+		"HOMELAB_BACKUP_PATH",
+		"HOMELAB_CALIBRE_CONF_PATH",
+		"HOMELAB_PAPERLESS_WEB_EXPORT_PATH",
+		"HOMELAB_IMMICH_DB_CONTAINER_NAME",
+		"HOMELAB_IMMICH_DB_DATABASE",
+		"HOMELAB_IMMICH_DB_USER",
+		"HOMELAB_IMMICH_DB_PASSWORD",
+		"HOMELAB_IMMICH_WEB_UPLOAD_PATH",
+		"HOMELAB_FIREFLY_DB_CONTAINER_NAME",
+		"HOMELAB_FIREFLY_DB_DATABASE",
+		"HOMELAB_FIREFLY_DB_USER",
+		"HOMELAB_FIREFLY_DB_PASSWORD",
+	}
+	for _, varName := range missing {
+		if !strings.Contains(err.Error(), varName) {
+			t.Errorf("expected error to list %q, got: %v", varName, err)
+		}
+	}
+	if strings.Count(err.Error(), "HOMELAB_BACKUP_PATH") != 1 {
+		t.Errorf("expected HOMELAB_BACKUP_PATH to be listed once, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "HOMELAB_CALIBRE_LIBRARY_PATH") {
+		t.Errorf("expected set variables not to be listed, got: %v", err)
+	}
+}
+
+func TestRunBackupLocal_BuildListErrorDoesNotEmptyDir(t *testing.T) {
+	emptyDirCalled := false
+	files := &mockFiles{
+		emptyDir: func(path string) error {
+			emptyDirCalled = true
+			return nil
+		},
+	}
+	vars := map[string]string{}
+	for name, value := range localBackupTestVars {
+		vars[name] = value
+	}
+	// Destinations must be absolute, so building the backup operations fails
+	vars["HOMELAB_BACKUP_DST_IMMICH_DB"] = "relative/immich-db"
+	env := &mockEnv{vars: vars}
+
+	err := runBackupLocal(files, env)
+
+	if !errors.Is(err, backup.ErrInvalidBackupDst) {
+		t.Fatalf("expected ErrInvalidBackupDst, got: %v", err)
+	}
+	if emptyDirCalled {
+		t.Error("expected the backup directory not to be emptied")
+	}
+}
+
+func TestRunBackupLocal_InvalidReadinessTimeout(t *testing.T) {
+	vars := map[string]string{}
+	for name, value := range localBackupTestVars {
+		vars[name] = value
+	}
+	vars["HOMELAB_FIREFLY_DB_READY_TIMEOUT"] = "five minutes"
+	env := &mockEnv{vars: vars}
+
+	err := runBackupLocal(&mockFiles{}, env)
+
+	if !errors.Is(err, backup.ErrInvalidReadinessTimeout) {
+		t.Fatalf("expected ErrInvalidReadinessTimeout, got: %v", err)
+	}
+	if code := ExitCode(err); code != ExitCodeConfigError {
+		t.Errorf("expected exit code %d, got %d", ExitCodeConfigError, code)
+	}
+}
+
+func TestRunBackupLocal_InvalidDumpFilenameDoesNotEmptyDir(t *testing.T) {
+	emptyDirCalled := false
+	files := &mockFiles{
+		emptyDir: func(path string) error {
+			emptyDirCalled = true
+			return nil
+		},
+	}
+	vars := maps.Clone(localBackupTestVars)
+	vars["HOMELAB_FIREFLY_DB_DUMP_FILENAME"] = "<db>;reboot.sql"
+	env := &mockEnv{vars: vars}
+
+	err := runBackupLocal(files, env)
+
+	if !errors.Is(err, backup.ErrInvalidDumpFilename) {
+		t.Fatalf("expected ErrInvalidDumpFilename, got: %v", err)
+	}
+	if code := ExitCode(err); code != ExitCodeConfigError {
+		t.Errorf("expected exit code %d, got %d", ExitCodeConfigError, code)
+	}
+	if emptyDirCalled {
+		t.Error("expected the backup directory not to be emptied")
+	}
+}
+
+func TestRunBackupLocal_SourceInsideBackupDirDoesNotEmptyDir(t *testing.T) {
+	emptyDirCalled := false
+	files := &mockFiles{
+		emptyDir: func(path string) error {
+			emptyDirCalled = true
+			return nil
+		},
+	}
+	vars := maps.Clone(localBackupTestVars)
+	vars["HOMELAB_CALIBRE_CONF_PATH"] = "/backup/calibre-conf"
+	env := &mockEnv{vars: vars}
+
+	err := runBackupLocal(files, env)
+
+	if !errors.Is(err, backup.ErrConflictingBackupPaths) {
+		t.Fatalf("expected ErrConflictingBackupPaths, got: %v", err)
+	}
+	if emptyDirCalled {
+		t.Error("expected the backup directory not to be emptied")
+	}
+}
+
+func TestRunBackupLocal_InvalidRetentionDoesNotEmptyDir(t *testing.T) {
+	emptyDirCalled := false
+	files := &mockFiles{
+		emptyDir: func(path string) error {
+			emptyDirCalled = true
+			return nil
+		},
+	}
+	vars := map[string]string{}
+	for name, value := range localBackupTestVars {
+		vars[name] = value
+	}
+	vars["HOMELAB_LOCAL_RETENTION_DAYS"] = "0"
+	env := &mockEnv{vars: vars}
+
+	err := runBackupLocal(files, env)
+
+	if !errors.Is(err, backup.ErrInvalidLocalRetention) {
+		t.Fatalf("expected ErrInvalidLocalRetention, got: %v", err)
+	}
+	if emptyDirCalled {
+		t.Error("expected the backup directory not to be emptied")
+	}
+}
+
+func TestEnsureNotRoot_RefusesRoot(t *testing.T) {
+	err := ensureNotRoot(func() int { return 0 }, false)
+
+	if !errors.Is(err, errRunningAsRoot) {
+		t.Errorf("expected errRunningAsRoot, got: %v", err)
+	}
+}
+
+func TestEnsureNotRoot_AllowRootBypass(t *testing.T) {
+	err := ensureNotRoot(func() int { return 0 }, true)
+
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestEnsureNotRoot_NonRootUser(t *testing.T) {
+	err := ensureNotRoot(func() int { return 1000 }, false)
+
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+// promptedPassword is a SecretPrompter that answers with a fixed password and counts the prompts
+type promptedPassword struct {
+	password string
+	prompts  int
+}
+
+func (p *promptedPassword) PromptSecret(message string) (string, error) {
+	p.prompts++
+	return p.password, nil
+}
+
+func TestLoadCloudBackupConfig_PromptsForMissingPassword(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+	}}
+	prompter := &promptedPassword{password: "typed-password"}
+
+	config, err := loadCloudBackupConfig(env, prompter)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if prompter.prompts != 1 || config.ResticPassword != "typed-password" {
+		t.Errorf("expected the typed password after 1 prompt, got %q after %d prompts", config.ResticPassword, prompter.prompts)
+	}
+	if _, exists := env.vars["HOMELAB_BACKUP_RESTIC_PASSWORD"]; exists {
+		t.Error("expected the typed password not to be stored in the environment")
+	}
+}
+
+func TestLoadCloudBackupConfig_NonInteractiveMissingPassword(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+	}}
+
+	_, err := loadCloudBackupConfig(env, nil)
+
+	if !errors.Is(err, system.ErrRequiredEnvNotFound) {
+		t.Errorf("expected ErrRequiredEnvNotFound, got: %v", err)
+	}
+}
+
+// answeringPrompter is a confirmationPrompter that answers with a fixed answer and captures the questions
+type answeringPrompter struct {
+	answer    string
+	questions []string
+}
+
+func (p *answeringPrompter) Prompt(message string) (string, error) {
+	p.questions = append(p.questions, message)
+	return p.answer, nil
+}
+
+var pruneTestConfig = backup.ResticConfig{RepositoryURL: "b2:bucket:path", Retention: "30d", KeepLast: 5}
+
+func TestRunCloudPrune_SkippedWithoutConfirmation(t *testing.T) {
+	for _, answer := range []string{"", "n", "no", "maybe"} {
+		t.Run(answer, func(t *testing.T) {
+			prompter := &answeringPrompter{answer: answer}
+			pruned := false
+
+			err := runCloudPrune(prompter, pruneTestConfig, false, func() error {
+				pruned = true
+				return nil
+			})
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if pruned {
+				t.Error("expected prune to be skipped")
+			}
+			expected := []string{"Permanently remove the snapshots of b2:bucket:path, keeping the snapshots within 30d and the latest 5? [y/N]: "}
+			if diff := cmp.Diff(expected, prompter.questions); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunCloudPrune_ProceedsWhenConfirmed(t *testing.T) {
+	for _, answer := range []string{"y", " YES "} {
+		t.Run(answer, func(t *testing.T) {
+			pruned := false
+
+			err := runCloudPrune(&answeringPrompter{answer: answer}, pruneTestConfig, false, func() error {
+				pruned = true
+				return nil
+			})
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if !pruned {
+				t.Error("expected prune to run")
+			}
+		})
+	}
+}
+
+func TestRunCloudPrune_ProceedsWithYes(t *testing.T) {
+	prompter := &answeringPrompter{answer: "n"}
+	expectedErr := errors.New("forget failed")
+
+	err := runCloudPrune(prompter, pruneTestConfig, true, func() error {
+		return expectedErr
+	})
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected the prune error, got: %v", err)
+	}
+	if len(prompter.questions) != 0 {
+		t.Errorf("expected no questions, got %q", prompter.questions)
+	}
+}
+
+func TestRunCloudPrune_NotConfirmedWithoutTerminal(t *testing.T) {
+	pruned := false
+
+	err := runCloudPrune(nil, pruneTestConfig, false, func() error {
+		pruned = true
+		return nil
+	})
+
+	if !errors.Is(err, errPruneNotConfirmed) {
+		t.Errorf("expected errPruneNotConfirmed, got: %v", err)
+	}
+	if pruned {
+		t.Error("expected prune to be skipped")
+	}
+}
+
+func TestDescribeRetention_WithoutKeepLast(t *testing.T) {
+	got := describeRetention(backup.ResticConfig{Retention: "6m"})
+
+	if got != "keeping the snapshots within 6m" {
+		t.Errorf("expected %q, got %q", "keeping the snapshots within 6m", got)
+	}
+}
+
+func TestEstimateLocalBackup_SumsSourceDirectories(t *testing.T) {
+	vars := maps.Clone(localBackupTestVars)
+	sizes := map[string]int{
+		"HOMELAB_CALIBRE_LIBRARY_PATH":      2048,
+		"HOMELAB_CALIBRE_CONF_PATH":         100,
+		"HOMELAB_PAPERLESS_WEB_EXPORT_PATH": 0,
+		"HOMELAB_IMMICH_WEB_UPLOAD_PATH":    1024,
+	}
+	for varName, size := range sizes {
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, "nested"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "nested", "file"), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		vars[varName] = dir
+	}
+	var out bytes.Buffer
+
+	err := estimateLocalBackup(&out, system.NewDefaultFilesHandler(), &mockEnv{vars: vars})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedLines := [][]string{
+		{vars["HOMELAB_CALIBRE_LIBRARY_PATH"], "2.0", "KiB"},
+		{vars["HOMELAB_CALIBRE_CONF_PATH"], "100", "B"},
+		{vars["HOMELAB_PAPERLESS_WEB_EXPORT_PATH"], "0", "B"},
+		{vars["HOMELAB_IMMICH_WEB_UPLOAD_PATH"], "1.0", "KiB"},
+		{"Total", "3.1", "KiB"},
+	}
+	var lines [][]string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		lines = append(lines, strings.Fields(line))
+	}
+	if diff := cmp.Diff(expectedLines, lines); diff != "" {
+		t.Errorf("estimate mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStartContainersForBackup_StartsAllWithoutDisabledServices(t *testing.T) {
+	var started [][]string
+	runner := &mockDockerRunner{
+		composeStart: func(services []string) error {
+			started = append(started, services)
+			return nil
+		},
+		composeStop: func(services []string) error {
+			t.Errorf("expected no service to be stopped, got: %v", services)
+			return nil
+		},
+	}
+
+	err := startContainersForBackup(runner, nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([][]string{{}}, started); diff != "" {
+		t.Errorf("started services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStartContainersForBackup_DisabledServiceIsNotLeftRunning(t *testing.T) {
+	var started, stopped []string
+	runner := &mockDockerRunner{
+		composeServices: func() ([]string, error) {
+			return []string{"calibre", "immich", "immich-db"}, nil
+		},
+		composeStart: func(services []string) error {
+			started = append(started, services...)
+			return nil
+		},
+		composeStop: func(services []string) error {
+			stopped = append(stopped, services...)
+			return nil
+		},
+	}
+
+	err := startContainersForBackup(runner, []string{"calibre"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"immich", "immich-db"}, started); diff != "" {
+		t.Errorf("started services mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"calibre"}, stopped); diff != "" {
+		t.Errorf("stopped services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStartContainersForBackup_StopFailure(t *testing.T) {
+	stopErr := errors.New("stop failed")
+	startCalled := false
+	runner := &mockDockerRunner{
+		composeServices: func() ([]string, error) {
+			return []string{"calibre", "immich"}, nil
+		},
+		composeStart: func(services []string) error {
+			startCalled = true
+			return nil
+		},
+		composeStop: func(services []string) error {
+			return stopErr
+		},
+	}
+
+	err := startContainersForBackup(runner, []string{"calibre"})
+
+	if !errors.Is(err, stopErr) {
+		t.Errorf("expected the stop error, got: %v", err)
+	}
+	if startCalled {
+		t.Error("expected no container to be started")
+	}
+}
+
+func TestRestartDisabledServices(t *testing.T) {
+	var started []string
+	runner := &mockDockerRunner{
+		composeStart: func(services []string) error {
+			started = append(started, services...)
+			return nil
+		},
+	}
+
+	err := restartDisabledServices(runner, []string{"calibre"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"calibre"}, started); diff != "" {
+		t.Errorf("started services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalBackupDisabledServices(t *testing.T) {
+	env := &mockEnv{vars: localBackupTestVars}
+
+	services, err := localBackupDisabledServices(env)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"calibre"}, services); diff != "" {
+		t.Errorf("disabled services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildLocalBackupServiceOperations_GroupsOperationsByService(t *testing.T) {
+	env := &mockEnv{vars: localBackupTestVars}
+	for _, service := range localBackupServices {
+		operations, err := buildLocalBackupServiceOperations(env, "/backup", service, localBackupOptions{})
+
+		if err != nil {
+			t.Fatalf("expected no error for %q, got: %v", service.Name, err)
+		}
+		for _, operation := range operations {
+			grouper, ok := operation.(backup.ExclusionGrouper)
+			if !ok {
+				t.Fatalf("expected the operations of %q to have an exclusion group", service.Name)
+			}
+			if grouper.ExclusionGroup() != service.Name {
+				t.Errorf("expected exclusion group %q, got %q", service.Name, grouper.ExclusionGroup())
+			}
+		}
+	}
+}
+
+func TestLoadLocalBackupOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		expected localBackupOptions
+	}{
+		{name: "unset", vars: map[string]string{}, expected: localBackupOptions{}},
+		{name: "incremental", vars: map[string]string{"HOMELAB_LOCAL_INCREMENTAL": "true"}, expected: localBackupOptions{incremental: true}},
+		{name: "not incremental", vars: map[string]string{"HOMELAB_LOCAL_INCREMENTAL": "false"}, expected: localBackupOptions{}},
+		{name: "skip unreadable", vars: map[string]string{"HOMELAB_LOCAL_SKIP_UNREADABLE": "true"}, expected: localBackupOptions{skipUnreadable: true}},
+		{name: "follow symlinks", vars: map[string]string{"HOMELAB_LOCAL_FOLLOW_SYMLINKS": "true"}, expected: localBackupOptions{followSymlinks: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := loadLocalBackupOptions(&mockEnv{vars: tt.vars})
+
+			if options != tt.expected {
+				t.Errorf("expected options %+v, got %+v", tt.expected, options)
+			}
+		})
+	}
+}
+
+func TestPrepareLocalBackupDir_EmptiesDir(t *testing.T) {
+	var emptiedPath string
+	files := &mockFiles{
+		emptyDir: func(path string) error {
+			emptiedPath = path
+			return nil
+		},
+	}
+
+	err := prepareLocalBackupDir(files, "/backup", localBackupOptions{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if emptiedPath != "/backup" {
+		t.Errorf("expected %q to be emptied, got %q", "/backup", emptiedPath)
+	}
+}
+
+func TestPrepareLocalBackupDir_IncrementalKeepsPreviousBackup(t *testing.T) {
+	emptyDirCalled := false
+	files := &mockFiles{
+		emptyDir: func(path string) error {
+			emptyDirCalled = true
+			return nil
+		},
+	}
+
+	err := prepareLocalBackupDir(files, "/backup", localBackupOptions{incremental: true})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if emptyDirCalled {
+		t.Error("expected the previous incremental backup not to be emptied")
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/dotenv"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/spf13/cobra"
+)
+
+var errDuplicateDotenvKeys = errors.New("duplicate keys in .env")
+
+func init() {
+	rootCmd.AddCommand(configCheckCmd)
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "config-check",
+	Short: "Check that all the variables used by docker-compose.yml are defined",
+	Long:  "Parses docker-compose.yml looking for ${VAR} references and checks that each of them is defined in the environment (typically, in .env). All the missing variables are reported at once. The .env file, or the last file of --env-file, is also checked for keys that are assigned more than once.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := system.NewDefaultEnv()
+		return errors.Join(
+			checkComposeVars(env, "docker-compose.yml"),
+			checkDotenvDuplicates(dotenvPath()),
+		)
+	},
+}
+
+// checkComposeVars checks that all the variables referenced by a docker compose file are defined
+func checkComposeVars(env system.Env, composeFilePath string) error {
+	slog.Info("Checking docker compose variables...", "file", composeFilePath)
+	data, err := os.ReadFile(composeFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", system.ErrRequiredFileNotFound, composeFilePath)
+	} else if err != nil {
+		return fmt.Errorf("failed to read %q: %w", composeFilePath, err)
+	}
+
+	missing := docker.FindMissingComposeVars(env, string(data))
+	if len(missing) > 0 {
+		return fmt.Errorf("%w (%d variables): %s", docker.ErrComposeVarsMissing, len(missing), strings.Join(missing, ", "))
+	}
+
+	slog.Info("All docker compose variables are defined")
+	return nil
+}
+
+// checkDotenvDuplicates checks that no key of a .env file is assigned more than once. Viper silently keeps one of the
+// values of a duplicate key, so the file may not mean what it seems to
+func checkDotenvDuplicates(dotenvPath string) error {
+	slog.Info("Checking .env for duplicate keys...", "file", dotenvPath)
+	file, err := os.Open(dotenvPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", system.ErrRequiredFileNotFound, dotenvPath)
+	} else if err != nil {
+		return fmt.Errorf("failed to read %q: %w", dotenvPath, err)
+	}
+	defer file.Close()
+
+	parsed, err := dotenv.Parse(file)
+	if err != nil {
+		return err
+	}
+	if duplicates := parsed.DuplicateKeys(); len(duplicates) > 0 {
+		return fmt.Errorf("%w (%d keys): %s", errDuplicateDotenvKeys, len(duplicates), strings.Join(duplicates, ", "))
+	}
+
+	slog.Info("No duplicate keys in .env", "file", dotenvPath)
+	return nil
+}
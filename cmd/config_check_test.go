@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+func TestCheckDotenvDuplicates_ReportsDuplicateKey(t *testing.T) {
+	dotenvPath := filepath.Join(t.TempDir(), ".env")
+	content := "HOMELAB_GENERAL_DOMAIN=home.example.com\nHOMELAB_GENERAL_TIMEZONE=UTC\nHOMELAB_GENERAL_DOMAIN=other.example.com\n"
+	if err := os.WriteFile(dotenvPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+
+	err := checkDotenvDuplicates(dotenvPath)
+
+	if !errors.Is(err, errDuplicateDotenvKeys) {
+		t.Fatalf("expected errDuplicateDotenvKeys, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "HOMELAB_GENERAL_DOMAIN") {
+		t.Errorf("expected error to contain the duplicate key, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "HOMELAB_GENERAL_TIMEZONE") {
+		t.Errorf("expected error not to contain keys that are not duplicated, got: %v", err)
+	}
+}
+
+func TestCheckDotenvDuplicates_NoDuplicates(t *testing.T) {
+	dotenvPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(dotenvPath, []byte("HOMELAB_GENERAL_DOMAIN=home.example.com\n"), 0o600); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+
+	if err := checkDotenvDuplicates(dotenvPath); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestCheckDotenvDuplicates_MissingFile(t *testing.T) {
+	err := checkDotenvDuplicates(filepath.Join(t.TempDir(), ".env"))
+
+	if !errors.Is(err, system.ErrRequiredFileNotFound) {
+		t.Errorf("expected ErrRequiredFileNotFound, got: %v", err)
+	}
+}
+
+func TestDotenvPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		envFiles []string
+		expected string
+	}{
+		{name: "no env files", envFiles: nil, expected: ".env"},
+		{name: "one env file", envFiles: []string{"base.env"}, expected: "base.env"},
+		{name: "last env file overrides", envFiles: []string{"base.env", "host.env"}, expected: "host.env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := envFiles
+			envFiles = tt.envFiles
+			defer func() { envFiles = previous }()
+
+			if got := dotenvPath(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/spf13/cobra"
+)
+
+// homelabEnvPrefix is the prefix of all the variables that configure the homelab
+const homelabEnvPrefix = "HOMELAB_"
+
+// Formats in which config show prints the configuration
+const (
+	configOutputText = "text"
+	configOutputJSON = "json"
+)
+
+var configShowOutput string
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configShowCmd.Flags().StringVarP(
+		&configShowOutput, "output", "o", configOutputText,
+		"Output format (text, json). The json format is an object that maps each variable to its value",
+	)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+	Long:  "Commands to inspect the configuration of the homelab.",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the configuration loaded from the .env file",
+	Long:  "Prints all the HOMELAB_* variables of the .env file (or of the files of --env-file), which are the values that the commands use, sorted by name. Variables of the process environment are not shown, since the commands don't read them. The values of passwords, secrets, tokens and keys are redacted.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showConfig(os.Stdout, system.NewDefaultEnv(), configShowOutput)
+	},
+}
+
+// showConfig prints the HOMELAB_* variables that have been loaded, redacting sensitive values. The text output has a NAME=value
+// line per variable, sorted by name, while the JSON output is an object that maps each variable to its value
+func showConfig(out io.Writer, env system.Env, output string) error {
+	if output != configOutputText && output != configOutputJSON {
+		return fmt.Errorf("invalid output format: %s (must be %s or %s)", output, configOutputText, configOutputJSON)
+	}
+
+	vars := env.ListByPrefix(homelabEnvPrefix)
+	if output == configOutputJSON {
+		redacted := make(map[string]string, len(vars))
+		for name, value := range vars {
+			redacted[name] = format.RedactValue(name, value)
+		}
+		// Maps are encoded with their keys sorted, so the output is stable
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(redacted)
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if _, err := fmt.Fprintf(out, "%s=%s\n", name, format.RedactValue(name, vars[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
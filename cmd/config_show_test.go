@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestShowConfig_ListsVariablesAndRedactsSecrets(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{
+		"HOMELAB_GENERAL_DOMAIN":            "home.example.com",
+		"HOMELAB_ADGUARD_PASSWORD":          "s3cret",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "",
+		"PATH":                              "/usr/bin",
+	}}
+	var out bytes.Buffer
+
+	err := showConfig(&out, env, configOutputText)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := "HOMELAB_ADGUARD_PASSWORD=<redacted>\n" +
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY=<redacted>\n" +
+		"HOMELAB_BACKUP_RESTIC_PASSWORD=\n" +
+		"HOMELAB_GENERAL_DOMAIN=home.example.com\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestShowConfig_JSONOutput(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{
+		"HOMELAB_GENERAL_DOMAIN":   "home.example.com",
+		"HOMELAB_ADGUARD_PASSWORD": "s3cret",
+		"PATH":                     "/usr/bin",
+	}}
+	var out bytes.Buffer
+
+	err := showConfig(&out, env, configOutputJSON)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var vars map[string]string
+	if err := json.Unmarshal(out.Bytes(), &vars); err != nil {
+		t.Fatalf("expected valid JSON, got %q: %v", out.String(), err)
+	}
+	expected := map[string]string{
+		"HOMELAB_GENERAL_DOMAIN":   "home.example.com",
+		"HOMELAB_ADGUARD_PASSWORD": "<redacted>",
+	}
+	if diff := cmp.Diff(expected, vars); diff != "" {
+		t.Errorf("vars mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestShowConfig_InvalidOutput(t *testing.T) {
+	var out bytes.Buffer
+
+	err := showConfig(&out, &mockEnv{}, "yaml")
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
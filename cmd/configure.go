@@ -1,7 +1,8 @@
 package cmd
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"log/slog"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/config"
@@ -9,36 +10,130 @@ import (
 )
 
 func init() {
+	var diff bool
+	var nonInteractive bool
+	var configPath string
+	var answersPath string
+	var strict bool
+	var allowStdinCommands bool
 	var configureCmd = &cobra.Command{
 		Use:   "configure",
 		Short: "Configure the environment variables for all services",
 		Long:  "This utility configures the environment for all services in this project",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			configurer := config.NewDefaultConfigurer()
-			return configure(configurer)
+			if strict && answersPath == "" {
+				return errors.New("--strict requires --answers")
+			}
+			// In strict mode nothing is prompted, so the values in the config file are always used as defaults
+			configurer := config.NewDefaultConfigurer(nonInteractive || strict).WithStdinCommands(allowStdinCommands)
+			if answersPath != "" {
+				answers, err := configurer.LoadAnswers(answersPath)
+				if err != nil {
+					return fmt.Errorf("failed to load answers: %w", err)
+				}
+				configurer.WithAnswers(answers, strict)
+			}
+			if diff {
+				return configureDiff(configurer, configPath)
+			}
+			return configure(configurer, configPath)
 		},
 	}
+	configureCmd.Flags().BoolVar(
+		&diff, "diff", false,
+		"Show the differences between the current .env and what configure would produce, without writing anything",
+	)
+	configureCmd.Flags().BoolVar(
+		&nonInteractive, "non-interactive", false,
+		"Use the values in the config file as the answers for IP and STRING variables, prompting only when no value is provided",
+	)
+	configureCmd.Flags().StringVar(
+		&configPath, "config", defaultConfigPath,
+		"Path of the config file. Use \"-\" to read it from stdin, together with --non-interactive so that no answers are read from stdin",
+	)
+	configureCmd.Flags().StringVar(
+		&answersPath, "answers", "",
+		"Path of a .env-style file with the values of the variables, which are validated like the prompted values and used instead of prompting. Unknown keys are ignored",
+	)
+	configureCmd.Flags().BoolVar(
+		&strict, "strict", false,
+		"Fail before writing anything if the answers file is missing a variable that would be prompted for. Requires --answers",
+	)
+	configureCmd.Flags().BoolVar(
+		&allowStdinCommands, "allow-stdin-commands", false,
+		"Run the shell commands of the COMMAND variables of a config read from stdin, which are refused otherwise",
+	)
+	var configurePromoteCmd = &cobra.Command{
+		Use:   "promote [generated-file]",
+		Short: "Make a generated .env file the active .env file",
+		Long:  "Replaces the .env file with a file generated by configure, after asking for confirmation. The current .env file is backed up to .env.bak. If no file is given, the most recent generated file is used. With --env-file, the last file of --env-file is replaced instead of .env",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			generatedPath := ""
+			if len(args) == 1 {
+				generatedPath = args[0]
+			}
+			if err := config.NewDotenvPromoter().Promote(generatedPath, dotenvPath()); err != nil {
+				return fmt.Errorf("failed to promote generated .env file: %w", err)
+			}
+			return nil
+		},
+	}
+	configureCmd.AddCommand(configurePromoteCmd)
 	rootCmd.AddCommand(configureCmd)
 }
 
+// defaultConfigPath is the config file that describes the environment variables of all services
+const defaultConfigPath = "files/config/env.config.json"
+
 // configure starts the process of configuring the environment
-func configure(configurer config.Configurer) error {
+func configure(configurer config.Configurer, configPath string) error {
 	slog.Info("Initiating configuration...")
-	configRoot, err := configurer.LoadConfig("files/config/env.config.json")
+	configRoot, err := configurer.LoadConfig(configPath)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return fmt.Errorf("failed to load config: %w", err)
 	}
 
 	envVars, err := configurer.ProcessConfig(configRoot)
 	if err != nil {
-		log.Fatalf("Failed to process config: %v", err)
+		return fmt.Errorf("failed to process config: %w", err)
 	}
 
 	err = configurer.WriteConfig(envVars)
 	if err != nil {
-		log.Fatalf("Failed to write config: %v", err)
+		return fmt.Errorf("failed to write config: %w", err)
 	}
 
 	slog.Info("Configuration finished successfully")
 	return nil
 }
+
+// configureDiff processes the configuration and prints how it differs from the currently loaded .env file
+func configureDiff(configurer config.Configurer, configPath string) error {
+	slog.Info("Computing configuration diff...")
+	configRoot, err := configurer.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	envVars, err := configurer.ProcessConfig(configRoot)
+	if err != nil {
+		return fmt.Errorf("failed to process config: %w", err)
+	}
+
+	diff := configurer.DiffConfig(envVars)
+	if len(diff.Added) == 0 && len(diff.Changed) == 0 && len(diff.Removed) == 0 {
+		fmt.Println("No changes")
+		return nil
+	}
+	for _, change := range diff.Added {
+		fmt.Printf("+ %s=%s\n", change.Name, change.NewValue)
+	}
+	for _, change := range diff.Changed {
+		fmt.Printf("~ %s: %s -> %s\n", change.Name, change.OldValue, change.NewValue)
+	}
+	for _, name := range diff.Removed {
+		fmt.Printf("- %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Formats in which Execute writes the error of a failed command into stderr
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// errorEnvelope is the JSON object written for a failed command when the error format is JSON
+type errorEnvelope struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+// validateErrorFormat checks that format is one of the supported error formats
+func validateErrorFormat(format string) error {
+	switch format {
+	case errorFormatText, errorFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid error format: %s (must be %s or %s)", format, errorFormatText, errorFormatJSON)
+	}
+}
+
+// writeError writes err into w in the given format. The JSON format includes the exit code of the error, so that
+// scripts don't need to read it separately. Any format other than JSON is written as plain text
+func writeError(w io.Writer, err error, format string) {
+	if format != errorFormatJSON {
+		fmt.Fprintln(w, err)
+		return
+	}
+	data, marshalErr := json.Marshal(errorEnvelope{Error: err.Error(), Code: ExitCode(err)})
+	if marshalErr != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintln(w, string(data))
+}
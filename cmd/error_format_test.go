@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteError_JSONEnvelope(t *testing.T) {
+	var out bytes.Buffer
+	err := fmt.Errorf("failed to load config: %w", config.ErrConfigFileParse)
+
+	writeError(&out, err, errorFormatJSON)
+
+	var got errorEnvelope
+	if unmarshalErr := json.Unmarshal(out.Bytes(), &got); unmarshalErr != nil {
+		t.Fatalf("expected valid JSON, got %q: %v", out.String(), unmarshalErr)
+	}
+	expected := errorEnvelope{Error: "failed to load config: failed to parse config file", Code: ExitCodeConfigError}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteError_JSONEscapesMessage(t *testing.T) {
+	var out bytes.Buffer
+
+	writeError(&out, errors.New("bad \"value\"\nsecond line"), errorFormatJSON)
+
+	expected := "{\"error\":\"bad \\\"value\\\"\\nsecond line\",\"code\":1}\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestWriteError_TextIsDefault(t *testing.T) {
+	var out bytes.Buffer
+
+	writeError(&out, errors.New("something failed"), errorFormatText)
+
+	if out.String() != "something failed\n" {
+		t.Errorf("expected plain text error, got %q", out.String())
+	}
+}
+
+func TestValidateErrorFormat(t *testing.T) {
+	for _, format := range []string{errorFormatText, errorFormatJSON} {
+		if err := validateErrorFormat(format); err != nil {
+			t.Errorf("expected format %q to be valid, got %v", format, err)
+		}
+	}
+	if err := validateErrorFormat("xml"); err == nil {
+		t.Error("expected error for unsupported format, got nil")
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/backup"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/config"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+// Exit codes of the process, so that scripts can tell different kinds of failures apart
+const (
+	ExitCodeSuccess           = 0
+	ExitCodeGenericError      = 1
+	ExitCodeMissingDependency = 2
+	ExitCodeConfigError       = 3
+	ExitCodeBackupFailure     = 4
+)
+
+// configErrors are the errors caused by a missing or invalid configuration
+var configErrors = []error{
+	system.ErrRequiredEnvNotFound,
+	system.ErrInvalidIntEnv,
+	system.ErrRequiredFileNotFound,
+	config.ErrConfigFileRead,
+	config.ErrConfigFileParse,
+	config.ErrVarType,
+	config.ErrVarAcquireVal,
+	config.ErrInvalidAnswer,
+	config.ErrConfigFileWrite,
+	config.ErrVarNotFound,
+	config.ErrVarNotRotatable,
+	config.ErrVarNotInDotenv,
+	config.ErrInvalidCharsetPool,
+	config.ErrStdinCommandVar,
+	docker.ErrComposeConfigInvalid,
+	docker.ErrComposeVarsMissing,
+	backup.ErrInvalidResticConfig,
+	backup.ErrInvalidBackupDst,
+	backup.ErrInvalidReadinessTimeout,
+	backup.ErrInvalidDumpFilename,
+}
+
+// missingDependencyErrors are the errors caused by a command that the program needs not being installed
+var missingDependencyErrors = []error{
+	backup.ErrResticNotFound,
+}
+
+// backupErrors are the errors caused by a failure while running a backup operation
+var backupErrors = []error{
+	backup.ErrBackupOperationFailed,
+	backup.ErrMultipleBackupOperationsFailed,
+	backup.ErrResticCommandFailed,
+	backup.ErrBackupHookFailed,
+	backup.ErrContainersNotReady,
+}
+
+// ExitCode maps an error returned by Execute to the exit code the process should finish with
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+	if slices.ContainsFunc(missingDependencyErrors, func(target error) bool { return errors.Is(err, target) }) {
+		return ExitCodeMissingDependency
+	}
+	if slices.ContainsFunc(configErrors, func(target error) bool { return errors.Is(err, target) }) {
+		return ExitCodeConfigError
+	}
+	if slices.ContainsFunc(backupErrors, func(target error) bool { return errors.Is(err, target) }) {
+		return ExitCodeBackupFailure
+	}
+	return ExitCodeGenericError
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/backup"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/config"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+func TestExitCode_NilError(t *testing.T) {
+	code := ExitCode(nil)
+
+	if code != ExitCodeSuccess {
+		t.Errorf("expected exit code %d, got %d", ExitCodeSuccess, code)
+	}
+}
+
+func TestExitCode_MissingEnvIsConfigError(t *testing.T) {
+	err := fmt.Errorf("failed to get backup path: %w", fmt.Errorf("%w: %q", system.ErrRequiredEnvNotFound, "HOMELAB_BACKUP_PATH"))
+
+	code := ExitCode(err)
+
+	if code != ExitCodeConfigError {
+		t.Errorf("expected exit code %d, got %d", ExitCodeConfigError, code)
+	}
+}
+
+func TestExitCode_ConfigFileParseIsConfigError(t *testing.T) {
+	err := fmt.Errorf("failed to load config: %w", config.ErrConfigFileParse)
+
+	code := ExitCode(err)
+
+	if code != ExitCodeConfigError {
+		t.Errorf("expected exit code %d, got %d", ExitCodeConfigError, code)
+	}
+}
+
+func TestExitCode_BackupOperationFailedIsBackupFailure(t *testing.T) {
+	err := fmt.Errorf("failed running backup operations: %w", fmt.Errorf("%w: %w", backup.ErrBackupOperationFailed, errors.New("disk full")))
+
+	code := ExitCode(err)
+
+	if code != ExitCodeBackupFailure {
+		t.Errorf("expected exit code %d, got %d", ExitCodeBackupFailure, code)
+	}
+}
+
+func TestExitCode_ResticCommandFailedIsBackupFailure(t *testing.T) {
+	err := fmt.Errorf("failed to create backup: %w", backup.ErrResticCommandFailed)
+
+	code := ExitCode(err)
+
+	if code != ExitCodeBackupFailure {
+		t.Errorf("expected exit code %d, got %d", ExitCodeBackupFailure, code)
+	}
+}
+
+func TestExitCode_UnknownErrorIsGenericError(t *testing.T) {
+	err := errors.New("something unexpected")
+
+	code := ExitCode(err)
+
+	if code != ExitCodeGenericError {
+		t.Errorf("expected exit code %d, got %d", ExitCodeGenericError, code)
+	}
+}
+
+func TestExitCode_ResticNotFoundIsMissingDependency(t *testing.T) {
+	err := fmt.Errorf("%w %q: %w", backup.ErrResticNotFound, "restic_0.16", errors.New("executable file not found in $PATH"))
+
+	code := ExitCode(err)
+
+	if code != ExitCodeMissingDependency {
+		t.Errorf("expected exit code %d, got %d", ExitCodeMissingDependency, code)
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsTail   int
+	logsFollow bool
+)
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().IntVar(
+		&logsTail, "tail", 100,
+		"Number of lines to show from the end of the logs. Use 0 to show all lines",
+	)
+	logsCmd.Flags().BoolVarP(
+		&logsFollow, "follow", "f", false,
+		"Follow the log output until interrupted with Ctrl-C",
+	)
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [service]",
+	Short: "Show the logs of a service (or all services if none specified)",
+	Long:  "Shows the logs of a service in your homelab. This is useful to diagnose, for example, why a database did not become ready during a backup.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Ctrl-C cancels the context, which stops the docker compose command cleanly
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		dockerRunner := newDockerRunner()
+		service := ""
+		if len(args) == 1 {
+			service = args[0]
+		}
+		return showLogs(ctx, dockerRunner, service, logsTail, logsFollow)
+	},
+}
+
+// showLogs shows the logs of a service by using docker compose.
+// If the service is empty, shows the logs of all services
+func showLogs(ctx context.Context, dockerRunner docker.Runner, service string, tail int, follow bool) error {
+	if service == "" {
+		slog.Info("Showing logs of all services", "tail", tail, "follow", follow)
+	} else {
+		slog.Info("Showing logs of service", "service", service, "tail", tail, "follow", follow)
+	}
+
+	err := dockerRunner.ContainerLogs(ctx, service, tail, follow)
+	if err != nil && ctx.Err() != nil {
+		// The user interrupted the command, which is the expected way of stopping to follow the logs
+		slog.Info("Stopped showing logs")
+		return nil
+	}
+	return err
+}
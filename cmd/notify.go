@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/notify"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+}
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Manage notifications",
+	Long:  "Commands to manage the notifications sent to the webhook configured in HOMELAB_NOTIFY_WEBHOOK_URL, in the format chosen with HOMELAB_NOTIFY_FORMAT (webhook, ntfy, discord). When the webhook is configured, the local and cloud backups notify whether they completed or failed.",
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test notification",
+	Long:  "Sends a sample notification to the webhook configured in HOMELAB_NOTIFY_WEBHOOK_URL, to check that it is received before relying on it.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendTestNotification(system.NewDefaultEnv())
+	},
+}
+
+// sendTestNotification sends the test event to the webhook configured in env
+func sendTestNotification(env system.Env) error {
+	notifier, err := notify.LoadWebhookNotifier(env)
+	if err != nil {
+		return err
+	}
+	if err := notifier.Send(notify.TestEvent()); err != nil {
+		return err
+	}
+	slog.Info("Test notification sent")
+	return nil
+}
+
+// notifyBackupResult sends the result of a backup to the webhook configured in env. Notifications are optional, so
+// nothing is sent when no webhook is configured, and failing to send one is only logged, so that it never changes the
+// result of the backup
+func notifyBackupResult(env system.Env, kind string, backupErr error) {
+	notifier, err := notify.LoadWebhookNotifier(env)
+	if errors.Is(err, notify.ErrNotifyEndpointNotSet) {
+		slog.Debug("Not sending the backup notification, because no webhook is configured", "backup", kind)
+		return
+	}
+	if err != nil {
+		slog.Warn("Not sending the backup notification", "backup", kind, "error", err)
+		return
+	}
+	if err := notifier.Send(notify.BackupEvent(kind, backupErr)); err != nil {
+		slog.Warn("Failed to send the backup notification", "backup", kind, "error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/notify"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSendTestNotification_SendsTestEvent(t *testing.T) {
+	var received []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("expected a JSON body, got: %v", err)
+		}
+		received = append(received, event)
+	}))
+	defer server.Close()
+	env := &mockEnv{vars: map[string]string{"HOMELAB_NOTIFY_WEBHOOK_URL": server.URL}}
+
+	err := sendTestNotification(env)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]notify.Event{notify.TestEvent()}, received); diff != "" {
+		t.Errorf("received events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSendTestNotification_EndpointNotSet(t *testing.T) {
+	for _, vars := range []map[string]string{{}, {"HOMELAB_NOTIFY_WEBHOOK_URL": " "}} {
+		err := sendTestNotification(&mockEnv{vars: vars})
+
+		if !errors.Is(err, notify.ErrNotifyEndpointNotSet) {
+			t.Errorf("expected ErrNotifyEndpointNotSet, got: %v", err)
+		}
+	}
+}
+
+func TestSendTestNotification_InvalidFormat(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{
+		"HOMELAB_NOTIFY_WEBHOOK_URL": "http://localhost:1",
+		"HOMELAB_NOTIFY_FORMAT":      "slack",
+	}}
+
+	err := sendTestNotification(env)
+
+	if !errors.Is(err, notify.ErrInvalidNotifyFormat) {
+		t.Errorf("expected ErrInvalidNotifyFormat, got: %v", err)
+	}
+}
+
+func TestNotifyBackupResult_SendsBackupEvent(t *testing.T) {
+	var received []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("expected a JSON body, got: %v", err)
+		}
+		received = append(received, event)
+	}))
+	defer server.Close()
+	env := &mockEnv{vars: map[string]string{"HOMELAB_NOTIFY_WEBHOOK_URL": server.URL}}
+	backupErr := errors.New("failed running backup operations")
+
+	notifyBackupResult(env, "local", backupErr)
+
+	if diff := cmp.Diff([]notify.Event{notify.BackupEvent("local", backupErr)}, received); diff != "" {
+		t.Errorf("received events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNotifyBackupResult_IgnoresNotificationErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	// Nothing is returned, so the backup result is never replaced by the notification error
+	notifyBackupResult(&mockEnv{vars: map[string]string{"HOMELAB_NOTIFY_WEBHOOK_URL": server.URL}}, "cloud", nil)
+	notifyBackupResult(&mockEnv{}, "cloud", nil)
+}
+
+func TestRunBackupLocal_NotifiesFailure(t *testing.T) {
+	var received []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("expected a JSON body, got: %v", err)
+		}
+		received = append(received, event)
+	}))
+	defer server.Close()
+	env := &mockEnv{vars: map[string]string{"HOMELAB_NOTIFY_WEBHOOK_URL": server.URL}}
+
+	err := runBackupLocal(&mockFiles{}, env)
+
+	if err == nil {
+		t.Fatal("expected the backup to fail without its configuration")
+	}
+	if len(received) != 1 || received[0].Success || received[0].Message != err.Error() {
+		t.Errorf("expected a failure notification with the backup error, got: %+v", received)
+	}
+}
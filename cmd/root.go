@@ -2,14 +2,24 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/dotenv"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 	"github.com/spf13/cobra"
 )
 
 var (
-	logLevel string
+	logLevel      string
+	requiredFiles []string
+	errorFormat   string
+	logFile       string
+	envFiles      []string
+	// logWriters are the writers that the logs are written to besides stdout, such as the file of --log-file
+	logWriters []io.Writer
 )
 
 var rootCmd = &cobra.Command{
@@ -18,17 +28,38 @@ var rootCmd = &cobra.Command{
 	Long:  "A Cobra CLI app for starting services, managing backups, and restoring instances in your homelab.",
 	// Tell Cobra to NOT show the usage/help text after an error
 	SilenceUsage: true,
+	// Execute writes the error itself, in the format chosen with --error-format
+	SilenceErrors: true,
 	CompletionOptions: cobra.CompletionOptions{
 		// See https://github.com/spf13/cobra/issues/1507
 		HiddenDefaultCmd: true,
 	},
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		return initLogger(logLevel)
+		if err := validateErrorFormat(errorFormat); err != nil {
+			return err
+		}
+		if logFile != "" {
+			file, err := openLogFile(logFile)
+			if err != nil {
+				return err
+			}
+			logWriters = append(logWriters, file)
+		}
+		if err := initLogger(logLevel, logWriters...); err != nil {
+			return err
+		}
+		if len(envFiles) > 0 {
+			dotenv.LoadDotEnvFiles(envFiles...)
+			if len(dotenv.LoadedFiles()) == 0 {
+				return fmt.Errorf("%w: none of the files of --env-file exist", system.ErrRequiredFileNotFound)
+			}
+		}
+		return nil
 	},
 }
 
-// initLogger initializes the global logger with the specified level
-func initLogger(level string) error {
+// initLogger initializes the global logger with the specified level. The logs are written to stdout and to writers
+func initLogger(level string, writers ...io.Writer) error {
 	var logLevelVar slog.Level
 
 	switch level {
@@ -48,22 +79,70 @@ func initLogger(level string) error {
 		Level: logLevelVar,
 	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, opts))
+	output := io.MultiWriter(append([]io.Writer{os.Stdout}, writers...)...)
+	logger := slog.New(slog.NewTextHandler(output, opts))
 	slog.SetDefault(logger)
 
 	return nil
 }
 
+// openLogFile opens a file to append logs to, creating it if it does not exist
+func openLogFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file %q: %w", path, err)
+	}
+	return file, nil
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVar(
 		&logLevel, "log-level", "info",
 		"Set the logging level (debug, info, warn, error)",
 	)
+	rootCmd.PersistentFlags().StringVar(
+		&logFile, "log-file", "",
+		"Also append the logs to this file",
+	)
+	rootCmd.PersistentFlags().StringSliceVar(
+		&envFiles, "env-file", []string{},
+		"Load the variables from this .env file instead of the default .env file, also when running docker compose (can be repeated, later files override earlier ones, missing files are skipped)",
+	)
+	rootCmd.PersistentFlags().StringSliceVar(
+		&requiredFiles, "require-file", []string{},
+		"Additional file that must exist in the working directory before running docker compose, such as files referenced by env_file directives (can be repeated)",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&errorFormat, "error-format", errorFormatText,
+		"Format of the error written to stderr when a command fails (text, json). The json format is {\"error\": \"...\", \"code\": N}",
+	)
+}
+
+// defaultDotenvPath is the .env file that the commands read and write when no --env-file is given
+const defaultDotenvPath = ".env"
+
+// dotenvPath returns the .env file that the commands that modify or check it work on, which is the last file of
+// --env-file, since its values override the ones of the others, or the default .env file
+func dotenvPath() string {
+	if len(envFiles) > 0 {
+		return envFiles[len(envFiles)-1]
+	}
+	return defaultDotenvPath
+}
+
+// newDockerRunner creates the Docker runner used by all commands
+func newDockerRunner() *docker.SystemRunner {
+	runner := docker.NewSystemRunner().WithRequiredFiles(requiredFiles...)
+	if len(envFiles) > 0 {
+		// docker compose must interpolate the same variables that were loaded from the files of --env-file
+		runner.WithEnvFiles(dotenv.LoadedFiles()...)
+	}
+	return runner
 }
 
 func Execute() error {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		writeError(os.Stderr, err, errorFormat)
 		return err
 	}
 	return nil
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rotateRestartServices []string
+)
+
+func init() {
+	rootCmd.AddCommand(rotateCmd)
+	rotateCmd.Flags().StringSliceVar(
+		&rotateRestartServices, "restart", []string{},
+		"Services to restart after rotating the secret, so that they pick up the new value. Asks for confirmation first",
+	)
+}
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate [variable]",
+	Short: "Rotate a generated secret",
+	Long:  "Generates a new value for a GENERATED variable (for example, HOMELAB_ADGUARD_PASSWORD) and updates it in the .env file, or in the last file of --env-file. Optionally restarts the services that use it.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configurer := config.NewDefaultConfigurer(false)
+		rotator := config.NewSecretRotator(newDockerRunner())
+		return rotateSecret(configurer, rotator, args[0], rotateRestartServices)
+	},
+}
+
+// rotateSecret rotates the secret stored in the varName variable of the .env file of dotenvPath
+func rotateSecret(configurer config.Configurer, rotator *config.SecretRotator, varName string, services []string) error {
+	slog.Info("Rotating secret", "varName", varName)
+	configRoot, err := configurer.LoadConfig(defaultConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := rotator.Rotate(configRoot, varName, dotenvPath(), services); err != nil {
+		return fmt.Errorf("failed to rotate secret: %w", err)
+	}
+
+	slog.Info("Secret rotated successfully", "varName", varName)
+	return nil
+}
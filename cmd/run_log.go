@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+// backupLogPathEnvVar is the environment variable with the directory of the log files of the backup runs. When it is
+// not set, they are written next to the path that the run backs up, because the local backup path is emptied by the
+// local backup
+const backupLogPathEnvVar = "HOMELAB_BACKUP_LOG_PATH"
+
+// runLogTimestampLayout is the layout of the time in the names of the log files, which sorts them by date
+const runLogTimestampLayout = "2006-01-02_15-04-05"
+
+// runLog is the log file of a single run of a backup, named after the time it started. While it is open, the logs
+// are written to it too, and it ends with a summary of the run
+type runLog struct {
+	name  string
+	path  string
+	file  io.WriteCloser
+	clock system.Time
+	start time.Time
+}
+
+// startRunLog creates the log file of a backup run named name, such as "backup-local", and starts writing the logs
+// to it. backupPathEnvVar is the environment variable with the path that the run backs up
+func startRunLog(env system.Env, files system.FilesHandler, clock system.Time, name string, backupPathEnvVar string) (*runLog, error) {
+	logDir, err := backupLogDir(env, backupPathEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	if err := files.CreateDirIfNotExists(logDir); err != nil {
+		return nil, err
+	}
+
+	start := clock.Now()
+	path := filepath.Join(logDir, fmt.Sprintf("%s-%s.log", name, start.Format(runLogTimestampLayout)))
+	file, err := files.CreateNewFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file: %w", err)
+	}
+	if err := initLogger(logLevel, append(logWriters, file)...); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	slog.Info("Writing the logs of the run", "path", path)
+	return &runLog{name: name, path: path, file: file, clock: clock, start: start}, nil
+}
+
+// backupLogDir returns the directory of the log files of the backup runs, which defaults to a directory next to the
+// value of backupPathEnvVar
+func backupLogDir(env system.Env, backupPathEnvVar string) (string, error) {
+	if logDir, exists := env.GetEnv(backupLogPathEnvVar); exists && strings.TrimSpace(logDir) != "" {
+		return strings.TrimSpace(logDir), nil
+	}
+	backupDir, err := env.GetRequiredEnv(backupPathEnvVar)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the directory of the log files, set %s: %w", backupLogPathEnvVar, err)
+	}
+	return filepath.Clean(backupDir) + "-logs", nil
+}
+
+// Finish writes the summary of the run, whose result is runErr, and closes the log file. The logs are no longer
+// written to it. It returns runErr, joined with the error of closing the file if any
+func (r *runLog) Finish(runErr error) error {
+	if err := initLogger(logLevel, logWriters...); err != nil {
+		return errors.Join(runErr, err)
+	}
+
+	// The summary is written to the file regardless of the log level, so that every run can be audited
+	fileLogger := slog.New(slog.NewTextHandler(r.file, nil))
+	duration := r.clock.Now().Sub(r.start).Round(time.Second)
+	if runErr != nil {
+		slog.Error("Backup run failed", "run", r.name, "duration", duration, "error", runErr)
+		fileLogger.Error("Backup run failed", "run", r.name, "duration", duration, "error", runErr)
+	} else {
+		slog.Info("Backup run succeeded", "run", r.name, "duration", duration, "logFile", r.path)
+		fileLogger.Info("Backup run succeeded", "run", r.name, "duration", duration)
+	}
+
+	if err := r.file.Close(); err != nil {
+		return errors.Join(runErr, fmt.Errorf("failed to close log file %q: %w", r.path, err))
+	}
+	return runErr
+}
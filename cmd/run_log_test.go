@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/backup"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+// newRunLogClock returns a clock that starts at a fixed time and advances a minute each time it is read
+func newRunLogClock() *mockTime {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &mockTime{now: func() time.Time {
+		current := now
+		now = now.Add(time.Minute)
+		return current
+	}}
+}
+
+func TestRunLog_WritesLogsAndSummary(t *testing.T) {
+	logDir := t.TempDir()
+	env := &mockEnv{vars: map[string]string{backupLogPathEnvVar: logDir}}
+	t.Cleanup(func() { _ = initLogger("info") })
+
+	runLog, err := startRunLog(env, system.NewDefaultFilesHandler(), newRunLogClock(), "backup-local", localBackupPathEnvVar)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	slog.Info("Copying directory")
+	err = runLog.Finish(nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(logDir, "backup-local-2025-01-02_03-04-05.log"))
+	if err != nil {
+		t.Fatalf("expected the log file to be created, got: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if !strings.Contains(string(content), "Copying directory") {
+		t.Errorf("expected the logs of the run in the file, got:\n%s", content)
+	}
+	lastLine := lines[len(lines)-1]
+	if !strings.Contains(lastLine, `msg="Backup run succeeded"`) || !strings.Contains(lastLine, "duration=1m0s") {
+		t.Errorf("expected the summary as the last line, got: %s", lastLine)
+	}
+}
+
+func TestRunLog_SummaryOfFailedRun(t *testing.T) {
+	logDir := t.TempDir()
+	env := &mockEnv{vars: map[string]string{backupLogPathEnvVar: logDir}}
+	t.Cleanup(func() { _ = initLogger("info") })
+	runErr := errors.New("restic command failed")
+
+	runLog, err := startRunLog(env, system.NewDefaultFilesHandler(), newRunLogClock(), "backup-cloud", backup.BackupPathEnvVar(""))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	err = runLog.Finish(runErr)
+
+	if !errors.Is(err, runErr) {
+		t.Fatalf("expected the error of the run, got: %v", err)
+	}
+	content, err := os.ReadFile(runLog.path)
+	if err != nil {
+		t.Fatalf("expected the log file to be created, got: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	lastLine := lines[len(lines)-1]
+	if !strings.Contains(lastLine, "level=ERROR") || !strings.Contains(lastLine, `error="restic command failed"`) {
+		t.Errorf("expected the failure summary as the last line, got: %s", lastLine)
+	}
+}
+
+func TestRunLog_StopsWritingAfterFinish(t *testing.T) {
+	logDir := t.TempDir()
+	env := &mockEnv{vars: map[string]string{backupLogPathEnvVar: logDir}}
+	t.Cleanup(func() { _ = initLogger("info") })
+
+	runLog, err := startRunLog(env, system.NewDefaultFilesHandler(), newRunLogClock(), "backup-local", localBackupPathEnvVar)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := runLog.Finish(nil); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	slog.Info("Logged after the run")
+
+	content, err := os.ReadFile(runLog.path)
+	if err != nil {
+		t.Fatalf("expected the log file to be created, got: %v", err)
+	}
+	if strings.Contains(string(content), "Logged after the run") {
+		t.Errorf("expected no logs after the summary, got:\n%s", content)
+	}
+}
+
+func TestBackupLogDir_DefaultsNextToBackupPath(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{localBackupPathEnvVar: "/mnt/backup/"}}
+
+	logDir, err := backupLogDir(env, localBackupPathEnvVar)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if logDir != "/mnt/backup-logs" {
+		t.Errorf("expected %q, got %q", "/mnt/backup-logs", logDir)
+	}
+}
+
+func TestBackupLogDir_DefaultsNextToProfilePath(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{"HOMELAB_BACKUP_OFFSITE_PATH": "/mnt/offsite"}}
+
+	logDir, err := backupLogDir(env, backup.BackupPathEnvVar("offsite"))
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if logDir != "/mnt/offsite-logs" {
+		t.Errorf("expected %q, got %q", "/mnt/offsite-logs", logDir)
+	}
+}
+
+func TestBackupLogDir_LogPathIsEnough(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{backupLogPathEnvVar: "/var/log/homelab"}}
+
+	logDir, err := backupLogDir(env, backup.BackupPathEnvVar("offsite"))
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if logDir != "/var/log/homelab" {
+		t.Errorf("expected %q, got %q", "/var/log/homelab", logDir)
+	}
+}
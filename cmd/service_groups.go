@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+// serviceGroupEnvVarPrefix is the prefix of the variables that define service groups. For example,
+// HOMELAB_SERVICE_GROUP_MEDIA="jellyfin radarr sonarr" defines the group "media"
+const serviceGroupEnvVarPrefix = "HOMELAB_SERVICE_GROUP_"
+
+var errUnknownServiceOrGroup = errors.New("unknown service or service group")
+
+// serviceGroups maps the name of each service group to its member services
+type serviceGroups map[string][]string
+
+// loadServiceGroups reads the service groups defined in the environment. The members of a group are separated by
+// commas or whitespace. Group names are case-insensitive
+func loadServiceGroups(env system.Env) serviceGroups {
+	groups := serviceGroups{}
+	for varName, value := range env.GetAllEnv() {
+		name, found := strings.CutPrefix(strings.ToUpper(varName), serviceGroupEnvVarPrefix)
+		if !found || name == "" {
+			continue
+		}
+		members := strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+		if len(members) == 0 {
+			slog.Warn("Ignoring service group without services", "varName", varName)
+			continue
+		}
+		groups[strings.ToLower(name)] = members
+	}
+	return groups
+}
+
+// resolveServices expands the service groups in names into their member services, keeping their order and removing
+// duplicates. When there are service groups, every name that is not a group must be a service of the docker compose
+// file, so that a mistyped group is reported instead of being passed to docker compose. No names means all services
+func resolveServices(dockerRunner docker.Runner, groups serviceGroups, names []string) ([]string, error) {
+	if len(groups) == 0 || len(names) == 0 {
+		return names, nil
+	}
+
+	var composeServices []string
+	composeServicesLoaded := false
+	var resolved []string
+	for _, name := range names {
+		members, isGroup := groups[strings.ToLower(name)]
+		if !isGroup {
+			if !composeServicesLoaded {
+				var err error
+				composeServices, err = dockerRunner.ComposeServices()
+				if err != nil {
+					return nil, fmt.Errorf("failed to get the services of the docker compose file: %w", err)
+				}
+				composeServicesLoaded = true
+			}
+			if !slices.Contains(composeServices, name) {
+				return nil, fmt.Errorf("%w: %q", errUnknownServiceOrGroup, name)
+			}
+			members = []string{name}
+		}
+		for _, member := range members {
+			if !slices.Contains(resolved, member) {
+				resolved = append(resolved, member)
+			}
+		}
+	}
+
+	return resolved, nil
+}
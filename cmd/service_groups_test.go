@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadServiceGroups(t *testing.T) {
+	env := &mockEnv{vars: map[string]string{
+		"HOMELAB_SERVICE_GROUP_MEDIA":   "jellyfin, radarr  sonarr",
+		"HOMELAB_SERVICE_GROUP_DOCS":    "paperless",
+		"HOMELAB_SERVICE_GROUP_EMPTY":   " , ",
+		"HOMELAB_SERVICE_GROUP_":        "immich",
+		"HOMELAB_GENERAL_SERVER_IP":     "192.168.1.2",
+		"HOMELAB_SERVICE_GROUPS_IGNORE": "calibre",
+	}}
+
+	groups := loadServiceGroups(env)
+
+	expected := serviceGroups{
+		"media": {"jellyfin", "radarr", "sonarr"},
+		"docs":  {"paperless"},
+	}
+	if diff := cmp.Diff(expected, groups); diff != "" {
+		t.Errorf("groups mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveServices(t *testing.T) {
+	groups := serviceGroups{
+		"media":  {"jellyfin", "radarr"},
+		"movies": {"radarr"},
+	}
+	tests := []struct {
+		name     string
+		groups   serviceGroups
+		names    []string
+		expected []string
+	}{
+		{name: "no names means all services", groups: groups, names: nil, expected: nil},
+		{name: "no groups keeps names", groups: nil, names: []string{"whatever"}, expected: []string{"whatever"}},
+		{name: "group is expanded", groups: groups, names: []string{"media"}, expected: []string{"jellyfin", "radarr"}},
+		{name: "group name is case-insensitive", groups: groups, names: []string{"MEDIA"}, expected: []string{"jellyfin", "radarr"}},
+		{
+			name:     "groups and services are mixed without duplicates",
+			groups:   groups,
+			names:    []string{"immich", "media", "movies", "jellyfin"},
+			expected: []string{"immich", "jellyfin", "radarr"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dockerRunner := &mockDockerRunner{
+				composeServices: func() ([]string, error) { return []string{"immich", "jellyfin", "radarr"}, nil },
+			}
+
+			services, err := resolveServices(dockerRunner, tc.groups, tc.names)
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, services); diff != "" {
+				t.Errorf("services mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveServices_UnknownGroup(t *testing.T) {
+	dockerRunner := &mockDockerRunner{
+		composeServices: func() ([]string, error) { return []string{"immich"}, nil },
+	}
+
+	_, err := resolveServices(dockerRunner, serviceGroups{"media": {"jellyfin"}}, []string{"media", "medai"})
+
+	if !errors.Is(err, errUnknownServiceOrGroup) {
+		t.Errorf("expected errUnknownServiceOrGroup, got: %v", err)
+	}
+}
+
+func TestResolveServices_ComposeServicesError(t *testing.T) {
+	expectedErr := errors.New("config failed")
+	dockerRunner := &mockDockerRunner{
+		composeServices: func() ([]string, error) { return nil, expectedErr },
+	}
+
+	_, err := resolveServices(dockerRunner, serviceGroups{"media": {"jellyfin"}}, []string{"immich"})
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
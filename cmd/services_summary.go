@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+)
+
+// stateNotCreated is shown for the requested services that have no container
+const stateNotCreated = "not created"
+
+// printServicesSummary prints the state of the services after an operation on them. If no service is provided, the
+// state of all services is printed. Failing to get the state is only logged, because the operation itself succeeded
+func printServicesSummary(out io.Writer, dockerRunner docker.Runner, services []string) {
+	statuses, err := dockerRunner.ComposePs(services)
+	if err != nil {
+		slog.Warn("Could not get the state of the services", "error", err)
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tSTATE")
+	for _, status := range buildServicesSummary(statuses, services) {
+		fmt.Fprintf(w, "%s\t%s\n", status.Service, status.State)
+	}
+	w.Flush()
+}
+
+// buildServicesSummary returns the statuses in the summary. Every requested service is included, even if docker
+// compose did not report a container for it
+func buildServicesSummary(statuses []docker.ServiceStatus, services []string) []docker.ServiceStatus {
+	summary := append([]docker.ServiceStatus{}, statuses...)
+	for _, service := range services {
+		found := slices.ContainsFunc(statuses, func(status docker.ServiceStatus) bool {
+			return status.Service == service
+		})
+		if !found {
+			summary = append(summary, docker.ServiceStatus{Service: service, State: stateNotCreated})
+		}
+	}
+	return summary
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrintServicesSummary_ReflectsRequestedServices(t *testing.T) {
+	var capturedServices []string
+	dockerRunner := &mockDockerRunner{
+		composePs: func(services []string) ([]docker.ServiceStatus, error) {
+			capturedServices = services
+			return []docker.ServiceStatus{
+				{Service: "immich", State: "running"},
+			}, nil
+		},
+	}
+	out := &bytes.Buffer{}
+
+	printServicesSummary(out, dockerRunner, []string{"immich", "paperless"})
+
+	if diff := cmp.Diff([]string{"immich", "paperless"}, capturedServices); diff != "" {
+		t.Errorf("requested services mismatch (-want +got):\n%s", diff)
+	}
+	expected := "SERVICE    STATE\n" +
+		"immich     running\n" +
+		"paperless  not created\n"
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Errorf("summary mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrintServicesSummary_AllServices(t *testing.T) {
+	dockerRunner := &mockDockerRunner{
+		composePs: func(services []string) ([]docker.ServiceStatus, error) {
+			return []docker.ServiceStatus{
+				{Service: "immich", State: "exited"},
+				{Service: "paperless", State: "exited"},
+			}, nil
+		},
+	}
+	out := &bytes.Buffer{}
+
+	printServicesSummary(out, dockerRunner, []string{})
+
+	expected := "SERVICE    STATE\n" +
+		"immich     exited\n" +
+		"paperless  exited\n"
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Errorf("summary mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrintServicesSummary_PsFails(t *testing.T) {
+	dockerRunner := &mockDockerRunner{
+		composePs: func(services []string) ([]docker.ServiceStatus, error) {
+			return nil, errors.New("ps failed")
+		},
+	}
+	out := &bytes.Buffer{}
+
+	printServicesSummary(out, dockerRunner, []string{"immich"})
+
+	if out.Len() != 0 {
+		t.Errorf("expected no summary to be printed, got: %q", out.String())
+	}
+}
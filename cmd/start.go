@@ -1,37 +1,85 @@
 package cmd
 
 import (
+	"errors"
 	"log/slog"
+	"os"
+	"time"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	startValidate    bool
+	startWait        bool
+	startWaitTimeout time.Duration
+)
+
+// defaultStartWaitTimeout is how long start --wait waits for the services to be healthy by default
+const defaultStartWaitTimeout = 2 * time.Minute
+
 func init() {
 	rootCmd.AddCommand(startCmd)
+	startCmd.Flags().BoolVar(
+		&startValidate, "validate", false,
+		"Validate docker-compose.yml and .env with docker compose config before starting the services",
+	)
+	startCmd.Flags().BoolVar(
+		&startWait, "wait", false,
+		"Wait until the started services are running and, if they have a health check, healthy. Containers that exited with code 0 are considered done",
+	)
+	startCmd.Flags().DurationVar(
+		&startWaitTimeout, "wait-timeout", defaultStartWaitTimeout,
+		"Maximum time to wait for the services to be healthy when using --wait",
+	)
 }
 
 var startCmd = &cobra.Command{
-	Use:   "start [service1 service2 ...]",
+	Use:   "start [service-or-group ...]",
 	Short: "Start services (or all services if none specified)",
-	Long:  "Starts services in your homelab. If no service is provided, this would start all services.",
+	Long: "Starts services in your homelab. If no service is provided, this would start all services. " +
+		"A service group defined with a HOMELAB_SERVICE_GROUP_<NAME> variable is expanded into its services.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		runner := docker.NewSystemRunner()
-		return startServices(runner, args...)
+		var waitTimeout time.Duration
+		if startWait {
+			if startWaitTimeout <= 0 {
+				return errors.New("--wait-timeout must be greater than zero")
+			}
+			waitTimeout = startWaitTimeout
+		}
+		runner := newDockerRunner()
+		groups := loadServiceGroups(system.NewDefaultEnv())
+		return startServices(runner, groups, startValidate, waitTimeout, args...)
 	},
 }
 
-// startServices starts services by using docker compose.
-// If the service is empty, starts all services
-func startServices(dockerRunner docker.Runner, services ...string) error {
+// startServices starts services by using docker compose. Service groups are expanded into their member services.
+// If the service is empty, starts all services. If validate is true, the compose configuration is validated first.
+// If waitTimeout is greater than zero, waits up to waitTimeout for the started services to be healthy
+func startServices(
+	dockerRunner docker.Runner, groups serviceGroups, validate bool, waitTimeout time.Duration, names ...string,
+) error {
+	if validate {
+		if err := validateComposeConfig(dockerRunner); err != nil {
+			return err
+		}
+	}
+
+	services, err := resolveServices(dockerRunner, groups, names)
+	if err != nil {
+		return err
+	}
+
 	if len(services) == 0 {
 		slog.Info("Starting all services...")
 	} else {
 		slog.Info("Starting services", "services", services)
 	}
 
-	err := dockerRunner.ComposeStart(services)
+	err = dockerRunner.ComposeStart(services)
 	if err != nil {
 		return err
 	}
@@ -42,5 +90,22 @@ func startServices(dockerRunner docker.Runner, services ...string) error {
 		slog.Info("Successfully started services", "services", services)
 	}
 
-	return nil
+	if waitTimeout > 0 {
+		slog.Info("Waiting for services to be healthy...", "timeout", waitTimeout)
+		err = dockerRunner.WaitUntilServicesAreHealthy(services, waitTimeout)
+		if err == nil {
+			slog.Info("Services are healthy")
+		}
+	}
+
+	// The summary is also printed when the services are not healthy in time, to show which ones are not ready
+	printServicesSummary(os.Stdout, dockerRunner, services)
+	return err
+}
+
+// validateComposeConfig validates docker-compose.yml and .env with docker compose config, which catches the
+// interpolation errors that checking that the files exist doesn't
+func validateComposeConfig(dockerRunner docker.Runner) error {
+	slog.Info("Validating docker compose configuration...")
+	return dockerRunner.ComposeValidate()
 }
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStartServices_ValidatesBeforeStarting(t *testing.T) {
+	var calls []string
+	dockerRunner := &mockDockerRunner{
+		composeValidate: func() error {
+			calls = append(calls, "validate")
+			return nil
+		},
+		composeStart: func(services []string) error {
+			calls = append(calls, "start")
+			return nil
+		},
+	}
+
+	err := startServices(dockerRunner, nil, true, 0, "immich")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"validate", "start"}, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStartServices_InvalidConfigAbortsStart(t *testing.T) {
+	expectedErr := errors.New("invalid interpolation format")
+	startCalled := false
+	dockerRunner := &mockDockerRunner{
+		composeValidate: func() error { return expectedErr },
+		composeStart: func(services []string) error {
+			startCalled = true
+			return nil
+		},
+	}
+
+	err := startServices(dockerRunner, nil, true, 0)
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to be %v, got: %v", expectedErr, err)
+	}
+	if startCalled {
+		t.Error("expected services not to be started when the configuration is invalid")
+	}
+}
+
+func TestStartServices_ExpandsServiceGroup(t *testing.T) {
+	var started []string
+	dockerRunner := &mockDockerRunner{
+		composeStart: func(services []string) error {
+			started = services
+			return nil
+		},
+	}
+	groups := serviceGroups{"media": {"jellyfin", "radarr", "sonarr"}}
+
+	err := startServices(dockerRunner, groups, false, 0, "media")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"jellyfin", "radarr", "sonarr"}, started); diff != "" {
+		t.Errorf("started services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStartServices_UnknownGroupAbortsStart(t *testing.T) {
+	startCalled := false
+	dockerRunner := &mockDockerRunner{
+		composeServices: func() ([]string, error) { return []string{"immich", "jellyfin"}, nil },
+		composeStart: func(services []string) error {
+			startCalled = true
+			return nil
+		},
+	}
+	groups := serviceGroups{"media": {"jellyfin"}}
+
+	err := startServices(dockerRunner, groups, false, 0, "medai")
+
+	if !errors.Is(err, errUnknownServiceOrGroup) {
+		t.Errorf("expected errUnknownServiceOrGroup, got: %v", err)
+	}
+	if startCalled {
+		t.Error("expected services not to be started when a group is unknown")
+	}
+}
+
+func TestStartServices_WaitsForHealthyServices(t *testing.T) {
+	var calls []string
+	var waitedServices []string
+	var waitedTimeout time.Duration
+	dockerRunner := &mockDockerRunner{
+		composeStart: func(services []string) error {
+			calls = append(calls, "start")
+			return nil
+		},
+		waitHealthy: func(services []string, timeout time.Duration) error {
+			calls = append(calls, "wait")
+			waitedServices, waitedTimeout = services, timeout
+			return nil
+		},
+	}
+
+	err := startServices(dockerRunner, nil, false, time.Minute, "immich")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"start", "wait"}, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"immich"}, waitedServices); diff != "" {
+		t.Errorf("waited services mismatch (-want +got):\n%s", diff)
+	}
+	if waitedTimeout != time.Minute {
+		t.Errorf("expected timeout %v, got %v", time.Minute, waitedTimeout)
+	}
+}
+
+func TestStartServices_WaitTimeoutIsReturned(t *testing.T) {
+	psCalled := false
+	dockerRunner := &mockDockerRunner{
+		waitHealthy: func(services []string, timeout time.Duration) error {
+			return docker.ErrTooManyRetries
+		},
+		composePs: func(services []string) ([]docker.ServiceStatus, error) {
+			psCalled = true
+			return nil, nil
+		},
+	}
+
+	err := startServices(dockerRunner, nil, false, time.Second, "immich")
+
+	if !errors.Is(err, docker.ErrTooManyRetries) {
+		t.Errorf("expected ErrTooManyRetries, got: %v", err)
+	}
+	if !psCalled {
+		t.Error("expected the services summary to be printed when the services are not healthy")
+	}
+}
+
+func TestStartServices_NoWaitByDefault(t *testing.T) {
+	waitCalled := false
+	dockerRunner := &mockDockerRunner{
+		waitHealthy: func(services []string, timeout time.Duration) error {
+			waitCalled = true
+			return nil
+		},
+	}
+
+	err := startServices(dockerRunner, nil, false, 0)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if waitCalled {
+		t.Error("expected services not to be waited for without --wait")
+	}
+}
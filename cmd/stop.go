@@ -2,35 +2,58 @@ package cmd
 
 import (
 	"log/slog"
+	"os"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 	"github.com/spf13/cobra"
 )
 
+var (
+	stopValidate bool
+)
+
 func init() {
 	rootCmd.AddCommand(stopCmd)
+	stopCmd.Flags().BoolVar(
+		&stopValidate, "validate", false,
+		"Validate docker-compose.yml and .env with docker compose config before stopping the services",
+	)
 }
 
 var stopCmd = &cobra.Command{
-	Use:   "stop [service1 service2 ...]",
+	Use:   "stop [service-or-group ...]",
 	Short: "Stops services (or all services if none specified)",
-	Long:  "Stops services in your homelab. If no service is provided, this would stop all services.",
+	Long: "Stops services in your homelab. If no service is provided, this would stop all services. " +
+		"A service group defined with a HOMELAB_SERVICE_GROUP_<NAME> variable is expanded into its services.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		dockerRunner := docker.NewSystemRunner()
-		return stopServices(dockerRunner, args...)
+		dockerRunner := newDockerRunner()
+		groups := loadServiceGroups(system.NewDefaultEnv())
+		return stopServices(dockerRunner, groups, stopValidate, args...)
 	},
 }
 
-// stopServices stops services by using docker compose.
-// If the service is empty, stops all services
-func stopServices(dockerRunner docker.Runner, services ...string) error {
+// stopServices stops services by using docker compose. Service groups are expanded into their member services.
+// If the service is empty, stops all services. If validate is true, the compose configuration is validated first
+func stopServices(dockerRunner docker.Runner, groups serviceGroups, validate bool, names ...string) error {
+	if validate {
+		if err := validateComposeConfig(dockerRunner); err != nil {
+			return err
+		}
+	}
+
+	services, err := resolveServices(dockerRunner, groups, names)
+	if err != nil {
+		return err
+	}
+
 	if len(services) == 0 {
 		slog.Info("Stopping all services...")
 	} else {
 		slog.Info("Stopping services", "services", services)
 	}
 
-	err := dockerRunner.ComposeStop(services)
+	err = dockerRunner.ComposeStop(services)
 	if err != nil {
 		return err
 	}
@@ -41,5 +64,6 @@ func stopServices(dockerRunner docker.Runner, services ...string) error {
 		slog.Info("Successfully stopped services", "services", services)
 	}
 
+	printServicesSummary(os.Stdout, dockerRunner, services)
 	return nil
 }
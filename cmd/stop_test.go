@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStopServices_ValidatesBeforeStopping(t *testing.T) {
+	var calls []string
+	dockerRunner := &mockDockerRunner{
+		composeValidate: func() error {
+			calls = append(calls, "validate")
+			return nil
+		},
+		composeStop: func(services []string) error {
+			calls = append(calls, "stop")
+			return nil
+		},
+	}
+
+	err := stopServices(dockerRunner, nil, true, "immich")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"validate", "stop"}, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStopServices_InvalidConfigAbortsStop(t *testing.T) {
+	expectedErr := errors.New("invalid interpolation format")
+	stopCalled := false
+	dockerRunner := &mockDockerRunner{
+		composeValidate: func() error { return expectedErr },
+		composeStop: func(services []string) error {
+			stopCalled = true
+			return nil
+		},
+	}
+
+	err := stopServices(dockerRunner, nil, true)
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to be %v, got: %v", expectedErr, err)
+	}
+	if stopCalled {
+		t.Error("expected services not to be stopped when the configuration is invalid")
+	}
+}
+
+func TestStopServices_NoValidationByDefault(t *testing.T) {
+	validateCalled := false
+	dockerRunner := &mockDockerRunner{
+		composeValidate: func() error {
+			validateCalled = true
+			return nil
+		},
+	}
+
+	err := stopServices(dockerRunner, nil, false)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if validateCalled {
+		t.Error("expected the configuration not to be validated without --validate")
+	}
+}
+
+func TestStopServices_ExpandsServiceGroup(t *testing.T) {
+	var stopped []string
+	dockerRunner := &mockDockerRunner{
+		composeServices: func() ([]string, error) { return []string{"immich", "jellyfin", "radarr"}, nil },
+		composeStop: func(services []string) error {
+			stopped = services
+			return nil
+		},
+	}
+	groups := serviceGroups{"media": {"jellyfin", "radarr"}}
+
+	err := stopServices(dockerRunner, groups, false, "media", "immich")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"jellyfin", "radarr", "immich"}, stopped); diff != "" {
+		t.Errorf("stopped services mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+// mockEnv is a mock implementation of system.Env backed by a map
+type mockEnv struct {
+	vars map[string]string
+}
+
+func (m *mockEnv) GetEnv(varName string) (string, bool) {
+	value, exists := m.vars[varName]
+	return value, exists
+}
+func (m *mockEnv) GetRequiredEnv(varName string) (string, error) {
+	value, exists := m.GetEnv(varName)
+	if !exists {
+		return "", fmt.Errorf("%w: %q", system.ErrRequiredEnvNotFound, varName)
+	}
+	return value, nil
+}
+func (m *mockEnv) GetBoolEnv(varName string) (bool, bool) {
+	value, exists := m.GetEnv(varName)
+	if !exists {
+		return false, false
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false
+	}
+	return boolValue, true
+}
+func (m *mockEnv) GetIntEnv(varName string) (int, bool, error) {
+	value, exists := m.GetEnv(varName)
+	if !exists {
+		return 0, false, nil
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, true, fmt.Errorf("%w %q: %w", system.ErrInvalidIntEnv, varName, err)
+	}
+	return intValue, true, nil
+}
+func (m *mockEnv) GetAllEnv() map[string]string { return m.vars }
+func (m *mockEnv) ListByPrefix(prefix string) map[string]string {
+	vars := make(map[string]string)
+	for name, value := range m.vars {
+		if strings.HasPrefix(name, prefix) {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+// mockFiles is a mock implementation of system.FilesHandler
+type mockFiles struct {
+	emptyDir func(path string) error
+}
+
+func (m *mockFiles) CreateDirIfNotExists(path string) error    { return nil }
+func (m *mockFiles) EnsureFilesInWD(filenames ...string) error { return nil }
+func (m *mockFiles) EnsureDirExists(path string) error         { return nil }
+func (m *mockFiles) EmptyDir(path string) error {
+	if m.emptyDir != nil {
+		return m.emptyDir(path)
+	}
+	return nil
+}
+func (m *mockFiles) CopyDir(srcPath string, dstPath string) error { return nil }
+func (m *mockFiles) CopyDirWithOptions(srcPath string, dstPath string, opts system.CopyDirOptions) ([]string, error) {
+	return nil, nil
+}
+func (m *mockFiles) Getwd() (dir string, err error)                    { return "", nil }
+func (m *mockFiles) WriteFile(path string, data []byte) error          { return nil }
+func (m *mockFiles) WriteSecretFile(path string, data []byte) error    { return nil }
+func (m *mockFiles) WriteNewFile(path string, data []byte) error       { return nil }
+func (m *mockFiles) CreateNewFile(path string) (io.WriteCloser, error) { return nil, nil }
+func (m *mockFiles) ReadFile(path string) ([]byte, error)              { return nil, nil }
+func (m *mockFiles) GetAbsPath(path string) (string, error)            { return path, nil }
+func (m *mockFiles) ListFiles(path string) ([]os.FileInfo, error)      { return nil, nil }
+func (m *mockFiles) RemoveFile(path string) error                      { return nil }
+func (m *mockFiles) DirSize(path string) (int64, error)                { return 0, nil }
+
+// mockDockerRunner is a mock implementation of docker.Runner
+type mockDockerRunner struct {
+	composeStart    func(services []string) error
+	composeStop     func(services []string) error
+	composeValidate func() error
+	composePs       func(services []string) ([]docker.ServiceStatus, error)
+	composeServices func() ([]string, error)
+	waitHealthy     func(services []string, timeout time.Duration) error
+}
+
+func (m *mockDockerRunner) ComposeStart(services []string) error {
+	if m.composeStart != nil {
+		return m.composeStart(services)
+	}
+	return nil
+}
+func (m *mockDockerRunner) ComposeStop(services []string) error {
+	if m.composeStop != nil {
+		return m.composeStop(services)
+	}
+	return nil
+}
+func (m *mockDockerRunner) ComposeValidate() error {
+	if m.composeValidate != nil {
+		return m.composeValidate()
+	}
+	return nil
+}
+func (m *mockDockerRunner) ComposePs(services []string) ([]docker.ServiceStatus, error) {
+	if m.composePs != nil {
+		return m.composePs(services)
+	}
+	return nil, nil
+}
+func (m *mockDockerRunner) ComposeServices() ([]string, error) {
+	if m.composeServices != nil {
+		return m.composeServices()
+	}
+	return nil, nil
+}
+func (m *mockDockerRunner) WaitUntilServicesAreHealthy(services []string, timeout time.Duration) error {
+	if m.waitHealthy != nil {
+		return m.waitHealthy(services, timeout)
+	}
+	return nil
+}
+func (m *mockDockerRunner) WaitUntilContainerHealthy(container string) error {
+	return nil
+}
+func (m *mockDockerRunner) ContainerLogs(ctx context.Context, name string, tail int, follow bool) error {
+	return nil
+}
+func (m *mockDockerRunner) ContainerExec(container string, cmd string) error { return nil }
+func (m *mockDockerRunner) WaitUntilContainerExecIsSuccessful(container string, cmd string, timeout time.Duration) error {
+	return nil
+}
+
+type mockTime struct {
+	now func() time.Time
+}
+
+func (m *mockTime) Sleep(d time.Duration) {}
+
+// After returns a channel that never receives, so that nothing waiting on it fires
+func (m *mockTime) After(d time.Duration) <-chan time.Time {
+	return nil
+}
+func (m *mockTime) Now() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Time{}
+}
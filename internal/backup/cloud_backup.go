@@ -1,32 +1,133 @@
 package backup
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/davidsilvasanmartin/auto-homelab/internal/dotenv"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 )
 
+// automaticTagPrefix is the default prefix of the tag of the snapshots created by RunFullBackup, which is followed by a
+// timestamp
+const automaticTagPrefix = "automatic-"
+
+// automaticTagTimeFormat is the layout of the timestamp in the tag of the snapshots created by RunFullBackup
+const automaticTagTimeFormat = "2006-01-02_15-04-05"
+
+// configHashTagPrefix is the prefix of the tag that contains the hash of the configuration
+const configHashTagPrefix = "config-"
+
+var (
+	ErrInvalidSince   = errors.New("invalid since duration")
+	ErrInvalidGroupBy = errors.New("invalid group by key")
+	ErrInvalidLatest  = errors.New("invalid number of latest snapshots")
+	ErrInterrupted    = errors.New("cloud backup interrupted")
+)
+
+// snapshotGroupByKeys are the keys that restic can group snapshots by
+var snapshotGroupByKeys = []string{"host", "paths", "tags"}
+
+// sinceDaysPattern matches the durations in days ("7d") or weeks ("2w") accepted by ParseSince
+var sinceDaysPattern = regexp.MustCompile(`^(\d+)([dw])$`)
+
+// readDotenvFiles returns the content of the loaded .env files, concatenated in the order they were loaded
+func (c *CloudBackup) readDotenvFiles() ([]byte, error) {
+	paths := c.dotenvFiles()
+	if len(paths) == 0 {
+		return nil, errors.New("no .env file was loaded")
+	}
+	var content []byte
+	for _, path := range paths {
+		data, err := c.files.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, data...)
+	}
+	return content, nil
+}
+
+// configHashTag returns a tag derived from the SHA-256 hash of the content of a configuration file. Like git does with
+// commit hashes, the hash is shortened to keep the tag readable
+func configHashTag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return configHashTagPrefix + hex.EncodeToString(sum[:])[:12]
+}
+
 // CloudBackup orchestrates cloud backup operations using restic
 type CloudBackup struct {
-	client ResticClient
-	files  system.FilesHandler
+	client   ResticClient
+	files    system.FilesHandler
+	commands system.Commands
+	time     system.Time
+	// out is where the summary of a full backup is printed
+	out    io.Writer
 	config ResticConfig
+	// ctx is the context that interrupts the restic commands. They are not interrupted when it is nil
+	ctx context.Context
+	// dotenvFiles returns the .env files that the configuration was loaded from, whose content is hashed when
+	// TagConfigHash is set
+	dotenvFiles func() []string
 }
 
 // NewCloudBackup creates a new cloud backup instance
 func NewCloudBackup(config ResticConfig) *CloudBackup {
 	return &CloudBackup{
-		client: NewDefaultResticClient(config),
-		files:  system.NewDefaultFilesHandler(),
-		config: config,
+		client:      NewDefaultResticClient(config),
+		files:       system.NewDefaultFilesHandler(),
+		commands:    system.NewDefaultCommands(),
+		time:        system.NewDefaultTime(),
+		out:         os.Stdout,
+		config:      config,
+		dotenvFiles: dotenv.LoadedFiles,
+	}
+}
+
+// NewInterruptibleCloudBackup creates a cloud backup whose restic commands are interrupted when ctx is done. Run its
+// operations with RunInterruptible, so that the repository is unlocked after an interruption
+func NewInterruptibleCloudBackup(ctx context.Context, config ResticConfig) *CloudBackup {
+	c := NewCloudBackup(config)
+	c.client = NewDefaultResticClient(config).WithContext(ctx)
+	c.ctx = ctx
+	return c
+}
+
+// RunInterruptible runs operation, such as RunFullBackup. If it fails because the context was done, the stale locks
+// that the interrupted restic command may have left are removed, so that the next commands don't fail because the
+// repository is locked
+func (c *CloudBackup) RunInterruptible(operation func() error) error {
+	err := operation()
+	if err == nil || c.ctx == nil || c.ctx.Err() == nil {
+		return err
 	}
+	slog.Warn("Cloud backup interrupted, removing the stale locks of the repository")
+	interruptedErr := fmt.Errorf("%w: %w", ErrInterrupted, err)
+	if unlockErr := c.client.Unlock(); unlockErr != nil {
+		return errors.Join(interruptedErr, fmt.Errorf("failed to unlock the repository: %w", unlockErr))
+	}
+	slog.Info("Repository unlocked")
+	return interruptedErr
 }
 
-// RunFullBackup executes a complete backup workflow: init, backup, and prune
+// RunFullBackup executes a complete backup workflow: init, backup, and prune. A summary is printed when it completes
 func (c *CloudBackup) RunFullBackup() error {
 	slog.Info("Starting full cloud backup workflow")
+	startTime := c.time.Now()
 
 	slog.Info("Checking if repository exists...")
 	if err := c.client.Init(); err != nil {
@@ -38,25 +139,65 @@ func (c *CloudBackup) RunFullBackup() error {
 		return fmt.Errorf("backup path does not exist: %w", err)
 	}
 
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	tags := []string{fmt.Sprintf("automatic-%s", timestamp)}
+	timestamp := startTime.Format(automaticTagTimeFormat)
+	tag := c.tagPrefix() + timestamp
+	tags := []string{tag}
+	if c.config.TagConfigHash {
+		content, err := c.readDotenvFiles()
+		if err != nil {
+			return fmt.Errorf("failed to read the configuration to tag the backup with its hash: %w", err)
+		}
+		tags = append(tags, configHashTag(content))
+	}
 	slog.Info("Creating backup", "path", c.config.BackupPath, "tags", tags)
-	if err := c.client.Backup(c.config.BackupPath, tags); err != nil {
+	var summary BackupSummary
+	err := c.retryIfLocked(func() error {
+		var err error
+		summary, err = c.client.Backup(c.config.BackupPath, tags)
+		return err
+	})
+	if err != nil {
 		return fmt.Errorf("failed to create backup: %w", err)
 	}
 	slog.Info("Backup completed successfully")
 
-	keepWithin := fmt.Sprintf("%dd", c.config.RetentionDays)
+	keepWithin := c.config.Retention
 	slog.Info("Pruning old backups", "keepWithin", keepWithin)
-	if err := c.client.Forget(keepWithin, true); err != nil {
+	if err := c.retryIfLocked(func() error { return c.client.Forget(keepWithin, true) }); err != nil {
 		return fmt.Errorf("failed to prune old backups: %w", err)
 	}
 	slog.Info("Pruning completed successfully")
 
 	slog.Info("Full cloud backup workflow completed successfully")
+	elapsed := c.time.Now().Sub(startTime).Round(time.Second)
+	fmt.Fprintf(c.out, "Backed up %s (%s processed, %s added) with tag %s in %s (keeping snapshots within %s)\n",
+		c.config.BackupPath, format.FormatSize(summary.TotalBytesProcessed), format.FormatSize(summary.DataAdded), tag, elapsed, keepWithin)
 	return nil
 }
 
+// retryIfLocked runs operation, and runs it once more after unlocking the repository if it failed because the
+// repository was locked. restic unlock only removes the stale locks of commands that are no longer running, so the
+// retry fails again if another restic command is still using the repository
+func (c *CloudBackup) retryIfLocked(operation func() error) error {
+	err := operation()
+	if !errors.Is(err, ErrRepositoryLocked) {
+		return err
+	}
+	slog.Warn("Repository is locked, removing its stale locks and retrying", "error", err)
+	if unlockErr := c.client.Unlock(); unlockErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to unlock the repository: %w", unlockErr))
+	}
+	return operation()
+}
+
+// tagPrefix returns the prefix of the tag of the snapshots created by RunFullBackup
+func (c *CloudBackup) tagPrefix() string {
+	if c.config.TagPrefix != "" {
+		return c.config.TagPrefix
+	}
+	return automaticTagPrefix
+}
+
 // Init initializes the repository
 func (c *CloudBackup) Init() error {
 	slog.Info("Initializing repository...")
@@ -77,18 +218,134 @@ func (c *CloudBackup) Check() error {
 	return nil
 }
 
-// ListSnapshots lists all snapshots in the repository
-func (c *CloudBackup) ListSnapshots() error {
-	slog.Info("Listing snapshots...")
-	if err := c.client.Snapshots(); err != nil {
+// ListSnapshots lists all snapshots in the repository. If groupBy is not empty, restic groups the snapshots by those
+// keys, which must be "host", "paths" or "tags". If latest is greater than zero, only the latest snapshots of each host
+// and path are listed
+func (c *CloudBackup) ListSnapshots(groupBy []string, latest int) error {
+	if latest < 0 {
+		return fmt.Errorf("%w: must be greater than zero, got %d", ErrInvalidLatest, latest)
+	}
+	for _, key := range groupBy {
+		if !slices.Contains(snapshotGroupByKeys, key) {
+			return fmt.Errorf("%w %q: must be one of %s", ErrInvalidGroupBy, key, strings.Join(snapshotGroupByKeys, ", "))
+		}
+	}
+	slog.Info("Listing snapshots...", "groupBy", groupBy, "latest", latest)
+	if err := c.client.Snapshots(groupBy, latest); err != nil {
 		return fmt.Errorf("failed to list snapshots: %w", err)
 	}
 	return nil
 }
 
+// ListAutomaticTags returns the unique tags of the snapshots created by RunFullBackup, sorted from oldest to newest
+func (c *CloudBackup) ListAutomaticTags() ([]string, error) {
+	snapshotsJSON, err := c.client.SnapshotsJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	return parseAutomaticTags(snapshotsJSON, c.tagPrefix())
+}
+
+// ListSnapshotsSince lists the snapshots in the repository that were taken within the last since. restic has no
+// filter by time, so the snapshots are filtered from the output of "restic snapshots --json"
+func (c *CloudBackup) ListSnapshotsSince(since time.Duration) error {
+	slog.Info("Listing snapshots...", "since", since)
+	snapshotsJSON, err := c.client.SnapshotsJSON()
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	snapshots, err := parseSnapshotsSince(snapshotsJSON, c.time.Now().Add(-since))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tHOST\tTAGS\tPATHS")
+	for _, snapshot := range snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", snapshot.ShortID, snapshot.Time.Local().Format(time.DateTime),
+			snapshot.Hostname, strings.Join(snapshot.Tags, ","), strings.Join(snapshot.Paths, ","))
+	}
+	w.Flush()
+	fmt.Fprintf(c.out, "%d snapshots\n", len(snapshots))
+	return nil
+}
+
+// ParseSince parses how far back to list snapshots from. Besides the units of time.ParseDuration (e.g. "36h"), days
+// ("7d") and weeks ("2w") are accepted, like restic does in its own durations
+func ParseSince(value string) (time.Duration, error) {
+	var duration time.Duration
+	if matches := sinceDaysPattern.FindStringSubmatch(value); matches != nil {
+		days, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return 0, fmt.Errorf("%w %q: %w", ErrInvalidSince, value, err)
+		}
+		if matches[2] == "w" {
+			days *= 7
+		}
+		duration = time.Duration(days) * 24 * time.Hour
+	} else {
+		var err error
+		duration, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("%w %q: %w", ErrInvalidSince, value, err)
+		}
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("%w %q: must be greater than zero", ErrInvalidSince, value)
+	}
+	return duration, nil
+}
+
+// resticSnapshot is the part of a snapshot in the output of "restic snapshots --json" that is used
+type resticSnapshot struct {
+	ShortID  string    `json:"short_id"`
+	Time     time.Time `json:"time"`
+	Hostname string    `json:"hostname"`
+	Tags     []string  `json:"tags"`
+	Paths    []string  `json:"paths"`
+}
+
+// parseSnapshotsSince returns the snapshots in the output of "restic snapshots --json" that were taken at or after
+// cutoff, sorted from oldest to newest
+func parseSnapshotsSince(snapshotsJSON []byte, cutoff time.Time) ([]resticSnapshot, error) {
+	var snapshots []resticSnapshot
+	if err := json.Unmarshal(snapshotsJSON, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshots: %w", err)
+	}
+	var recent []resticSnapshot
+	for _, snapshot := range snapshots {
+		if !snapshot.Time.Before(cutoff) {
+			recent = append(recent, snapshot)
+		}
+	}
+	slices.SortStableFunc(recent, func(a, b resticSnapshot) int {
+		return a.Time.Compare(b.Time)
+	})
+	return recent, nil
+}
+
+// parseAutomaticTags extracts the unique tags that start with prefix from the output of "restic snapshots --json".
+// Because the timestamp in the tags sorts chronologically, they are returned sorted from oldest to newest
+func parseAutomaticTags(snapshotsJSON []byte, prefix string) ([]string, error) {
+	var snapshots []resticSnapshot
+	if err := json.Unmarshal(snapshotsJSON, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshots: %w", err)
+	}
+	var tags []string
+	for _, snapshot := range snapshots {
+		for _, tag := range snapshot.Tags {
+			if strings.HasPrefix(tag, prefix) && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags, nil
+}
+
 // Prune removes old backups according to retention policy
 func (c *CloudBackup) Prune() error {
-	keepWithin := fmt.Sprintf("%dd", c.config.RetentionDays)
+	keepWithin := c.config.Retention
 	slog.Info("Pruning old backups", "keepWithin", keepWithin)
 	if err := c.client.Forget(keepWithin, true); err != nil {
 		return fmt.Errorf("failed to prune old backups: %w", err)
@@ -97,23 +354,79 @@ func (c *CloudBackup) Prune() error {
 	return nil
 }
 
-// Restore restores the latest snapshot to a target directory
-func (c *CloudBackup) Restore(targetDir string) error {
-	slog.Info("Restoring latest snapshot", "targetDir", targetDir)
+// ChangePassword changes the password of the repository to the content of newPasswordFile. The password in
+// RESTIC_PASSWORD must then be updated, as it no longer opens the repository
+func (c *CloudBackup) ChangePassword(newPasswordFile string) error {
+	slog.Info("Changing repository password", "newPasswordFile", newPasswordFile)
+	newPasswordFile, err := c.files.GetAbsPath(newPasswordFile)
+	if err != nil {
+		return fmt.Errorf("failed to convert new password file to an absolute path: %w", err)
+	}
+	content, err := c.files.ReadFile(newPasswordFile)
+	if err != nil {
+		return fmt.Errorf("failed to read new password file: %w", err)
+	}
+	if strings.TrimSpace(string(content)) == "" {
+		return fmt.Errorf("%w: new password file %q is empty", ErrInvalidResticConfig, newPasswordFile)
+	}
+
+	if err := c.client.ChangePassword(newPasswordFile); err != nil {
+		return fmt.Errorf("failed to change repository password: %w", err)
+	}
+	slog.Warn("Repository password changed. Update the RESTIC_PASSWORD variable of the .env file with the new password, or restic will no longer open the repository")
+	return nil
+}
+
+// Copy copies the snapshots of the repository into the secondary repository, which must already be initialized
+func (c *CloudBackup) Copy() error {
+	if c.config.CopyRepositoryURL == "" {
+		return fmt.Errorf("%w: no secondary repository is configured to copy the snapshots into", ErrInvalidResticConfig)
+	}
+	slog.Info("Copying snapshots to the secondary repository")
+	if err := c.client.Copy(); err != nil {
+		return fmt.Errorf("failed to copy snapshots: %w", err)
+	}
+	slog.Info("Copy completed successfully")
+	return nil
+}
+
+// Restore restores the latest snapshot to a target directory. When dryRun is true, it only prints what would be
+// restored, and the target directory is not created. Otherwise, the owner and mode of the restored files are changed
+// if RestoreOwner and RestoreMode are configured
+func (c *CloudBackup) Restore(targetDir string, dryRun bool) error {
+	slog.Info("Restoring latest snapshot", "targetDir", targetDir, "dryRun", dryRun)
 
-	// Ensure target directory exists
 	targetDir, err := c.files.GetAbsPath(targetDir)
 	if err != nil {
 		return fmt.Errorf("failed to convert target directory to an absolute path: %w", err)
 	}
-	if err := c.files.CreateDirIfNotExists(targetDir); err != nil {
-		return fmt.Errorf("failed to create target directory: %w", err)
+	// Ensure target directory exists, unless we are not going to write anything to it
+	if !dryRun {
+		if err := c.files.CreateDirIfNotExists(targetDir); err != nil {
+			return fmt.Errorf("failed to create target directory: %w", err)
+		}
 	}
 
-	if err := c.client.Restore(targetDir); err != nil {
+	if err := c.client.Restore(targetDir, dryRun); err != nil {
 		return fmt.Errorf("failed to restore snapshot: %w", err)
 	}
 
+	if dryRun {
+		slog.Info("Restore dry run completed successfully", "targetDir", targetDir)
+		return nil
+	}
+	if c.config.RestoreOwner != "" {
+		slog.Info("Changing owner of restored files", "targetDir", targetDir, "owner", c.config.RestoreOwner)
+		if err := c.commands.ExecCommand("chown", "-R", c.config.RestoreOwner, targetDir).Run(); err != nil {
+			return fmt.Errorf("failed to change the owner of the restored files: %w", err)
+		}
+	}
+	if c.config.RestoreMode != "" {
+		slog.Info("Changing mode of restored files", "targetDir", targetDir, "mode", c.config.RestoreMode)
+		if err := c.commands.ExecCommand("chmod", "-R", c.config.RestoreMode, targetDir).Run(); err != nil {
+			return fmt.Errorf("failed to change the mode of the restored files: %w", err)
+		}
+	}
 	slog.Info("Restore completed successfully", "targetDir", targetDir)
 	return nil
 }
@@ -1,18 +1,32 @@
 package backup
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/google/go-cmp/cmp"
 )
 
 type mockResticClient struct {
 	initFunc      func() error
-	backupFunc    func(path string, tags []string) error
+	backupFunc    func(path string, tags []string) (BackupSummary, error)
 	forgetFunc    func(keepWithin string, prune bool) error
 	checkFunc     func() error
-	snapshotsFunc func() error
+	snapshotsFunc func(groupBy []string, latest int) error
+	snapshotsJSON func() ([]byte, error)
 	listFilesFunc func(snapshotID string) error
-	restoreFunc   func(targetDir string) error
+	restoreFunc   func(targetDir string, dryRun bool) error
+	changePasswd  func(newPasswordFile string) error
+	copyFunc      func() error
+	unlockFunc    func() error
 }
 
 func (m *mockResticClient) Init() error {
@@ -21,11 +35,11 @@ func (m *mockResticClient) Init() error {
 	}
 	return nil
 }
-func (m *mockResticClient) Backup(path string, tags []string) error {
+func (m *mockResticClient) Backup(path string, tags []string) (BackupSummary, error) {
 	if m.backupFunc != nil {
 		return m.backupFunc(path, tags)
 	}
-	return nil
+	return BackupSummary{}, nil
 }
 func (m *mockResticClient) Forget(keepWithin string, prune bool) error {
 	if m.forgetFunc != nil {
@@ -39,21 +53,48 @@ func (m *mockResticClient) Check() error {
 	}
 	return nil
 }
-func (m *mockResticClient) Snapshots() error {
+func (m *mockResticClient) Snapshots(groupBy []string, latest int) error {
 	if m.snapshotsFunc != nil {
-		return m.snapshotsFunc()
+		return m.snapshotsFunc(groupBy, latest)
 	}
 	return nil
 }
+func (m *mockResticClient) SnapshotsJSON() ([]byte, error) {
+	if m.snapshotsJSON != nil {
+		return m.snapshotsJSON()
+	}
+	return []byte("[]"), nil
+}
 func (m *mockResticClient) ListFiles(snapshotID string) error {
 	if m.listFilesFunc != nil {
 		return m.listFilesFunc(snapshotID)
 	}
 	return nil
 }
-func (m *mockResticClient) Restore(targetDir string) error {
+func (m *mockResticClient) Restore(targetDir string, dryRun bool) error {
 	if m.restoreFunc != nil {
-		return m.restoreFunc(targetDir)
+		return m.restoreFunc(targetDir, dryRun)
+	}
+	return nil
+}
+
+func (m *mockResticClient) ChangePassword(newPasswordFile string) error {
+	if m.changePasswd != nil {
+		return m.changePasswd(newPasswordFile)
+	}
+	return nil
+}
+
+func (m *mockResticClient) Copy() error {
+	if m.copyFunc != nil {
+		return m.copyFunc()
+	}
+	return nil
+}
+
+func (m *mockResticClient) Unlock() error {
+	if m.unlockFunc != nil {
+		return m.unlockFunc()
 	}
 	return nil
 }
@@ -71,10 +112,10 @@ func TestCloudBackup_RunFullBackup_Success(t *testing.T) {
 				initCalled = true
 				return nil
 			},
-			backupFunc: func(path string, tags []string) error {
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
 				backupCalled = true
 				capturedBackupPath = path
-				return nil
+				return BackupSummary{}, nil
 			},
 			forgetFunc: func(keepWithin string, prune bool) error {
 				forgetCalled = true
@@ -84,9 +125,11 @@ func TestCloudBackup_RunFullBackup_Success(t *testing.T) {
 			},
 		},
 		files: &mockFilesHandler{},
+		time:  &mockTime{},
+		out:   io.Discard,
 		config: ResticConfig{
-			BackupPath:    "/data/backup",
-			RetentionDays: 30,
+			BackupPath: "/data/backup",
+			Retention:  "30d",
 		},
 	}
 
@@ -116,24 +159,31 @@ func TestCloudBackup_RunFullBackup_Success(t *testing.T) {
 }
 
 func TestCloudBackup_RunFullBackup_TagsContainTimestamp(t *testing.T) {
+	fixedTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local)
 	var capturedTags []string
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
 			initFunc: func() error {
 				return nil
 			},
-			backupFunc: func(path string, tags []string) error {
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
 				capturedTags = tags
-				return nil
+				return BackupSummary{}, nil
 			},
 			forgetFunc: func(keepWithin string, prune bool) error {
 				return nil
 			},
 		},
 		files: &mockFilesHandler{},
+		time: &mockTime{
+			now: func() time.Time {
+				return fixedTime
+			},
+		},
+		out: io.Discard,
 		config: ResticConfig{
-			BackupPath:    "/data/backup",
-			RetentionDays: 30,
+			BackupPath: "/data/backup",
+			Retention:  "30d",
 		},
 	}
 
@@ -145,103 +195,102 @@ func TestCloudBackup_RunFullBackup_TagsContainTimestamp(t *testing.T) {
 	if len(capturedTags) != 1 {
 		t.Fatalf("expected 1 tag, got %d", len(capturedTags))
 	}
-	// Tag should start with "automatic-"
-	tag := capturedTags[0]
-	if len(tag) < 10 || tag[:10] != "automatic-" {
-		t.Errorf("expected tag to start with 'automatic-', got: %q", tag)
+	expectedTag := "automatic-2025-01-02_03-04-05"
+	if capturedTags[0] != expectedTag {
+		t.Errorf("expected tag %q, got: %q", expectedTag, capturedTags[0])
 	}
 }
 
-func TestCloudBackup_RunFullBackup_InitFails(t *testing.T) {
-	expectedErr := errors.New("init failed")
-	backupCalled := false
+func TestCloudBackup_RunFullBackup_TagsUseCustomPrefix(t *testing.T) {
+	fixedTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local)
+	var capturedTags []string
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			initFunc: func() error {
-				return expectedErr
-			},
-			backupFunc: func(path string, tags []string) error {
-				backupCalled = true
-				return nil
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				capturedTags = tags
+				return BackupSummary{}, nil
 			},
 		},
 		files: &mockFilesHandler{},
+		time: &mockTime{
+			now: func() time.Time {
+				return fixedTime
+			},
+		},
+		out: io.Discard,
 		config: ResticConfig{
-			BackupPath:    "/data/backup",
-			RetentionDays: 30,
+			BackupPath: "/data/backup",
+			Retention:  "30d",
+			TagPrefix:  "media-host-",
 		},
 	}
 
 	err := cloudBackup.RunFullBackup()
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
-	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
 	}
-	if backupCalled {
-		t.Error("expected Backup NOT to be called when Init fails")
+	if diff := cmp.Diff([]string{"media-host-2025-01-02_03-04-05"}, capturedTags); diff != "" {
+		t.Errorf("tags mismatch (-want +got):\n%s", diff)
 	}
 }
 
-func TestCloudBackup_RunFullBackup_BackupPathDoesNotExist(t *testing.T) {
-	expectedErr := errors.New("path does not exist")
-	backupCalled := false
+func TestCloudBackup_RunFullBackup_PrintsSummary(t *testing.T) {
+	startTime := time.Date(2025, 3, 14, 15, 9, 26, 0, time.Local)
+	times := []time.Time{startTime, startTime.Add(2*time.Minute + 5*time.Second)}
+	var out bytes.Buffer
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			initFunc: func() error {
-				return nil
-			},
-			backupFunc: func(path string, tags []string) error {
-				backupCalled = true
-				return nil
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				return BackupSummary{DataAdded: 12 * 1024 * 1024, TotalBytesProcessed: 3 * 1024 * 1024 * 1024}, nil
 			},
 		},
-		files: &mockFilesHandler{
-			ensureDirExists: func(path string) error {
-				return expectedErr
+		files: &mockFilesHandler{},
+		time: &mockTime{
+			now: func() time.Time {
+				now := times[0]
+				times = times[1:]
+				return now
 			},
 		},
+		out: &out,
 		config: ResticConfig{
-			BackupPath:    "/data/backup",
-			RetentionDays: 30,
+			BackupPath: "/data/backup",
+			Retention:  "30d",
 		},
 	}
 
 	err := cloudBackup.RunFullBackup()
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	summary := out.String()
+	if !strings.Contains(summary, "automatic-2025-03-14_15-09-26") {
+		t.Errorf("expected summary to contain the snapshot tag, got: %q", summary)
 	}
-	if backupCalled {
-		t.Error("expected Backup NOT to be called when path doesn't exist")
+	if !strings.Contains(summary, "2m5s") {
+		t.Errorf("expected summary to contain the elapsed time, got: %q", summary)
+	}
+	if !strings.Contains(summary, "3.0 GiB processed, 12.0 MiB added") {
+		t.Errorf("expected summary to contain the sizes reported by restic, got: %q", summary)
 	}
 }
 
-func TestCloudBackup_RunFullBackup_BackupFails(t *testing.T) {
-	expectedErr := errors.New("backup failed")
-	forgetCalled := false
+func TestCloudBackup_RunFullBackup_NoSummaryOnFailure(t *testing.T) {
+	var out bytes.Buffer
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			initFunc: func() error {
-				return nil
-			},
-			backupFunc: func(path string, tags []string) error {
-				return expectedErr
-			},
-			forgetFunc: func(keepWithin string, prune bool) error {
-				forgetCalled = true
-				return nil
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				return BackupSummary{}, errors.New("backup failed")
 			},
 		},
 		files: &mockFilesHandler{},
+		time:  &mockTime{},
+		out:   &out,
 		config: ResticConfig{
-			BackupPath:    "/data/backup",
-			RetentionDays: 30,
+			BackupPath: "/data/backup",
+			Retention:  "30d",
 		},
 	}
 
@@ -250,126 +299,205 @@ func TestCloudBackup_RunFullBackup_BackupFails(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	if out.Len() != 0 {
+		t.Errorf("expected no summary, got: %q", out.String())
 	}
-	if forgetCalled {
-		t.Error("expected Forget NOT to be called when Backup fails")
+}
+
+func TestConfigHashTag_StableForIdenticalContent(t *testing.T) {
+	content := []byte("HOMELAB_GENERAL_DOMAIN=\"example.com\"\n")
+
+	first := configHashTag(content)
+	second := configHashTag([]byte(string(content)))
+
+	if first != second {
+		t.Errorf("expected identical content to produce the same tag, got %q and %q", first, second)
+	}
+	if !strings.HasPrefix(first, "config-") || len(first) != len("config-")+12 {
+		t.Errorf("expected a tag like config-<12 hex characters>, got %q", first)
 	}
 }
 
-func TestCloudBackup_RunFullBackup_ForgetFails(t *testing.T) {
-	expectedErr := errors.New("forget failed")
+func TestConfigHashTag_DiffersForChangedContent(t *testing.T) {
+	original := configHashTag([]byte("HOMELAB_GENERAL_DOMAIN=\"example.com\"\n"))
+	changed := configHashTag([]byte("HOMELAB_GENERAL_DOMAIN=\"example.org\"\n"))
+
+	if original == changed {
+		t.Errorf("expected changed content to produce a different tag, got %q for both", original)
+	}
+}
+
+func TestCloudBackup_RunFullBackup_TagsContainConfigHash(t *testing.T) {
+	content := []byte("HOMELAB_GENERAL_DOMAIN=\"example.com\"\n")
+	dotenvPath := "/home/user/.config/auto-homelab/.env"
+	var capturedPath string
+	var capturedTags []string
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			initFunc: func() error {
-				return nil
-			},
-			backupFunc: func(path string, tags []string) error {
-				return nil
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				capturedTags = tags
+				return BackupSummary{}, nil
 			},
-			forgetFunc: func(keepWithin string, prune bool) error {
-				return expectedErr
+		},
+		files: &mockFilesHandler{
+			readFile: func(path string) ([]byte, error) {
+				capturedPath = path
+				return content, nil
 			},
 		},
-		files: &mockFilesHandler{},
+		time: &mockTime{},
+		out:  io.Discard,
 		config: ResticConfig{
 			BackupPath:    "/data/backup",
-			RetentionDays: 30,
+			Retention:     "30d",
+			TagConfigHash: true,
 		},
+		dotenvFiles: func() []string { return []string{dotenvPath} },
 	}
 
 	err := cloudBackup.RunFullBackup()
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	if capturedPath != dotenvPath {
+		t.Errorf("expected the loaded config file %q to be read, got %q", dotenvPath, capturedPath)
+	}
+	if len(capturedTags) != 2 {
+		t.Fatalf("expected 2 tags, got %v", capturedTags)
+	}
+	if capturedTags[1] != configHashTag(content) {
+		t.Errorf("expected tag %q, got %q", configHashTag(content), capturedTags[1])
 	}
 }
 
-func TestCloudBackup_Init_Success(t *testing.T) {
-	initCalled := false
+func TestCloudBackup_RunFullBackup_ConfigHashReadError(t *testing.T) {
+	expectedErr := errors.New("permission denied")
+	backupCalled := false
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			initFunc: func() error {
-				initCalled = true
-				return nil
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				backupCalled = true
+				return BackupSummary{}, nil
 			},
 		},
-		files:  &mockFilesHandler{},
-		config: ResticConfig{},
+		files: &mockFilesHandler{
+			readFile: func(path string) ([]byte, error) {
+				return nil, expectedErr
+			},
+		},
+		time: &mockTime{},
+		out:  io.Discard,
+		config: ResticConfig{
+			BackupPath:    "/data/backup",
+			Retention:     "30d",
+			TagConfigHash: true,
+		},
+		dotenvFiles: func() []string { return []string{".env"} },
 	}
 
-	err := cloudBackup.Init()
+	err := cloudBackup.RunFullBackup()
 
-	if err != nil {
-		t.Fatalf("expected no error, got: %v", err)
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
 	}
-	if !initCalled {
-		t.Error("expected Init to be called on client")
+	if backupCalled {
+		t.Error("expected Backup not to be called")
 	}
 }
 
-func TestCloudBackup_Init_Error(t *testing.T) {
-	expectedErr := errors.New("init failed")
+func TestCloudBackup_RunFullBackup_ConfigHashOfLayeredFiles(t *testing.T) {
+	contents := map[string][]byte{
+		"base.env": []byte("HOMELAB_GENERAL_DOMAIN=\"example.com\"\n"),
+		"host.env": []byte("HOMELAB_GENERAL_DOMAIN=\"example.org\"\n"),
+	}
+	var capturedTags []string
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			initFunc: func() error {
-				return expectedErr
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				capturedTags = tags
+				return BackupSummary{}, nil
 			},
 		},
-		files:  &mockFilesHandler{},
-		config: ResticConfig{},
+		files: &mockFilesHandler{
+			readFile: func(path string) ([]byte, error) {
+				return contents[path], nil
+			},
+		},
+		time: &mockTime{},
+		out:  io.Discard,
+		config: ResticConfig{
+			BackupPath:    "/data/backup",
+			Retention:     "30d",
+			TagConfigHash: true,
+		},
+		dotenvFiles: func() []string { return []string{"base.env", "host.env"} },
 	}
 
-	err := cloudBackup.Init()
+	err := cloudBackup.RunFullBackup()
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	expected := configHashTag(append(slices.Clone(contents["base.env"]), contents["host.env"]...))
+	if len(capturedTags) != 2 || capturedTags[1] != expected {
+		t.Errorf("expected the tag %q of both files, got %v", expected, capturedTags)
 	}
 }
 
-func TestCloudBackup_Check_Success(t *testing.T) {
-	checkCalled := false
+func TestCloudBackup_RunFullBackup_ConfigHashWithoutLoadedDotenv(t *testing.T) {
+	backupCalled := false
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			checkFunc: func() error {
-				checkCalled = true
-				return nil
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				backupCalled = true
+				return BackupSummary{}, nil
 			},
 		},
-		files:  &mockFilesHandler{},
-		config: ResticConfig{},
+		files: &mockFilesHandler{},
+		time:  &mockTime{},
+		out:   io.Discard,
+		config: ResticConfig{
+			BackupPath:    "/data/backup",
+			Retention:     "30d",
+			TagConfigHash: true,
+		},
+		dotenvFiles: func() []string { return nil },
 	}
 
-	err := cloudBackup.Check()
+	err := cloudBackup.RunFullBackup()
 
-	if err != nil {
-		t.Fatalf("expected no error, got: %v", err)
+	if err == nil {
+		t.Fatal("expected an error when no .env file was loaded")
 	}
-	if !checkCalled {
-		t.Error("expected Check to be called on client")
+	if backupCalled {
+		t.Error("expected Backup not to be called")
 	}
 }
 
-func TestCloudBackup_Check_Error(t *testing.T) {
-	expectedErr := errors.New("check failed")
+func TestCloudBackup_RunFullBackup_InitFails(t *testing.T) {
+	expectedErr := errors.New("init failed")
+	backupCalled := false
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			checkFunc: func() error {
+			initFunc: func() error {
 				return expectedErr
 			},
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				backupCalled = true
+				return BackupSummary{}, nil
+			},
+		},
+		files: &mockFilesHandler{},
+		time:  &mockTime{},
+		out:   io.Discard,
+		config: ResticConfig{
+			BackupPath: "/data/backup",
+			Retention:  "30d",
 		},
-		files:  &mockFilesHandler{},
-		config: ResticConfig{},
 	}
 
-	err := cloudBackup.Check()
+	err := cloudBackup.RunFullBackup()
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -377,44 +505,38 @@ func TestCloudBackup_Check_Error(t *testing.T) {
 	if !errors.Is(err, expectedErr) {
 		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
 	}
+	if backupCalled {
+		t.Error("expected Backup NOT to be called when Init fails")
+	}
 }
 
-func TestCloudBackup_ListSnapshots_Success(t *testing.T) {
-	snapshotsCalled := false
+func TestCloudBackup_RunFullBackup_BackupPathDoesNotExist(t *testing.T) {
+	expectedErr := errors.New("path does not exist")
+	backupCalled := false
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			snapshotsFunc: func() error {
-				snapshotsCalled = true
+			initFunc: func() error {
 				return nil
 			},
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				backupCalled = true
+				return BackupSummary{}, nil
+			},
 		},
-		files:  &mockFilesHandler{},
-		config: ResticConfig{},
-	}
-
-	err := cloudBackup.ListSnapshots()
-
-	if err != nil {
-		t.Fatalf("expected no error, got: %v", err)
-	}
-	if !snapshotsCalled {
-		t.Error("expected Snapshots to be called on client")
-	}
-}
-
-func TestCloudBackup_ListSnapshots_Error(t *testing.T) {
-	expectedErr := errors.New("snapshots failed")
-	cloudBackup := &CloudBackup{
-		client: &mockResticClient{
-			snapshotsFunc: func() error {
+		files: &mockFilesHandler{
+			ensureDirExists: func(path string) error {
 				return expectedErr
 			},
 		},
-		files:  &mockFilesHandler{},
-		config: ResticConfig{},
+		time: &mockTime{},
+		out:  io.Discard,
+		config: ResticConfig{
+			BackupPath: "/data/backup",
+			Retention:  "30d",
+		},
 	}
 
-	err := cloudBackup.ListSnapshots()
+	err := cloudBackup.RunFullBackup()
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -422,34 +544,506 @@ func TestCloudBackup_ListSnapshots_Error(t *testing.T) {
 	if !errors.Is(err, expectedErr) {
 		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
 	}
+	if backupCalled {
+		t.Error("expected Backup NOT to be called when path doesn't exist")
+	}
 }
 
-func TestCloudBackup_Prune_Success(t *testing.T) {
+func TestCloudBackup_RunFullBackup_BackupFails(t *testing.T) {
+	expectedErr := errors.New("backup failed")
 	forgetCalled := false
-	var capturedKeepWithin string
-	var capturedPrune bool
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
+			initFunc: func() error {
+				return nil
+			},
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				return BackupSummary{}, expectedErr
+			},
 			forgetFunc: func(keepWithin string, prune bool) error {
 				forgetCalled = true
-				capturedKeepWithin = keepWithin
-				capturedPrune = prune
 				return nil
 			},
 		},
 		files: &mockFilesHandler{},
+		time:  &mockTime{},
+		out:   io.Discard,
 		config: ResticConfig{
-			RetentionDays: 14,
+			BackupPath: "/data/backup",
+			Retention:  "30d",
 		},
 	}
 
-	err := cloudBackup.Prune()
+	err := cloudBackup.RunFullBackup()
 
-	if err != nil {
-		t.Fatalf("expected no error, got: %v", err)
+	if err == nil {
+		t.Fatal("expected error, got nil")
 	}
-	if !forgetCalled {
-		t.Error("expected Forget to be called on client")
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+	if forgetCalled {
+		t.Error("expected Forget NOT to be called when Backup fails")
+	}
+}
+
+func TestCloudBackup_RunInterruptible_CancellationUnlocks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	backupStarted := make(chan struct{})
+	var executedCmds []string
+	client := (&DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommandContext: func(ctx context.Context, cmd string) system.RunnableCommand {
+				executedCmds = append(executedCmds, cmd)
+				if !strings.Contains(cmd, "restic backup") {
+					return &mockRunnableCommand{}
+				}
+				// The backup runs until it is interrupted
+				return &mockRunnableCommand{runFunc: func() error {
+					close(backupStarted)
+					<-ctx.Done()
+					return ctx.Err()
+				}}
+			},
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmds = append(executedCmds, cmd)
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+	}).WithContext(ctx)
+	cloudBackup := &CloudBackup{
+		client: client,
+		files:  &mockFilesHandler{},
+		time:   &mockTime{},
+		out:    io.Discard,
+		config: ResticConfig{BackupPath: "/data/backup", Retention: "30d"},
+		ctx:    ctx,
+	}
+	go func() {
+		<-backupStarted
+		cancel()
+	}()
+
+	err := cloudBackup.RunInterruptible(cloudBackup.RunFullBackup)
+
+	if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected ErrInterrupted wrapping the cancellation, got: %v", err)
+	}
+	lastCmd := executedCmds[len(executedCmds)-1]
+	if !strings.HasSuffix(lastCmd, "restic unlock") {
+		t.Errorf("expected the repository to be unlocked last, got commands: %v", executedCmds)
+	}
+	if slices.ContainsFunc(executedCmds, func(cmd string) bool { return strings.Contains(cmd, "restic forget") }) {
+		t.Errorf("expected no prune after the interruption, got commands: %v", executedCmds)
+	}
+}
+
+func TestCloudBackup_RunInterruptible_FailureWithoutCancellation(t *testing.T) {
+	expectedErr := errors.New("backup failed")
+	unlockCalled := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			unlockFunc: func() error {
+				unlockCalled = true
+				return nil
+			},
+		},
+		ctx: context.Background(),
+	}
+
+	err := cloudBackup.RunInterruptible(func() error { return expectedErr })
+
+	if !errors.Is(err, expectedErr) || errors.Is(err, ErrInterrupted) {
+		t.Errorf("expected the error of the operation, got: %v", err)
+	}
+	if unlockCalled {
+		t.Error("expected Unlock NOT to be called when the operation was not interrupted")
+	}
+}
+
+func TestCloudBackup_RunInterruptible_UnlockFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	unlockErr := errors.New("repository unreachable")
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			unlockFunc: func() error {
+				return unlockErr
+			},
+		},
+		ctx: ctx,
+	}
+
+	err := cloudBackup.RunInterruptible(func() error { return ctx.Err() })
+
+	if !errors.Is(err, ErrInterrupted) || !errors.Is(err, unlockErr) {
+		t.Errorf("expected ErrInterrupted and the unlock error, got: %v", err)
+	}
+}
+
+func TestCloudBackup_RunFullBackup_RetriesAfterStaleLock(t *testing.T) {
+	var calls []string
+	backupAttempts := 0
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				calls = append(calls, "backup")
+				backupAttempts++
+				if backupAttempts == 1 {
+					return BackupSummary{}, fmt.Errorf("%w: %w", ErrResticCommandFailed, ErrRepositoryLocked)
+				}
+				return BackupSummary{}, nil
+			},
+			unlockFunc: func() error {
+				calls = append(calls, "unlock")
+				return nil
+			},
+			forgetFunc: func(keepWithin string, prune bool) error {
+				calls = append(calls, "forget")
+				return nil
+			},
+		},
+		files:  &mockFilesHandler{},
+		time:   &mockTime{},
+		out:    io.Discard,
+		config: ResticConfig{BackupPath: "/data/backup", Retention: "30d"},
+	}
+
+	err := cloudBackup.RunFullBackup()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"backup", "unlock", "backup", "forget"}, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCloudBackup_RunFullBackup_StillLockedAfterUnlock(t *testing.T) {
+	var calls []string
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				calls = append(calls, "backup")
+				return BackupSummary{}, fmt.Errorf("%w: %w", ErrResticCommandFailed, ErrRepositoryLocked)
+			},
+			unlockFunc: func() error {
+				calls = append(calls, "unlock")
+				return nil
+			},
+		},
+		files:  &mockFilesHandler{},
+		time:   &mockTime{},
+		out:    io.Discard,
+		config: ResticConfig{BackupPath: "/data/backup", Retention: "30d"},
+	}
+
+	err := cloudBackup.RunFullBackup()
+
+	if !errors.Is(err, ErrRepositoryLocked) {
+		t.Errorf("expected ErrRepositoryLocked, got: %v", err)
+	}
+	// The backup is retried only once
+	if diff := cmp.Diff([]string{"backup", "unlock", "backup"}, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCloudBackup_RunFullBackup_UnlockFailsAfterLock(t *testing.T) {
+	unlockErr := errors.New("repository unreachable")
+	backupAttempts := 0
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				backupAttempts++
+				return BackupSummary{}, fmt.Errorf("%w: %w", ErrResticCommandFailed, ErrRepositoryLocked)
+			},
+			unlockFunc: func() error {
+				return unlockErr
+			},
+		},
+		files:  &mockFilesHandler{},
+		time:   &mockTime{},
+		out:    io.Discard,
+		config: ResticConfig{BackupPath: "/data/backup", Retention: "30d"},
+	}
+
+	err := cloudBackup.RunFullBackup()
+
+	if !errors.Is(err, ErrRepositoryLocked) || !errors.Is(err, unlockErr) {
+		t.Errorf("expected the lock and the unlock errors, got: %v", err)
+	}
+	if backupAttempts != 1 {
+		t.Errorf("expected no retry when the unlock fails, got %d attempts", backupAttempts)
+	}
+}
+
+func TestCloudBackup_RunFullBackup_ForgetFails(t *testing.T) {
+	expectedErr := errors.New("forget failed")
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			initFunc: func() error {
+				return nil
+			},
+			backupFunc: func(path string, tags []string) (BackupSummary, error) {
+				return BackupSummary{}, nil
+			},
+			forgetFunc: func(keepWithin string, prune bool) error {
+				return expectedErr
+			},
+		},
+		files: &mockFilesHandler{},
+		time:  &mockTime{},
+		out:   io.Discard,
+		config: ResticConfig{
+			BackupPath: "/data/backup",
+			Retention:  "30d",
+		},
+	}
+
+	err := cloudBackup.RunFullBackup()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestCloudBackup_Init_Success(t *testing.T) {
+	initCalled := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			initFunc: func() error {
+				initCalled = true
+				return nil
+			},
+		},
+		files:  &mockFilesHandler{},
+		config: ResticConfig{},
+	}
+
+	err := cloudBackup.Init()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !initCalled {
+		t.Error("expected Init to be called on client")
+	}
+}
+
+func TestCloudBackup_Init_Error(t *testing.T) {
+	expectedErr := errors.New("init failed")
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			initFunc: func() error {
+				return expectedErr
+			},
+		},
+		files:  &mockFilesHandler{},
+		config: ResticConfig{},
+	}
+
+	err := cloudBackup.Init()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestCloudBackup_Check_Success(t *testing.T) {
+	checkCalled := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			checkFunc: func() error {
+				checkCalled = true
+				return nil
+			},
+		},
+		files:  &mockFilesHandler{},
+		config: ResticConfig{},
+	}
+
+	err := cloudBackup.Check()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !checkCalled {
+		t.Error("expected Check to be called on client")
+	}
+}
+
+func TestCloudBackup_Check_Error(t *testing.T) {
+	expectedErr := errors.New("check failed")
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			checkFunc: func() error {
+				return expectedErr
+			},
+		},
+		files:  &mockFilesHandler{},
+		config: ResticConfig{},
+	}
+
+	err := cloudBackup.Check()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestCloudBackup_ListSnapshots_Success(t *testing.T) {
+	snapshotsCalled := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			snapshotsFunc: func(groupBy []string, latest int) error {
+				snapshotsCalled = true
+				return nil
+			},
+		},
+		files:  &mockFilesHandler{},
+		config: ResticConfig{},
+	}
+
+	err := cloudBackup.ListSnapshots(nil, 0)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !snapshotsCalled {
+		t.Error("expected Snapshots to be called on client")
+	}
+}
+
+func TestCloudBackup_ListSnapshots_GroupBy(t *testing.T) {
+	var capturedGroupBy []string
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			snapshotsFunc: func(groupBy []string, latest int) error {
+				capturedGroupBy = groupBy
+				return nil
+			},
+		},
+	}
+
+	err := cloudBackup.ListSnapshots([]string{"host", "tags"}, 0)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"host", "tags"}, capturedGroupBy); diff != "" {
+		t.Errorf("group by mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCloudBackup_ListSnapshots_InvalidGroupBy(t *testing.T) {
+	snapshotsCalled := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			snapshotsFunc: func(groupBy []string, latest int) error {
+				snapshotsCalled = true
+				return nil
+			},
+		},
+	}
+
+	err := cloudBackup.ListSnapshots([]string{"host", "hostname"}, 0)
+
+	if !errors.Is(err, ErrInvalidGroupBy) {
+		t.Errorf("expected ErrInvalidGroupBy, got: %v", err)
+	}
+	if snapshotsCalled {
+		t.Error("expected snapshots not to be listed with an invalid key")
+	}
+}
+
+func TestCloudBackup_ListSnapshots_Latest(t *testing.T) {
+	var capturedLatest int
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			snapshotsFunc: func(groupBy []string, latest int) error {
+				capturedLatest = latest
+				return nil
+			},
+		},
+	}
+
+	err := cloudBackup.ListSnapshots(nil, 3)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedLatest != 3 {
+		t.Errorf("expected latest %d, got %d", 3, capturedLatest)
+	}
+}
+
+func TestCloudBackup_ListSnapshots_NegativeLatest(t *testing.T) {
+	err := (&CloudBackup{client: &mockResticClient{}}).ListSnapshots(nil, -1)
+
+	if !errors.Is(err, ErrInvalidLatest) {
+		t.Errorf("expected ErrInvalidLatest, got: %v", err)
+	}
+}
+
+func TestCloudBackup_ListSnapshots_Error(t *testing.T) {
+	expectedErr := errors.New("snapshots failed")
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			snapshotsFunc: func(groupBy []string, latest int) error {
+				return expectedErr
+			},
+		},
+		files:  &mockFilesHandler{},
+		config: ResticConfig{},
+	}
+
+	err := cloudBackup.ListSnapshots(nil, 0)
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestCloudBackup_Prune_Success(t *testing.T) {
+	forgetCalled := false
+	var capturedKeepWithin string
+	var capturedPrune bool
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			forgetFunc: func(keepWithin string, prune bool) error {
+				forgetCalled = true
+				capturedKeepWithin = keepWithin
+				capturedPrune = prune
+				return nil
+			},
+		},
+		files: &mockFilesHandler{},
+		config: ResticConfig{
+			Retention: "14d",
+		},
+	}
+
+	err := cloudBackup.Prune()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !forgetCalled {
+		t.Error("expected Forget to be called on client")
 	}
 	if capturedKeepWithin != "14d" {
 		t.Errorf("expected keepWithin %q, got %q", "14d", capturedKeepWithin)
@@ -469,7 +1063,7 @@ func TestCloudBackup_Prune_Error(t *testing.T) {
 		},
 		files: &mockFilesHandler{},
 		config: ResticConfig{
-			RetentionDays: 14,
+			Retention: "14d",
 		},
 	}
 
@@ -490,7 +1084,7 @@ func TestCloudBackup_Restore_Success(t *testing.T) {
 	var capturedCreateDir string
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			restoreFunc: func(targetDir string) error {
+			restoreFunc: func(targetDir string, dryRun bool) error {
 				restoreCalled = true
 				capturedTargetDir = targetDir
 				return nil
@@ -506,7 +1100,7 @@ func TestCloudBackup_Restore_Success(t *testing.T) {
 		config: ResticConfig{},
 	}
 
-	err := cloudBackup.Restore("/restore/target")
+	err := cloudBackup.Restore("/restore/target", false)
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
@@ -525,13 +1119,114 @@ func TestCloudBackup_Restore_Success(t *testing.T) {
 	}
 }
 
+func TestCloudBackup_Restore_ChangesOwnerAndMode(t *testing.T) {
+	var commands [][]string
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{},
+		files:  &mockFilesHandler{},
+		commands: &mockCommands{
+			execCommand: func(name string, arg ...string) system.RunnableCommand {
+				commands = append(commands, append([]string{name}, arg...))
+				return &mockRunnableCommand{}
+			},
+		},
+		config: ResticConfig{RestoreOwner: "1000:1000", RestoreMode: "750"},
+	}
+
+	err := cloudBackup.Restore("/restore/target", false)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := [][]string{
+		{"chown", "-R", "1000:1000", "/restore/target"},
+		{"chmod", "-R", "750", "/restore/target"},
+	}
+	if diff := cmp.Diff(expected, commands); diff != "" {
+		t.Errorf("commands mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCloudBackup_Restore_NoOwnerOrModeChangesByDefault(t *testing.T) {
+	execCommandCalled := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{},
+		files:  &mockFilesHandler{},
+		commands: &mockCommands{
+			execCommand: func(name string, arg ...string) system.RunnableCommand {
+				execCommandCalled = true
+				return &mockRunnableCommand{}
+			},
+		},
+		config: ResticConfig{},
+	}
+
+	err := cloudBackup.Restore("/restore/target", false)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if execCommandCalled {
+		t.Error("expected no command to be run when the owner and mode are not configured")
+	}
+}
+
+func TestCloudBackup_Restore_DryRunDoesNotChangeOwnerOrMode(t *testing.T) {
+	execCommandCalled := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{},
+		files:  &mockFilesHandler{},
+		commands: &mockCommands{
+			execCommand: func(name string, arg ...string) system.RunnableCommand {
+				execCommandCalled = true
+				return &mockRunnableCommand{}
+			},
+		},
+		config: ResticConfig{RestoreOwner: "1000:1000", RestoreMode: "750"},
+	}
+
+	err := cloudBackup.Restore("/restore/target", true)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if execCommandCalled {
+		t.Error("expected no command to be run in a dry run")
+	}
+}
+
+func TestCloudBackup_Restore_ChownError(t *testing.T) {
+	expectedErr := errors.New("operation not permitted")
+	var commandNames []string
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{},
+		files:  &mockFilesHandler{},
+		commands: &mockCommands{
+			execCommand: func(name string, arg ...string) system.RunnableCommand {
+				commandNames = append(commandNames, name)
+				return &mockRunnableCommand{runFunc: func() error { return expectedErr }}
+			},
+		},
+		config: ResticConfig{RestoreOwner: "1000:1000", RestoreMode: "750"},
+	}
+
+	err := cloudBackup.Restore("/restore/target", false)
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+	if diff := cmp.Diff([]string{"chown"}, commandNames); diff != "" {
+		t.Errorf("expected chmod not to run after chown fails (-want +got):\n%s", diff)
+	}
+}
+
 func TestCloudBackup_Restore_GetAbsPathError(t *testing.T) {
 	expectedErr := errors.New("get abs path failed")
 	createDirCalled := false
 	restoreCalled := false
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			restoreFunc: func(targetDir string) error {
+			restoreFunc: func(targetDir string, dryRun bool) error {
 				restoreCalled = true
 				return nil
 			},
@@ -548,7 +1243,7 @@ func TestCloudBackup_Restore_GetAbsPathError(t *testing.T) {
 		config: ResticConfig{},
 	}
 
-	err := cloudBackup.Restore("/restore/target")
+	err := cloudBackup.Restore("/restore/target", false)
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -569,7 +1264,7 @@ func TestCloudBackup_Restore_CreateDirError(t *testing.T) {
 	restoreCalled := false
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			restoreFunc: func(targetDir string) error {
+			restoreFunc: func(targetDir string, dryRun bool) error {
 				restoreCalled = true
 				return nil
 			},
@@ -582,7 +1277,7 @@ func TestCloudBackup_Restore_CreateDirError(t *testing.T) {
 		config: ResticConfig{},
 	}
 
-	err := cloudBackup.Restore("/restore/target")
+	err := cloudBackup.Restore("/restore/target", false)
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -599,7 +1294,7 @@ func TestCloudBackup_Restore_RestoreError(t *testing.T) {
 	expectedErr := errors.New("restore failed")
 	cloudBackup := &CloudBackup{
 		client: &mockResticClient{
-			restoreFunc: func(targetDir string) error {
+			restoreFunc: func(targetDir string, dryRun bool) error {
 				return expectedErr
 			},
 		},
@@ -607,7 +1302,7 @@ func TestCloudBackup_Restore_RestoreError(t *testing.T) {
 		config: ResticConfig{},
 	}
 
-	err := cloudBackup.Restore("/restore/target")
+	err := cloudBackup.Restore("/restore/target", false)
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -617,6 +1312,38 @@ func TestCloudBackup_Restore_RestoreError(t *testing.T) {
 	}
 }
 
+func TestCloudBackup_Restore_DryRunDoesNotCreateTargetDir(t *testing.T) {
+	createDirCalled := false
+	var capturedDryRun bool
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			restoreFunc: func(targetDir string, dryRun bool) error {
+				capturedDryRun = dryRun
+				return nil
+			},
+		},
+		files: &mockFilesHandler{
+			createDirIfNotExists: func(path string) error {
+				createDirCalled = true
+				return nil
+			},
+		},
+		config: ResticConfig{},
+	}
+
+	err := cloudBackup.Restore("/restore/target", true)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if createDirCalled {
+		t.Error("expected CreateDirIfNotExists NOT to be called in dry run mode")
+	}
+	if !capturedDryRun {
+		t.Error("expected Restore to be called on client with dryRun set to true")
+	}
+}
+
 func TestCloudBackup_ListFiles_Success(t *testing.T) {
 	listFilesCalled := false
 	var capturedSnapshotID string
@@ -666,3 +1393,323 @@ func TestCloudBackup_ListFiles_Error(t *testing.T) {
 		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
 	}
 }
+
+func TestParseAutomaticTags_Deduplicated(t *testing.T) {
+	snapshotsJSON := []byte(`[
+		{"id": "a1", "time": "2025-01-03T03:00:00Z", "tags": ["automatic-2025-01-03_03-00-00", "config-0123456789ab"]},
+		{"id": "b2", "time": "2025-01-01T03:00:00Z", "tags": ["automatic-2025-01-01_03-00-00"]},
+		{"id": "c3", "time": "2025-01-02T10:00:00Z", "tags": ["manual"]},
+		{"id": "d4", "time": "2025-01-02T11:00:00Z"},
+		{"id": "e5", "time": "2025-01-03T03:00:00Z", "tags": ["automatic-2025-01-03_03-00-00"]}
+	]`)
+
+	tags, err := parseAutomaticTags(snapshotsJSON, automaticTagPrefix)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := []string{"automatic-2025-01-01_03-00-00", "automatic-2025-01-03_03-00-00"}
+	if diff := cmp.Diff(expected, tags); diff != "" {
+		t.Errorf("tags mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseAutomaticTags_InvalidJSON(t *testing.T) {
+	_, err := parseAutomaticTags([]byte("Fatal: repository does not exist"), automaticTagPrefix)
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCloudBackup_ListAutomaticTags_Success(t *testing.T) {
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			snapshotsJSON: func() ([]byte, error) {
+				return []byte(`[{"tags": ["automatic-2025-01-01_03-00-00"]}]`), nil
+			},
+		},
+	}
+
+	tags, err := cloudBackup.ListAutomaticTags()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"automatic-2025-01-01_03-00-00"}, tags); diff != "" {
+		t.Errorf("tags mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCloudBackup_ListAutomaticTags_CustomPrefix(t *testing.T) {
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			snapshotsJSON: func() ([]byte, error) {
+				return []byte(`[
+					{"tags": ["media-host-2025-01-02_03-00-00"]},
+					{"tags": ["automatic-2025-01-01_03-00-00"]},
+					{"tags": ["nas-host-2025-01-01_04-00-00"]}
+				]`), nil
+			},
+		},
+		config: ResticConfig{TagPrefix: "media-host-"},
+	}
+
+	tags, err := cloudBackup.ListAutomaticTags()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"media-host-2025-01-02_03-00-00"}, tags); diff != "" {
+		t.Errorf("tags mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCloudBackup_ListAutomaticTags_SnapshotsError(t *testing.T) {
+	expectedErr := errors.New("snapshots failed")
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			snapshotsJSON: func() ([]byte, error) {
+				return nil, expectedErr
+			},
+		},
+	}
+
+	_, err := cloudBackup.ListAutomaticTags()
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestCloudBackup_ChangePassword_Success(t *testing.T) {
+	var capturedFile string
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			changePasswd: func(newPasswordFile string) error {
+				capturedFile = newPasswordFile
+				return nil
+			},
+		},
+		files: &mockFilesHandler{
+			getAbsPath: func(path string) (string, error) {
+				return "/home/user/" + path, nil
+			},
+			readFile: func(path string) ([]byte, error) {
+				return []byte("new-password\n"), nil
+			},
+		},
+	}
+
+	err := cloudBackup.ChangePassword("new-password.txt")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedFile != "/home/user/new-password.txt" {
+		t.Errorf("expected the absolute path of the new password file, got: %q", capturedFile)
+	}
+}
+
+func TestCloudBackup_ChangePassword_EmptyPasswordFile(t *testing.T) {
+	called := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			changePasswd: func(newPasswordFile string) error {
+				called = true
+				return nil
+			},
+		},
+		files: &mockFilesHandler{
+			readFile: func(path string) ([]byte, error) {
+				return []byte(" \n"), nil
+			},
+		},
+	}
+
+	err := cloudBackup.ChangePassword("/secrets/new-password.txt")
+
+	if !errors.Is(err, ErrInvalidResticConfig) {
+		t.Errorf("expected ErrInvalidResticConfig, got: %v", err)
+	}
+	if called {
+		t.Error("expected the password not to be changed")
+	}
+}
+
+func TestCloudBackup_ChangePassword_ResticFails(t *testing.T) {
+	expectedErr := errors.New("wrong password")
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			changePasswd: func(newPasswordFile string) error {
+				return expectedErr
+			},
+		},
+		files: &mockFilesHandler{
+			readFile: func(path string) ([]byte, error) {
+				return []byte("new-password"), nil
+			},
+		},
+	}
+
+	err := cloudBackup.ChangePassword("/secrets/new-password.txt")
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestCloudBackup_Copy_Success(t *testing.T) {
+	called := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			copyFunc: func() error {
+				called = true
+				return nil
+			},
+		},
+		config: ResticConfig{CopyRepositoryURL: "/mnt/disk2/restic", CopyResticPassword: "p4"},
+	}
+
+	err := cloudBackup.Copy()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !called {
+		t.Error("expected the snapshots to be copied")
+	}
+}
+
+func TestCloudBackup_Copy_NotConfigured(t *testing.T) {
+	called := false
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			copyFunc: func() error {
+				called = true
+				return nil
+			},
+		},
+	}
+
+	err := cloudBackup.Copy()
+
+	if !errors.Is(err, ErrInvalidResticConfig) {
+		t.Errorf("expected ErrInvalidResticConfig, got: %v", err)
+	}
+	if called {
+		t.Error("expected no copy without a secondary repository")
+	}
+}
+
+func TestCloudBackup_Copy_ResticFails(t *testing.T) {
+	expectedErr := errors.New("copy failed")
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			copyFunc: func() error { return expectedErr },
+		},
+		config: ResticConfig{CopyRepositoryURL: "/mnt/disk2/restic", CopyResticPassword: "p4"},
+	}
+
+	err := cloudBackup.Copy()
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+// recentSnapshotsJSON contains snapshots taken 10 days, 3 days and 1 hour before 2025-01-15 12:00 UTC, out of order
+var recentSnapshotsJSON = []byte(`[
+	{"short_id": "b2", "time": "2025-01-12T12:00:00Z", "hostname": "media", "tags": ["automatic-2025-01-12_12-00-00"], "paths": ["/data"]},
+	{"short_id": "a1", "time": "2025-01-05T12:00:00Z", "hostname": "media", "tags": ["automatic-2025-01-05_12-00-00"], "paths": ["/data"]},
+	{"short_id": "c3", "time": "2025-01-15T11:00:00Z", "hostname": "media", "paths": ["/data"]}
+]`)
+
+func TestParseSnapshotsSince_OnlyReturnsSnapshotsWithinWindow(t *testing.T) {
+	cutoff := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	snapshots, err := parseSnapshotsSince(recentSnapshotsJSON, cutoff)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var ids []string
+	for _, snapshot := range snapshots {
+		ids = append(ids, snapshot.ShortID)
+	}
+	if diff := cmp.Diff([]string{"b2", "c3"}, ids); diff != "" {
+		t.Errorf("snapshots mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseSnapshotsSince_InvalidJSON(t *testing.T) {
+	_, err := parseSnapshotsSince([]byte("Fatal: repository does not exist"), time.Time{})
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCloudBackup_ListSnapshotsSince_PrintsRecentSnapshots(t *testing.T) {
+	var out bytes.Buffer
+	cloudBackup := &CloudBackup{
+		client: &mockResticClient{
+			snapshotsJSON: func() ([]byte, error) { return recentSnapshotsJSON, nil },
+		},
+		time: &mockTime{
+			now: func() time.Time { return time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC) },
+		},
+		out: &out,
+	}
+
+	err := cloudBackup.ListSnapshotsSince(7 * 24 * time.Hour)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	output := out.String()
+	if strings.Contains(output, "a1") {
+		t.Errorf("expected the snapshot older than the window not to be listed, got:\n%s", output)
+	}
+	for _, expected := range []string{"b2", "c3", "automatic-2025-01-12_12-00-00", "2 snapshots"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{value: "7d", expected: 7 * 24 * time.Hour},
+		{value: "2w", expected: 14 * 24 * time.Hour},
+		{value: "36h", expected: 36 * time.Hour},
+		{value: "90m", expected: 90 * time.Minute},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			since, err := ParseSince(tc.value)
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if since != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, since)
+			}
+		})
+	}
+}
+
+func TestParseSince_Invalid(t *testing.T) {
+	for _, value := range []string{"", "7", "d", "7 days", "0d", "-1h"} {
+		t.Run(value, func(t *testing.T) {
+			_, err := ParseSince(value)
+
+			if !errors.Is(err, ErrInvalidSince) {
+				t.Errorf("expected ErrInvalidSince, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+var (
+	ErrBackupHookFailed = errors.New("backup hook failed")
+)
+
+const (
+	preHookEnvVar  = "HOMELAB_BACKUP_PRE_HOOK"
+	postHookEnvVar = "HOMELAB_BACKUP_POST_HOOK"
+)
+
+// BackupHooks runs the commands configured in HOMELAB_BACKUP_PRE_HOOK and HOMELAB_BACKUP_POST_HOOK around a backup.
+// For example, they can be used to put a service in maintenance mode while it is being backed up
+type BackupHooks struct {
+	commands system.Commands
+	env      system.Env
+}
+
+// NewBackupHooks creates a new BackupHooks instance
+func NewBackupHooks() *BackupHooks {
+	return &BackupHooks{
+		commands: system.NewDefaultCommands(),
+		env:      system.NewDefaultEnv(),
+	}
+}
+
+// RunAround runs the pre hook, then the backup, then the post hook. The backup is not run if the pre hook fails.
+// The post hook always runs, even if the pre hook or the backup fail, and its error is reported separately from
+// theirs. Hooks that are not defined are skipped
+func (h *BackupHooks) RunAround(backupFn func() error) error {
+	err := h.runHook(preHookEnvVar)
+	if err == nil {
+		err = backupFn()
+	}
+
+	if postHookErr := h.runHook(postHookEnvVar); postHookErr != nil {
+		return errors.Join(err, postHookErr)
+	}
+	return err
+}
+
+// runHook runs the command defined in the varName environment variable, if any
+func (h *BackupHooks) runHook(varName string) error {
+	hook, exists := h.env.GetEnv(varName)
+	if !exists || strings.TrimSpace(hook) == "" {
+		return nil
+	}
+
+	slog.Info("Running backup hook", "varName", varName, "hook", hook)
+	if err := h.commands.ExecShellCommand(hook).Run(); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrBackupHookFailed, varName, err)
+	}
+	slog.Info("Successfully ran backup hook", "varName", varName)
+	return nil
+}
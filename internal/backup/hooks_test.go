@@ -0,0 +1,168 @@
+package backup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newHooksEnv(preHook string, postHook string) *mockEnv {
+	return &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			switch varName {
+			case "HOMELAB_BACKUP_PRE_HOOK":
+				return preHook, preHook != ""
+			case "HOMELAB_BACKUP_POST_HOOK":
+				return postHook, postHook != ""
+			}
+			return "", false
+		},
+	}
+}
+
+func TestBackupHooks_RunAround_RunsHooksInOrder(t *testing.T) {
+	var calls []string
+	hooks := &BackupHooks{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				calls = append(calls, cmd)
+				return &mockRunnableCommand{}
+			},
+		},
+		env: newHooksEnv("pre-hook-cmd", "post-hook-cmd"),
+	}
+
+	err := hooks.RunAround(func() error {
+		calls = append(calls, "backup")
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := []string{"pre-hook-cmd", "backup", "post-hook-cmd"}
+	if diff := cmp.Diff(expected, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBackupHooks_RunAround_NoHooksDefined(t *testing.T) {
+	commandCalled := false
+	backupCalled := false
+	hooks := &BackupHooks{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				commandCalled = true
+				return &mockRunnableCommand{}
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	err := hooks.RunAround(func() error {
+		backupCalled = true
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !backupCalled {
+		t.Error("expected backup to be called")
+	}
+	if commandCalled {
+		t.Error("expected no hook command to be run")
+	}
+}
+
+func TestBackupHooks_RunAround_PostHookRunsWhenBackupFails(t *testing.T) {
+	backupErr := errors.New("backup failed")
+	var calls []string
+	hooks := &BackupHooks{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				calls = append(calls, cmd)
+				return &mockRunnableCommand{}
+			},
+		},
+		env: newHooksEnv("pre-hook-cmd", "post-hook-cmd"),
+	}
+
+	err := hooks.RunAround(func() error {
+		calls = append(calls, "backup")
+		return backupErr
+	})
+
+	if !errors.Is(err, backupErr) {
+		t.Errorf("expected error to wrap %v, got: %v", backupErr, err)
+	}
+	if errors.Is(err, ErrBackupHookFailed) {
+		t.Errorf("expected error NOT to wrap ErrBackupHookFailed, got: %v", err)
+	}
+	expected := []string{"pre-hook-cmd", "backup", "post-hook-cmd"}
+	if diff := cmp.Diff(expected, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBackupHooks_RunAround_PreHookFailureSkipsBackupButRunsPostHook(t *testing.T) {
+	hookErr := errors.New("hook failed")
+	var calls []string
+	hooks := &BackupHooks{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				calls = append(calls, cmd)
+				if cmd == "pre-hook-cmd" {
+					return &mockRunnableCommand{runFunc: func() error { return hookErr }}
+				}
+				return &mockRunnableCommand{}
+			},
+		},
+		env: newHooksEnv("pre-hook-cmd", "post-hook-cmd"),
+	}
+
+	err := hooks.RunAround(func() error {
+		calls = append(calls, "backup")
+		return nil
+	})
+
+	if !errors.Is(err, ErrBackupHookFailed) {
+		t.Errorf("expected ErrBackupHookFailed, got: %v", err)
+	}
+	if !errors.Is(err, hookErr) {
+		t.Errorf("expected error to wrap %v, got: %v", hookErr, err)
+	}
+	expected := []string{"pre-hook-cmd", "post-hook-cmd"}
+	if diff := cmp.Diff(expected, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBackupHooks_RunAround_ReportsBackupAndPostHookErrors(t *testing.T) {
+	backupErr := errors.New("backup failed")
+	hookErr := errors.New("hook failed")
+	hooks := &BackupHooks{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{runFunc: func() error { return hookErr }}
+			},
+		},
+		env: newHooksEnv("", "post-hook-cmd"),
+	}
+
+	err := hooks.RunAround(func() error {
+		return backupErr
+	})
+
+	if !errors.Is(err, backupErr) {
+		t.Errorf("expected error to wrap %v, got: %v", backupErr, err)
+	}
+	if !errors.Is(err, ErrBackupHookFailed) {
+		t.Errorf("expected ErrBackupHookFailed, got: %v", err)
+	}
+	if !errors.Is(err, hookErr) {
+		t.Errorf("expected error to wrap %v, got: %v", hookErr, err)
+	}
+}
@@ -1,25 +1,101 @@
 package backup
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 )
 
+var (
+	ErrInvalidDumpFilename     = errors.New("invalid database dump filename")
+	ErrNestedBackupPaths       = errors.New("backup source and destination are nested")
+	ErrInvalidReadinessTimeout = errors.New("invalid readiness timeout")
+)
+
 // LocalBackup is the interface for all backup operations
 type LocalBackup interface {
 	// Run executes the backup operation
 	Run() error
 }
 
+// ReadinessCheck is a command that succeeds once a container is ready to be backed up
+type ReadinessCheck struct {
+	ContainerName string
+	Cmd           string
+	// Timeout is how long the command is retried for. The default wait of docker.Runner is used when it is zero
+	Timeout time.Duration
+}
+
+// LoadReadinessTimeout returns the readiness timeout in the variable varName, such as "5m". It returns zero, which
+// makes the backup use the default wait, when the variable is not set
+func LoadReadinessTimeout(env system.Env, varName string) (time.Duration, error) {
+	value, exists := env.GetEnv(varName)
+	if !exists || strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%w %q: %w", ErrInvalidReadinessTimeout, varName, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("%w %q: must be greater than zero, got %q", ErrInvalidReadinessTimeout, varName, value)
+	}
+	return timeout, nil
+}
+
+// ReadinessChecker is implemented by the backup operations that need a container to be ready before they can run
+type ReadinessChecker interface {
+	// ReadinessCheck returns the check that must succeed before running the backup operation
+	ReadinessCheck() ReadinessCheck
+}
+
+// DstPathProvider is implemented by the backup operations that store the backup in a destination directory
+type DstPathProvider interface {
+	// DstPath returns the directory where the backup is stored
+	DstPath() string
+}
+
+// SrcPathProvider is implemented by the backup operations that copy a source directory
+type SrcPathProvider interface {
+	// SrcPath returns the directory that is backed up
+	SrcPath() string
+}
+
+// DisabledServicesProvider is implemented by the backup operations whose data must not change while they run, so the
+// services that write it must not be running during the backup
+type DisabledServicesProvider interface {
+	// RequiresServicesDisabled returns the Docker Compose services that must be stopped during the backup
+	RequiresServicesDisabled() []string
+}
+
+// ExclusionGrouper is implemented by the backup operations that can be kept from running at the same time as some
+// other operations
+type ExclusionGrouper interface {
+	// ExclusionGroup returns the group of operations that must run one after the other, or an empty string if the
+	// operation can run at the same time as any other
+	ExclusionGroup() string
+	// SetExclusionGroup puts the operation in a group of operations that must run one after the other
+	SetExclusionGroup(group string)
+}
+
 // baseLocalBackup contains common backup functionality
 type baseLocalBackup struct {
 	dstPath string
 	files   system.FilesHandler
+	// exclusionGroup is the group of operations that must not run at the same time as this one
+	exclusionGroup string
 }
 
 // newBaseLocalBackup creates a new base backup instance
@@ -33,6 +109,80 @@ func newBaseLocalBackup(
 	}
 }
 
+// DstPath returns the directory where the backup is stored
+func (b *baseLocalBackup) DstPath() string {
+	return b.dstPath
+}
+
+// ExclusionGroup returns the group of operations that must not run at the same time as this one
+func (b *baseLocalBackup) ExclusionGroup() string {
+	return b.exclusionGroup
+}
+
+// SetExclusionGroup makes the backup run one after the other with the operations of the same group, such as the ones
+// that stop and start the same service, instead of at the same time
+func (b *baseLocalBackup) SetExclusionGroup(group string) {
+	b.exclusionGroup = group
+}
+
+// quoteExtraArgs quotes each argument for the shell and joins them, with a leading space so that they can be appended
+// to a command. It returns an empty string when there are no arguments
+func quoteExtraArgs(textFormatter format.TextFormatter, args []string) string {
+	var quoted strings.Builder
+	for _, arg := range args {
+		quoted.WriteString(" " + textFormatter.QuoteForPOSIXShell(arg))
+	}
+	return quoted.String()
+}
+
+const (
+	// dumpFilenameDBPlaceholder is replaced with the name of the database in dump filename templates
+	dumpFilenameDBPlaceholder = "<db>"
+	// dumpFilenameTimestampPlaceholder is replaced with the time of the backup in dump filename templates
+	dumpFilenameTimestampPlaceholder = "<ts>"
+	// defaultDumpFilenameTemplate names database dumps after the database, such as "immich.sql"
+	defaultDumpFilenameTemplate = dumpFilenameDBPlaceholder + ".sql"
+	// dumpFilenameTimeFormat is the format of dumpFilenameTimestampPlaceholder, which is safe to use in filenames
+	dumpFilenameTimeFormat = "2006-01-02_15-04-05"
+)
+
+// dumpFilenamePattern matches the filenames that are safe to use unquoted in the shell redirect of a dump
+var dumpFilenamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// resolveDumpFilename replaces the placeholders of a dump filename template. An empty template resolves to
+// defaultDumpFilenameTemplate, and the clock is only read when the template contains dumpFilenameTimestampPlaceholder.
+// The shell redirects the dump into the destination directory, so the filename can only have letters, digits, dots,
+// dashes and underscores
+func resolveDumpFilename(template string, dbName string, clock system.Time) (string, error) {
+	if template == "" {
+		template = defaultDumpFilenameTemplate
+	}
+	filename := strings.ReplaceAll(template, dumpFilenameDBPlaceholder, dbName)
+	if strings.Contains(filename, dumpFilenameTimestampPlaceholder) {
+		timestamp := clock.Now().Format(dumpFilenameTimeFormat)
+		filename = strings.ReplaceAll(filename, dumpFilenameTimestampPlaceholder, timestamp)
+	}
+	if filename == "." || filename == ".." || !dumpFilenamePattern.MatchString(filename) {
+		return "", fmt.Errorf("%w %q: it can only have letters, digits, dots, dashes and underscores", ErrInvalidDumpFilename, filename)
+	}
+	return filename, nil
+}
+
+// LoadDumpFilenameTemplate returns the dump filename template in the variable varName, such as "<db>-<ts>.sql". It
+// returns an empty string, which makes the backup use defaultDumpFilenameTemplate, when the variable is not set. The
+// template is checked with dbName, so that an invalid one fails before any backup starts
+func LoadDumpFilenameTemplate(env system.Env, varName string, dbName string) (string, error) {
+	value, exists := env.GetEnv(varName)
+	template := strings.TrimSpace(value)
+	if !exists || template == "" {
+		return "", nil
+	}
+	if _, err := resolveDumpFilename(template, dbName, system.NewDefaultTime()); err != nil {
+		return "", fmt.Errorf("%q: %w", varName, err)
+	}
+	return template, nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////////
 ///// SPECIFIC BACKUPS below
 ///////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -46,6 +196,17 @@ type DirectoryLocalBackup struct {
 	//  and at that point we have missed all the nice abstractions we have made on top of Docker with the
 	//  docker.Runner interface
 	preCommand string
+	// env resolves the ${VAR} references in preCommand
+	env system.Env
+	// stderr receives the standard error of preCommand as it runs. Its tail is also included in the error when
+	// preCommand fails
+	stderr io.Writer
+	// incremental makes the backup copy only the files that changed, with rsync
+	incremental bool
+	// copyOptions are used to copy the directory natively instead of with cp, when any of them is set
+	copyOptions system.CopyDirOptions
+	// disabledServices are the services that must be stopped while the directory is copied
+	disabledServices []string
 }
 
 // NewDirectoryLocalBackup creates a new directory backup instance
@@ -61,29 +222,103 @@ func NewDirectoryLocalBackup(
 		commands:   system.NewDefaultCommands(),
 		srcPath:    srcPath,
 		preCommand: preCommand,
+		env:        system.NewDefaultEnv(),
+		stderr:     os.Stderr,
 	}
 }
 
+func (d *DirectoryLocalBackup) SrcPath() string {
+	return d.srcPath
+}
+
+// WithIncremental makes the backup copy only the files that changed since the previous backup, and delete the files
+// that no longer exist in the source, by using rsync. This only saves time when the destination keeps the previous
+// backup. If rsync is not installed, the previous copy is deleted and the whole directory is copied
+func (d *DirectoryLocalBackup) WithIncremental() *DirectoryLocalBackup {
+	d.incremental = true
+	return d
+}
+
+// WithSkipUnreadable makes the backup skip and log the files and directories that cannot be read, such as the ones
+// owned by another user, instead of failing the whole copy. With rsync, this accepts its partial transfers instead
+func (d *DirectoryLocalBackup) WithSkipUnreadable() *DirectoryLocalBackup {
+	d.copyOptions.SkipUnreadable = true
+	return d
+}
+
+// WithFollowSymlinks makes the backup copy the files and directories that symbolic links point to, instead of the
+// links, so that the backup does not depend on files outside of it
+func (d *DirectoryLocalBackup) WithFollowSymlinks() *DirectoryLocalBackup {
+	d.copyOptions.FollowSymlinks = true
+	return d
+}
+
+// WithServicesDisabled makes the services not be started for the backup, and be stopped if they are running, because
+// they write to the directory while it is copied. For example, a service that keeps a SQLite database in the directory
+func (d *DirectoryLocalBackup) WithServicesDisabled(services ...string) *DirectoryLocalBackup {
+	d.disabledServices = append(d.disabledServices, services...)
+	return d
+}
+
+func (d *DirectoryLocalBackup) RequiresServicesDisabled() []string {
+	return d.disabledServices
+}
+
 // Run executes the directory backup operation
 func (d *DirectoryLocalBackup) Run() error {
 	slog.Info("Running directory local backup", "srcPath", d.srcPath, "dstPath", d.dstPath)
+	// The references are expanded first, so that nothing is done if any of them is unknown
+	preCommand, err := expandEnvReferences(d.env, d.preCommand)
+	if err != nil {
+		return fmt.Errorf("invalid pre-command: %w", err)
+	}
+	if err := d.ensurePathsNotNested(); err != nil {
+		return err
+	}
+
 	if err := d.files.CreateDirIfNotExists(d.dstPath); err != nil {
 		return err
 	}
 
-	if d.preCommand != "" {
-		slog.Info("Running pre-command", "preCommand", d.preCommand)
-		cmd := d.commands.ExecShellCommand(d.preCommand)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("pre-command failed: %w", err)
+	if preCommand != "" {
+		slog.Info("Running pre-command", "preCommand", preCommand)
+		if err := d.runPreCommand(preCommand); err != nil {
+			return err
 		}
-		slog.Info("Successfully ran pre-command", "preCommand", d.preCommand)
+		slog.Info("Successfully ran pre-command", "preCommand", preCommand)
 	}
 
 	if err := d.files.EnsureDirExists(d.srcPath); err != nil {
 		return err
 	}
 
+	if d.incremental {
+		if _, err := d.commands.LookPath("rsync"); err == nil {
+			if err := d.syncDir(); err != nil {
+				return err
+			}
+			slog.Info("Directory local backup ran successfully", "srcPath", d.srcPath, "dstPath", d.dstPath, "incremental", true)
+			return nil
+		}
+		slog.Warn("rsync is not installed, copying the whole directory", "srcPath", d.srcPath)
+		// The previous copy is deleted, so that the files that no longer exist in the source are not kept
+		if err := d.files.EmptyDir(filepath.Join(d.dstPath, filepath.Base(filepath.Clean(d.srcPath)))); err != nil {
+			return err
+		}
+	}
+
+	if d.copyOptions != (system.CopyDirOptions{}) {
+		skipped, err := d.files.CopyDirWithOptions(d.srcPath, d.dstPath, d.copyOptions)
+		if err != nil {
+			return err
+		}
+		if len(skipped) > 0 {
+			slog.Warn("Directory local backup skipped unreadable files", "srcPath", d.srcPath, "skipped", skipped)
+		}
+		slog.Info("Directory local backup ran successfully", "srcPath", d.srcPath, "dstPath", d.dstPath, "skipped", len(skipped))
+		return nil
+	}
+
 	if err := d.files.CopyDir(d.srcPath, d.dstPath); err != nil {
 		return err
 	}
@@ -92,6 +327,119 @@ func (d *DirectoryLocalBackup) Run() error {
 	return nil
 }
 
+// ensurePathsNotNested fails if the destination is inside the source, which would make every backup copy the
+// previous ones, or if the source is inside the destination, which would make the copy write into its own source.
+// The sources inside the main backup directory, which is emptied before this runs, are checked by
+// LocalBackupList.ValidateSourcesOutside
+func (d *DirectoryLocalBackup) ensurePathsNotNested() error {
+	absSrcPath, err := d.files.GetAbsPath(d.srcPath)
+	if err != nil {
+		return err
+	}
+	absDstPath, err := d.files.GetAbsPath(d.dstPath)
+	if err != nil {
+		return err
+	}
+	if isSubpath(absSrcPath, absDstPath) || isSubpath(absDstPath, absSrcPath) {
+		return fmt.Errorf("%w: %q and %q", ErrNestedBackupPaths, absSrcPath, absDstPath)
+	}
+	return nil
+}
+
+// isSubpath returns whether path is parent or is inside it. Both paths must be clean and absolute
+func isSubpath(parent string, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// preCommandStderrTailLines is the number of lines at the end of the standard error of a failed pre-command that
+// are included in the error
+const preCommandStderrTailLines = 10
+
+// runPreCommand runs the pre-command, capturing its standard error so that the end of it can be reported if it fails
+func (d *DirectoryLocalBackup) runPreCommand(preCommand string) error {
+	var captured bytes.Buffer
+	var stderr io.Writer = &captured
+	if d.stderr != nil {
+		stderr = io.MultiWriter(d.stderr, &captured)
+	}
+	cmd := d.commands.ExecShellCommandWithStderr(preCommand, stderr)
+	if err := cmd.Run(); err != nil {
+		if tail := lastLines(captured.String(), preCommandStderrTailLines); tail != "" {
+			return fmt.Errorf("pre-command failed: %w\nstderr:\n%s", err, tail)
+		}
+		return fmt.Errorf("pre-command failed: %w", err)
+	}
+	return nil
+}
+
+// lastLines returns the last n lines of text, ignoring the blank space around it
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// envReferencePattern matches the ${VAR} references of a command. The name must be a valid shell variable name
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnvReferences replaces the ${VAR} references in command with the values of the environment variables. The
+// values are inserted verbatim, without quoting. Other uses of "$", such as "$VAR" or "$(...)", are left for the shell.
+// Every unknown reference is reported in the error, so that they can all be fixed at once
+func expandEnvReferences(env system.Env, command string) (string, error) {
+	var missing []string
+	expanded := envReferencePattern.ReplaceAllStringFunc(command, func(reference string) string {
+		varName := envReferencePattern.FindStringSubmatch(reference)[1]
+		value, exists := env.GetEnv(varName)
+		if !exists {
+			if !slices.Contains(missing, varName) {
+				missing = append(missing, varName)
+			}
+			return reference
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", system.ErrRequiredEnvNotFound, strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// rsyncExitCodePartialTransfer is the exit code of rsync when some files could not be transferred, such as the ones
+// that could not be read
+const rsyncExitCodePartialTransfer = 23
+
+// syncDir copies the source directory into the destination directory with rsync. Like CopyDir, the source directory
+// itself is copied into the destination, because its path has no trailing slash
+func (d *DirectoryLocalBackup) syncDir() error {
+	cleanSrcPath := filepath.Clean(d.srcPath)
+	cleanDstPath := filepath.Clean(d.dstPath) + string(filepath.Separator)
+	args := []string{"-a", "--delete"}
+	if d.copyOptions.FollowSymlinks {
+		args = append(args, "--copy-links")
+	}
+	args = append(args, cleanSrcPath, cleanDstPath)
+	cmd := d.commands.ExecCommand("rsync", args...)
+	if err := cmd.Run(); err != nil {
+		var exitErr interface{ ExitCode() int }
+		if d.copyOptions.SkipUnreadable && errors.As(err, &exitErr) && exitErr.ExitCode() == rsyncExitCodePartialTransfer {
+			// rsync has already logged the files it skipped. It does not delete anything after such errors
+			slog.Warn("Directory local backup skipped the files that rsync could not transfer", "srcPath", d.srcPath)
+			return nil
+		}
+		return fmt.Errorf("failed to sync directory %q into %q: %w", cleanSrcPath, cleanDstPath, err)
+	}
+	return nil
+}
+
+// defaultPostgreSQLReadinessCmd is the command that checks whether a PostgreSQL database accepts connections
+const defaultPostgreSQLReadinessCmd = "pg_isready -q"
+
 // PostgreSQLLocalBackup handles PostgreSQL database backups using docker exec
 type PostgreSQLLocalBackup struct {
 	*baseLocalBackup
@@ -101,6 +449,17 @@ type PostgreSQLLocalBackup struct {
 	dbName        string
 	username      string
 	password      string
+	// dbNames overrides dbName when it is not empty, so that several databases of the same server are dumped
+	dbNames []string
+	// readinessCmd overrides defaultPostgreSQLReadinessCmd when it is not empty
+	readinessCmd string
+	// extraArgs are passed to pg_dump, before the name of the database
+	extraArgs []string
+	// filenameTemplate overrides defaultDumpFilenameTemplate when it is not empty
+	filenameTemplate string
+	time             system.Time
+	// readinessTimeout is how long the readiness command is retried for. The default wait is used when it is zero
+	readinessTimeout time.Duration
 }
 
 // NewPostgreSQLLocalBackup creates a new PostgreSQL backup instance
@@ -116,39 +475,128 @@ func NewPostgreSQLLocalBackup(containerName, dbName, username, password, dstPath
 		dbName:        dbName,
 		username:      username,
 		password:      password,
+		time:          system.NewDefaultTime(),
+	}
+}
+
+// WithFilenameTemplate sets the name of the dump file. "<db>" is replaced with the name of the database and "<ts>"
+// with the time of the backup. For example, "<db>-<ts>.sql" keeps the dumps of different days apart
+func (p *PostgreSQLLocalBackup) WithFilenameTemplate(template string) *PostgreSQLLocalBackup {
+	p.filenameTemplate = template
+	return p
+}
+
+// WithDatabases replaces the database to dump with several databases of the same server, which are dumped one after
+// the other into their own files. When there is more than one, the filename template must contain "<db>"
+func (p *PostgreSQLLocalBackup) WithDatabases(dbNames ...string) *PostgreSQLLocalBackup {
+	p.dbNames = append(p.dbNames, dbNames...)
+	return p
+}
+
+// databases returns the names of the databases to dump
+func (p *PostgreSQLLocalBackup) databases() []string {
+	if len(p.dbNames) > 0 {
+		return p.dbNames
+	}
+	return []string{p.dbName}
+}
+
+// WithExtraArgs adds arguments to the pg_dump command. For example, "--no-owner" or "--clean"
+func (p *PostgreSQLLocalBackup) WithExtraArgs(args ...string) *PostgreSQLLocalBackup {
+	p.extraArgs = append(p.extraArgs, args...)
+	return p
+}
+
+// WithReadinessCmd replaces the default command that checks whether the database accepts connections. For example,
+// for custom images or databases that require authentication to be pinged
+func (p *PostgreSQLLocalBackup) WithReadinessCmd(cmd string) *PostgreSQLLocalBackup {
+	p.readinessCmd = cmd
+	return p
+}
+
+// WithReadinessTimeout replaces how long the backup waits for the database to accept connections. For example, for
+// databases that replay a large write-ahead log when they start
+func (p *PostgreSQLLocalBackup) WithReadinessTimeout(timeout time.Duration) *PostgreSQLLocalBackup {
+	p.readinessTimeout = timeout
+	return p
+}
+
+// ReadinessCheck returns the check that succeeds once the PostgreSQL database accepts connections
+func (p *PostgreSQLLocalBackup) ReadinessCheck() ReadinessCheck {
+	cmd := defaultPostgreSQLReadinessCmd
+	if p.readinessCmd != "" {
+		cmd = p.readinessCmd
 	}
+	return ReadinessCheck{ContainerName: p.containerName, Cmd: cmd, Timeout: p.readinessTimeout}
 }
 
 // Run executes the PostgreSQL backup
 func (p *PostgreSQLLocalBackup) Run() error {
-	slog.Info("Running PostgreSQL local backup", "containerName", p.containerName, "dbName", p.dbName, "dstPath", p.dstPath)
+	dbNames := p.databases()
+	slog.Info("Running PostgreSQL local backup", "containerName", p.containerName, "dbNames", dbNames, "dstPath", p.dstPath)
+	if len(dbNames) > 1 && p.filenameTemplate != "" && !strings.Contains(p.filenameTemplate, dumpFilenameDBPlaceholder) {
+		return fmt.Errorf("%w %q: it must contain %s to dump several databases", ErrInvalidDumpFilename, p.filenameTemplate, dumpFilenameDBPlaceholder)
+	}
 	if err := p.files.CreateDirIfNotExists(p.dstPath); err != nil {
 		return err
 	}
 
-	backupFile := filepath.Join(p.dstPath, p.dbName+".sql")
+	// The filenames are resolved first, so that no database is dumped if any of them is invalid
+	backupFiles := make([]string, len(dbNames))
+	for i, dbName := range dbNames {
+		filename, err := resolveDumpFilename(p.filenameTemplate, dbName, p.time)
+		if err != nil {
+			return err
+		}
+		backupFiles[i] = filepath.Join(p.dstPath, filename)
+	}
+
+	readinessCheck := p.ReadinessCheck()
+	if err := p.dockerRunner.WaitUntilContainerExecIsSuccessful(readinessCheck.ContainerName, readinessCheck.Cmd, readinessCheck.Timeout); err != nil {
+		return fmt.Errorf("PostgreSQL database %s not ready: %w", strings.Join(dbNames, ", "), err)
+	}
 
-	if err := p.dockerRunner.WaitUntilContainerExecIsSuccessful(p.containerName, "pg_isready -q"); err != nil {
-		return fmt.Errorf("PostgreSQL database %s not ready: %w", p.dbName, err)
+	// Every database is dumped even if another one fails, and the failures are reported together
+	var errs []error
+	for i, dbName := range dbNames {
+		if err := p.dumpDatabase(dbName, backupFiles[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 
+	slog.Info("PostgreSQL local backup ran successfully", "containerName", p.containerName, "dbNames", dbNames, "dstPath", p.dstPath)
+	return nil
+}
+
+// dumpDatabase dumps a single database into backupFile
+func (p *PostgreSQLLocalBackup) dumpDatabase(dbName string, backupFile string) error {
 	quotedPassword := p.textFormatter.QuoteForPOSIXShell(p.password)
 	containerCmd := fmt.Sprintf(
-		`/bin/bash -c "PGPASSWORD=%s pg_dump --username %s %s" > %s`,
+		`/bin/bash -c "PGPASSWORD=%s pg_dump --username %s%s %s" > %s`,
 		quotedPassword,
 		p.username,
-		p.dbName,
+		quoteExtraArgs(p.textFormatter, p.extraArgs),
+		dbName,
 		backupFile,
 	)
-	err := p.dockerRunner.ContainerExec(p.containerName, containerCmd)
-	if err != nil {
-		return fmt.Errorf("error backing up PostgreSQL database %s: %w", p.dbName, err)
+	if err := p.dockerRunner.ContainerExec(p.containerName, containerCmd); err != nil {
+		return fmt.Errorf("error backing up PostgreSQL database %s: %w", dbName, err)
 	}
 
-	slog.Info("PostgreSQL local backup ran successfully", "containerName", p.containerName, "dbName", p.dbName, "dstPath", p.dstPath, "backupFile", backupFile)
+	slog.Info("Dumped PostgreSQL database", "containerName", p.containerName, "dbName", dbName, "backupFile", backupFile)
 	return nil
 }
 
+// consistentDumpArgs make mysqldump and mariadb-dump take a consistent snapshot of InnoDB tables without locking them,
+// and stream rows instead of buffering whole tables in memory
+const consistentDumpArgs = " --single-transaction --quick"
+
+// defaultMySQLReadinessCmd is the command that checks whether a MySQL database accepts connections
+const defaultMySQLReadinessCmd = "mysqladmin ping -h localhost --silent"
+
 // MySQLLocalBackup handles MySQL database backups using docker exec
 type MySQLLocalBackup struct {
 	*baseLocalBackup
@@ -158,6 +606,17 @@ type MySQLLocalBackup struct {
 	dbName        string
 	username      string
 	password      string
+	// readinessCmd overrides defaultMySQLReadinessCmd when it is not empty
+	readinessCmd string
+	// extraArgs are passed to mysqldump, before the name of the database
+	extraArgs []string
+	// skipConsistentDump removes consistentDumpArgs from the mysqldump command
+	skipConsistentDump bool
+	// filenameTemplate overrides defaultDumpFilenameTemplate when it is not empty
+	filenameTemplate string
+	time             system.Time
+	// readinessTimeout is how long the readiness command is retried for. The default wait is used when it is zero
+	readinessTimeout time.Duration
 }
 
 // NewMySQLLocalBackup creates a new MySQL backup instance
@@ -173,7 +632,60 @@ func NewMySQLLocalBackup(containerName, dbName, username, password, dstPath stri
 		dbName:        dbName,
 		username:      username,
 		password:      password,
+		time:          system.NewDefaultTime(),
+	}
+}
+
+// WithFilenameTemplate sets the name of the dump file. "<db>" is replaced with the name of the database and "<ts>"
+// with the time of the backup. For example, "<db>-<ts>.sql" keeps the dumps of different days apart
+func (m *MySQLLocalBackup) WithFilenameTemplate(template string) *MySQLLocalBackup {
+	m.filenameTemplate = template
+	return m
+}
+
+// WithExtraArgs adds arguments to the mysqldump command. For example, "--routines"
+func (m *MySQLLocalBackup) WithExtraArgs(args ...string) *MySQLLocalBackup {
+	m.extraArgs = append(m.extraArgs, args...)
+	return m
+}
+
+// WithoutConsistentDump stops passing "--single-transaction --quick" to mysqldump. For example, for databases with
+// MyISAM tables, where "--single-transaction" doesn't guarantee consistency
+func (m *MySQLLocalBackup) WithoutConsistentDump() *MySQLLocalBackup {
+	m.skipConsistentDump = true
+	return m
+}
+
+// dumpArgs returns the arguments passed to mysqldump before the name of the database
+func (m *MySQLLocalBackup) dumpArgs() string {
+	args := quoteExtraArgs(m.textFormatter, m.extraArgs)
+	if m.skipConsistentDump {
+		return args
+	}
+	return consistentDumpArgs + args
+}
+
+// WithReadinessCmd replaces the default command that checks whether the database accepts connections. For example,
+// for custom images or databases that require authentication to be pinged
+func (m *MySQLLocalBackup) WithReadinessCmd(cmd string) *MySQLLocalBackup {
+	m.readinessCmd = cmd
+	return m
+}
+
+// WithReadinessTimeout replaces how long the backup waits for the database to accept connections. For example, for
+// databases that replay a large write-ahead log when they start
+func (m *MySQLLocalBackup) WithReadinessTimeout(timeout time.Duration) *MySQLLocalBackup {
+	m.readinessTimeout = timeout
+	return m
+}
+
+// ReadinessCheck returns the check that succeeds once the MySQL database accepts connections
+func (m *MySQLLocalBackup) ReadinessCheck() ReadinessCheck {
+	cmd := defaultMySQLReadinessCmd
+	if m.readinessCmd != "" {
+		cmd = m.readinessCmd
 	}
+	return ReadinessCheck{ContainerName: m.containerName, Cmd: cmd, Timeout: m.readinessTimeout}
 }
 
 // Run executes the MySQL backup
@@ -183,17 +695,23 @@ func (m *MySQLLocalBackup) Run() error {
 		return err
 	}
 
-	backupFile := filepath.Join(m.dstPath, m.dbName+".sql")
+	filename, err := resolveDumpFilename(m.filenameTemplate, m.dbName, m.time)
+	if err != nil {
+		return err
+	}
+	backupFile := filepath.Join(m.dstPath, filename)
 
-	if err := m.dockerRunner.WaitUntilContainerExecIsSuccessful(m.containerName, "mysqladmin ping -h localhost --silent"); err != nil {
+	readinessCheck := m.ReadinessCheck()
+	if err := m.dockerRunner.WaitUntilContainerExecIsSuccessful(readinessCheck.ContainerName, readinessCheck.Cmd, readinessCheck.Timeout); err != nil {
 		return fmt.Errorf("MySQL database %s not ready: %w", m.dbName, err)
 	}
 
 	quotedPassword := m.textFormatter.QuoteForPOSIXShell(m.password)
 	containerCmd := fmt.Sprintf(
-		`/bin/bash -c "MYSQL_PWD=%s mysqldump --user %s %s" > %s`,
+		`/bin/bash -c "MYSQL_PWD=%s mysqldump --user %s%s %s" > %s`,
 		quotedPassword,
 		m.username,
+		m.dumpArgs(),
 		m.dbName,
 		backupFile,
 	)
@@ -205,6 +723,9 @@ func (m *MySQLLocalBackup) Run() error {
 	return nil
 }
 
+// defaultMariaDBReadinessCmd is the command that checks whether a MariaDB database accepts connections
+const defaultMariaDBReadinessCmd = "mariadb-admin ping -h localhost --silent"
+
 // MariaDBLocalBackup handles MariaDB database backups using docker exec
 type MariaDBLocalBackup struct {
 	*baseLocalBackup
@@ -214,6 +735,17 @@ type MariaDBLocalBackup struct {
 	dbName        string
 	username      string
 	password      string
+	// readinessCmd overrides defaultMariaDBReadinessCmd when it is not empty
+	readinessCmd string
+	// extraArgs are passed to mariadb-dump, before the name of the database
+	extraArgs []string
+	// skipConsistentDump removes consistentDumpArgs from the mariadb-dump command
+	skipConsistentDump bool
+	// filenameTemplate overrides defaultDumpFilenameTemplate when it is not empty
+	filenameTemplate string
+	time             system.Time
+	// readinessTimeout is how long the readiness command is retried for. The default wait is used when it is zero
+	readinessTimeout time.Duration
 }
 
 // NewMariaDBLocalBackup creates a new MariaDB backup instance
@@ -229,7 +761,60 @@ func NewMariaDBLocalBackup(containerName, dbName, username, password, dstPath st
 		dbName:        dbName,
 		username:      username,
 		password:      password,
+		time:          system.NewDefaultTime(),
+	}
+}
+
+// WithFilenameTemplate sets the name of the dump file. "<db>" is replaced with the name of the database and "<ts>"
+// with the time of the backup. For example, "<db>-<ts>.sql" keeps the dumps of different days apart
+func (m *MariaDBLocalBackup) WithFilenameTemplate(template string) *MariaDBLocalBackup {
+	m.filenameTemplate = template
+	return m
+}
+
+// WithExtraArgs adds arguments to the mariadb-dump command. For example, "--routines"
+func (m *MariaDBLocalBackup) WithExtraArgs(args ...string) *MariaDBLocalBackup {
+	m.extraArgs = append(m.extraArgs, args...)
+	return m
+}
+
+// WithoutConsistentDump stops passing "--single-transaction --quick" to mariadb-dump. For example, for databases with
+// MyISAM tables, where "--single-transaction" doesn't guarantee consistency
+func (m *MariaDBLocalBackup) WithoutConsistentDump() *MariaDBLocalBackup {
+	m.skipConsistentDump = true
+	return m
+}
+
+// dumpArgs returns the arguments passed to mariadb-dump before the name of the database
+func (m *MariaDBLocalBackup) dumpArgs() string {
+	args := quoteExtraArgs(m.textFormatter, m.extraArgs)
+	if m.skipConsistentDump {
+		return args
+	}
+	return consistentDumpArgs + args
+}
+
+// WithReadinessCmd replaces the default command that checks whether the database accepts connections. For example,
+// for custom images or databases that require authentication to be pinged
+func (m *MariaDBLocalBackup) WithReadinessCmd(cmd string) *MariaDBLocalBackup {
+	m.readinessCmd = cmd
+	return m
+}
+
+// WithReadinessTimeout replaces how long the backup waits for the database to accept connections. For example, for
+// databases that replay a large write-ahead log when they start
+func (m *MariaDBLocalBackup) WithReadinessTimeout(timeout time.Duration) *MariaDBLocalBackup {
+	m.readinessTimeout = timeout
+	return m
+}
+
+// ReadinessCheck returns the check that succeeds once the MariaDB database accepts connections
+func (m *MariaDBLocalBackup) ReadinessCheck() ReadinessCheck {
+	cmd := defaultMariaDBReadinessCmd
+	if m.readinessCmd != "" {
+		cmd = m.readinessCmd
 	}
+	return ReadinessCheck{ContainerName: m.containerName, Cmd: cmd, Timeout: m.readinessTimeout}
 }
 
 // Run executes the MariaDB backup
@@ -239,17 +824,23 @@ func (m *MariaDBLocalBackup) Run() error {
 		return err
 	}
 
-	backupFile := filepath.Join(m.dstPath, m.dbName+".sql")
+	filename, err := resolveDumpFilename(m.filenameTemplate, m.dbName, m.time)
+	if err != nil {
+		return err
+	}
+	backupFile := filepath.Join(m.dstPath, filename)
 
-	if err := m.dockerRunner.WaitUntilContainerExecIsSuccessful(m.containerName, "mariadb-admin ping -h localhost --silent"); err != nil {
+	readinessCheck := m.ReadinessCheck()
+	if err := m.dockerRunner.WaitUntilContainerExecIsSuccessful(readinessCheck.ContainerName, readinessCheck.Cmd, readinessCheck.Timeout); err != nil {
 		return fmt.Errorf("MariaDB database %s not ready: %w", m.dbName, err)
 	}
 
 	quotedPassword := m.textFormatter.QuoteForPOSIXShell(m.password)
 	containerCmd := fmt.Sprintf(
-		`/bin/bash -c "MYSQL_PWD=%s mariadb-dump --user %s %s" > %s`,
+		`/bin/bash -c "MYSQL_PWD=%s mariadb-dump --user %s%s %s" > %s`,
 		quotedPassword,
 		m.username,
+		m.dumpArgs(),
 		m.dbName,
 		backupFile,
 	)
@@ -260,3 +851,55 @@ func (m *MariaDBLocalBackup) Run() error {
 	slog.Info("MariaDB local backup ran successfully", "containerName", m.containerName, "dbName", m.dbName, "dstPath", m.dstPath, "backupFile", backupFile)
 	return nil
 }
+
+// VolumeLocalBackup handles backups of Docker named volumes, which have no host path that can be copied. It runs a
+// helper container that mounts the volume and archives its contents into the destination directory
+type VolumeLocalBackup struct {
+	*baseLocalBackup
+	commands      system.Commands
+	textFormatter format.TextFormatter
+	volumeName    string
+}
+
+// NewVolumeLocalBackup creates a new Docker named volume backup instance
+func NewVolumeLocalBackup(volumeName, dstPath string) *VolumeLocalBackup {
+	return &VolumeLocalBackup{
+		baseLocalBackup: newBaseLocalBackup(
+			dstPath,
+			system.NewDefaultFilesHandler(),
+		),
+		commands:      system.NewDefaultCommands(),
+		textFormatter: format.NewDefaultTextFormatter(),
+		volumeName:    volumeName,
+	}
+}
+
+// Run executes the Docker named volume backup
+func (v *VolumeLocalBackup) Run() error {
+	slog.Info("Running volume local backup", "volumeName", v.volumeName, "dstPath", v.dstPath)
+	if err := v.files.CreateDirIfNotExists(v.dstPath); err != nil {
+		return err
+	}
+
+	// Docker would take a relative path for the name of a volume, so the destination must be absolute
+	absDstPath, err := v.files.GetAbsPath(v.dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to convert destination to an absolute path: %w", err)
+	}
+
+	backupFile := v.volumeName + ".tar.gz"
+	// The volume is mounted read-only, so that the backup can't modify it
+	cmdStr := fmt.Sprintf(
+		"docker run --rm -v %s:/data:ro -v %s:/backup alpine tar czf %s -C /data .",
+		v.textFormatter.QuoteForPOSIXShell(v.volumeName),
+		v.textFormatter.QuoteForPOSIXShell(absDstPath),
+		v.textFormatter.QuoteForPOSIXShell("/backup/"+backupFile),
+	)
+	cmd := v.commands.ExecShellCommand(cmdStr)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error backing up volume %s: %w", v.volumeName, err)
+	}
+
+	slog.Info("Volume local backup ran successfully", "volumeName", v.volumeName, "dstPath", v.dstPath, "backupFile", filepath.Join(absDstPath, backupFile))
+	return nil
+}
@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+var (
+	ErrInvalidBackupDst = errors.New("invalid backup destination")
+)
+
+// localBackupDstEnvVarName builds the name of the environment variable that overrides the destination of a local
+// backup. For example, the "immich-db" backup is overridden with HOMELAB_BACKUP_DST_IMMICH_DB
+func localBackupDstEnvVarName(name string) string {
+	return "HOMELAB_BACKUP_DST_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// LocalBackupDst returns the destination path of the local backup called name. By default, it is the name directory
+// inside mainBackupDir. It can be overridden with an absolute path in HOMELAB_BACKUP_DST_<NAME>, for example to
+// spread the backups across several disks. An overridden destination is used verbatim
+func LocalBackupDst(env system.Env, mainBackupDir string, name string) (string, error) {
+	varName := localBackupDstEnvVarName(name)
+	dst, exists := env.GetEnv(varName)
+	if !exists || strings.TrimSpace(dst) == "" {
+		return filepath.Join(mainBackupDir, name), nil
+	}
+	if !filepath.IsAbs(dst) {
+		return "", fmt.Errorf("%w %q: %q is not an absolute path", ErrInvalidBackupDst, varName, dst)
+	}
+	return dst, nil
+}
@@ -0,0 +1,58 @@
+package backup
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLocalBackupDst_DefaultIsNestedInMainBackupDir(t *testing.T) {
+	env := &mockEnv{}
+
+	dst, err := LocalBackupDst(env, "/backup", "immich-db")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if dst != "/backup/immich-db" {
+		t.Errorf("expected destination %q, got %q", "/backup/immich-db", dst)
+	}
+}
+
+func TestLocalBackupDst_OverriddenDstIsUsedVerbatim(t *testing.T) {
+	var requestedVar string
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			requestedVar = varName
+			return "/mnt/disk2/immich-db-backup/", true
+		},
+	}
+
+	dst, err := LocalBackupDst(env, "/backup", "immich-db")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if requestedVar != "HOMELAB_BACKUP_DST_IMMICH_DB" {
+		t.Errorf("expected var %q to be requested, got %q", "HOMELAB_BACKUP_DST_IMMICH_DB", requestedVar)
+	}
+	if dst != "/mnt/disk2/immich-db-backup/" {
+		t.Errorf("expected destination %q, got %q", "/mnt/disk2/immich-db-backup/", dst)
+	}
+}
+
+func TestLocalBackupDst_RelativeOverrideIsRejected(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			return "disk2/immich-db", true
+		},
+	}
+
+	_, err := LocalBackupDst(env, "/backup", "immich-db")
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrInvalidBackupDst) {
+		t.Errorf("expected ErrInvalidBackupDst, got: %v", err)
+	}
+}
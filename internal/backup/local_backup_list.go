@@ -3,21 +3,33 @@ package backup
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
 	"sync"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 )
 
 var (
 	ErrBackupOperationFailed          = errors.New("backup operation failed")
 	ErrMultipleBackupOperationsFailed = errors.New("multiple backup operations failed")
+	ErrContainersNotReady             = errors.New("containers not ready for backup")
+	ErrConflictingBackupPaths         = errors.New("conflicting backup paths")
 )
 
 type LocalBackupList struct {
-	backups []LocalBackup
+	backups      []LocalBackup
+	dockerRunner docker.Runner
+	files        system.FilesHandler
 }
 
 func NewLocalBackupList() *LocalBackupList {
 	return &LocalBackupList{
-		backups: []LocalBackup{},
+		backups:      []LocalBackup{},
+		dockerRunner: docker.NewSystemRunner(),
+		files:        system.NewDefaultFilesHandler(),
 	}
 }
 
@@ -26,18 +38,207 @@ func (l *LocalBackupList) Add(backup LocalBackup) {
 	l.backups = append(l.backups, backup)
 }
 
-// RunAll runs all backup operations concurrently
+// DstPaths returns the distinct destination directories of the backup operations, in the order they were added
+func (l *LocalBackupList) DstPaths() []string {
+	var paths []string
+	for _, operation := range l.backups {
+		provider, ok := operation.(DstPathProvider)
+		if !ok {
+			continue
+		}
+		if path := provider.DstPath(); !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// SrcPaths returns the distinct source directories of the directory backups, in the order they were added
+func (l *LocalBackupList) SrcPaths() []string {
+	var paths []string
+	for _, operation := range l.backups {
+		provider, ok := operation.(SrcPathProvider)
+		if !ok {
+			continue
+		}
+		if path := provider.SrcPath(); !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ServicesDisabled returns the distinct services that must be stopped during the backup, in the order they were added
+func (l *LocalBackupList) ServicesDisabled() []string {
+	var services []string
+	for _, operation := range l.backups {
+		provider, ok := operation.(DisabledServicesProvider)
+		if !ok {
+			continue
+		}
+		for _, service := range provider.RequiresServicesDisabled() {
+			if !slices.Contains(services, service) {
+				services = append(services, service)
+			}
+		}
+	}
+	return services
+}
+
+// backupPaths are the absolute source and destination directories of a backup operation. Each of them is empty if
+// the operation does not have it
+type backupPaths struct {
+	srcPath string
+	dstPath string
+}
+
+// Validate checks that the backup operations can't copy each other's files. No destination can be inside the source of
+// a directory backup, because the backup would copy itself, and no two directory backups can share a destination,
+// because they would copy into the same directory. Database dumps can share a destination, because their files have
+// different names. Every conflict is reported
+func (l *LocalBackupList) Validate() error {
+	var paths []backupPaths
+	for _, operation := range l.backups {
+		var operationPaths backupPaths
+		if provider, ok := operation.(SrcPathProvider); ok {
+			absPath, err := l.files.GetAbsPath(provider.SrcPath())
+			if err != nil {
+				return err
+			}
+			operationPaths.srcPath = filepath.Clean(absPath)
+		}
+		if provider, ok := operation.(DstPathProvider); ok {
+			absPath, err := l.files.GetAbsPath(provider.DstPath())
+			if err != nil {
+				return err
+			}
+			operationPaths.dstPath = filepath.Clean(absPath)
+		}
+		paths = append(paths, operationPaths)
+	}
+
+	var errs []error
+	for i, operationPaths := range paths {
+		if operationPaths.dstPath == "" {
+			continue
+		}
+		for j, otherPaths := range paths {
+			if otherPaths.srcPath != "" && isSubpath(otherPaths.srcPath, operationPaths.dstPath) {
+				errs = append(errs, fmt.Errorf("%w: destination %q is inside source %q",
+					ErrConflictingBackupPaths, operationPaths.dstPath, otherPaths.srcPath))
+			}
+			if j > i && operationPaths.srcPath != "" && otherPaths.srcPath != "" && operationPaths.dstPath == otherPaths.dstPath {
+				errs = append(errs, fmt.Errorf("%w: directories %q and %q are copied into the same destination %q",
+					ErrConflictingBackupPaths, operationPaths.srcPath, otherPaths.srcPath, operationPaths.dstPath))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateSourcesOutside checks that no directory backup copies a source inside dir, such as the main backup directory,
+// which is emptied before the backup and would delete the source. Every conflict is reported
+func (l *LocalBackupList) ValidateSourcesOutside(dir string) error {
+	absDir, err := l.files.GetAbsPath(dir)
+	if err != nil {
+		return err
+	}
+	absDir = filepath.Clean(absDir)
+	var errs []error
+	for _, srcPath := range l.SrcPaths() {
+		absSrcPath, err := l.files.GetAbsPath(srcPath)
+		if err != nil {
+			return err
+		}
+		if isSubpath(absDir, filepath.Clean(absSrcPath)) {
+			errs = append(errs, fmt.Errorf("%w: source %q is inside %q", ErrConflictingBackupPaths, absSrcPath, absDir))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WaitUntilReady waits concurrently until the containers needed by the backup operations are ready. Each distinct
+// readiness check is run once. All checks are attempted, and the ones that never succeed are reported together, so
+// that the backup can fail before any operation has started
+func (l *LocalBackupList) WaitUntilReady() error {
+	var checks []ReadinessCheck
+	for _, operation := range l.backups {
+		checker, ok := operation.(ReadinessChecker)
+		if !ok {
+			continue
+		}
+		if check := checker.ReadinessCheck(); !slices.Contains(checks, check) {
+			checks = append(checks, check)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errChan := make(chan error, len(checks))
+	for _, check := range checks {
+		wg.Add(1)
+		go func(c ReadinessCheck) {
+			defer wg.Done()
+			slog.Info("Waiting until container is ready", "containerName", c.ContainerName, "cmd", c.Cmd, "timeout", c.Timeout)
+			if err := l.dockerRunner.WaitUntilContainerExecIsSuccessful(c.ContainerName, c.Cmd, c.Timeout); err != nil {
+				errChan <- fmt.Errorf("container %s: %w", c.ContainerName, err)
+			}
+		}(check)
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	var errs []error
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("%w (%d containers): %w", ErrContainersNotReady, len(errs), errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// RunAll runs all backup operations concurrently, except for the operations of the same exclusion group, which run
+// one after the other in the order they were added. Every operation runs even if others fail. Nothing runs if the
+// operations are not valid
 func (l *LocalBackupList) RunAll() error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	var batches [][]LocalBackup
+	groupBatches := make(map[string]int)
+	for _, operation := range l.backups {
+		group := ""
+		if grouper, ok := operation.(ExclusionGrouper); ok {
+			group = grouper.ExclusionGroup()
+		}
+		if group == "" {
+			batches = append(batches, []LocalBackup{operation})
+			continue
+		}
+		if i, ok := groupBatches[group]; ok {
+			batches[i] = append(batches[i], operation)
+			continue
+		}
+		groupBatches[group] = len(batches)
+		batches = append(batches, []LocalBackup{operation})
+	}
+
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(l.backups))
-	for _, operation := range l.backups {
+	for _, batch := range batches {
 		wg.Add(1)
-		go func(op LocalBackup) {
+		go func(ops []LocalBackup) {
 			defer wg.Done()
-			if err := op.Run(); err != nil {
-				errChan <- fmt.Errorf("%w: %w", ErrBackupOperationFailed, err)
+			for _, op := range ops {
+				if err := op.Run(); err != nil {
+					errChan <- fmt.Errorf("%w: %w", ErrBackupOperationFailed, err)
+				}
 			}
-		}(operation)
+		}(batch)
 	}
 
 	wg.Wait()
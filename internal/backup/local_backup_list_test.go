@@ -3,9 +3,14 @@ package backup
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 // mockLocalBackup is a mock implementation of LocalBackup for testing
@@ -20,6 +25,30 @@ func (m *mockLocalBackup) Run() error {
 	return nil
 }
 
+// mockReadinessLocalBackup is a mock implementation of LocalBackup that needs a container to be ready
+type mockReadinessLocalBackup struct {
+	mockLocalBackup
+	readinessCheck ReadinessCheck
+}
+
+func (m *mockReadinessLocalBackup) ReadinessCheck() ReadinessCheck {
+	return m.readinessCheck
+}
+
+// mockGroupedLocalBackup is a mock implementation of LocalBackup that belongs to an exclusion group
+type mockGroupedLocalBackup struct {
+	mockLocalBackup
+	exclusionGroup string
+}
+
+func (m *mockGroupedLocalBackup) ExclusionGroup() string {
+	return m.exclusionGroup
+}
+
+func (m *mockGroupedLocalBackup) SetExclusionGroup(group string) {
+	m.exclusionGroup = group
+}
+
 func TestLocalBackupList_RunAll_ThreeSuccessful(t *testing.T) {
 	var executionCount atomic.Int32
 	list := NewLocalBackupList()
@@ -149,3 +178,335 @@ func TestLocalBackupList_RunAll_ConcurrentExecution(t *testing.T) {
 		t.Errorf("backups appear to run sequentially (took %v), expected concurrent execution", elapsed)
 	}
 }
+
+func TestLocalBackupList_WaitUntilReady_AttemptsAllChecksOnce(t *testing.T) {
+	var mu sync.Mutex
+	var checkedContainers []string
+	list := &LocalBackupList{
+		dockerRunner: &mockDockerRunner{
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				mu.Lock()
+				defer mu.Unlock()
+				checkedContainers = append(checkedContainers, containerName)
+				return nil
+			},
+		},
+	}
+	list.Add(&mockReadinessLocalBackup{readinessCheck: ReadinessCheck{ContainerName: "postgres", Cmd: "pg_isready -q"}})
+	// Two databases in the same container only need to be checked once
+	list.Add(&mockReadinessLocalBackup{readinessCheck: ReadinessCheck{ContainerName: "postgres", Cmd: "pg_isready -q"}})
+	list.Add(&mockReadinessLocalBackup{readinessCheck: ReadinessCheck{ContainerName: "mariadb", Cmd: "mariadb-admin ping"}})
+	// Backups that don't need a container are ignored
+	list.Add(&mockLocalBackup{})
+
+	err := list.WaitUntilReady()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	sort.Strings(checkedContainers)
+	expected := []string{"mariadb", "postgres"}
+	if diff := cmp.Diff(expected, checkedContainers); diff != "" {
+		t.Errorf("checked containers mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalBackupList_WaitUntilReady_PassesTimeouts(t *testing.T) {
+	var mu sync.Mutex
+	timeouts := make(map[string]time.Duration)
+	list := &LocalBackupList{
+		dockerRunner: &mockDockerRunner{
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				mu.Lock()
+				defer mu.Unlock()
+				timeouts[containerName] = timeout
+				return nil
+			},
+		},
+	}
+	list.Add(&mockReadinessLocalBackup{readinessCheck: ReadinessCheck{ContainerName: "immich-db", Cmd: "pg_isready -q", Timeout: 5 * time.Minute}})
+	list.Add(&mockReadinessLocalBackup{readinessCheck: ReadinessCheck{ContainerName: "firefly-db", Cmd: "mariadb-admin ping"}})
+
+	err := list.WaitUntilReady()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := map[string]time.Duration{"immich-db": 5 * time.Minute, "firefly-db": 0}
+	if diff := cmp.Diff(expected, timeouts); diff != "" {
+		t.Errorf("timeouts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalBackupList_WaitUntilReady_ReportsFailureAfterAttemptingAllChecks(t *testing.T) {
+	var checkCount atomic.Int32
+	notReadyErr := errors.New("too many retries")
+	list := &LocalBackupList{
+		dockerRunner: &mockDockerRunner{
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				checkCount.Add(1)
+				if containerName == "mysql" {
+					return notReadyErr
+				}
+				return nil
+			},
+		},
+	}
+	list.Add(&mockReadinessLocalBackup{readinessCheck: ReadinessCheck{ContainerName: "postgres", Cmd: "pg_isready -q"}})
+	list.Add(&mockReadinessLocalBackup{readinessCheck: ReadinessCheck{ContainerName: "mysql", Cmd: "mysqladmin ping"}})
+	list.Add(&mockReadinessLocalBackup{readinessCheck: ReadinessCheck{ContainerName: "mariadb", Cmd: "mariadb-admin ping"}})
+
+	err := list.WaitUntilReady()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrContainersNotReady) {
+		t.Errorf("expected ErrContainersNotReady, got: %v", err)
+	}
+	if !errors.Is(err, notReadyErr) {
+		t.Errorf("expected error to wrap %v, got: %v", notReadyErr, err)
+	}
+	if checkCount.Load() != 3 {
+		t.Errorf("expected 3 readiness checks to be attempted, got %d", checkCount.Load())
+	}
+}
+
+func TestLocalBackupList_DstPaths_Distinct(t *testing.T) {
+	list := NewLocalBackupList()
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/calibre"}})
+	list.Add(&PostgreSQLLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/mnt/disk/dumps"}})
+	list.Add(&MySQLLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/mnt/disk/dumps"}})
+	// Operations without a destination directory are skipped
+	list.Add(&mockLocalBackup{})
+
+	paths := list.DstPaths()
+
+	expected := []string{"/backup/calibre", "/mnt/disk/dumps"}
+	if diff := cmp.Diff(expected, paths); diff != "" {
+		t.Errorf("destination paths mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalBackupList_SrcPaths_Distinct(t *testing.T) {
+	list := NewLocalBackupList()
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/calibre"}, srcPath: "/data/calibre"})
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/immich"}, srcPath: "/data/immich"})
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/other"}, srcPath: "/data/calibre"})
+	// Operations without a source directory are skipped
+	list.Add(&PostgreSQLLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/dumps"}})
+
+	paths := list.SrcPaths()
+
+	expected := []string{"/data/calibre", "/data/immich"}
+	if diff := cmp.Diff(expected, paths); diff != "" {
+		t.Errorf("source paths mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalBackupList_ServicesDisabled_Distinct(t *testing.T) {
+	list := NewLocalBackupList()
+	list.Add(NewDirectoryLocalBackup("/data/calibre", "/backup/calibre", "").WithServicesDisabled("calibre"))
+	list.Add(NewDirectoryLocalBackup("/data/immich", "/backup/immich", "").WithServicesDisabled("immich", "calibre"))
+	// Operations that don't require any service to be stopped are skipped
+	list.Add(NewDirectoryLocalBackup("/data/other", "/backup/other", ""))
+	list.Add(&PostgreSQLLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/dumps"}})
+
+	services := list.ServicesDisabled()
+
+	expected := []string{"calibre", "immich"}
+	if diff := cmp.Diff(expected, services); diff != "" {
+		t.Errorf("disabled services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalBackupList_Validate_SharedDestination(t *testing.T) {
+	list := &LocalBackupList{files: &mockFilesHandler{}}
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/library"}, srcPath: "/data/calibre"})
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/library/"}, srcPath: "/data/immich"})
+
+	err := list.Validate()
+
+	if !errors.Is(err, ErrConflictingBackupPaths) {
+		t.Errorf("expected ErrConflictingBackupPaths, got: %v", err)
+	}
+}
+
+func TestLocalBackupList_Validate_DestinationInsideOtherSource(t *testing.T) {
+	list := &LocalBackupList{files: &mockFilesHandler{}}
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/calibre"}, srcPath: "/data"})
+	list.Add(&PostgreSQLLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/data/dumps"}})
+
+	err := list.Validate()
+
+	if !errors.Is(err, ErrConflictingBackupPaths) {
+		t.Errorf("expected ErrConflictingBackupPaths, got: %v", err)
+	}
+}
+
+func TestLocalBackupList_Validate_DisjointPaths(t *testing.T) {
+	list := &LocalBackupList{files: &mockFilesHandler{}}
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/calibre"}, srcPath: "/data/calibre"})
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/immich"}, srcPath: "/data/immich"})
+	// Database dumps can share a destination
+	list.Add(&PostgreSQLLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/dumps"}})
+	list.Add(&MySQLLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/backup/dumps"}})
+	list.Add(&mockLocalBackup{})
+
+	err := list.Validate()
+
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestLocalBackupList_ValidateSourcesOutside_SourceInsideDir(t *testing.T) {
+	list := NewLocalBackupList()
+	list.files = &mockFilesHandler{}
+	list.Add(NewDirectoryLocalBackup("/backup/photos", "/disk2/photos", ""))
+	list.Add(NewDirectoryLocalBackup("/data/books", "/backup/books", ""))
+
+	err := list.ValidateSourcesOutside("/backup/")
+
+	if !errors.Is(err, ErrConflictingBackupPaths) {
+		t.Fatalf("expected ErrConflictingBackupPaths, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "/backup/photos") || strings.Contains(err.Error(), "/data/books") {
+		t.Errorf("expected only the source inside the directory to be reported, got: %v", err)
+	}
+}
+
+func TestLocalBackupList_ValidateSourcesOutside_DisjointPaths(t *testing.T) {
+	list := NewLocalBackupList()
+	list.files = &mockFilesHandler{}
+	list.Add(NewDirectoryLocalBackup("/backup-sources/photos", "/backup/photos", ""))
+
+	err := list.ValidateSourcesOutside("/backup")
+
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestLocalBackupList_RunAll_InvalidRunsNothing(t *testing.T) {
+	list := &LocalBackupList{files: &mockFilesHandler{}}
+	list.Add(&DirectoryLocalBackup{baseLocalBackup: &baseLocalBackup{dstPath: "/data/backup"}, srcPath: "/data"})
+	var ran bool
+	list.Add(&mockLocalBackup{runFunc: func() error {
+		ran = true
+		return nil
+	}})
+
+	err := list.RunAll()
+
+	if !errors.Is(err, ErrConflictingBackupPaths) {
+		t.Errorf("expected ErrConflictingBackupPaths, got: %v", err)
+	}
+	if ran {
+		t.Error("expected no operation to run")
+	}
+}
+
+func TestLocalBackupList_RunAll_SameExclusionGroupDoesNotOverlap(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	var mu sync.Mutex
+	var order []int
+	list := NewLocalBackupList()
+	for i := 1; i <= 3; i++ {
+		backupNum := i
+		list.Add(&mockGroupedLocalBackup{
+			exclusionGroup: "immich",
+			mockLocalBackup: mockLocalBackup{
+				runFunc: func() error {
+					current := running.Add(1)
+					defer running.Add(-1)
+					for {
+						previous := maxRunning.Load()
+						if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					mu.Lock()
+					order = append(order, backupNum)
+					mu.Unlock()
+					return nil
+				},
+			},
+		})
+	}
+
+	err := list.RunAll()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if maxRunning.Load() != 1 {
+		t.Errorf("expected backups of the same group not to overlap, got %d running at once", maxRunning.Load())
+	}
+	if diff := cmp.Diff([]int{1, 2, 3}, order); diff != "" {
+		t.Errorf("expected backups of the same group to run in the order they were added (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalBackupList_RunAll_DifferentExclusionGroupsOverlap(t *testing.T) {
+	// Each backup waits until the other one has started, which only happens if both run at the same time
+	started := []chan struct{}{make(chan struct{}), make(chan struct{})}
+	list := NewLocalBackupList()
+	for i, group := range []string{"immich", "nextcloud"} {
+		own, other := started[i], started[1-i]
+		list.Add(&mockGroupedLocalBackup{
+			exclusionGroup: group,
+			mockLocalBackup: mockLocalBackup{
+				runFunc: func() error {
+					close(own)
+					select {
+					case <-other:
+						return nil
+					case <-time.After(time.Second):
+						return errors.New("the other backup did not run at the same time")
+					}
+				},
+			},
+		})
+	}
+
+	err := list.RunAll()
+
+	if err != nil {
+		t.Errorf("expected backups of different groups to overlap, got: %v", err)
+	}
+}
+
+func TestLocalBackupList_RunAll_ExclusionGroupRunsAllOnFailure(t *testing.T) {
+	var executionCount atomic.Int32
+	errorFrom1 := errors.New("backup 1 crashed")
+	list := NewLocalBackupList()
+	list.Add(&mockGroupedLocalBackup{
+		exclusionGroup: "immich",
+		mockLocalBackup: mockLocalBackup{
+			runFunc: func() error {
+				executionCount.Add(1)
+				return errorFrom1
+			},
+		},
+	})
+	list.Add(&mockGroupedLocalBackup{
+		exclusionGroup: "immich",
+		mockLocalBackup: mockLocalBackup{
+			runFunc: func() error {
+				executionCount.Add(1)
+				return nil
+			},
+		},
+	})
+
+	err := list.RunAll()
+
+	if !errors.Is(err, errorFrom1) {
+		t.Errorf("expected error to contain backup 1 error, got: %v", err)
+	}
+	if executionCount.Load() != 2 {
+		t.Errorf("expected 2 backups to execute, got %d", executionCount.Load())
+	}
+}
@@ -1,15 +1,24 @@
 package backup
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/google/go-cmp/cmp"
 )
 
 type mockDockerRunner struct {
 	containerExec                      func(containerName string, cmd string) error
-	waitUntilContainerExecIsSuccessful func(containerName string, cmd string) error
+	waitUntilContainerExecIsSuccessful func(containerName string, cmd string, timeout time.Duration) error
 }
 
 func (m *mockDockerRunner) ComposeStart(serviceNames []string) error {
@@ -18,9 +27,27 @@ func (m *mockDockerRunner) ComposeStart(serviceNames []string) error {
 func (m *mockDockerRunner) ComposeStop(serviceNames []string) error {
 	return nil
 }
-func (m *mockDockerRunner) WaitUntilContainerExecIsSuccessful(containerName string, cmd string) error {
+func (m *mockDockerRunner) ComposeValidate() error {
+	return nil
+}
+func (m *mockDockerRunner) ComposePs(serviceNames []string) ([]docker.ServiceStatus, error) {
+	return nil, nil
+}
+func (m *mockDockerRunner) ComposeServices() ([]string, error) {
+	return nil, nil
+}
+func (m *mockDockerRunner) WaitUntilServicesAreHealthy(services []string, timeout time.Duration) error {
+	return nil
+}
+func (m *mockDockerRunner) WaitUntilContainerHealthy(container string) error {
+	return nil
+}
+func (m *mockDockerRunner) ContainerLogs(ctx context.Context, name string, tail int, follow bool) error {
+	return nil
+}
+func (m *mockDockerRunner) WaitUntilContainerExecIsSuccessful(containerName string, cmd string, timeout time.Duration) error {
 	if m.waitUntilContainerExecIsSuccessful != nil {
-		return m.waitUntilContainerExecIsSuccessful(containerName, cmd)
+		return m.waitUntilContainerExecIsSuccessful(containerName, cmd, timeout)
 	}
 	return nil
 }
@@ -142,6 +169,76 @@ func TestDirectoryLocalBackup_Run_PreCommandError(t *testing.T) {
 	}
 }
 
+func TestDirectoryLocalBackup_Run_PreCommandErrorIncludesStderr(t *testing.T) {
+	expectedErr := errors.New("exit status 1")
+	var streamed bytes.Buffer
+	var stderrLines []string
+	for i := 1; i <= 12; i++ {
+		stderrLines = append(stderrLines, fmt.Sprintf("line %d", i))
+	}
+	backup := &DirectoryLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		commands: &mockCommands{
+			execShellCommandStderr: func(cmd string, stderr io.Writer) system.RunnableCommand {
+				return &mockRunnableCommand{
+					runFunc: func() error {
+						fmt.Fprintln(stderr, strings.Join(stderrLines, "\n"))
+						return expectedErr
+					},
+				}
+			},
+		},
+		stderr:     &streamed,
+		srcPath:    "/src",
+		preCommand: "docker exec container-name failing-command",
+	}
+
+	err := backup.Run()
+
+	if !errors.Is(err, expectedErr) {
+		t.Fatalf("expected error to be %v, got: %v", expectedErr, err)
+	}
+	expectedTail := strings.Join(stderrLines[2:], "\n")
+	if !strings.HasSuffix(err.Error(), "stderr:\n"+expectedTail) {
+		t.Errorf("expected error to end with the last %d lines of stderr, got: %v", preCommandStderrTailLines, err)
+	}
+	if strings.Contains(err.Error(), "line 2\n") {
+		t.Errorf("expected error not to include the first lines of stderr, got: %v", err)
+	}
+	if streamed.String() != strings.Join(stderrLines, "\n")+"\n" {
+		t.Errorf("expected stderr to also be streamed, got: %q", streamed.String())
+	}
+}
+
+func TestDirectoryLocalBackup_Run_PreCommandErrorWithoutStderr(t *testing.T) {
+	expectedErr := errors.New("exit status 1")
+	backup := &DirectoryLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{runFunc: func() error { return expectedErr }}
+			},
+		},
+		srcPath:    "/src",
+		preCommand: "docker exec container-name failing-command",
+	}
+
+	err := backup.Run()
+
+	if !errors.Is(err, expectedErr) {
+		t.Fatalf("expected error to be %v, got: %v", expectedErr, err)
+	}
+	if strings.Contains(err.Error(), "stderr") {
+		t.Errorf("expected no stderr section when nothing was written, got: %v", err)
+	}
+}
+
 func TestDirectoryLocalBackup_Run_EnsureDirExistsError(t *testing.T) {
 	expectedErr := errors.New("source directory not found")
 	backup := &DirectoryLocalBackup{
@@ -243,6 +340,78 @@ func TestDirectoryLocalBackup_Run_CorrectPathsUsed(t *testing.T) {
 	}
 }
 
+func TestDirectoryLocalBackup_Run_NestedPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		srcPath string
+		dstPath string
+	}{
+		{name: "destination inside source", srcPath: "/data", dstPath: "/data/backups/data"},
+		{name: "source inside destination", srcPath: "/backups/data", dstPath: "/backups"},
+		{name: "same path", srcPath: "/data", dstPath: "/data/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var createDirCalled, copyDirCalled bool
+			backup := &DirectoryLocalBackup{
+				baseLocalBackup: &baseLocalBackup{
+					dstPath: tt.dstPath,
+					files: &mockFilesHandler{
+						getAbsPath: func(path string) (string, error) {
+							return filepath.Clean(path), nil
+						},
+						createDirIfNotExists: func(path string) error {
+							createDirCalled = true
+							return nil
+						},
+						copyDir: func(srcPath string, dstPath string) error {
+							copyDirCalled = true
+							return nil
+						},
+					},
+				},
+				commands: &mockCommands{},
+				srcPath:  tt.srcPath,
+			}
+
+			err := backup.Run()
+
+			if !errors.Is(err, ErrNestedBackupPaths) {
+				t.Errorf("expected ErrNestedBackupPaths, got: %v", err)
+			}
+			if createDirCalled || copyDirCalled {
+				t.Error("expected nothing to be created or copied")
+			}
+		})
+	}
+}
+
+func TestDirectoryLocalBackup_Run_DisjointPathsWithCommonPrefix(t *testing.T) {
+	var copyDirCalled bool
+	backup := &DirectoryLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/data-backups",
+			files: &mockFilesHandler{
+				copyDir: func(srcPath string, dstPath string) error {
+					copyDirCalled = true
+					return nil
+				},
+			},
+		},
+		commands: &mockCommands{},
+		srcPath:  "/data",
+	}
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !copyDirCalled {
+		t.Error("expected CopyDir to be called")
+	}
+}
+
 func TestDirectoryLocalBackup_Run_PreCommandNotExecutedWhenEmpty(t *testing.T) {
 	var preCommandCalled bool
 	backup := &DirectoryLocalBackup{
@@ -270,18 +439,29 @@ func TestDirectoryLocalBackup_Run_PreCommandNotExecutedWhenEmpty(t *testing.T) {
 	}
 }
 
-func TestPostgreSQLLocalBackup_Run_Success(t *testing.T) {
-	backup := &PostgreSQLLocalBackup{
+func TestDirectoryLocalBackup_Run_PreCommandEnvReferences(t *testing.T) {
+	var capturedPreCmd string
+	vars := map[string]string{
+		"HOMELAB_PAPERLESS_SERVICE": "paperless",
+		"HOMELAB_EXPORT_DIR":        "../export",
+	}
+	backup := &DirectoryLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
 			dstPath: "/dst",
 			files:   &mockFilesHandler{},
 		},
-		dockerRunner:  &mockDockerRunner{},
-		textFormatter: &mockTextFormatter{},
-		containerName: "postgres-container",
-		dbName:        "testdb",
-		username:      "testuser",
-		password:      "testpass",
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				capturedPreCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		env: &mockEnv{getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		}},
+		srcPath:    "/src",
+		preCommand: "docker compose exec -T ${HOMELAB_PAPERLESS_SERVICE} document_exporter -d ${HOMELAB_EXPORT_DIR} $HOME",
 	}
 
 	err := backup.Run()
@@ -289,178 +469,874 @@ func TestPostgreSQLLocalBackup_Run_Success(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
+	expectedPreCmd := "docker compose exec -T paperless document_exporter -d ../export $HOME"
+	if capturedPreCmd != expectedPreCmd {
+		t.Errorf("expected pre-command %q, got: %q", expectedPreCmd, capturedPreCmd)
+	}
 }
 
-func TestPostgreSQLLocalBackup_Run_CreateDirError(t *testing.T) {
-	expectedErr := errors.New("permission denied")
-	backup := &PostgreSQLLocalBackup{
+func TestDirectoryLocalBackup_Run_PreCommandUnknownEnvReferences(t *testing.T) {
+	var preCommandCalled bool
+	var createDirCalled bool
+	backup := &DirectoryLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
 			dstPath: "/dst",
 			files: &mockFilesHandler{
 				createDirIfNotExists: func(path string) error {
-					return expectedErr
+					createDirCalled = true
+					return nil
 				},
 			},
 		},
-		dockerRunner:  &mockDockerRunner{},
-		textFormatter: &mockTextFormatter{},
-		containerName: "postgres-container",
-		dbName:        "testdb",
-		username:      "testuser",
-		password:      "testpass",
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				preCommandCalled = true
+				return &mockRunnableCommand{}
+			},
+		},
+		env:        &mockEnv{},
+		srcPath:    "/src",
+		preCommand: "docker exec ${TEST_CONTAINER} export --to ${TEST_DIR} ${TEST_CONTAINER}",
 	}
 
 	err := backup.Run()
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if !errors.Is(err, system.ErrRequiredEnvNotFound) {
+		t.Fatalf("expected ErrRequiredEnvNotFound, got: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to be %v, got: %v", expectedErr, err)
+	if !strings.HasSuffix(err.Error(), "TEST_CONTAINER, TEST_DIR") {
+		t.Errorf("expected every unknown reference to be listed once, got: %v", err)
+	}
+	if preCommandCalled || createDirCalled {
+		t.Error("expected nothing to run when a reference is unknown")
 	}
 }
 
-func TestPostgreSQLLocalBackup_Run_WaitUntilContainerExecIsSuccessfulError(t *testing.T) {
-	expectedErr := errors.New("database not ready")
-	backup := &PostgreSQLLocalBackup{
+func TestDirectoryLocalBackup_Run_IncrementalUsesRsync(t *testing.T) {
+	var capturedName string
+	var capturedArgs []string
+	var copyDirCalled bool
+	backup := &DirectoryLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
-			dstPath: "/dst",
-			files:   &mockFilesHandler{},
+			dstPath: "/dst/",
+			files: &mockFilesHandler{
+				copyDir: func(srcPath string, dstPath string) error {
+					copyDirCalled = true
+					return nil
+				},
+			},
 		},
-		dockerRunner: &mockDockerRunner{
-			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string) error {
-				return expectedErr
+		commands: &mockCommands{
+			lookPath: func(name string) (string, error) {
+				return "/usr/bin/" + name, nil
+			},
+			execCommand: func(name string, arg ...string) system.RunnableCommand {
+				capturedName = name
+				capturedArgs = arg
+				return &mockRunnableCommand{}
 			},
 		},
-		textFormatter: &mockTextFormatter{},
-		containerName: "postgres-container",
-		dbName:        "testdb",
-		username:      "testuser",
-		password:      "testpass",
+		srcPath: "/src/",
 	}
+	backup.WithIncremental()
 
 	err := backup.Run()
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	if capturedName != "rsync" {
+		t.Errorf("expected command %q, got %q", "rsync", capturedName)
+	}
+	expectedArgs := []string{"-a", "--delete", "/src", "/dst/"}
+	if diff := cmp.Diff(expectedArgs, capturedArgs); diff != "" {
+		t.Errorf("rsync arguments mismatch (-want +got):\n%s", diff)
+	}
+	if copyDirCalled {
+		t.Error("expected CopyDir not to be called when rsync is used")
 	}
 }
 
-func TestPostgreSQLLocalBackup_Run_ContainerExecError(t *testing.T) {
-	expectedErr := errors.New("pg_dump failed")
-	backup := &PostgreSQLLocalBackup{
+func TestDirectoryLocalBackup_Run_IncrementalFallsBackToCopyWithoutRsync(t *testing.T) {
+	var copyDirCalled bool
+	var execCommandCalled bool
+	var emptiedPath string
+	backup := &DirectoryLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
 			dstPath: "/dst",
-			files:   &mockFilesHandler{},
+			files: &mockFilesHandler{
+				emptyDir: func(path string) error {
+					emptiedPath = path
+					return nil
+				},
+				copyDir: func(srcPath string, dstPath string) error {
+					copyDirCalled = true
+					return nil
+				},
+			},
 		},
-		dockerRunner: &mockDockerRunner{
-			containerExec: func(containerName string, cmd string) error {
-				return expectedErr
+		commands: &mockCommands{
+			execCommand: func(name string, arg ...string) system.RunnableCommand {
+				execCommandCalled = true
+				return &mockRunnableCommand{}
 			},
 		},
-		textFormatter: &mockTextFormatter{},
-		containerName: "postgres-container",
-		dbName:        "testdb",
-		username:      "testuser",
-		password:      "testpass",
+		srcPath: "/src",
 	}
+	backup.WithIncremental()
 
 	err := backup.Run()
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	if !copyDirCalled {
+		t.Error("expected CopyDir to be called when rsync is not installed")
+	}
+	if emptiedPath != "/dst/src" {
+		t.Errorf("expected the previous copy %q to be deleted, got %q", "/dst/src", emptiedPath)
+	}
+	if execCommandCalled {
+		t.Error("expected rsync not to be executed when it is not installed")
 	}
 }
 
-func TestPostgreSQLLocalBackup_Run_CorrectReadinessCheck(t *testing.T) {
-	var capturedContainerName string
-	var capturedCmd string
-	containerName := "postgres-container"
-	backup := &PostgreSQLLocalBackup{
+func TestDirectoryLocalBackup_Run_IncrementalRsyncWithCopyOptions(t *testing.T) {
+	var capturedArgs []string
+	backup := &DirectoryLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
 			dstPath: "/dst",
 			files:   &mockFilesHandler{},
 		},
-		dockerRunner: &mockDockerRunner{
-			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string) error {
-				capturedContainerName = containerName
-				capturedCmd = cmd
-				return nil
+		commands: &mockCommands{
+			lookPath: func(name string) (string, error) {
+				return "/usr/bin/" + name, nil
+			},
+			execCommand: func(name string, arg ...string) system.RunnableCommand {
+				capturedArgs = arg
+				return &mockRunnableCommand{runFunc: func() error {
+					return &exitCodeError{code: rsyncExitCodePartialTransfer}
+				}}
 			},
 		},
-		textFormatter: &mockTextFormatter{},
-		containerName: containerName,
-		dbName:        "testdb",
-		username:      "testuser",
-		password:      "testpass",
+		srcPath: "/src",
 	}
+	backup.WithIncremental().WithFollowSymlinks().WithSkipUnreadable()
 
 	err := backup.Run()
 
 	if err != nil {
-		t.Fatalf("expected no error, got: %v", err)
-	}
-	if capturedContainerName != containerName {
-		t.Errorf("expected container name %q, got %q", containerName, capturedContainerName)
+		t.Fatalf("expected the partial transfer to be accepted, got: %v", err)
 	}
-	expectedCmd := "pg_isready -q"
-	if capturedCmd != expectedCmd {
-		t.Errorf("expected readiness check command %q, got %q", expectedCmd, capturedCmd)
+	expectedArgs := []string{"-a", "--delete", "--copy-links", "/src", "/dst/"}
+	if diff := cmp.Diff(expectedArgs, capturedArgs); diff != "" {
+		t.Errorf("rsync arguments mismatch (-want +got):\n%s", diff)
 	}
 }
 
-func TestPostgreSQLLocalBackup_Run_CorrectBackupCommand(t *testing.T) {
-	var capturedContainerName string
-	var capturedCmd string
-	containerName := "postgres-container"
-	dbName := "mydb"
-	username := "myuser"
-	password := "mypass"
-	dstPath := "/dst"
-	backup := &PostgreSQLLocalBackup{
+func TestDirectoryLocalBackup_Run_IncrementalRsyncPartialTransferFailsWithoutSkipUnreadable(t *testing.T) {
+	backup := &DirectoryLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
-			dstPath: dstPath,
+			dstPath: "/dst",
 			files:   &mockFilesHandler{},
 		},
-		dockerRunner: &mockDockerRunner{
-			containerExec: func(containerName string, cmd string) error {
-				capturedContainerName = containerName
-				capturedCmd = cmd
-				return nil
+		commands: &mockCommands{
+			lookPath: func(name string) (string, error) {
+				return "/usr/bin/" + name, nil
+			},
+			execCommand: func(name string, arg ...string) system.RunnableCommand {
+				return &mockRunnableCommand{runFunc: func() error {
+					return &exitCodeError{code: rsyncExitCodePartialTransfer}
+				}}
 			},
 		},
-		textFormatter: &mockTextFormatter{},
-		containerName: containerName,
-		dbName:        dbName,
-		username:      username,
-		password:      password,
+		srcPath: "/src",
 	}
+	backup.WithIncremental()
 
 	err := backup.Run()
 
-	if err != nil {
-		t.Fatalf("expected no error, got: %v", err)
-	}
-	if capturedContainerName != containerName {
-		t.Errorf("expected container name %q, got %q", containerName, capturedContainerName)
-	}
-	expectedCmd := `/bin/bash -c "PGPASSWORD='mypass' pg_dump --username myuser mydb" > /dst/mydb.sql`
-	if capturedCmd != expectedCmd {
-		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
+	if err == nil {
+		t.Error("expected the partial transfer to fail the backup, got nil")
 	}
 }
 
-func TestMySQLLocalBackup_Run_Success(t *testing.T) {
-	backup := &MySQLLocalBackup{
+func TestDirectoryLocalBackup_Run_SkipUnreadable(t *testing.T) {
+	var copyDirCalled bool
+	var copiedSrcPath, copiedDstPath string
+	var copiedOpts system.CopyDirOptions
+	backup := &DirectoryLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
 			dstPath: "/dst",
-			files:   &mockFilesHandler{},
+			files: &mockFilesHandler{
+				copyDir: func(srcPath string, dstPath string) error {
+					copyDirCalled = true
+					return nil
+				},
+				copyDirWithOptions: func(srcPath string, dstPath string, opts system.CopyDirOptions) ([]string, error) {
+					copiedSrcPath = srcPath
+					copiedOpts = opts
+					copiedDstPath = dstPath
+					return []string{"/src/secret"}, nil
+				},
+			},
+		},
+		commands: &mockCommands{},
+		srcPath:  "/src",
+	}
+	backup.WithSkipUnreadable()
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if copyDirCalled {
+		t.Error("expected CopyDir not to be called when unreadable files are skipped")
+	}
+	if copiedSrcPath != "/src" || copiedDstPath != "/dst" {
+		t.Errorf("expected CopyDirWithOptions(%q, %q), got (%q, %q)", "/src", "/dst", copiedSrcPath, copiedDstPath)
+	}
+	if expected := (system.CopyDirOptions{SkipUnreadable: true}); copiedOpts != expected {
+		t.Errorf("expected options %+v, got %+v", expected, copiedOpts)
+	}
+}
+
+func TestDirectoryLocalBackup_Run_FollowSymlinks(t *testing.T) {
+	var copiedOpts system.CopyDirOptions
+	backup := &DirectoryLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files: &mockFilesHandler{
+				copyDirWithOptions: func(srcPath string, dstPath string, opts system.CopyDirOptions) ([]string, error) {
+					copiedOpts = opts
+					return nil, nil
+				},
+			},
+		},
+		commands: &mockCommands{},
+		srcPath:  "/src",
+	}
+	backup.WithFollowSymlinks().WithSkipUnreadable()
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if expected := (system.CopyDirOptions{SkipUnreadable: true, FollowSymlinks: true}); copiedOpts != expected {
+		t.Errorf("expected options %+v, got %+v", expected, copiedOpts)
+	}
+}
+
+func TestDirectoryLocalBackup_Run_SkipUnreadableError(t *testing.T) {
+	expectedErr := errors.New("copy failed")
+	backup := &DirectoryLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files: &mockFilesHandler{
+				copyDirWithOptions: func(srcPath string, dstPath string, opts system.CopyDirOptions) ([]string, error) {
+					return nil, expectedErr
+				},
+			},
+		},
+		commands: &mockCommands{},
+		srcPath:  "/src",
+	}
+	backup.WithSkipUnreadable()
+
+	err := backup.Run()
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestDirectoryLocalBackup_Run_IncrementalRsyncError(t *testing.T) {
+	expectedErr := errors.New("rsync failed")
+	backup := &DirectoryLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		commands: &mockCommands{
+			lookPath: func(name string) (string, error) {
+				return "/usr/bin/" + name, nil
+			},
+			execCommand: func(name string, arg ...string) system.RunnableCommand {
+				return &mockRunnableCommand{runFunc: func() error { return expectedErr }}
+			},
+		},
+		srcPath: "/src",
+	}
+	backup.WithIncremental()
+
+	err := backup.Run()
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to be %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_Success(t *testing.T) {
+	backup := &PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner:  &mockDockerRunner{},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_CreateDirError(t *testing.T) {
+	expectedErr := errors.New("permission denied")
+	backup := &PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files: &mockFilesHandler{
+				createDirIfNotExists: func(path string) error {
+					return expectedErr
+				},
+			},
+		},
+		dockerRunner:  &mockDockerRunner{},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}
+
+	err := backup.Run()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to be %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_WaitUntilContainerExecIsSuccessfulError(t *testing.T) {
+	expectedErr := errors.New("database not ready")
+	backup := &PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				return expectedErr
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}
+
+	err := backup.Run()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_PassesReadinessTimeout(t *testing.T) {
+	var capturedTimeout time.Duration
+	backup := NewPostgreSQLLocalBackup("immich-db", "immich", "user", "pass", "/dst").
+		WithReadinessTimeout(5 * time.Minute)
+	backup.files = &mockFilesHandler{}
+	backup.textFormatter = &mockTextFormatter{}
+	backup.dockerRunner = &mockDockerRunner{
+		waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+			capturedTimeout = timeout
+			return errors.New("database not ready")
+		},
+	}
+
+	_ = backup.Run()
+
+	if capturedTimeout != 5*time.Minute {
+		t.Errorf("expected timeout %v, got %v", 5*time.Minute, capturedTimeout)
+	}
+}
+
+func TestLoadReadinessTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		expected time.Duration
+	}{
+		{name: "not set", vars: map[string]string{}, expected: 0},
+		{name: "empty", vars: map[string]string{"HOMELAB_IMMICH_DB_READY_TIMEOUT": " "}, expected: 0},
+		{name: "minutes", vars: map[string]string{"HOMELAB_IMMICH_DB_READY_TIMEOUT": "5m"}, expected: 5 * time.Minute},
+		{name: "trimmed", vars: map[string]string{"HOMELAB_IMMICH_DB_READY_TIMEOUT": " 90s "}, expected: 90 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &mockEnv{getEnvFunc: func(varName string) (string, bool) {
+				value, exists := tt.vars[varName]
+				return value, exists
+			}}
+
+			timeout, err := LoadReadinessTimeout(env, "HOMELAB_IMMICH_DB_READY_TIMEOUT")
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if timeout != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, timeout)
+			}
+		})
+	}
+}
+
+func TestLoadReadinessTimeout_Invalid(t *testing.T) {
+	for _, value := range []string{"5", "five minutes", "0s", "-1m"} {
+		t.Run(value, func(t *testing.T) {
+			env := &mockEnv{getEnvFunc: func(varName string) (string, bool) {
+				return value, true
+			}}
+
+			_, err := LoadReadinessTimeout(env, "HOMELAB_IMMICH_DB_READY_TIMEOUT")
+
+			if !errors.Is(err, ErrInvalidReadinessTimeout) {
+				t.Errorf("expected ErrInvalidReadinessTimeout, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveDumpFilename_RejectsUnsafeCharacters(t *testing.T) {
+	for _, template := range []string{"$(id).sql", "<db>;rm.sql", "<db>|cat", "*.sql", "<db><x", "a b.sql", "../<db>.sql", "..", "<db>'.sql"} {
+		t.Run(template, func(t *testing.T) {
+			_, err := resolveDumpFilename(template, "mydb", &mockTime{})
+
+			if !errors.Is(err, ErrInvalidDumpFilename) {
+				t.Errorf("expected ErrInvalidDumpFilename, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadDumpFilenameTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		expected string
+	}{
+		{name: "not set", vars: map[string]string{}, expected: ""},
+		{name: "empty", vars: map[string]string{"HOMELAB_IMMICH_DB_DUMP_FILENAME": " "}, expected: ""},
+		{name: "dated", vars: map[string]string{"HOMELAB_IMMICH_DB_DUMP_FILENAME": "<db>-<ts>.sql"}, expected: "<db>-<ts>.sql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &mockEnv{getEnvFunc: func(varName string) (string, bool) {
+				value, exists := tt.vars[varName]
+				return value, exists
+			}}
+
+			template, err := LoadDumpFilenameTemplate(env, "HOMELAB_IMMICH_DB_DUMP_FILENAME", "immich")
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if template != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, template)
+			}
+		})
+	}
+}
+
+func TestLoadDumpFilenameTemplate_Invalid(t *testing.T) {
+	env := &mockEnv{getEnvFunc: func(varName string) (string, bool) {
+		return "<db> $(id).sql", true
+	}}
+
+	_, err := LoadDumpFilenameTemplate(env, "HOMELAB_IMMICH_DB_DUMP_FILENAME", "immich")
+
+	if !errors.Is(err, ErrInvalidDumpFilename) {
+		t.Errorf("expected ErrInvalidDumpFilename, got: %v", err)
+	}
+}
+
+func TestDatabaseLocalBackups_ReadinessCheckIncludesTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		backup ReadinessChecker
+	}{
+		{name: "PostgreSQL", backup: NewPostgreSQLLocalBackup("db", "name", "user", "pass", "/dst").WithReadinessTimeout(time.Minute)},
+		{name: "MySQL", backup: NewMySQLLocalBackup("db", "name", "user", "pass", "/dst").WithReadinessTimeout(time.Minute)},
+		{name: "MariaDB", backup: NewMariaDBLocalBackup("db", "name", "user", "pass", "/dst").WithReadinessTimeout(time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if timeout := tt.backup.ReadinessCheck().Timeout; timeout != time.Minute {
+				t.Errorf("expected timeout %v, got %v", time.Minute, timeout)
+			}
+		})
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_ContainerExecError(t *testing.T) {
+	expectedErr := errors.New("pg_dump failed")
+	backup := &PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				return expectedErr
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}
+
+	err := backup.Run()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_CorrectReadinessCheck(t *testing.T) {
+	var capturedContainerName string
+	var capturedCmd string
+	containerName := "postgres-container"
+	backup := &PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				capturedContainerName = containerName
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: containerName,
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedContainerName != containerName {
+		t.Errorf("expected container name %q, got %q", containerName, capturedContainerName)
+	}
+	expectedCmd := "pg_isready -q"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected readiness check command %q, got %q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_CustomReadinessCheck(t *testing.T) {
+	var capturedCmd string
+	backup := (&PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}).WithReadinessCmd("pg_isready -q -U backup -d testdb")
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "pg_isready -q -U backup -d testdb"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected readiness check command %q, got %q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_CorrectBackupCommand(t *testing.T) {
+	var capturedContainerName string
+	var capturedCmd string
+	containerName := "postgres-container"
+	dbName := "mydb"
+	username := "myuser"
+	password := "mypass"
+	dstPath := "/dst"
+	backup := &PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: dstPath,
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				capturedContainerName = containerName
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: containerName,
+		dbName:        dbName,
+		username:      username,
+		password:      password,
+	}
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedContainerName != containerName {
+		t.Errorf("expected container name %q, got %q", containerName, capturedContainerName)
+	}
+	expectedCmd := `/bin/bash -c "PGPASSWORD='mypass' pg_dump --username myuser mydb" > /dst/mydb.sql`
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_ExtraArgsBeforeDatabaseName(t *testing.T) {
+	var capturedCmd string
+	backup := (&PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+	}).WithExtraArgs("--no-owner", "--clean")
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := `/bin/bash -c "PGPASSWORD='mypass' pg_dump --username myuser '--no-owner' '--clean' mydb" > /dst/mydb.sql`
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_FilenameTemplate(t *testing.T) {
+	var capturedCmd string
+	backup := (&PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+		time: &mockTime{now: func() time.Time {
+			return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		}},
+	}).WithFilenameTemplate("<db>-<ts>.sql")
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := `/bin/bash -c "PGPASSWORD='mypass' pg_dump --username myuser mydb" > /dst/mydb-2025-01-02_03-04-05.sql`
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_InvalidFilenameTemplate(t *testing.T) {
+	var containerExecCalled bool
+	backup := (&PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				containerExecCalled = true
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+	}).WithFilenameTemplate("../<db>.sql")
+
+	err := backup.Run()
+
+	if !errors.Is(err, ErrInvalidDumpFilename) {
+		t.Errorf("expected error to be %v, got: %v", ErrInvalidDumpFilename, err)
+	}
+	if containerExecCalled {
+		t.Error("expected the dump not to run with an invalid filename")
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_WithDatabasesDumpsEachIntoItsOwnFile(t *testing.T) {
+	var capturedCmds []string
+	backup := (&PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				capturedCmds = append(capturedCmds, cmd)
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+	}).WithDatabases("nextcloud", "gitea")
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmds := []string{
+		`/bin/bash -c "PGPASSWORD='mypass' pg_dump --username myuser nextcloud" > /dst/nextcloud.sql`,
+		`/bin/bash -c "PGPASSWORD='mypass' pg_dump --username myuser gitea" > /dst/gitea.sql`,
+	}
+	if diff := cmp.Diff(expectedCmds, capturedCmds); diff != "" {
+		t.Errorf("commands mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_WithDatabasesAggregatesErrors(t *testing.T) {
+	nextcloudErr := errors.New("database nextcloud does not exist")
+	giteaErr := errors.New("permission denied for database gitea")
+	var dumpedDBs []string
+	backup := (&PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				switch {
+				case strings.Contains(cmd, " nextcloud\""):
+					dumpedDBs = append(dumpedDBs, "nextcloud")
+					return nextcloudErr
+				case strings.Contains(cmd, " gitea\""):
+					dumpedDBs = append(dumpedDBs, "gitea")
+					return giteaErr
+				default:
+					dumpedDBs = append(dumpedDBs, "vaultwarden")
+					return nil
+				}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+	}).WithDatabases("nextcloud", "gitea", "vaultwarden")
+
+	err := backup.Run()
+
+	if !errors.Is(err, nextcloudErr) || !errors.Is(err, giteaErr) {
+		t.Errorf("expected error to wrap both failures, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"nextcloud", "gitea", "vaultwarden"}, dumpedDBs); diff != "" {
+		t.Errorf("expected every database to be dumped (-want +got):\n%s", diff)
+	}
+}
+
+func TestPostgreSQLLocalBackup_Run_WithDatabasesRequiresDBPlaceholder(t *testing.T) {
+	var containerExecCalled bool
+	backup := (&PostgreSQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				containerExecCalled = true
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "postgres-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+	}).WithDatabases("nextcloud", "gitea").WithFilenameTemplate("dump.sql")
+
+	err := backup.Run()
+
+	if !errors.Is(err, ErrInvalidDumpFilename) {
+		t.Errorf("expected error to be %v, got: %v", ErrInvalidDumpFilename, err)
+	}
+	if containerExecCalled {
+		t.Error("expected no database to be dumped into the same file")
+	}
+}
+
+func TestMySQLLocalBackup_Run_Success(t *testing.T) {
+	backup := &MySQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
 		},
 		dockerRunner:  &mockDockerRunner{},
 		textFormatter: &mockTextFormatter{},
@@ -514,129 +1390,256 @@ func TestMySQLLocalBackup_Run_WaitUntilContainerExecIsSuccessfulError(t *testing
 			files:   &mockFilesHandler{},
 		},
 		dockerRunner: &mockDockerRunner{
-			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string) error {
-				return expectedErr
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				return expectedErr
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "mysql-container",
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}
+
+	err := backup.Run()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestMySQLLocalBackup_Run_ContainerExecError(t *testing.T) {
+	expectedErr := errors.New("pg_dump failed")
+	backup := &MySQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				return expectedErr
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "mysql-container",
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}
+
+	err := backup.Run()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestMySQLLocalBackup_Run_CorrectReadinessCheck(t *testing.T) {
+	var capturedContainerName string
+	var capturedCmd string
+	containerName := "mysql-container"
+	backup := &MySQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				capturedContainerName = containerName
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: containerName,
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedContainerName != containerName {
+		t.Errorf("expected container name %q, got %q", containerName, capturedContainerName)
+	}
+	expectedCmd := "mysqladmin ping -h localhost --silent"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected readiness check command %q, got %q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestMySQLLocalBackup_Run_CustomReadinessCheck(t *testing.T) {
+	var capturedCmd string
+	backup := (&MySQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "mysql-container",
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}).WithReadinessCmd("mysqladmin ping -h 127.0.0.1 -u root --silent")
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "mysqladmin ping -h 127.0.0.1 -u root --silent"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected readiness check command %q, got %q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestMySQLLocalBackup_Run_CorrectBackupCommand(t *testing.T) {
+	var capturedContainerName string
+	var capturedCmd string
+	containerName := "mysql-container"
+	dbName := "mydb"
+	username := "myuser"
+	password := "mypass"
+	dstPath := "/dst"
+	backup := &MySQLLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: dstPath,
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				capturedContainerName = containerName
+				capturedCmd = cmd
+				return nil
 			},
 		},
 		textFormatter: &mockTextFormatter{},
-		containerName: "mysql-container",
-		dbName:        "testdb",
-		username:      "testuser",
-		password:      "testpass",
+		containerName: containerName,
+		dbName:        dbName,
+		username:      username,
+		password:      password,
 	}
 
 	err := backup.Run()
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	if capturedContainerName != containerName {
+		t.Errorf("expected container name %q, got %q", containerName, capturedContainerName)
+	}
+	expectedCmd := `/bin/bash -c "MYSQL_PWD='mypass' mysqldump --user myuser --single-transaction --quick mydb" > /dst/mydb.sql`
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
 	}
 }
 
-func TestMySQLLocalBackup_Run_ContainerExecError(t *testing.T) {
-	expectedErr := errors.New("pg_dump failed")
-	backup := &MySQLLocalBackup{
+func TestMySQLLocalBackup_Run_ExtraArgsBeforeDatabaseName(t *testing.T) {
+	var capturedCmd string
+	backup := (&MySQLLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
 			dstPath: "/dst",
 			files:   &mockFilesHandler{},
 		},
 		dockerRunner: &mockDockerRunner{
 			containerExec: func(containerName string, cmd string) error {
-				return expectedErr
+				capturedCmd = cmd
+				return nil
 			},
 		},
 		textFormatter: &mockTextFormatter{},
 		containerName: "mysql-container",
-		dbName:        "testdb",
-		username:      "testuser",
-		password:      "testpass",
-	}
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+	}).WithExtraArgs("--routines")
 
 	err := backup.Run()
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	expectedCmd := `/bin/bash -c "MYSQL_PWD='mypass' mysqldump --user myuser --single-transaction --quick '--routines' mydb" > /dst/mydb.sql`
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
 	}
 }
 
-func TestMySQLLocalBackup_Run_CorrectReadinessCheck(t *testing.T) {
-	var capturedContainerName string
+func TestMySQLLocalBackup_Run_WithoutConsistentDump(t *testing.T) {
 	var capturedCmd string
-	containerName := "mysql-container"
-	backup := &MySQLLocalBackup{
+	backup := (&MySQLLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
 			dstPath: "/dst",
 			files:   &mockFilesHandler{},
 		},
 		dockerRunner: &mockDockerRunner{
-			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string) error {
-				capturedContainerName = containerName
+			containerExec: func(containerName string, cmd string) error {
 				capturedCmd = cmd
 				return nil
 			},
 		},
 		textFormatter: &mockTextFormatter{},
-		containerName: containerName,
-		dbName:        "testdb",
-		username:      "testuser",
-		password:      "testpass",
-	}
+		containerName: "mysql-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+	}).WithoutConsistentDump()
 
 	err := backup.Run()
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
-	if capturedContainerName != containerName {
-		t.Errorf("expected container name %q, got %q", containerName, capturedContainerName)
-	}
-	expectedCmd := "mysqladmin ping -h localhost --silent"
+	expectedCmd := `/bin/bash -c "MYSQL_PWD='mypass' mysqldump --user myuser mydb" > /dst/mydb.sql`
 	if capturedCmd != expectedCmd {
-		t.Errorf("expected readiness check command %q, got %q", expectedCmd, capturedCmd)
+		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
 	}
 }
 
-func TestMySQLLocalBackup_Run_CorrectBackupCommand(t *testing.T) {
-	var capturedContainerName string
+func TestMySQLLocalBackup_Run_FilenameTemplate(t *testing.T) {
 	var capturedCmd string
-	containerName := "mysql-container"
-	dbName := "mydb"
-	username := "myuser"
-	password := "mypass"
-	dstPath := "/dst"
-	backup := &MySQLLocalBackup{
+	backup := (&MySQLLocalBackup{
 		baseLocalBackup: &baseLocalBackup{
-			dstPath: dstPath,
+			dstPath: "/dst",
 			files:   &mockFilesHandler{},
 		},
 		dockerRunner: &mockDockerRunner{
 			containerExec: func(containerName string, cmd string) error {
-				capturedContainerName = containerName
 				capturedCmd = cmd
 				return nil
 			},
 		},
 		textFormatter: &mockTextFormatter{},
-		containerName: containerName,
-		dbName:        dbName,
-		username:      username,
-		password:      password,
-	}
+		containerName: "mysql-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+		time: &mockTime{now: func() time.Time {
+			return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		}},
+	}).WithFilenameTemplate("<ts>_<db>.dump")
 
 	err := backup.Run()
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
-	if capturedContainerName != containerName {
-		t.Errorf("expected container name %q, got %q", containerName, capturedContainerName)
-	}
-	expectedCmd := `/bin/bash -c "MYSQL_PWD='mypass' mysqldump --user myuser mydb" > /dst/mydb.sql`
+	expectedCmd := `/bin/bash -c "MYSQL_PWD='mypass' mysqldump --user myuser --single-transaction --quick mydb" > /dst/2025-01-02_03-04-05_mydb.dump`
 	if capturedCmd != expectedCmd {
 		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
 	}
@@ -700,7 +1703,7 @@ func TestMariaDBLocalBackup_Run_WaitUntilContainerExecIsSuccessfulError(t *testi
 			files:   &mockFilesHandler{},
 		},
 		dockerRunner: &mockDockerRunner{
-			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string) error {
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
 				return expectedErr
 			},
 		},
@@ -760,7 +1763,7 @@ func TestMariaDBLocalBackup_Run_CorrectReadinessCheck(t *testing.T) {
 			files:   &mockFilesHandler{},
 		},
 		dockerRunner: &mockDockerRunner{
-			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string) error {
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
 				capturedContainerName = containerName
 				capturedCmd = cmd
 				return nil
@@ -787,6 +1790,37 @@ func TestMariaDBLocalBackup_Run_CorrectReadinessCheck(t *testing.T) {
 	}
 }
 
+func TestMariaDBLocalBackup_Run_CustomReadinessCheck(t *testing.T) {
+	var capturedCmd string
+	backup := (&MariaDBLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			waitUntilContainerExecIsSuccessful: func(containerName string, cmd string, timeout time.Duration) error {
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "mariadb-container",
+		dbName:        "testdb",
+		username:      "testuser",
+		password:      "testpass",
+	}).WithReadinessCmd("healthcheck.sh --connect")
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "healthcheck.sh --connect"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected readiness check command %q, got %q", expectedCmd, capturedCmd)
+	}
+}
+
 func TestMariaDBLocalBackup_Run_CorrectBackupCommand(t *testing.T) {
 	var capturedContainerName string
 	var capturedCmd string
@@ -822,8 +1856,200 @@ func TestMariaDBLocalBackup_Run_CorrectBackupCommand(t *testing.T) {
 	if capturedContainerName != containerName {
 		t.Errorf("expected container name %q, got %q", containerName, capturedContainerName)
 	}
+	expectedCmd := `/bin/bash -c "MYSQL_PWD='mypass' mariadb-dump --user myuser --single-transaction --quick mydb" > /dst/mydb.sql`
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestMariaDBLocalBackup_Run_ExtraArgsBeforeDatabaseName(t *testing.T) {
+	var capturedCmd string
+	backup := (&MariaDBLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "mariadb-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+	}).WithExtraArgs("--routines")
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := `/bin/bash -c "MYSQL_PWD='mypass' mariadb-dump --user myuser --single-transaction --quick '--routines' mydb" > /dst/mydb.sql`
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestMariaDBLocalBackup_Run_WithoutConsistentDump(t *testing.T) {
+	var capturedCmd string
+	backup := (&MariaDBLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "mariadb-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+	}).WithoutConsistentDump()
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
 	expectedCmd := `/bin/bash -c "MYSQL_PWD='mypass' mariadb-dump --user myuser mydb" > /dst/mydb.sql`
 	if capturedCmd != expectedCmd {
 		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
 	}
 }
+
+func TestVolumeLocalBackup_Run_CorrectDockerRunCommand(t *testing.T) {
+	var capturedCmd string
+	var capturedCreateDir string
+	backup := &VolumeLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst/volumes",
+			files: &mockFilesHandler{
+				createDirIfNotExists: func(path string) error {
+					capturedCreateDir = path
+					return nil
+				},
+			},
+		},
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				capturedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		volumeName:    "vaultwarden_data",
+	}
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedCreateDir != "/dst/volumes" {
+		t.Errorf("expected create dir %q, got %q", "/dst/volumes", capturedCreateDir)
+	}
+	expectedCmd := "docker run --rm -v 'vaultwarden_data':/data:ro -v '/dst/volumes':/backup alpine tar czf '/backup/vaultwarden_data.tar.gz' -C /data ."
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command %q, got %q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestVolumeLocalBackup_Run_CreateDirError(t *testing.T) {
+	expectedErr := errors.New("permission denied")
+	commandExecuted := false
+	backup := &VolumeLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files: &mockFilesHandler{
+				createDirIfNotExists: func(path string) error {
+					return expectedErr
+				},
+			},
+		},
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				commandExecuted = true
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		volumeName:    "vaultwarden_data",
+	}
+
+	err := backup.Run()
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+	if commandExecuted {
+		t.Error("expected docker run NOT to be executed when CreateDirIfNotExists fails")
+	}
+}
+
+func TestVolumeLocalBackup_Run_DockerRunError(t *testing.T) {
+	expectedErr := errors.New("docker run failed")
+	backup := &VolumeLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{
+					runFunc: func() error {
+						return expectedErr
+					},
+				}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		volumeName:    "vaultwarden_data",
+	}
+
+	err := backup.Run()
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestMariaDBLocalBackup_Run_FilenameTemplate(t *testing.T) {
+	var capturedCmd string
+	backup := (&MariaDBLocalBackup{
+		baseLocalBackup: &baseLocalBackup{
+			dstPath: "/dst",
+			files:   &mockFilesHandler{},
+		},
+		dockerRunner: &mockDockerRunner{
+			containerExec: func(containerName string, cmd string) error {
+				capturedCmd = cmd
+				return nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		containerName: "mariadb-container",
+		dbName:        "mydb",
+		username:      "myuser",
+		password:      "mypass",
+		time: &mockTime{now: func() time.Time {
+			return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		}},
+	}).WithFilenameTemplate("<ts>_<db>.dump")
+
+	err := backup.Run()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := `/bin/bash -c "MYSQL_PWD='mypass' mariadb-dump --user myuser --single-transaction --quick mydb" > /dst/2025-01-02_03-04-05_mydb.dump`
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command:\n%q\ngot:\n%q", expectedCmd, capturedCmd)
+	}
+}
@@ -0,0 +1,88 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+var (
+	ErrInvalidLocalRetention = errors.New("invalid local retention")
+)
+
+// localRetentionDaysEnvVar is the environment variable with the number of days that dated local archives are kept.
+// When it is not set, local archives are never pruned
+const localRetentionDaysEnvVar = "HOMELAB_LOCAL_RETENTION_DAYS"
+
+// datedArchiveTimestampPattern matches the timestamps that dumpFilenameTimestampPlaceholder writes into filenames
+var datedArchiveTimestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}`)
+
+// LocalArchivePruner deletes the dated archives that local backups leave in their destination directories, once they
+// are older than the retention period
+type LocalArchivePruner struct {
+	files         system.FilesHandler
+	time          system.Time
+	retentionDays int
+}
+
+// LoadLocalArchivePruner creates a pruner from HOMELAB_LOCAL_RETENTION_DAYS. It returns nil when the variable is not
+// set, because local archives are kept forever by default
+func LoadLocalArchivePruner(env system.Env) (*LocalArchivePruner, error) {
+	retentionDays, exists, err := env.GetIntEnv(localRetentionDaysEnvVar)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLocalRetention, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	if retentionDays < 1 {
+		return nil, fmt.Errorf("%w %q: must be at least 1 day, got %d", ErrInvalidLocalRetention, localRetentionDaysEnvVar, retentionDays)
+	}
+	return &LocalArchivePruner{
+		files:         system.NewDefaultFilesHandler(),
+		time:          system.NewDefaultTime(),
+		retentionDays: retentionDays,
+	}, nil
+}
+
+// isDatedArchive tells whether a file was written by a backup with a timestamp in its name, such as a database dump
+// named with "<db>-<ts>.sql". Only these files are pruned, so that any other file in a destination directory is safe
+func isDatedArchive(filename string) bool {
+	for _, timestamp := range datedArchiveTimestampPattern.FindAllString(filename, -1) {
+		if _, err := time.Parse(dumpFilenameTimeFormat, timestamp); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Prune deletes the dated archives inside dirs that were last modified before the retention period. Subdirectories
+// are not visited. Every directory is pruned even if another one fails, and the failures are reported together
+func (p *LocalArchivePruner) Prune(dirs []string) error {
+	cutoff := p.time.Now().AddDate(0, 0, -p.retentionDays)
+	var errs []error
+	for _, dir := range dirs {
+		infos, err := p.files.ListFiles(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, info := range infos {
+			if !isDatedArchive(info.Name()) || !info.ModTime().Before(cutoff) {
+				continue
+			}
+			path := filepath.Join(dir, info.Name())
+			if err := p.files.RemoveFile(path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			slog.Info("Pruned expired local archive", "path", path, "modTime", info.ModTime())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadLocalArchivePruner_NotSet(t *testing.T) {
+	pruner, err := LoadLocalArchivePruner(&mockEnv{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if pruner != nil {
+		t.Errorf("expected no pruner when %s is not set, got: %+v", localRetentionDaysEnvVar, pruner)
+	}
+}
+
+func TestLoadLocalArchivePruner_Set(t *testing.T) {
+	env := &mockEnv{getEnvFunc: func(varName string) (string, bool) {
+		return "7", varName == localRetentionDaysEnvVar
+	}}
+
+	pruner, err := LoadLocalArchivePruner(env)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if pruner == nil || pruner.retentionDays != 7 {
+		t.Errorf("expected a pruner keeping 7 days, got: %+v", pruner)
+	}
+}
+
+func TestLoadLocalArchivePruner_InvalidValues(t *testing.T) {
+	for _, value := range []string{"0", "-3", "a week"} {
+		env := &mockEnv{getEnvFunc: func(varName string) (string, bool) {
+			return value, varName == localRetentionDaysEnvVar
+		}}
+
+		_, err := LoadLocalArchivePruner(env)
+
+		if !errors.Is(err, ErrInvalidLocalRetention) {
+			t.Errorf("for value %q: expected ErrInvalidLocalRetention, got: %v", value, err)
+		}
+	}
+}
+
+func TestIsDatedArchive(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected bool
+	}{
+		{"immich-2025-01-02_03-04-05.sql", true},
+		{"2025-01-02_03-04-05_firefly.dump", true},
+		{"immich.sql", false},
+		{"notes-2025-01-02.txt", false},
+		// Looks like a timestamp, but isn't a valid date
+		{"immich-2025-13-45_03-04-05.sql", false},
+	}
+	for _, tt := range tests {
+		if got := isDatedArchive(tt.filename); got != tt.expected {
+			t.Errorf("for %q: expected %v, got %v", tt.filename, tt.expected, got)
+		}
+	}
+}
+
+func TestIsDatedArchive_MatchesDatedDumpFilenames(t *testing.T) {
+	clock := &mockTime{now: func() time.Time {
+		return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	}}
+	for _, template := range []string{"<db>-<ts>.sql", "<ts>_<db>.dump"} {
+		filename, err := resolveDumpFilename(template, "immich", clock)
+		if err != nil {
+			t.Fatalf("expected no error for %q, got: %v", template, err)
+		}
+		if !isDatedArchive(filename) {
+			t.Errorf("expected the dump %q written with %q to be prunable", filename, template)
+		}
+	}
+}
+
+func TestLocalArchivePruner_Prune_RemovesOnlyExpiredArchives(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var removed []string
+	pruner := &LocalArchivePruner{
+		files: &mockFilesHandler{
+			listFiles: func(path string) ([]os.FileInfo, error) {
+				return []os.FileInfo{
+					// Expired
+					&mockFileInfo{name: "immich-2025-01-01_03-00-00.sql", modTime: now.AddDate(0, 0, -59)},
+					&mockFileInfo{name: "immich-2025-02-21_03-00-00.sql", modTime: now.AddDate(0, 0, -8)},
+					// Within the retention period
+					&mockFileInfo{name: "immich-2025-02-23_03-00-00.sql", modTime: now.AddDate(0, 0, -6)},
+					&mockFileInfo{name: "immich-2025-03-01_03-00-00.sql", modTime: now.Add(-9 * time.Hour)},
+					// Old, but not a dated archive
+					&mockFileInfo{name: "immich.sql", modTime: now.AddDate(-1, 0, 0)},
+					&mockFileInfo{name: "README.txt", modTime: now.AddDate(-1, 0, 0)},
+				}, nil
+			},
+			removeFile: func(path string) error {
+				removed = append(removed, path)
+				return nil
+			},
+		},
+		time:          &mockTime{now: func() time.Time { return now }},
+		retentionDays: 7,
+	}
+
+	err := pruner.Prune([]string{"/mnt/disk/dumps"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := []string{
+		"/mnt/disk/dumps/immich-2025-01-01_03-00-00.sql",
+		"/mnt/disk/dumps/immich-2025-02-21_03-00-00.sql",
+	}
+	if diff := cmp.Diff(expected, removed); diff != "" {
+		t.Errorf("removed files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalArchivePruner_Prune_ContinuesAfterErrors(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	listErr := errors.New("permission denied")
+	var listedDirs []string
+	pruner := &LocalArchivePruner{
+		files: &mockFilesHandler{
+			listFiles: func(path string) ([]os.FileInfo, error) {
+				listedDirs = append(listedDirs, path)
+				if path == "/unreadable" {
+					return nil, listErr
+				}
+				return []os.FileInfo{
+					&mockFileInfo{name: "firefly-2025-01-01_03-00-00.sql", modTime: now.AddDate(0, 0, -59)},
+				}, nil
+			},
+		},
+		time:          &mockTime{now: func() time.Time { return now }},
+		retentionDays: 7,
+	}
+
+	err := pruner.Prune([]string{"/unreadable", "/mnt/disk/dumps"})
+
+	if !errors.Is(err, listErr) {
+		t.Errorf("expected error to wrap %v, got: %v", listErr, err)
+	}
+	if diff := cmp.Diff([]string{"/unreadable", "/mnt/disk/dumps"}, listedDirs); diff != "" {
+		t.Errorf("listed directories mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -1,7 +1,16 @@
 package backup
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
@@ -11,20 +20,52 @@ import (
 type ResticClient interface {
 	// Init initializes a new restic repository if it doesn't exist
 	Init() error
-	// Backup creates a new backup snapshot
-	Backup(path string, tags []string) error
+	// Backup creates a new backup snapshot, and returns the summary that restic prints when it finishes
+	Backup(path string, tags []string) (BackupSummary, error)
 	// Forget removes snapshots according to retention policy
 	Forget(keepWithin string, prune bool) error
 	// Check verifies repository integrity
 	Check() error
-	// Snapshots lists all snapshots
-	Snapshots() error
+	// Snapshots lists all snapshots. If groupBy is not empty, the snapshots are grouped by those keys. If latest is
+	// greater than zero, only the latest snapshots of each host and path are listed
+	Snapshots(groupBy []string, latest int) error
+	// SnapshotsJSON returns the list of all snapshots in restic's JSON format
+	SnapshotsJSON() ([]byte, error)
 	// ListFiles lists files in a specific snapshot
 	ListFiles(snapshotID string) error
-	// Restore restores the latest snapshot to a target directory
-	Restore(targetDir string) error
+	// Restore restores the latest snapshot to a target directory. When dryRun is true, restic only reports what
+	// would be restored without writing anything
+	Restore(targetDir string, dryRun bool) error
+	// ChangePassword changes the password of the current repository key to the content of newPasswordFile
+	ChangePassword(newPasswordFile string) error
+	// Copy copies the snapshots of the repository into the secondary repository
+	Copy() error
+	// Unlock removes the stale locks left in the repository by restic commands that did not exit cleanly
+	Unlock() error
 }
 
+var (
+	ErrResticCommandFailed = errors.New("restic command failed")
+	ErrRepositoryLocked    = errors.New("repository is already locked")
+	ErrResticNotFound      = errors.New("restic binary not found")
+)
+
+// RequireResticBinary checks that the restic binary of config, which may have been overridden with
+// HOMELAB_RESTIC_BINARY, is a name found on PATH or the path of an executable
+func RequireResticBinary(commands system.Commands, config ResticConfig) error {
+	binary := config.ResticBinary
+	if binary == "" {
+		binary = defaultResticBinary
+	}
+	if _, err := commands.LookPath(binary); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrResticNotFound, binary, err)
+	}
+	return nil
+}
+
+// resticExitCodeLocked is the exit code of restic when it fails to lock the repository
+const resticExitCodeLocked = 11
+
 // ResticConfig holds the configuration for restic operations
 type ResticConfig struct {
 	RepositoryURL    string
@@ -32,45 +73,159 @@ type ResticConfig struct {
 	B2ApplicationKey string
 	ResticPassword   string
 	BackupPath       string
-	RetentionDays    int
+	// Retention is how long snapshots are kept for, as a duration of restic forget --keep-within, such as "30d" or "6m"
+	Retention string
+	// KeepLast is the number of most recent snapshots that are kept regardless of their age, in addition to the ones
+	// within Retention. No snapshots are kept because of their number when it is zero
+	KeepLast int
+	// ReadConcurrency is the number of files that restic backup reads at the same time. Restic's default is used when
+	// it is zero
+	ReadConcurrency int
+	// PackSize is the target size of the pack files that restic backup creates, in MiB. Restic's default is used when it
+	// is zero
+	PackSize int
+	// OneFileSystem stops restic from crossing filesystem boundaries while backing up BackupPath. For example, to
+	// avoid backing up network shares that are mounted under it
+	OneFileSystem bool
+	// TagConfigHash adds the hash of the content of the loaded .env files as a tag to the snapshots of full backups, so
+	// that they can be traced back to the configuration that produced them
+	TagConfigHash bool
+	// ResticBinary is the name or path of the restic executable. Defaults to "restic" when empty
+	ResticBinary string
+	// RestoreOwner is the "UID:GID" (or "UID") that the restored files are given, so that they belong to the user of the
+	// container. The owner is not changed when it is empty
+	RestoreOwner string
+	// RestoreMode is the octal (e.g. "750") or symbolic (e.g. "u=rwX,g=rX,o=") mode that the restored files are given.
+	// The mode is not changed when it is empty
+	RestoreMode string
+	// TagPrefix is the prefix of the tag of the snapshots created by full backups, which is followed by a timestamp. A
+	// host-specific prefix tells apart the snapshots of hosts that share a repository. Defaults to "automatic-" when empty
+	TagPrefix string
+	// CopyRepositoryURL is the secondary repository that the snapshots are copied into, for redundancy. Both
+	// repositories share the B2 credentials. There is no secondary repository when it is empty
+	CopyRepositoryURL string
+	// CopyResticPassword is the password of the secondary repository
+	CopyResticPassword string
 }
 
 // DefaultResticClient is the default implementation of ResticClient
 type DefaultResticClient struct {
 	commands      system.Commands
 	textFormatter format.TextFormatter
-	config        ResticConfig
+	time          system.Time
+	// heartbeatInterval is how often a log tells that a restic command is still running. There are no heartbeats when
+	// it is zero
+	heartbeatInterval time.Duration
+	config            ResticConfig
+	// ctx interrupts the running restic command when it is done. Commands are not interrupted when it is nil
+	ctx context.Context
 }
 
+// defaultResticHeartbeatInterval is how often a log tells that a restic command is still running
+const defaultResticHeartbeatInterval = 30 * time.Second
+
 // NewDefaultResticClient creates a new restic client with the provided configuration
 func NewDefaultResticClient(config ResticConfig) *DefaultResticClient {
 	return &DefaultResticClient{
-		commands:      system.NewDefaultCommands(),
-		textFormatter: format.NewDefaultTextFormatter(),
-		config:        config,
+		commands:          system.NewDefaultCommands(),
+		textFormatter:     format.NewDefaultTextFormatter(),
+		time:              system.NewDefaultTime(),
+		heartbeatInterval: defaultResticHeartbeatInterval,
+		config:            config,
 	}
 }
 
-// execRestic executes a restic command with the configured environment
-// It uses shell execution to properly set environment variables
+// WithContext makes the restic commands be interrupted when ctx is done, such as when the user presses Ctrl-C. restic
+// is sent an interrupt signal rather than killed, so that it can finish what it is writing and remove its lock
+func (r *DefaultResticClient) WithContext(ctx context.Context) *DefaultResticClient {
+	r.ctx = ctx
+	return r
+}
+
+// execRestic executes a restic command with the configured environment. Its output is written to the console
 func (r *DefaultResticClient) execRestic(args ...string) error {
-	// Build the command with environment variables
-	// We need to properly escape the values to prevent shell injection
-	envVars := fmt.Sprintf(
-		"RESTIC_REPOSITORY=%s B2_ACCOUNT_ID=%s B2_ACCOUNT_KEY=%s RESTIC_PASSWORD=%s",
-		r.textFormatter.QuoteForPOSIXShell(r.config.RepositoryURL),
-		r.textFormatter.QuoteForPOSIXShell(r.config.B2KeyID),
-		r.textFormatter.QuoteForPOSIXShell(r.config.B2ApplicationKey),
-		r.textFormatter.QuoteForPOSIXShell(r.config.ResticPassword),
-	)
+	return r.execCommand(r.newCommand().WithArgs(args...))
+}
+
+// execResticWithStdout is like execRestic, but the standard output of the command is written into stdout
+func (r *DefaultResticClient) execResticWithStdout(stdout io.Writer, args ...string) error {
+	cmd := r.newCommand().WithArgs(args...)
+	if r.ctx != nil {
+		return r.runRestic(r.commands.ExecShellCommandContextWithStdout(r.ctx, cmd.String(), stdout), cmd.Name())
+	}
+	return r.runRestic(r.commands.ExecShellCommandWithStdout(cmd.String(), stdout), cmd.Name())
+}
 
-	cmdStr := envVars + " restic"
-	for _, arg := range args {
-		cmdStr += " " + arg
+// execCommand executes a restic command built by newCommand or newCopyCommand. Its output is written to the console
+func (r *DefaultResticClient) execCommand(cmd *resticCommand) error {
+	if r.ctx != nil {
+		return r.runRestic(r.commands.ExecShellCommandContext(r.ctx, cmd.String()), cmd.Name())
 	}
+	return r.runRestic(r.commands.ExecShellCommand(cmd.String()), cmd.Name())
+}
+
+// newCommand starts a restic command with the configured environment. It uses shell execution to properly set
+// environment variables, whose values are quoted to prevent shell injection
+func (r *DefaultResticClient) newCommand() *resticCommand {
+	return newResticCommand(r.textFormatter, r.config.ResticBinary).
+		WithEnv("RESTIC_REPOSITORY", r.config.RepositoryURL).
+		WithEnv("B2_ACCOUNT_ID", r.config.B2KeyID).
+		WithEnv("B2_ACCOUNT_KEY", r.config.B2ApplicationKey).
+		WithEnv("RESTIC_PASSWORD", r.config.ResticPassword)
+}
 
-	cmd := r.commands.ExecShellCommand(cmdStr)
-	return cmd.Run()
+// newCopyCommand starts a restic command with the environment of both repositories. restic copy reads the destination
+// repository from RESTIC_REPOSITORY and the source repository from RESTIC_FROM_REPOSITORY, so the secondary
+// repository is the destination and the configured repository is the source
+func (r *DefaultResticClient) newCopyCommand() *resticCommand {
+	return newResticCommand(r.textFormatter, r.config.ResticBinary).
+		WithEnv("RESTIC_REPOSITORY", r.config.CopyRepositoryURL).
+		WithEnv("B2_ACCOUNT_ID", r.config.B2KeyID).
+		WithEnv("B2_ACCOUNT_KEY", r.config.B2ApplicationKey).
+		WithEnv("RESTIC_PASSWORD", r.config.CopyResticPassword).
+		WithEnv("RESTIC_FROM_REPOSITORY", r.config.RepositoryURL).
+		WithEnv("RESTIC_FROM_PASSWORD", r.config.ResticPassword)
+}
+
+// runRestic runs a restic command, logging heartbeats while it runs
+func (r *DefaultResticClient) runRestic(cmd system.RunnableCommand, command string) error {
+	if r.heartbeatInterval > 0 {
+		stopHeartbeat := r.startHeartbeat(command)
+		defer stopHeartbeat()
+	}
+
+	if err := cmd.Run(); err != nil {
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == resticExitCodeLocked {
+			return fmt.Errorf("%w: %w: %w", ErrResticCommandFailed, ErrRepositoryLocked, err)
+		}
+		return fmt.Errorf("%w: %w", ErrResticCommandFailed, err)
+	}
+	return nil
+}
+
+// startHeartbeat logs every heartbeatInterval that the restic command is still running, so that long operations
+// without output don't look stuck. The returned function stops the heartbeat, and returns once it has stopped
+func (r *DefaultResticClient) startHeartbeat(command string) (stop func()) {
+	startTime := r.time.Now()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			case <-r.time.After(r.heartbeatInterval):
+				elapsed := r.time.Now().Sub(startTime).Round(time.Second)
+				slog.Info("restic command still running", "command", command, "elapsed", elapsed)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
 }
 
 // Init initializes a new restic repository if it doesn't exist
@@ -87,17 +242,142 @@ func (r *DefaultResticClient) Init() error {
 }
 
 // Backup creates a new backup snapshot
-func (r *DefaultResticClient) Backup(path string, tags []string) error {
-	args := []string{"backup", path, "--verbose"}
+func (r *DefaultResticClient) Backup(path string, tags []string) (BackupSummary, error) {
+	// The output is read as JSON so that the summary can be parsed from it. restic still writes its errors to the
+	// standard error
+	args := []string{"backup", path, "--json"}
+	if r.config.OneFileSystem {
+		args = append(args, "--one-file-system")
+	}
+	if r.config.ReadConcurrency > 0 {
+		args = append(args, "--read-concurrency", strconv.Itoa(r.config.ReadConcurrency))
+	}
+	if r.config.PackSize > 0 {
+		args = append(args, "--pack-size", strconv.Itoa(r.config.PackSize))
+	}
 	for _, tag := range tags {
 		args = append(args, "--tag", tag)
 	}
-	return r.execRestic(args...)
+
+	// The output is read as restic writes it, so that the progress is logged while the backup runs
+	output := &backupOutput{}
+	err := r.execResticWithStdout(output, args...)
+	output.Flush()
+	if err != nil {
+		return BackupSummary{}, err
+	}
+	summary, found := output.Summary()
+	if !found {
+		slog.Warn("restic did not print the summary of the backup")
+		return BackupSummary{}, nil
+	}
+	slog.Info("restic backup summary", "snapshot", summary.SnapshotID, "filesNew", summary.FilesNew,
+		"filesChanged", summary.FilesChanged, "dataAdded", summary.DataAdded, "totalBytesProcessed", summary.TotalBytesProcessed)
+	return summary, nil
+}
+
+// BackupSummary is the summary message of "restic backup --json", which is printed when the backup finishes
+type BackupSummary struct {
+	MessageType  string `json:"message_type"`
+	FilesNew     int    `json:"files_new"`
+	FilesChanged int    `json:"files_changed"`
+	// DataAdded is the size of the new data added to the repository, before compression
+	DataAdded int64 `json:"data_added"`
+	// TotalBytesProcessed is the size of all the files that were backed up, including the unchanged ones
+	TotalBytesProcessed int64  `json:"total_bytes_processed"`
+	SnapshotID          string `json:"snapshot_id"`
+}
+
+// backupProgressLogStep is how many more percent of a backup must be done before its progress is logged again
+const backupProgressLogStep = 10
+
+// backupMessage is a message of "restic backup --json". Only the fields of the messages that are logged are read
+type backupMessage struct {
+	MessageType string  `json:"message_type"`
+	PercentDone float64 `json:"percent_done"`
+	FilesDone   int     `json:"files_done"`
+	TotalFiles  int     `json:"total_files"`
+	BytesDone   int64   `json:"bytes_done"`
+	TotalBytes  int64   `json:"total_bytes"`
+	Item        string  `json:"item"`
+	Error       struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// backupOutput reads the output of "restic backup --json" as it is written, where every line is a JSON message. It
+// logs the progress of the backup every backupProgressLogStep, and the files that could not be read, and keeps the
+// summary
+type backupOutput struct {
+	// partial is the last line written, until its end is written too
+	partial []byte
+	summary BackupSummary
+	found   bool
+	// nextPercent is the percentage of the backup that must be done before its progress is logged again
+	nextPercent int
+}
+
+func (o *backupOutput) Write(p []byte) (int, error) {
+	o.partial = append(o.partial, p...)
+	for {
+		end := bytes.IndexByte(o.partial, '\n')
+		if end == -1 {
+			return len(p), nil
+		}
+		o.readLine(o.partial[:end])
+		o.partial = o.partial[end+1:]
+	}
 }
 
-// Forget removes snapshots according to retention policy
+// Flush reads the last line of the output, if restic did not end it with a newline
+func (o *backupOutput) Flush() {
+	if len(o.partial) > 0 {
+		o.readLine(o.partial)
+		o.partial = nil
+	}
+}
+
+// Summary returns the summary of the backup. It returns false if restic did not print it
+func (o *backupOutput) Summary() (BackupSummary, bool) {
+	return o.summary, o.found
+}
+
+func (o *backupOutput) readLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	var message backupMessage
+	if err := json.Unmarshal(line, &message); err != nil {
+		// The lines that are not JSON messages are logged as they are, so that nothing restic prints is lost
+		slog.Info("restic backup output", "line", string(line))
+		return
+	}
+	switch message.MessageType {
+	case "status":
+		percent := int(message.PercentDone * 100)
+		if percent >= o.nextPercent {
+			slog.Info("restic backup progress", "percentDone", percent,
+				"filesDone", message.FilesDone, "totalFiles", message.TotalFiles,
+				"bytesDone", message.BytesDone, "totalBytes", message.TotalBytes)
+			o.nextPercent = percent - percent%backupProgressLogStep + backupProgressLogStep
+		}
+	case "error":
+		slog.Warn("restic could not back up an item", "item", message.Item, "error", message.Error.Message)
+	case "summary":
+		if err := json.Unmarshal(line, &o.summary); err == nil {
+			o.found = true
+		}
+	}
+}
+
+// Forget removes snapshots according to retention policy. A snapshot is kept if it is within keepWithin or, when
+// KeepLast is set, if it is one of the KeepLast most recent snapshots
 func (r *DefaultResticClient) Forget(keepWithin string, prune bool) error {
 	args := []string{"forget", "--keep-within", keepWithin}
+	if r.config.KeepLast > 0 {
+		args = append(args, "--keep-last", strconv.Itoa(r.config.KeepLast))
+	}
 	if prune {
 		args = append(args, "--prune")
 	}
@@ -109,9 +389,26 @@ func (r *DefaultResticClient) Check() error {
 	return r.execRestic("check")
 }
 
-// Snapshots lists all snapshots
-func (r *DefaultResticClient) Snapshots() error {
-	return r.execRestic("snapshots")
+// Snapshots lists all snapshots. If groupBy is not empty, the snapshots are grouped by those keys. If latest is
+// greater than zero, only the latest snapshots of each host and path are listed
+func (r *DefaultResticClient) Snapshots(groupBy []string, latest int) error {
+	args := []string{"snapshots"}
+	if len(groupBy) > 0 {
+		args = append(args, "--group-by", strings.Join(groupBy, ","))
+	}
+	if latest > 0 {
+		args = append(args, "--latest", strconv.Itoa(latest))
+	}
+	return r.execRestic(args...)
+}
+
+// SnapshotsJSON returns the list of all snapshots in restic's JSON format
+func (r *DefaultResticClient) SnapshotsJSON() ([]byte, error) {
+	var stdout bytes.Buffer
+	if err := r.execResticWithStdout(&stdout, "snapshots", "--json"); err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
 }
 
 // ListFiles lists files in a specific snapshot
@@ -120,6 +417,28 @@ func (r *DefaultResticClient) ListFiles(snapshotID string) error {
 }
 
 // Restore restores the latest snapshot to a target directory
-func (r *DefaultResticClient) Restore(targetDir string) error {
-	return r.execRestic("restore", "latest", "--target", r.textFormatter.QuoteForPOSIXShell(targetDir), "--verbose")
+func (r *DefaultResticClient) Restore(targetDir string, dryRun bool) error {
+	cmd := r.newCommand().WithArgs("restore", "latest", "--target").WithQuotedArg(targetDir)
+	if dryRun {
+		cmd.WithArgs("--dry-run")
+	}
+	return r.execCommand(cmd.WithArgs("--verbose"))
+}
+
+// ChangePassword changes the password of the current repository key. The new password is read from a file, because
+// restic would otherwise prompt for it, and its commands don't read from the terminal
+func (r *DefaultResticClient) ChangePassword(newPasswordFile string) error {
+	return r.execCommand(r.newCommand().WithArgs("key", "passwd", "--new-password-file").WithQuotedArg(newPasswordFile))
+}
+
+// Copy copies the snapshots of the repository that are not in the secondary repository yet into it
+func (r *DefaultResticClient) Copy() error {
+	return r.execCommand(r.newCopyCommand().WithArgs("copy", "--verbose"))
+}
+
+// Unlock removes the stale locks of the repository. It is never interrupted by the context, because it is run to
+// clean up after the context is done
+func (r *DefaultResticClient) Unlock() error {
+	cmd := r.newCommand().WithArgs("unlock")
+	return r.runRestic(r.commands.ExecShellCommand(cmd.String()), cmd.Name())
 }
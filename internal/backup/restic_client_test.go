@@ -1,10 +1,20 @@
 package backup
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestDefaultResticClient_Init_RepositoryExists(t *testing.T) {
@@ -140,12 +150,12 @@ func TestDefaultResticClient_Backup_Success(t *testing.T) {
 		},
 	}
 
-	err := client.Backup("/data/backup", []string{"tag1", "tag2"})
+	_, err := client.Backup("/data/backup", []string{"tag1", "tag2"})
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
-	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic backup /data/backup --verbose --tag tag1 --tag tag2"
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic backup /data/backup --json --tag tag1 --tag tag2"
 	if executedCmd != expectedCmd {
 		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
 	}
@@ -169,17 +179,157 @@ func TestDefaultResticClient_Backup_Success_EmptyTags(t *testing.T) {
 		},
 	}
 
-	err := client.Backup("/data/backup", []string{})
+	_, err := client.Backup("/data/backup", []string{})
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
-	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic backup /data/backup --verbose"
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic backup /data/backup --json"
 	if executedCmd != expectedCmd {
 		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
 	}
 }
 
+func TestDefaultResticClient_Backup_OneFileSystem(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+			OneFileSystem:    true,
+		},
+	}
+
+	_, err := client.Backup("/data/backup", []string{"tag1"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic backup /data/backup --json --one-file-system --tag tag1"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
+func TestDefaultResticClient_Backup_Tuning(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+			ReadConcurrency:  8,
+			PackSize:         64,
+		},
+	}
+
+	_, err := client.Backup("/data/backup", []string{"tag1"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic backup /data/backup --json --read-concurrency 8 --pack-size 64 --tag tag1"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
+func TestDefaultResticClient_Backup_ParsesSummary(t *testing.T) {
+	output := `{"message_type":"status","percent_done":0.5,"total_files":3}
+{"message_type":"summary","files_new":2,"files_changed":1,"data_added":1536,"total_bytes_processed":1048576,"snapshot_id":"4f5c9a1b"}
+`
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+				return &mockRunnableCommand{
+					runFunc: func() error {
+						_, err := io.WriteString(stdout, output)
+						return err
+					},
+				}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+	}
+
+	summary, err := client.Backup("/data/backup", []string{"tag1"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := BackupSummary{
+		MessageType:         "summary",
+		FilesNew:            2,
+		FilesChanged:        1,
+		DataAdded:           1536,
+		TotalBytesProcessed: 1048576,
+		SnapshotID:          "4f5c9a1b",
+	}
+	if diff := cmp.Diff(expected, summary); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBackupOutput_NoSummary(t *testing.T) {
+	output := &backupOutput{}
+	io.WriteString(output, "{\"message_type\":\"status\"}\nnot json\n")
+	output.Flush()
+
+	if _, found := output.Summary(); found {
+		t.Error("expected no summary to be found")
+	}
+}
+
+func TestBackupOutput_SummarySplitAcrossWrites(t *testing.T) {
+	output := &backupOutput{}
+	io.WriteString(output, `{"message_type":"status","percent_done":0.25}`+"\n"+`{"message_type":"sum`)
+	io.WriteString(output, `mary","files_new":2,"snapshot_id":"4f5c9a1b"}`)
+	output.Flush()
+
+	summary, found := output.Summary()
+
+	if !found {
+		t.Fatal("expected the summary to be found")
+	}
+	if summary.FilesNew != 2 || summary.SnapshotID != "4f5c9a1b" {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestBackupOutput_LogsProgressEveryStep(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(previous)
+	output := &backupOutput{}
+
+	for _, percentDone := range []string{"0", "0.05", "0.12", "0.15", "0.5", "1"} {
+		io.WriteString(output, `{"message_type":"status","percent_done":`+percentDone+"}\n")
+	}
+
+	got := strings.Count(logs.String(), "restic backup progress")
+	if got != 4 {
+		t.Errorf("expected 4 progress logs, got %d:\n%s", got, logs.String())
+	}
+}
+
 func TestDefaultResticClient_Backup_Error(t *testing.T) {
 	expectedErr := errors.New("backup failed")
 	client := &DefaultResticClient{
@@ -201,7 +351,7 @@ func TestDefaultResticClient_Backup_Error(t *testing.T) {
 		},
 	}
 
-	err := client.Backup("/data/backup", []string{"tag1"})
+	_, err := client.Backup("/data/backup", []string{"tag1"})
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -209,6 +359,9 @@ func TestDefaultResticClient_Backup_Error(t *testing.T) {
 	if !errors.Is(err, expectedErr) {
 		t.Errorf("expected error to be %v, got: %v", expectedErr, err)
 	}
+	if !errors.Is(err, ErrResticCommandFailed) {
+		t.Errorf("expected ErrResticCommandFailed, got: %v", err)
+	}
 }
 
 func TestDefaultResticClient_Forget_Success_WithPrune(t *testing.T) {
@@ -269,6 +422,36 @@ func TestDefaultResticClient_Forget_Success_WithoutPrune(t *testing.T) {
 	}
 }
 
+func TestDefaultResticClient_Forget_WithKeepLast(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+			KeepLast:         10,
+		},
+	}
+
+	err := client.Forget("6m", true)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic forget --keep-within 6m --keep-last 10 --prune"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
 func TestDefaultResticClient_Check_Success(t *testing.T) {
 	var executedCmd string
 	client := &DefaultResticClient{
@@ -316,7 +499,7 @@ func TestDefaultResticClient_Snapshots_Success(t *testing.T) {
 		},
 	}
 
-	err := client.Snapshots()
+	err := client.Snapshots(nil, 0)
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
@@ -327,6 +510,64 @@ func TestDefaultResticClient_Snapshots_Success(t *testing.T) {
 	}
 }
 
+func TestDefaultResticClient_Snapshots_GroupBy(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+		},
+	}
+
+	err := client.Snapshots([]string{"host", "tags"}, 0)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic snapshots --group-by host,tags"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
+func TestDefaultResticClient_Snapshots_Latest(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+		},
+	}
+
+	err := client.Snapshots([]string{"host"}, 5)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic snapshots --group-by host --latest 5"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
 func TestDefaultResticClient_ListFiles_Success(t *testing.T) {
 	var executedCmd string
 	client := &DefaultResticClient{
@@ -356,6 +597,167 @@ func TestDefaultResticClient_ListFiles_Success(t *testing.T) {
 	}
 }
 
+func TestDefaultResticClient_ChangePassword_Success(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+		},
+	}
+
+	err := client.ChangePassword("/secrets/new-password.txt")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic key passwd --new-password-file '/secrets/new-password.txt'"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
+func TestDefaultResticClient_Copy_IncludesBothRepositories(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:      "b2:b:p",
+			B2KeyID:            "k1",
+			B2ApplicationKey:   "a2",
+			ResticPassword:     "p3",
+			CopyRepositoryURL:  "/mnt/disk2/restic",
+			CopyResticPassword: "p4",
+		},
+	}
+
+	err := client.Copy()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='/mnt/disk2/restic' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p4' " +
+		"RESTIC_FROM_REPOSITORY='b2:b:p' RESTIC_FROM_PASSWORD='p3' restic copy --verbose"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
+func TestDefaultResticClient_WithContext_InterruptsCommand(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := (&DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommandContext: func(ctx context.Context, cmd string) system.RunnableCommand {
+				// Like the interrupted restic, the command exits once the context is done
+				return &mockRunnableCommand{runFunc: func() error {
+					<-ctx.Done()
+					return ctx.Err()
+				}}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+	}).WithContext(ctx)
+	cancel()
+
+	_, err := client.Backup("/data/backup", []string{"tag1"})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the command to be interrupted, got: %v", err)
+	}
+}
+
+func TestDefaultResticClient_Unlock_IgnoresContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var executedCmd string
+	client := (&DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+			execShellCommandContext: func(ctx context.Context, cmd string) system.RunnableCommand {
+				t.Errorf("expected unlock not to be run with the context, got: %q", cmd)
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+		},
+	}).WithContext(ctx)
+
+	err := client.Unlock()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic unlock"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
+// exitCodeError is an error of a command that exited with a code, like exec.ExitError
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+func (e *exitCodeError) ExitCode() int { return e.code }
+
+func TestDefaultResticClient_Backup_ClassifiesLockError(t *testing.T) {
+	tests := []struct {
+		name           string
+		exitCode       int
+		expectedLocked bool
+	}{
+		{name: "locked", exitCode: 11, expectedLocked: true},
+		{name: "other failure", exitCode: 1, expectedLocked: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &DefaultResticClient{
+				commands: &mockCommands{
+					execShellCommand: func(cmd string) system.RunnableCommand {
+						return &mockRunnableCommand{runFunc: func() error {
+							return &exitCodeError{code: tt.exitCode}
+						}}
+					},
+				},
+				textFormatter: &mockTextFormatter{},
+			}
+
+			_, err := client.Backup("/data/backup", []string{"tag1"})
+
+			if !errors.Is(err, ErrResticCommandFailed) {
+				t.Fatalf("expected ErrResticCommandFailed, got: %v", err)
+			}
+			if errors.Is(err, ErrRepositoryLocked) != tt.expectedLocked {
+				t.Errorf("expected ErrRepositoryLocked to be %v, got: %v", tt.expectedLocked, err)
+			}
+		})
+	}
+}
+
 func TestDefaultResticClient_Restore_Success(t *testing.T) {
 	var executedCmd string
 	client := &DefaultResticClient{
@@ -374,7 +776,7 @@ func TestDefaultResticClient_Restore_Success(t *testing.T) {
 		},
 	}
 
-	err := client.Restore("/restore/path")
+	err := client.Restore("/restore/path", false)
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
@@ -384,3 +786,240 @@ func TestDefaultResticClient_Restore_Success(t *testing.T) {
 		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
 	}
 }
+
+func TestDefaultResticClient_Restore_DryRun(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+		},
+	}
+
+	err := client.Restore("/restore/path", true)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic restore latest --target '/restore/path' --dry-run --verbose"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
+func TestDefaultResticClient_ExecRestic_CustomBinary(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+			ResticBinary:     "/opt/restic/restic_0.16",
+		},
+	}
+
+	err := client.Check()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' /opt/restic/restic_0.16 check"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected last command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+}
+
+func TestDefaultResticClient_Heartbeat_FiresOnSchedule(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	startTime := time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+	ticks := make(chan time.Time)
+	var requestedIntervals []time.Duration
+	nowCalls := 0
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{
+					runFunc: func() error {
+						// Each tick is received by the heartbeat, so the command "runs" for three intervals
+						for i := 0; i < 3; i++ {
+							ticks <- time.Time{}
+						}
+						return nil
+					},
+				}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		time: &mockTime{
+			now: func() time.Time {
+				elapsed := time.Duration(nowCalls) * 30 * time.Second
+				nowCalls++
+				return startTime.Add(elapsed)
+			},
+			after: func(d time.Duration) <-chan time.Time {
+				requestedIntervals = append(requestedIntervals, d)
+				return ticks
+			},
+		},
+		heartbeatInterval: 30 * time.Second,
+		config:            ResticConfig{},
+	}
+
+	_, err := client.Backup("/data", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, elapsed := range []string{"30s", "1m0s", "1m30s"} {
+		expectedLog := "msg=\"restic command still running\" command=backup elapsed=" + elapsed
+		if !strings.Contains(logs.String(), expectedLog) {
+			t.Errorf("expected heartbeat %q, got logs:\n%s", expectedLog, logs.String())
+		}
+	}
+	if count := strings.Count(logs.String(), "still running"); count != 3 {
+		t.Errorf("expected 3 heartbeats, got %d", count)
+	}
+	for _, interval := range requestedIntervals {
+		if interval != 30*time.Second {
+			t.Errorf("expected heartbeats every %v, got %v", 30*time.Second, interval)
+		}
+	}
+}
+
+func TestDefaultResticClient_Heartbeat_DisabledWithoutInterval(t *testing.T) {
+	afterCalled := false
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		time: &mockTime{
+			after: func(d time.Duration) <-chan time.Time {
+				afterCalled = true
+				return nil
+			},
+		},
+		config: ResticConfig{},
+	}
+
+	err := client.Check()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if afterCalled {
+		t.Error("expected no heartbeat when the interval is zero")
+	}
+}
+
+func TestDefaultResticClient_SnapshotsJSON_ReturnsStdout(t *testing.T) {
+	var executedCmd string
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+				executedCmd = cmd
+				return &mockRunnableCommand{
+					runFunc: func() error {
+						_, err := io.WriteString(stdout, `[{"tags":["automatic-2025-01-01_03-00-00"]}]`)
+						return err
+					},
+				}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		config: ResticConfig{
+			RepositoryURL:    "b2:b:p",
+			B2KeyID:          "k1",
+			B2ApplicationKey: "a2",
+			ResticPassword:   "p3",
+		},
+	}
+
+	snapshotsJSON, err := client.SnapshotsJSON()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "RESTIC_REPOSITORY='b2:b:p' B2_ACCOUNT_ID='k1' B2_ACCOUNT_KEY='a2' RESTIC_PASSWORD='p3' restic snapshots --json"
+	if executedCmd != expectedCmd {
+		t.Errorf("expected command to be %q, got: %q", expectedCmd, executedCmd)
+	}
+	if string(snapshotsJSON) != `[{"tags":["automatic-2025-01-01_03-00-00"]}]` {
+		t.Errorf("expected the standard output of restic, got: %q", snapshotsJSON)
+	}
+}
+
+func TestDefaultResticClient_SnapshotsJSON_Error(t *testing.T) {
+	client := &DefaultResticClient{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{runFunc: func() error { return errors.New("exit status 1") }}
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+	}
+
+	_, err := client.SnapshotsJSON()
+
+	if !errors.Is(err, ErrResticCommandFailed) {
+		t.Errorf("expected ErrResticCommandFailed, got: %v", err)
+	}
+}
+
+// pathWithExecutables creates the executables names in a temporary directory and makes it the only directory of PATH
+func pathWithExecutables(t *testing.T, names ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatalf("failed to create executable %s: %v", name, err)
+		}
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestRequireResticBinary_OnlyCustomBinaryAvailable(t *testing.T) {
+	pathWithExecutables(t, "restic_0.16")
+
+	err := RequireResticBinary(system.NewDefaultCommands(), ResticConfig{ResticBinary: "restic_0.16"})
+
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestRequireResticBinary_DefaultBinaryMissing(t *testing.T) {
+	pathWithExecutables(t, "restic_0.16")
+
+	err := RequireResticBinary(system.NewDefaultCommands(), ResticConfig{})
+
+	if !errors.Is(err, ErrResticNotFound) {
+		t.Errorf("expected ErrResticNotFound, got: %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), `"restic"`) {
+		t.Errorf("expected the error to name the binary, got: %v", err)
+	}
+}
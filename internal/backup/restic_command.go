@@ -0,0 +1,68 @@
+package backup
+
+import (
+	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
+)
+
+// resticEnvVar is an environment variable that a restic command is run with
+type resticEnvVar struct {
+	name  string
+	value string
+}
+
+// resticCommand builds the shell command that runs restic, as "NAME='value' ... restic arg ...". The values of the
+// environment variables are always quoted for POSIX shells, because they hold credentials and URLs. Arguments are
+// added as they are with WithArgs, or quoted with WithQuotedArg when they come from the user, such as paths
+type resticCommand struct {
+	textFormatter format.TextFormatter
+	binary        string
+	env           []resticEnvVar
+	args          []string
+}
+
+// newResticCommand creates a command that runs binary, or "restic" when binary is empty
+func newResticCommand(textFormatter format.TextFormatter, binary string) *resticCommand {
+	if binary == "" {
+		binary = defaultResticBinary
+	}
+	return &resticCommand{textFormatter: textFormatter, binary: binary}
+}
+
+// WithEnv adds an environment variable to the command. Variables are written in the order they are added
+func (c *resticCommand) WithEnv(name string, value string) *resticCommand {
+	c.env = append(c.env, resticEnvVar{name: name, value: value})
+	return c
+}
+
+// WithArgs adds arguments to the command without quoting them
+func (c *resticCommand) WithArgs(args ...string) *resticCommand {
+	c.args = append(c.args, args...)
+	return c
+}
+
+// WithQuotedArg adds an argument to the command, quoted for POSIX shells
+func (c *resticCommand) WithQuotedArg(arg string) *resticCommand {
+	c.args = append(c.args, c.textFormatter.QuoteForPOSIXShell(arg))
+	return c
+}
+
+// Name returns the restic command being run, which is its first argument, such as "backup" or "snapshots"
+func (c *resticCommand) Name() string {
+	if len(c.args) == 0 {
+		return ""
+	}
+	return c.args[0]
+}
+
+// String returns the shell command
+func (c *resticCommand) String() string {
+	parts := make([]string, 0, len(c.env)+len(c.args)+1)
+	for _, envVar := range c.env {
+		parts = append(parts, envVar.name+"="+c.textFormatter.QuoteForPOSIXShell(envVar.value))
+	}
+	parts = append(parts, c.binary)
+	parts = append(parts, c.args...)
+	return strings.Join(parts, " ")
+}
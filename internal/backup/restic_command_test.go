@@ -0,0 +1,43 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
+)
+
+func TestResticCommand_String_QuotesEnvValues(t *testing.T) {
+	cmd := newResticCommand(format.NewDefaultTextFormatter(), "").
+		WithEnv("RESTIC_REPOSITORY", "b2:bucket:path with spaces").
+		WithEnv("RESTIC_PASSWORD", `it's $secret "quoted"`).
+		WithEnv("B2_ACCOUNT_KEY", "").
+		WithArgs("snapshots", "--json")
+
+	expected := `RESTIC_REPOSITORY='b2:bucket:path with spaces' RESTIC_PASSWORD='it'"'"'s $secret "quoted"' B2_ACCOUNT_KEY='' restic snapshots --json`
+	if cmd.String() != expected {
+		t.Errorf("expected %q, got %q", expected, cmd.String())
+	}
+}
+
+func TestResticCommand_WithQuotedArg(t *testing.T) {
+	cmd := newResticCommand(format.NewDefaultTextFormatter(), "/opt/restic_0.16").
+		WithArgs("restore", "latest", "--target").
+		WithQuotedArg("/restore/it's here; rm -rf /").
+		WithArgs("--verbose")
+
+	expected := `/opt/restic_0.16 restore latest --target '/restore/it'"'"'s here; rm -rf /' --verbose`
+	if cmd.String() != expected {
+		t.Errorf("expected %q, got %q", expected, cmd.String())
+	}
+}
+
+func TestResticCommand_Name(t *testing.T) {
+	formatter := format.NewDefaultTextFormatter()
+
+	if name := newResticCommand(formatter, "").WithArgs("key", "passwd").Name(); name != "key" {
+		t.Errorf("expected %q, got %q", "key", name)
+	}
+	if name := newResticCommand(formatter, "").Name(); name != "" {
+		t.Errorf("expected an empty name without arguments, got %q", name)
+	}
+}
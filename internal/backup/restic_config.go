@@ -0,0 +1,252 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+var (
+	ErrInvalidResticConfig = errors.New("invalid restic configuration")
+	ErrInvalidRetention    = errors.New("invalid retention")
+)
+
+// resticEnvPrefix is the prefix of all the environment variables that configure restic
+const resticEnvPrefix = "HOMELAB_BACKUP"
+
+// resticBinaryEnvVar is the environment variable that overrides the name or path of the restic binary. It is shared
+// by all backup profiles
+const resticBinaryEnvVar = "HOMELAB_RESTIC_BINARY"
+
+// defaultResticBinary is the restic binary used when HOMELAB_RESTIC_BINARY is not set
+const defaultResticBinary = "restic"
+
+// defaultRetention is how long snapshots are kept for when RETENTION_DAYS is not set
+const defaultRetention = "30d"
+
+// retentionPattern matches a retention with a unit: days, weeks, months or years
+var retentionPattern = regexp.MustCompile(`^(\d+)([dwmy])$`)
+
+// tagPrefixPattern matches the prefixes that can be used in restic tags, which are separated by commas
+var tagPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// restoreOwnerPattern matches a numeric "UID:GID" or "UID", as accepted by chown
+var restoreOwnerPattern = regexp.MustCompile(`^\d+(:\d+)?$`)
+
+// restoreModePattern matches an octal mode, or a comma-separated list of symbolic modes, as accepted by chmod
+var restoreModePattern = regexp.MustCompile(`^([0-7]{3,4}|[ugoa]*[-+=][rwxXst]*(,[ugoa]*[-+=][rwxXst]*)*)$`)
+
+// resticEnvVarName builds the name of a restic environment variable for a profile. The unnamed profile ("") reads
+// HOMELAB_BACKUP_<NAME>, while a named profile reads HOMELAB_BACKUP_<PROFILE>_<NAME>
+func resticEnvVarName(profile string, name string) string {
+	if profile == "" {
+		return fmt.Sprintf("%s_%s", resticEnvPrefix, name)
+	}
+	return fmt.Sprintf("%s_%s_%s", resticEnvPrefix, strings.ToUpper(profile), name)
+}
+
+// BackupPathEnvVar returns the name of the environment variable with the path that a profile backs up to the cloud
+func BackupPathEnvVar(profile string) string {
+	return resticEnvVarName(profile, "PATH")
+}
+
+// SecretPrompter asks the user for a value without showing it on the screen, such as a password
+type SecretPrompter interface {
+	PromptSecret(message string) (string, error)
+}
+
+// WithPromptedResticPassword returns env if the restic password of the profile is configured. Otherwise, the password
+// is asked with prompter, and env is returned with the password added. The password is only kept in memory, for the
+// current command, and is never written into the .env file
+func WithPromptedResticPassword(env system.Env, profile string, prompter SecretPrompter) (system.Env, error) {
+	varName := resticEnvVarName(profile, "RESTIC_PASSWORD")
+	if value, exists := env.GetEnv(varName); exists && strings.TrimSpace(value) != "" {
+		return env, nil
+	}
+
+	password, err := prompter.PromptSecret(fmt.Sprintf("%s is not set. Enter the restic repository password: ", varName))
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, fmt.Errorf("%w: %q", system.ErrRequiredEnvNotFound, varName)
+	}
+	return &promptedEnv{Env: env, varName: varName, value: password}, nil
+}
+
+// promptedEnv is an environment with a variable whose value was asked to the user
+type promptedEnv struct {
+	system.Env
+	varName string
+	value   string
+}
+
+func (e *promptedEnv) GetEnv(varName string) (string, bool) {
+	if varName == e.varName {
+		return e.value, true
+	}
+	return e.Env.GetEnv(varName)
+}
+
+func (e *promptedEnv) GetRequiredEnv(varName string) (string, error) {
+	if varName == e.varName {
+		return e.value, nil
+	}
+	return e.Env.GetRequiredEnv(varName)
+}
+
+// ParseRetention parses how long snapshots are kept for, and returns it as a duration for restic forget --keep-within.
+// The value is a number followed by a unit: "d" for days, "w" for weeks, "m" for months or "y" for years, such as "4w"
+// or "6m". A number without unit is a number of days, so "30" is "30d". restic does not understand weeks, so they are
+// converted into days
+func ParseRetention(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	number, unit := value, "d"
+	if matches := retentionPattern.FindStringSubmatch(value); matches != nil {
+		number, unit = matches[1], matches[2]
+	}
+	amount, err := strconv.Atoi(number)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: must be a number followed by d, w, m or y, such as 30d or 6m", ErrInvalidRetention, value)
+	}
+	if amount < 1 {
+		return "", fmt.Errorf("%w %q: must be at least 1", ErrInvalidRetention, value)
+	}
+	if unit == "w" {
+		amount, unit = amount*7, "d"
+	}
+	return fmt.Sprintf("%d%s", amount, unit), nil
+}
+
+// LoadResticConfig loads the restic configuration of a backup profile from environment variables.
+// An empty profile loads the default, unnamed profile
+func LoadResticConfig(env system.Env, profile string) (ResticConfig, error) {
+	repositoryURL, err := env.GetRequiredEnv(resticEnvVarName(profile, "RESTIC_REPOSITORY"))
+	if err != nil {
+		return ResticConfig{}, err
+	}
+
+	b2KeyID, err := env.GetRequiredEnv(resticEnvVarName(profile, "B2_KEY_ID"))
+	if err != nil {
+		return ResticConfig{}, err
+	}
+
+	b2ApplicationKey, err := env.GetRequiredEnv(resticEnvVarName(profile, "B2_APPLICATION_KEY"))
+	if err != nil {
+		return ResticConfig{}, err
+	}
+
+	resticPassword, err := env.GetRequiredEnv(resticEnvVarName(profile, "RESTIC_PASSWORD"))
+	if err != nil {
+		return ResticConfig{}, err
+	}
+
+	backupPath, err := env.GetRequiredEnv(resticEnvVarName(profile, "PATH"))
+	if err != nil {
+		return ResticConfig{}, err
+	}
+
+	retentionVarName := resticEnvVarName(profile, "RETENTION_DAYS")
+	retention := defaultRetention
+	if value, exists := env.GetEnv(retentionVarName); exists {
+		retention, err = ParseRetention(value)
+		if err != nil {
+			return ResticConfig{}, fmt.Errorf("%w %q: %w", ErrInvalidResticConfig, retentionVarName, err)
+		}
+	} else {
+		slog.Info("Retention not set, using the default", "varName", retentionVarName, "retention", defaultRetention)
+	}
+
+	keepLast, err := getPositiveIntEnv(env, resticEnvVarName(profile, "KEEP_LAST"))
+	if err != nil {
+		return ResticConfig{}, err
+	}
+	readConcurrency, err := getPositiveIntEnv(env, resticEnvVarName(profile, "READ_CONCURRENCY"))
+	if err != nil {
+		return ResticConfig{}, err
+	}
+	packSize, err := getPositiveIntEnv(env, resticEnvVarName(profile, "PACK_SIZE"))
+	if err != nil {
+		return ResticConfig{}, err
+	}
+
+	// Invalid values are ignored by GetBoolEnv, so that restic crosses filesystem boundaries as it does by default
+	oneFileSystem, _ := env.GetBoolEnv(resticEnvVarName(profile, "ONE_FILE_SYSTEM"))
+
+	tagConfigHash, _ := env.GetBoolEnv(resticEnvVarName(profile, "TAG_CONFIG_HASH"))
+
+	resticBinary := defaultResticBinary
+	if value, exists := env.GetEnv(resticBinaryEnvVar); exists {
+		resticBinary = strings.TrimSpace(value)
+		if resticBinary == "" {
+			return ResticConfig{}, fmt.Errorf("%w: %q must not be empty", ErrInvalidResticConfig, resticBinaryEnvVar)
+		}
+	}
+
+	restoreOwnerVarName := resticEnvVarName(profile, "RESTORE_OWNER")
+	restoreOwner, _ := env.GetEnv(restoreOwnerVarName)
+	restoreOwner = strings.TrimSpace(restoreOwner)
+	if restoreOwner != "" && !restoreOwnerPattern.MatchString(restoreOwner) {
+		return ResticConfig{}, fmt.Errorf("%w: %q must be a numeric UID:GID, got %q", ErrInvalidResticConfig, restoreOwnerVarName, restoreOwner)
+	}
+
+	restoreModeVarName := resticEnvVarName(profile, "RESTORE_MODE")
+	restoreMode, _ := env.GetEnv(restoreModeVarName)
+	restoreMode = strings.TrimSpace(restoreMode)
+	if restoreMode != "" && !restoreModePattern.MatchString(restoreMode) {
+		return ResticConfig{}, fmt.Errorf("%w: %q must be a chmod mode, got %q", ErrInvalidResticConfig, restoreModeVarName, restoreMode)
+	}
+
+	tagPrefixVarName := resticEnvVarName(profile, "TAG_PREFIX")
+	tagPrefix, _ := env.GetEnv(tagPrefixVarName)
+	tagPrefix = strings.TrimSpace(tagPrefix)
+	if tagPrefix != "" && !tagPrefixPattern.MatchString(tagPrefix) {
+		return ResticConfig{}, fmt.Errorf("%w: %q must only contain letters, digits, \".\", \"_\" and \"-\", got %q", ErrInvalidResticConfig, tagPrefixVarName, tagPrefix)
+	}
+
+	copyRepositoryVarName := resticEnvVarName(profile, "COPY_RESTIC_REPOSITORY")
+	copyPasswordVarName := resticEnvVarName(profile, "COPY_RESTIC_PASSWORD")
+	copyRepositoryURL, copyRepositoryExists := env.GetEnv(copyRepositoryVarName)
+	copyResticPassword, copyPasswordExists := env.GetEnv(copyPasswordVarName)
+	if copyRepositoryExists != copyPasswordExists {
+		return ResticConfig{}, fmt.Errorf("%w: %q and %q must be set together", ErrInvalidResticConfig, copyRepositoryVarName, copyPasswordVarName)
+	}
+
+	return ResticConfig{
+		RepositoryURL:      repositoryURL,
+		B2KeyID:            b2KeyID,
+		B2ApplicationKey:   b2ApplicationKey,
+		ResticPassword:     resticPassword,
+		BackupPath:         backupPath,
+		Retention:          retention,
+		KeepLast:           keepLast,
+		ReadConcurrency:    readConcurrency,
+		PackSize:           packSize,
+		OneFileSystem:      oneFileSystem,
+		TagConfigHash:      tagConfigHash,
+		ResticBinary:       resticBinary,
+		RestoreOwner:       restoreOwner,
+		RestoreMode:        restoreMode,
+		TagPrefix:          tagPrefix,
+		CopyRepositoryURL:  copyRepositoryURL,
+		CopyResticPassword: copyResticPassword,
+	}, nil
+}
+
+// getPositiveIntEnv returns the value of an optional integer variable, which must be at least 1. It returns zero when
+// the variable is not set
+func getPositiveIntEnv(env system.Env, varName string) (int, error) {
+	value, exists, err := env.GetIntEnv(varName)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidResticConfig, err)
+	}
+	if exists && value < 1 {
+		return 0, fmt.Errorf("%w: %q must be at least 1, got %d", ErrInvalidResticConfig, varName, value)
+	}
+	return value, nil
+}
@@ -0,0 +1,706 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadResticConfig_DefaultProfile(t *testing.T) {
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+		"HOMELAB_BACKUP_RETENTION_DAYS":     "30",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := ResticConfig{
+		RepositoryURL:    "b2:bucket:path",
+		B2KeyID:          "key-id",
+		B2ApplicationKey: "app-key",
+		ResticPassword:   "password",
+		BackupPath:       "/data/backup",
+		Retention:        "30d",
+		ResticBinary:     "restic",
+	}
+	if diff := cmp.Diff(expected, config); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadResticConfig_NamedProfileReadsPrefixedVars(t *testing.T) {
+	vars := map[string]string{
+		// The default profile's vars must be ignored when a named profile is requested
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":         "b2:default:path",
+		"HOMELAB_BACKUP_PHOTOS_RESTIC_REPOSITORY":  "b2:photos:path",
+		"HOMELAB_BACKUP_PHOTOS_B2_KEY_ID":          "photos-key-id",
+		"HOMELAB_BACKUP_PHOTOS_B2_APPLICATION_KEY": "photos-app-key",
+		"HOMELAB_BACKUP_PHOTOS_RESTIC_PASSWORD":    "photos-password",
+		"HOMELAB_BACKUP_PHOTOS_PATH":               "/data/photos",
+		"HOMELAB_BACKUP_PHOTOS_RETENTION_DAYS":     "90",
+	}
+	var requestedVars []string
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			requestedVars = append(requestedVars, varName)
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "photos")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedVars := []string{
+		"HOMELAB_BACKUP_PHOTOS_RESTIC_REPOSITORY",
+		"HOMELAB_BACKUP_PHOTOS_B2_KEY_ID",
+		"HOMELAB_BACKUP_PHOTOS_B2_APPLICATION_KEY",
+		"HOMELAB_BACKUP_PHOTOS_RESTIC_PASSWORD",
+		"HOMELAB_BACKUP_PHOTOS_PATH",
+		"HOMELAB_BACKUP_PHOTOS_RETENTION_DAYS",
+		"HOMELAB_BACKUP_PHOTOS_KEEP_LAST",
+		"HOMELAB_BACKUP_PHOTOS_READ_CONCURRENCY",
+		"HOMELAB_BACKUP_PHOTOS_PACK_SIZE",
+		"HOMELAB_BACKUP_PHOTOS_ONE_FILE_SYSTEM",
+		"HOMELAB_BACKUP_PHOTOS_TAG_CONFIG_HASH",
+		// The restic binary is shared by all profiles
+		"HOMELAB_RESTIC_BINARY",
+		"HOMELAB_BACKUP_PHOTOS_RESTORE_OWNER",
+		"HOMELAB_BACKUP_PHOTOS_RESTORE_MODE",
+		"HOMELAB_BACKUP_PHOTOS_TAG_PREFIX",
+		"HOMELAB_BACKUP_PHOTOS_COPY_RESTIC_REPOSITORY",
+		"HOMELAB_BACKUP_PHOTOS_COPY_RESTIC_PASSWORD",
+	}
+	if diff := cmp.Diff(expectedVars, requestedVars); diff != "" {
+		t.Errorf("requested vars mismatch (-want +got):\n%s", diff)
+	}
+	expected := ResticConfig{
+		RepositoryURL:    "b2:photos:path",
+		B2KeyID:          "photos-key-id",
+		B2ApplicationKey: "photos-app-key",
+		ResticPassword:   "photos-password",
+		BackupPath:       "/data/photos",
+		Retention:        "90d",
+		ResticBinary:     "restic",
+	}
+	if diff := cmp.Diff(expected, config); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadResticConfig_MissingVar(t *testing.T) {
+	env := &mockEnv{}
+
+	_, err := LoadResticConfig(env, "photos")
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, system.ErrRequiredEnvNotFound) {
+		t.Errorf("expected ErrRequiredEnvNotFound, got: %v", err)
+	}
+}
+
+func TestLoadResticConfig_InvalidRetentionDays(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			if varName == "HOMELAB_BACKUP_RETENTION_DAYS" {
+				return "thirty", true
+			}
+			return "value", true
+		},
+	}
+
+	_, err := LoadResticConfig(env, "")
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrInvalidResticConfig) {
+		t.Errorf("expected ErrInvalidResticConfig, got: %v", err)
+	}
+}
+
+func TestLoadResticConfig_NonPositiveRetentionDays(t *testing.T) {
+	for _, retentionDays := range []string{"0", "-5"} {
+		t.Run(retentionDays, func(t *testing.T) {
+			env := &mockEnv{
+				getEnvFunc: func(varName string) (string, bool) {
+					if varName == "HOMELAB_BACKUP_RETENTION_DAYS" {
+						return retentionDays, true
+					}
+					return "value", true
+				},
+			}
+
+			_, err := LoadResticConfig(env, "")
+
+			if !errors.Is(err, ErrInvalidResticConfig) {
+				t.Errorf("expected ErrInvalidResticConfig, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadResticConfig_RetentionWithUnit(t *testing.T) {
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+		"HOMELAB_BACKUP_RETENTION_DAYS":     "6m",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if config.Retention != "6m" {
+		t.Errorf("expected retention %q, got %q", "6m", config.Retention)
+	}
+}
+
+func TestLoadResticConfig_KeepLast(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    int
+		expectedErr bool
+	}{
+		{value: "5", expected: 5},
+		{value: "0", expectedErr: true},
+		{value: "five", expectedErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			vars := map[string]string{
+				"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+				"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+				"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+				"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+				"HOMELAB_BACKUP_PATH":               "/data/backup",
+				"HOMELAB_BACKUP_KEEP_LAST":          tt.value,
+			}
+			env := &mockEnv{
+				getEnvFunc: func(varName string) (string, bool) {
+					value, exists := vars[varName]
+					return value, exists
+				},
+			}
+
+			config, err := LoadResticConfig(env, "")
+
+			if tt.expectedErr {
+				if !errors.Is(err, ErrInvalidResticConfig) {
+					t.Errorf("expected ErrInvalidResticConfig, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if config.KeepLast != tt.expected {
+				t.Errorf("expected keep last %d, got %d", tt.expected, config.KeepLast)
+			}
+		})
+	}
+}
+
+func TestLoadResticConfig_BackupTuning(t *testing.T) {
+	tests := []struct {
+		name                    string
+		readConcurrency         string
+		packSize                string
+		expectedReadConcurrency int
+		expectedPackSize        int
+		expectedErr             bool
+	}{
+		{name: "both set", readConcurrency: "8", packSize: "64", expectedReadConcurrency: 8, expectedPackSize: 64},
+		{name: "zero read concurrency", readConcurrency: "0", packSize: "64", expectedErr: true},
+		{name: "negative pack size", readConcurrency: "8", packSize: "-16", expectedErr: true},
+		{name: "invalid pack size", readConcurrency: "8", packSize: "big", expectedErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := map[string]string{
+				"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+				"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+				"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+				"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+				"HOMELAB_BACKUP_PATH":               "/data/backup",
+				"HOMELAB_BACKUP_READ_CONCURRENCY":   tt.readConcurrency,
+				"HOMELAB_BACKUP_PACK_SIZE":          tt.packSize,
+			}
+			env := &mockEnv{
+				getEnvFunc: func(varName string) (string, bool) {
+					value, exists := vars[varName]
+					return value, exists
+				},
+			}
+
+			config, err := LoadResticConfig(env, "")
+
+			if tt.expectedErr {
+				if !errors.Is(err, ErrInvalidResticConfig) {
+					t.Errorf("expected ErrInvalidResticConfig, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if config.ReadConcurrency != tt.expectedReadConcurrency || config.PackSize != tt.expectedPackSize {
+				t.Errorf("expected read concurrency %d and pack size %d, got %d and %d",
+					tt.expectedReadConcurrency, tt.expectedPackSize, config.ReadConcurrency, config.PackSize)
+			}
+		})
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "30", expected: "30d"},
+		{value: "30d", expected: "30d"},
+		{value: "4w", expected: "28d"},
+		{value: "6m", expected: "6m"},
+		{value: "1y", expected: "1y"},
+		{value: " 14d ", expected: "14d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			retention, err := ParseRetention(tt.value)
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if retention != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, retention)
+			}
+		})
+	}
+}
+
+func TestParseRetention_Invalid(t *testing.T) {
+	for _, value := range []string{"", "thirty", "30x", "1y6m", "d", "0d", "-5", "1.5y"} {
+		t.Run(value, func(t *testing.T) {
+			_, err := ParseRetention(value)
+
+			if !errors.Is(err, ErrInvalidRetention) {
+				t.Errorf("expected ErrInvalidRetention, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadResticConfig_UnsetRetentionDaysUsesDefault(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if config.Retention != defaultRetention {
+		t.Errorf("expected default retention %q, got %q", defaultRetention, config.Retention)
+	}
+	if !strings.Contains(logs.String(), "HOMELAB_BACKUP_RETENTION_DAYS") {
+		t.Errorf("expected a notice about the default retention days, got logs: %q", logs.String())
+	}
+}
+
+func TestLoadResticConfig_CustomResticBinary(t *testing.T) {
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+		"HOMELAB_BACKUP_RETENTION_DAYS":     "30",
+		"HOMELAB_RESTIC_BINARY":             "restic_0.16",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if config.ResticBinary != "restic_0.16" {
+		t.Errorf("expected restic binary %q, got %q", "restic_0.16", config.ResticBinary)
+	}
+}
+
+func TestLoadResticConfig_OneFileSystem(t *testing.T) {
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+		"HOMELAB_BACKUP_RETENTION_DAYS":     "30",
+		"HOMELAB_BACKUP_ONE_FILE_SYSTEM":    "true",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !config.OneFileSystem {
+		t.Error("expected OneFileSystem to be true")
+	}
+}
+
+func TestLoadResticConfig_TagConfigHash(t *testing.T) {
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+		"HOMELAB_BACKUP_RETENTION_DAYS":     "30",
+		"HOMELAB_BACKUP_TAG_CONFIG_HASH":    "true",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !config.TagConfigHash {
+		t.Error("expected TagConfigHash to be true")
+	}
+}
+
+func TestLoadResticConfig_RestoreOwnerAndMode(t *testing.T) {
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+		"HOMELAB_BACKUP_RETENTION_DAYS":     "30",
+		"HOMELAB_BACKUP_RESTORE_OWNER":      "1000:1000",
+		"HOMELAB_BACKUP_RESTORE_MODE":       "u=rwX,g=rX,o=",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if config.RestoreOwner != "1000:1000" {
+		t.Errorf("expected restore owner %q, got %q", "1000:1000", config.RestoreOwner)
+	}
+	if config.RestoreMode != "u=rwX,g=rX,o=" {
+		t.Errorf("expected restore mode %q, got %q", "u=rwX,g=rX,o=", config.RestoreMode)
+	}
+}
+
+func TestLoadResticConfig_InvalidRestoreOwnerOrMode(t *testing.T) {
+	invalidVars := map[string]string{
+		"HOMELAB_BACKUP_RESTORE_OWNER": "www-data",
+		"HOMELAB_BACKUP_RESTORE_MODE":  "rwxr-x---",
+	}
+	for invalidVarName, invalidValue := range invalidVars {
+		vars := map[string]string{
+			"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+			"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+			"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+			"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+			"HOMELAB_BACKUP_PATH":               "/data/backup",
+			"HOMELAB_BACKUP_RETENTION_DAYS":     "30",
+			invalidVarName:                      invalidValue,
+		}
+		env := &mockEnv{
+			getEnvFunc: func(varName string) (string, bool) {
+				value, exists := vars[varName]
+				return value, exists
+			},
+		}
+
+		_, err := LoadResticConfig(env, "")
+
+		if !errors.Is(err, ErrInvalidResticConfig) {
+			t.Errorf("for %s=%q: expected ErrInvalidResticConfig, got: %v", invalidVarName, invalidValue, err)
+		}
+	}
+}
+
+func TestLoadResticConfig_TagPrefix(t *testing.T) {
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+		"HOMELAB_BACKUP_RETENTION_DAYS":     "30",
+		"HOMELAB_BACKUP_TAG_PREFIX":         " media-host- ",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if config.TagPrefix != "media-host-" {
+		t.Errorf("expected tag prefix %q, got %q", "media-host-", config.TagPrefix)
+	}
+}
+
+func TestLoadResticConfig_InvalidTagPrefix(t *testing.T) {
+	for _, tagPrefix := range []string{"media,host-", "media host-"} {
+		t.Run(tagPrefix, func(t *testing.T) {
+			env := &mockEnv{
+				getEnvFunc: func(varName string) (string, bool) {
+					switch varName {
+					case "HOMELAB_BACKUP_TAG_PREFIX":
+						return tagPrefix, true
+					case "HOMELAB_BACKUP_RETENTION_DAYS":
+						return "30", true
+					case "HOMELAB_BACKUP_RESTIC_REPOSITORY", "HOMELAB_BACKUP_B2_KEY_ID", "HOMELAB_BACKUP_B2_APPLICATION_KEY",
+						"HOMELAB_BACKUP_RESTIC_PASSWORD", "HOMELAB_BACKUP_PATH":
+						return "value", true
+					}
+					return "", false
+				},
+			}
+
+			_, err := LoadResticConfig(env, "")
+
+			if !errors.Is(err, ErrInvalidResticConfig) {
+				t.Errorf("expected ErrInvalidResticConfig, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadResticConfig_CopyRepository(t *testing.T) {
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":      "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":              "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY":     "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":        "password",
+		"HOMELAB_BACKUP_PATH":                   "/data/backup",
+		"HOMELAB_BACKUP_RETENTION_DAYS":         "30",
+		"HOMELAB_BACKUP_COPY_RESTIC_REPOSITORY": "/mnt/disk2/restic",
+		"HOMELAB_BACKUP_COPY_RESTIC_PASSWORD":   "copy-password",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	config, err := LoadResticConfig(env, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if config.CopyRepositoryURL != "/mnt/disk2/restic" || config.CopyResticPassword != "copy-password" {
+		t.Errorf("expected copy repository %q with password %q, got %q with %q",
+			"/mnt/disk2/restic", "copy-password", config.CopyRepositoryURL, config.CopyResticPassword)
+	}
+}
+
+func TestLoadResticConfig_IncompleteCopyRepository(t *testing.T) {
+	for _, copyVarName := range []string{"HOMELAB_BACKUP_COPY_RESTIC_REPOSITORY", "HOMELAB_BACKUP_COPY_RESTIC_PASSWORD"} {
+		t.Run(copyVarName, func(t *testing.T) {
+			vars := map[string]string{
+				"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+				"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+				"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+				"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+				"HOMELAB_BACKUP_PATH":               "/data/backup",
+				"HOMELAB_BACKUP_RETENTION_DAYS":     "30",
+				copyVarName:                         "value",
+			}
+			env := &mockEnv{
+				getEnvFunc: func(varName string) (string, bool) {
+					value, exists := vars[varName]
+					return value, exists
+				},
+			}
+
+			_, err := LoadResticConfig(env, "")
+
+			if !errors.Is(err, ErrInvalidResticConfig) {
+				t.Errorf("expected ErrInvalidResticConfig, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadResticConfig_EmptyResticBinary(t *testing.T) {
+	vars := map[string]string{
+		"HOMELAB_BACKUP_RESTIC_REPOSITORY":  "b2:bucket:path",
+		"HOMELAB_BACKUP_B2_KEY_ID":          "key-id",
+		"HOMELAB_BACKUP_B2_APPLICATION_KEY": "app-key",
+		"HOMELAB_BACKUP_RESTIC_PASSWORD":    "password",
+		"HOMELAB_BACKUP_PATH":               "/data/backup",
+		"HOMELAB_BACKUP_RETENTION_DAYS":     "30",
+		"HOMELAB_RESTIC_BINARY":             "  ",
+	}
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			value, exists := vars[varName]
+			return value, exists
+		},
+	}
+
+	_, err := LoadResticConfig(env, "")
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrInvalidResticConfig) {
+		t.Errorf("expected ErrInvalidResticConfig, got: %v", err)
+	}
+}
+
+// mockSecretPrompter is a mock implementation of SecretPrompter that answers with a fixed password
+type mockSecretPrompter struct {
+	password string
+	messages []string
+}
+
+func (m *mockSecretPrompter) PromptSecret(message string) (string, error) {
+	m.messages = append(m.messages, message)
+	return m.password, nil
+}
+
+func TestWithPromptedResticPassword_PromptsWhenNotConfigured(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			return "", false
+		},
+	}
+	prompter := &mockSecretPrompter{password: "typed-password"}
+
+	promptedEnv, err := WithPromptedResticPassword(env, "photos", prompter)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(prompter.messages) != 1 || !strings.Contains(prompter.messages[0], "HOMELAB_BACKUP_PHOTOS_RESTIC_PASSWORD") {
+		t.Errorf("expected a single prompt naming the variable, got: %q", prompter.messages)
+	}
+	password, err := promptedEnv.GetRequiredEnv("HOMELAB_BACKUP_PHOTOS_RESTIC_PASSWORD")
+	if err != nil || password != "typed-password" {
+		t.Errorf("expected password %q, got %q (%v)", "typed-password", password, err)
+	}
+	if _, exists := promptedEnv.GetEnv("HOMELAB_BACKUP_PHOTOS_PATH"); exists {
+		t.Error("expected the other variables to be read from the environment")
+	}
+}
+
+func TestWithPromptedResticPassword_SkipsPromptWhenConfigured(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			return "configured-password", varName == "HOMELAB_BACKUP_RESTIC_PASSWORD"
+		},
+	}
+	prompter := &mockSecretPrompter{password: "typed-password"}
+
+	promptedEnv, err := WithPromptedResticPassword(env, "", prompter)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(prompter.messages) != 0 {
+		t.Errorf("expected no prompt, got: %q", prompter.messages)
+	}
+	if promptedEnv != env {
+		t.Error("expected the environment to be returned unchanged")
+	}
+}
+
+func TestWithPromptedResticPassword_EmptyAnswer(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			return "", false
+		},
+	}
+
+	_, err := WithPromptedResticPassword(env, "", &mockSecretPrompter{})
+
+	if !errors.Is(err, system.ErrRequiredEnvNotFound) {
+		t.Errorf("expected ErrRequiredEnvNotFound, got: %v", err)
+	}
+}
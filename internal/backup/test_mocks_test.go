@@ -1,12 +1,27 @@
 package backup
 
-import "github.com/davidsilvasanmartin/auto-homelab/internal/system"
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
 
 type mockFilesHandler struct {
 	createDirIfNotExists func(path string) error
 	ensureDirExists      func(path string) error
+	emptyDir             func(path string) error
 	copyDir              func(srcPath string, dstPath string) error
+	copyDirWithOptions   func(srcPath string, dstPath string, opts system.CopyDirOptions) ([]string, error)
 	getAbsPath           func(path string) (string, error)
+	readFile             func(path string) ([]byte, error)
+	listFiles            func(path string) ([]os.FileInfo, error)
+	removeFile           func(path string) error
 }
 
 func (m *mockFilesHandler) CreateDirIfNotExists(path string) error {
@@ -22,21 +37,67 @@ func (m *mockFilesHandler) EnsureDirExists(path string) error {
 	}
 	return nil
 }
-func (m *mockFilesHandler) EmptyDir(path string) error { return nil }
+func (m *mockFilesHandler) EmptyDir(path string) error {
+	if m.emptyDir != nil {
+		return m.emptyDir(path)
+	}
+	return nil
+}
 func (m *mockFilesHandler) CopyDir(srcPath string, dstPath string) error {
 	if m.copyDir != nil {
 		return m.copyDir(srcPath, dstPath)
 	}
 	return nil
 }
-func (m *mockFilesHandler) Getwd() (dir string, err error)           { return "", nil }
-func (m *mockFilesHandler) WriteFile(path string, data []byte) error { return nil }
+func (m *mockFilesHandler) CopyDirWithOptions(srcPath string, dstPath string, opts system.CopyDirOptions) ([]string, error) {
+	if m.copyDirWithOptions != nil {
+		return m.copyDirWithOptions(srcPath, dstPath, opts)
+	}
+	return nil, nil
+}
+func (m *mockFilesHandler) Getwd() (dir string, err error)                    { return "", nil }
+func (m *mockFilesHandler) WriteFile(path string, data []byte) error          { return nil }
+func (m *mockFilesHandler) WriteSecretFile(path string, data []byte) error    { return nil }
+func (m *mockFilesHandler) WriteNewFile(path string, data []byte) error       { return nil }
+func (m *mockFilesHandler) CreateNewFile(path string) (io.WriteCloser, error) { return nil, nil }
+func (m *mockFilesHandler) ReadFile(path string) ([]byte, error) {
+	if m.readFile != nil {
+		return m.readFile(path)
+	}
+	return nil, nil
+}
 func (m *mockFilesHandler) GetAbsPath(path string) (string, error) {
 	if m.getAbsPath != nil {
 		return m.getAbsPath(path)
 	}
 	return path, nil
 }
+func (m *mockFilesHandler) ListFiles(path string) ([]os.FileInfo, error) {
+	if m.listFiles != nil {
+		return m.listFiles(path)
+	}
+	return nil, nil
+}
+func (m *mockFilesHandler) RemoveFile(path string) error {
+	if m.removeFile != nil {
+		return m.removeFile(path)
+	}
+	return nil
+}
+func (m *mockFilesHandler) DirSize(path string) (int64, error) { return 0, nil }
+
+// mockFileInfo is a mock implementation of os.FileInfo for testing
+type mockFileInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (m *mockFileInfo) Name() string       { return m.name }
+func (m *mockFileInfo) Size() int64        { return 0 }
+func (m *mockFileInfo) Mode() os.FileMode  { return 0 }
+func (m *mockFileInfo) ModTime() time.Time { return m.modTime }
+func (m *mockFileInfo) IsDir() bool        { return false }
+func (m *mockFileInfo) Sys() interface{}   { return nil }
 
 type mockTextFormatter struct{}
 
@@ -48,16 +109,61 @@ func (m *mockTextFormatter) QuoteForPOSIXShell(key string) string {
 }
 
 type mockCommands struct {
+	execCommand      func(name string, arg ...string) system.RunnableCommand
 	execShellCommand func(cmd string) system.RunnableCommand
+	// execShellCommandContext falls back to execShellCommand when it is nil
+	execShellCommandContext func(ctx context.Context, cmd string) system.RunnableCommand
+	// execShellCommandStderr falls back to execShellCommand when it is nil
+	execShellCommandStderr func(cmd string, stderr io.Writer) system.RunnableCommand
+	// execShellCommandStdout falls back to execShellCommand when it is nil
+	execShellCommandStdout func(cmd string, stdout io.Writer) system.RunnableCommand
+	lookPath               func(name string) (string, error)
 }
 
-func (m *mockCommands) ExecCommand(name string, arg ...string) system.RunnableCommand { return nil }
+func (m *mockCommands) ExecCommand(name string, arg ...string) system.RunnableCommand {
+	if m.execCommand != nil {
+		return m.execCommand(name, arg...)
+	}
+	return nil
+}
 func (m *mockCommands) ExecShellCommand(cmd string) system.RunnableCommand {
 	if m.execShellCommand != nil {
 		return m.execShellCommand(cmd)
 	}
 	return nil
 }
+func (m *mockCommands) ExecShellCommandContext(ctx context.Context, cmd string) system.RunnableCommand {
+	if m.execShellCommandContext != nil {
+		return m.execShellCommandContext(ctx, cmd)
+	}
+	return m.ExecShellCommand(cmd)
+}
+func (m *mockCommands) ExecShellCommandWithStderr(cmd string, stderr io.Writer) system.RunnableCommand {
+	if m.execShellCommandStderr != nil {
+		return m.execShellCommandStderr(cmd, stderr)
+	}
+	return m.ExecShellCommand(cmd)
+}
+func (m *mockCommands) ExecShellCommandWithStdout(cmd string, stdout io.Writer) system.RunnableCommand {
+	if m.execShellCommandStdout != nil {
+		return m.execShellCommandStdout(cmd, stdout)
+	}
+	return m.ExecShellCommand(cmd)
+}
+func (m *mockCommands) ExecShellCommandContextWithStdout(ctx context.Context, cmd string, stdout io.Writer) system.RunnableCommand {
+	if m.execShellCommandContext != nil {
+		return m.execShellCommandContext(ctx, cmd)
+	}
+	return m.ExecShellCommandWithStdout(cmd, stdout)
+}
+
+// LookPath assumes by default that the executable is NOT installed
+func (m *mockCommands) LookPath(name string) (string, error) {
+	if m.lookPath != nil {
+		return m.lookPath(name)
+	}
+	return "", exec.ErrNotFound
+}
 
 type mockRunnableCommand struct {
 	runFunc func() error
@@ -69,3 +175,62 @@ func (m *mockRunnableCommand) Run() error {
 	}
 	return nil
 }
+
+type mockEnv struct {
+	getEnvFunc func(varName string) (string, bool)
+}
+
+// GetEnv assumes by default that the variable does NOT exist
+func (m *mockEnv) GetEnv(varName string) (string, bool) {
+	if m.getEnvFunc != nil {
+		return m.getEnvFunc(varName)
+	}
+	return "", false
+}
+func (m *mockEnv) GetRequiredEnv(varName string) (string, error) {
+	if value, exists := m.GetEnv(varName); exists {
+		return value, nil
+	}
+	return "", fmt.Errorf("%w: %q", system.ErrRequiredEnvNotFound, varName)
+}
+func (m *mockEnv) GetBoolEnv(varName string) (bool, bool) {
+	value, exists := m.GetEnv(varName)
+	if !exists {
+		return false, false
+	}
+	return value == "true", true
+}
+func (m *mockEnv) GetIntEnv(varName string) (int, bool, error) {
+	value, exists := m.GetEnv(varName)
+	if !exists {
+		return 0, false, nil
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, true, fmt.Errorf("%w %q: %w", system.ErrInvalidIntEnv, varName, err)
+	}
+	return intValue, true, nil
+}
+func (m *mockEnv) GetAllEnv() map[string]string                 { return map[string]string{} }
+func (m *mockEnv) ListByPrefix(prefix string) map[string]string { return map[string]string{} }
+
+type mockTime struct {
+	now   func() time.Time
+	after func(d time.Duration) <-chan time.Time
+}
+
+func (m *mockTime) Sleep(d time.Duration) {}
+
+// After returns by default a channel that never receives, so that nothing waiting on it fires
+func (m *mockTime) After(d time.Duration) <-chan time.Time {
+	if m.after != nil {
+		return m.after(d)
+	}
+	return nil
+}
+func (m *mockTime) Now() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Time{}
+}
@@ -1,15 +1,20 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
-	"time"
 
+	"github.com/davidsilvasanmartin/auto-homelab/internal/dotenv"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 )
@@ -17,20 +22,28 @@ import (
 // Orchestration logic for the config feature
 
 type Configurer interface {
-	// LoadConfig loads the configuration from a file
+	// LoadConfig loads the configuration from a file, or from the standard input if the path is "-"
 	LoadConfig(configFilePath string) (*ConfigRoot, error)
 	// ProcessConfig processes the configuration and retrieves variable values
 	ProcessConfig(configRoot *ConfigRoot) (*EnvVarRoot, error)
-	// WriteConfig writes the processed configuration into a timestamped generated .env file
+	// WriteConfig writes the processed configuration into a timestamped generated .env file, or into the output
+	// writer of the configurer if it has one
 	WriteConfig(envVarRoot *EnvVarRoot) error
+	// DiffConfig compares the processed configuration against the currently loaded environment
+	DiffConfig(envVarRoot *EnvVarRoot) *EnvVarDiff
 }
 
 var (
-	ErrConfigFileRead  = errors.New("failed to read config file")
-	ErrConfigFileParse = errors.New("failed to parse config file")
-	ErrVarType         = errors.New("error processing variable type")
-	ErrVarAcquireVal   = errors.New("error acquiring value for variable")
-	ErrConfigFileWrite = errors.New("failed to write config file")
+	ErrConfigFileRead    = errors.New("failed to read config file")
+	ErrConfigFileParse   = errors.New("failed to parse config file")
+	ErrVarType           = errors.New("error processing variable type")
+	ErrVarAcquireVal     = errors.New("error acquiring value for variable")
+	ErrConfigFileWrite   = errors.New("failed to write config file")
+	ErrAnswersFileRead   = errors.New("failed to read answers file")
+	ErrMissingAnswers    = errors.New("answers file is missing required variables")
+	ErrInvalidAnswer     = errors.New("invalid value in answers file for variable")
+	ErrNoGeneratedConfig = errors.New("no generated .env file found")
+	ErrStdinCommandVar   = errors.New("COMMAND variables are not allowed in a config read from stdin")
 )
 
 type DefaultConfigurer struct {
@@ -38,18 +51,81 @@ type DefaultConfigurer struct {
 	strategyRegistry StrategyRegistry
 	textFormatter    format.TextFormatter
 	files            system.FilesHandler
+	env              system.Env
+	time             system.Time
+	// stdin is where the configuration is read from when its path is StdinConfigPath
+	stdin io.Reader
+	// out receives the generated .env content instead of a file, when it is not nil
+	out io.Writer
+	// answers contains the values of the variables read from an answers file, which are used instead of acquiring
+	// them through their strategies
+	answers map[string]string
+	// strict makes ProcessConfig fail before acquiring anything when a required variable has no answer
+	strict bool
+	// allowStdinCommands makes LoadConfig accept COMMAND variables in a configuration read from the standard input,
+	// whose shell commands would otherwise be run without the user having seen them
+	allowStdinCommands bool
 }
 
-func NewDefaultConfigurer() *DefaultConfigurer {
+// NewDefaultConfigurer creates a new configurer. If nonInteractive is true, the config's values are used as the
+// answers instead of prompting the user, whenever they are provided
+func NewDefaultConfigurer(nonInteractive bool) *DefaultConfigurer {
 	return &DefaultConfigurer{
 		prompter:         NewConsolePrompter(),
-		strategyRegistry: NewDefaultStrategyRegistry(),
+		strategyRegistry: NewDefaultStrategyRegistry(nonInteractive),
 		textFormatter:    format.NewDefaultTextFormatter(),
 		files:            system.NewDefaultFilesHandler(),
+		env:              system.NewDefaultEnv(),
+		time:             system.NewDefaultTime(),
+		stdin:            os.Stdin,
 	}
 }
 
+// NewConfigurer creates a configurer whose I/O is controlled by the caller, for example to embed it in another
+// program. The user interacts with it through prompter, and the generated .env content is written into out
+func NewConfigurer(prompter Prompter, files system.FilesHandler, out io.Writer, nonInteractive bool) *DefaultConfigurer {
+	return &DefaultConfigurer{
+		prompter:         prompter,
+		strategyRegistry: NewStrategyRegistry(prompter, files, nonInteractive),
+		textFormatter:    format.NewDefaultTextFormatter(),
+		files:            files,
+		env:              system.NewDefaultEnv(),
+		time:             system.NewDefaultTime(),
+		stdin:            os.Stdin,
+		out:              out,
+	}
+}
+
+// redactedValue replaces the values of sensitive variables when they are shown to the user
+const redactedValue = format.RedactedValue
+
+// sensitiveVarTypes contains the variable types whose values must never be shown to the user. COMMAND variables are
+// usually secrets too, such as the output of "openssl rand"
+var sensitiveVarTypes = []string{"GENERATED", "COMMAND"}
+
+// isSensitiveVar returns whether the value of a variable must never be shown to the user, either because of its type or
+// because its name tells that it is a secret, such as a STRING variable named *_PASSWORD
+func isSensitiveVar(envVar EnvVar) bool {
+	return slices.Contains(sensitiveVarTypes, strings.ToUpper(envVar.Type)) || format.IsSensitiveName(envVar.Name)
+}
+
+// StdinConfigPath is the config path that makes LoadConfig read the configuration from the standard input
+const StdinConfigPath = "-"
+
 func (c *DefaultConfigurer) LoadConfig(configFilePath string) (*ConfigRoot, error) {
+	if configFilePath == StdinConfigPath {
+		configRoot, err := c.ReadConfig(c.stdin)
+		if err != nil {
+			return nil, err
+		}
+		if !c.allowStdinCommands {
+			if commandVars := commandVarNames(configRoot); len(commandVars) > 0 {
+				return nil, fmt.Errorf("%w: %s", ErrStdinCommandVar, strings.Join(commandVars, ", "))
+			}
+		}
+		return configRoot, nil
+	}
+
 	data, err := os.ReadFile(configFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %q", ErrConfigFileRead, configFilePath)
@@ -63,7 +139,110 @@ func (c *DefaultConfigurer) LoadConfig(configFilePath string) (*ConfigRoot, erro
 	return &configRoot, nil
 }
 
+// ReadConfig loads the configuration from a reader
+func (c *DefaultConfigurer) ReadConfig(reader io.Reader) (*ConfigRoot, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigFileRead, err)
+	}
+
+	var configRoot ConfigRoot
+	if err := json.Unmarshal(data, &configRoot); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigFileParse, err)
+	}
+
+	return &configRoot, nil
+}
+
+// commandVarNames returns the full names of the COMMAND variables of the configuration
+func commandVarNames(configRoot *ConfigRoot) []string {
+	var names []string
+	for _, configSection := range configRoot.Sections {
+		for _, configVar := range configSection.Vars {
+			if strings.EqualFold(configVar.Type, "COMMAND") {
+				names = append(names, fmt.Sprintf("%s_%s_%s", configRoot.Prefix, configSection.Name, configVar.Name))
+			}
+		}
+	}
+	return names
+}
+
+// WithStdinCommands makes the configurer accept COMMAND variables in a configuration read from the standard input.
+// They are refused by default, because their commands are run as soon as the configuration is processed
+func (c *DefaultConfigurer) WithStdinCommands(allow bool) *DefaultConfigurer {
+	c.allowStdinCommands = allow
+	return c
+}
+
+// WithAnswers makes the configurer use answers as the values of the variables, keyed by their full name. Answers for
+// variables that are not in the configuration are ignored. If strict is true, every required variable must have an
+// answer
+func (c *DefaultConfigurer) WithAnswers(answers map[string]string, strict bool) *DefaultConfigurer {
+	c.answers = answers
+	c.strict = strict
+	return c
+}
+
+// LoadAnswers reads an answers file, which has the same KEY=value format as a .env file
+func (c *DefaultConfigurer) LoadAnswers(answersFilePath string) (map[string]string, error) {
+	data, err := c.files.ReadFile(answersFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAnswersFileRead, err)
+	}
+
+	parsed, err := dotenv.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAnswersFileRead, err)
+	}
+
+	answers := make(map[string]string)
+	for _, entry := range parsed.Entries {
+		if entry.IsAssignment() {
+			answers[entry.Key] = entry.Value
+		}
+	}
+	return answers, nil
+}
+
+// isRequiredVar returns whether a variable needs an answer in strict mode, which is when acquiring it would prompt the
+// user
+func isRequiredVar(configVar ConfigVar, strategy AcquireStrategy) bool {
+	return configVar.Value == nil || !strategy.UsesDefault()
+}
+
+// missingAnswers returns the full names of the required variables that have no answer
+func (c *DefaultConfigurer) missingAnswers(configRoot *ConfigRoot) ([]string, error) {
+	var missing []string
+	for _, configSection := range configRoot.Sections {
+		for _, configVar := range configSection.Vars {
+			varName := fmt.Sprintf("%s_%s_%s", configRoot.Prefix, configSection.Name, configVar.Name)
+			strategy, err := c.strategyRegistry.Get(configVar.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q (varName=%q): %w", ErrVarType, configVar.Type, varName, err)
+			}
+			if _, ok := c.answers[varName]; !ok && isRequiredVar(configVar, strategy) {
+				missing = append(missing, varName)
+			}
+		}
+	}
+	return missing, nil
+}
+
 func (c *DefaultConfigurer) ProcessConfig(configRoot *ConfigRoot) (*EnvVarRoot, error) {
+	if c.strict {
+		missing, err := c.missingAnswers(configRoot)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrMissingAnswers, strings.Join(missing, ", "))
+		}
+	}
+
+	if err := c.setCharsetPools(configRoot.CharsetPools); err != nil {
+		return nil, err
+	}
+
 	root := &EnvVarRoot{
 		Sections: make([]EnvVarSection, 0, len(configRoot.Sections)),
 	}
@@ -88,13 +267,25 @@ func (c *DefaultConfigurer) ProcessConfig(configRoot *ConfigRoot) (*EnvVarRoot,
 				return nil, fmt.Errorf("%w %q (varName=%q): %w", ErrVarType, configVar.Type, varName, err)
 			}
 
-			value, err := strategy.Acquire(varName, configVar.Value)
-			if err != nil {
-				return nil, fmt.Errorf("%w %q: %w", ErrVarAcquireVal, varName, err)
+			var value string
+			if answer, ok := c.answers[varName]; ok {
+				c.prompter.Info("Using the value from the answers file")
+				// Answers are checked like the user's input, so an answers file can't produce a .env file that the
+				// prompts would have rejected
+				value, err = strategy.Validate(varName, answer, configVar.Value)
+				if err != nil {
+					return nil, fmt.Errorf("%w %q: %w", ErrInvalidAnswer, varName, err)
+				}
+			} else {
+				value, err = strategy.Acquire(varName, configVar.Value)
+				if err != nil {
+					return nil, fmt.Errorf("%w %q: %w", ErrVarAcquireVal, varName, err)
+				}
 			}
 
 			envVar := EnvVar{
 				Name:        varName,
+				Type:        configVar.Type,
 				Description: configVar.Description,
 				Value:       value,
 			}
@@ -107,6 +298,29 @@ func (c *DefaultConfigurer) ProcessConfig(configRoot *ConfigRoot) (*EnvVarRoot,
 	return root, nil
 }
 
+// charsetPoolsSetter is implemented by the strategies that generate values from the custom charset pools of the config
+// file
+type charsetPoolsSetter interface {
+	SetCharsetPools(pools map[string]string)
+}
+
+// setCharsetPools validates the custom charset pools of the config file and passes them to the GENERATED strategy
+func (c *DefaultConfigurer) setCharsetPools(pools map[string]string) error {
+	normalized, err := normalizeCharsetPools(pools)
+	if err != nil {
+		return err
+	}
+	strategy, err := c.strategyRegistry.Get("GENERATED")
+	if err != nil {
+		// Without a GENERATED strategy, the pools can't be used by any variable
+		return nil
+	}
+	if setter, ok := strategy.(charsetPoolsSetter); ok {
+		setter.SetCharsetPools(normalized)
+	}
+	return nil
+}
+
 func (c *DefaultConfigurer) WriteConfig(envVarRoot *EnvVarRoot) error {
 	builder := newDotenvBuilder(c.textFormatter)
 	for _, section := range envVarRoot.Sections {
@@ -117,20 +331,32 @@ func (c *DefaultConfigurer) WriteConfig(envVarRoot *EnvVarRoot) error {
 	}
 	content := builder.build()
 
-	timestamp := time.Now().Unix()
-	// Start name with .env so the file is shown next to other .env files; end file with .env so that
-	// we have syntax highlighting when opening it
-	filename := fmt.Sprintf(".env.generated.%d.env", timestamp)
+	if c.out != nil {
+		if _, err := io.WriteString(c.out, content); err != nil {
+			return fmt.Errorf("%w: %w", ErrConfigFileWrite, err)
+		}
+		slog.Info("wrote config", "totalVars", builder.totalVars)
+		return nil
+	}
+
+	timestamp := c.time.Now().Unix()
 
 	wd, err := c.files.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %w", err)
 	}
 
-	outputPath := filepath.Join(wd, filename)
-
-	if err := c.files.WriteFile(outputPath, []byte(content)); err != nil {
-		return fmt.Errorf("%w %q: %w", ErrConfigFileWrite, outputPath, err)
+	// Files are never overwritten: if a file generated in the same second exists, a numeric suffix is added
+	var outputPath string
+	for attempt := 0; ; attempt++ {
+		outputPath = filepath.Join(wd, generatedConfigFilename(timestamp, attempt))
+		err = c.files.WriteNewFile(outputPath, []byte(content))
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, system.ErrFileAlreadyExists) || attempt+1 >= maxGeneratedConfigAttempts {
+			return fmt.Errorf("%w %q: %w", ErrConfigFileWrite, outputPath, err)
+		}
 	}
 
 	slog.Info("wrote config file", "outputPath", outputPath, "totalVars", builder.totalVars)
@@ -138,6 +364,113 @@ func (c *DefaultConfigurer) WriteConfig(envVarRoot *EnvVarRoot) error {
 	return nil
 }
 
+// maxGeneratedConfigAttempts is the number of filenames that WriteConfig tries before giving up, when files generated
+// in the same second already exist
+const maxGeneratedConfigAttempts = 100
+
+// generatedConfigFilename returns the name of a generated .env file. The attempt number is added as a suffix to the
+// timestamp, except for the first attempt
+func generatedConfigFilename(timestamp int64, attempt int) string {
+	// Start name with .env so the file is shown next to other .env files; end file with .env so that
+	// we have syntax highlighting when opening it
+	if attempt == 0 {
+		return fmt.Sprintf(".env.generated.%d.env", timestamp)
+	}
+	return fmt.Sprintf(".env.generated.%d-%d.env", timestamp, attempt)
+}
+
+// generatedConfigPattern matches the names built by generatedConfigFilename, capturing their timestamp and attempt
+var generatedConfigPattern = regexp.MustCompile(`^\.env\.generated\.(\d+)(?:-(\d+))?\.env$`)
+
+// parseGeneratedConfigFilename returns the timestamp and attempt number of the name of a generated .env file. It
+// returns false for any other name
+func parseGeneratedConfigFilename(name string) (timestamp int64, attempt int, ok bool) {
+	matches := generatedConfigPattern.FindStringSubmatch(name)
+	if matches == nil {
+		return 0, 0, false
+	}
+	timestamp, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if matches[2] != "" {
+		if attempt, err = strconv.Atoi(matches[2]); err != nil {
+			return 0, 0, false
+		}
+	}
+	return timestamp, attempt, true
+}
+
+// latestGeneratedConfig returns the path of the most recent generated .env file in dir. Files are compared by the
+// timestamp and attempt number in their names, which, unlike their modification times, don't change when they are
+// copied. Files whose names don't match the generated names are skipped
+func latestGeneratedConfig(files system.FilesHandler, dir string) (string, error) {
+	entries, err := files.ListFiles(dir)
+	if err != nil {
+		return "", err
+	}
+
+	latestName := ""
+	var latestTimestamp int64
+	latestAttempt := 0
+	for _, entry := range entries {
+		timestamp, attempt, ok := parseGeneratedConfigFilename(entry.Name())
+		if !ok {
+			continue
+		}
+		if latestName == "" || timestamp > latestTimestamp || (timestamp == latestTimestamp && attempt > latestAttempt) {
+			latestName, latestTimestamp, latestAttempt = entry.Name(), timestamp, attempt
+		}
+	}
+	if latestName == "" {
+		return "", fmt.Errorf("%w in %q", ErrNoGeneratedConfig, dir)
+	}
+	return filepath.Join(dir, latestName), nil
+}
+
+func (c *DefaultConfigurer) DiffConfig(envVarRoot *EnvVarRoot) *EnvVarDiff {
+	current := c.env.GetAllEnv()
+	diff := &EnvVarDiff{
+		Added:   []EnvVarChange{},
+		Changed: []EnvVarChange{},
+		Removed: []string{},
+	}
+
+	processed := make(map[string]bool)
+	for _, section := range envVarRoot.Sections {
+		for _, envVar := range section.Vars {
+			processed[envVar.Name] = true
+			newValue := envVar.Value
+			if isSensitiveVar(envVar) {
+				newValue = redactedValue
+			}
+
+			oldValue, exists := current[envVar.Name]
+			if !exists {
+				diff.Added = append(diff.Added, EnvVarChange{Name: envVar.Name, NewValue: newValue})
+				continue
+			}
+			if oldValue == envVar.Value {
+				continue
+			}
+			if newValue == redactedValue {
+				oldValue = redactedValue
+			}
+			diff.Changed = append(diff.Changed, EnvVarChange{Name: envVar.Name, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	for name := range current {
+		if !processed[name] {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	// Map iteration order is random, so we sort to get a stable output
+	slices.Sort(diff.Removed)
+
+	return diff
+}
+
 // dotenvBuilder builds a .env file from the parsed configuration
 type dotenvBuilder struct {
 	lines         []string
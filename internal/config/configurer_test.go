@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -108,11 +111,13 @@ var envVarRoot *EnvVarRoot = &EnvVarRoot{
 			Vars: []EnvVar{
 				{
 					Name:        "TEST_DATABASE_HOST",
+					Type:        "STRING",
 					Description: "Database host",
 					Value:       "TEST_DATABASE_HOST#127.0.0.1#value",
 				},
 				{
 					Name:        "TEST_DATABASE_PASSWORD",
+					Type:        "STRING",
 					Description: "Database password",
 					Value:       "TEST_DATABASE_PASSWORD##value",
 				},
@@ -124,6 +129,7 @@ var envVarRoot *EnvVarRoot = &EnvVarRoot{
 			Vars: []EnvVar{
 				{
 					Name:        "TEST_SERVER_NAME",
+					Type:        "STRING",
 					Description: "Server name",
 					Value:       "TEST_SERVER_NAME#MyServer#value",
 				},
@@ -179,6 +185,87 @@ func TestDefaultConfigurer_LoadConfig_Success(t *testing.T) {
 	}
 }
 
+func TestDefaultConfigurer_LoadConfig_FromStdin(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+		stdin:            strings.NewReader(configJSON),
+	}
+
+	result, err := configurer.LoadConfig(StdinConfigPath)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if diff := cmp.Diff(configRoot, result); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultConfigurer_LoadConfig_FromStdinInvalidJSON(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+		stdin:            strings.NewReader("not json"),
+	}
+
+	_, err := configurer.LoadConfig(StdinConfigPath)
+
+	if err == nil {
+		t.Fatal("expected error for invalid JSON, got nil")
+	}
+	if !errors.Is(err, ErrConfigFileParse) {
+		t.Errorf("expected ErrConfigFileParse, got: %v", err)
+	}
+}
+
+const commandConfigJSON = `{
+	"prefix": "HOMELAB",
+	"sections": [{"name": "DB", "vars": [{"name": "PASSWORD", "type": "COMMAND", "value": "openssl rand -hex 16"}]}]
+}`
+
+func TestDefaultConfigurer_LoadConfig_FromStdinRefusesCommandVars(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+		stdin:            strings.NewReader(commandConfigJSON),
+	}
+
+	_, err := configurer.LoadConfig(StdinConfigPath)
+
+	if !errors.Is(err, ErrStdinCommandVar) {
+		t.Fatalf("expected ErrStdinCommandVar, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "HOMELAB_DB_PASSWORD") {
+		t.Errorf("expected the error to name the COMMAND variable, got: %v", err)
+	}
+}
+
+func TestDefaultConfigurer_LoadConfig_FromStdinAllowsCommandVarsWhenEnabled(t *testing.T) {
+	configurer := (&DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+		stdin:            strings.NewReader(commandConfigJSON),
+	}).WithStdinCommands(true)
+
+	result, err := configurer.LoadConfig(StdinConfigPath)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result.Sections) != 1 || result.Sections[0].Vars[0].Type != "COMMAND" {
+		t.Errorf("expected the COMMAND variable to be loaded, got: %+v", result)
+	}
+}
+
 func TestDefaultConfigurer_LoadConfig_FileNotFound(t *testing.T) {
 	configurer := &DefaultConfigurer{
 		prompter:         &mockPrompter{},
@@ -285,6 +372,48 @@ func TestDefaultConfigurer_ProcessConfig_Success(t *testing.T) {
 	}
 }
 
+func TestDefaultConfigurer_ProcessConfig_UsesCustomCharsetPools(t *testing.T) {
+	spec := "NOAMBIG:64"
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: NewStrategyRegistry(&mockPrompter{}, &mockFiles{}, false),
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+	}
+	root := &ConfigRoot{
+		Prefix:       "TEST",
+		CharsetPools: map[string]string{"noambig": "xyz"},
+		Sections: []ConfigSection{
+			{Name: "DB", Vars: []ConfigVar{{Name: "PASSWORD", Type: "GENERATED", Value: &spec}}},
+		},
+	}
+
+	result, err := configurer.ProcessConfig(root)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if value := result.Sections[0].Vars[0].Value; !regexp.MustCompile(`^[xyz]{64}$`).MatchString(value) {
+		t.Errorf("expected a 64 characters value from the custom pool, got %q", value)
+	}
+}
+
+func TestDefaultConfigurer_ProcessConfig_InvalidCharsetPool(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+	}
+	root := &ConfigRoot{Prefix: "TEST", CharsetPools: map[string]string{"ALL": "abc"}}
+
+	_, err := configurer.ProcessConfig(root)
+
+	if !errors.Is(err, ErrInvalidCharsetPool) {
+		t.Errorf("expected ErrInvalidCharsetPool, got: %v", err)
+	}
+}
+
 func TestDefaultConfigurer_ProcessConfig_EmptyConfig(t *testing.T) {
 	configurer := &DefaultConfigurer{
 		prompter:         &mockPrompter{},
@@ -408,11 +537,16 @@ func TestDefaultConfigurer_WriteConfig_Success(t *testing.T) {
 		prompter:         &mockPrompter{},
 		strategyRegistry: &mockStrategyRegistry{},
 		textFormatter:    testTextFormatter,
+		time: &mockTime{
+			now: func() time.Time {
+				return time.Unix(1700000000, 0)
+			},
+		},
 		files: &mockFiles{
 			getwd: func() (dir string, err error) {
 				return "/home/user", nil
 			},
-			writeFile: func(path string, data []byte) error {
+			writeNewFile: func(path string, data []byte) error {
 				capturedPath = path
 				capturedData = data
 				return nil
@@ -425,11 +559,9 @@ func TestDefaultConfigurer_WriteConfig_Success(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	if !strings.HasPrefix(capturedPath, "/home/user/.env.generated.") {
-		t.Errorf("expected path to start with '/home/user/.env.generated.', got %q", capturedPath)
-	}
-	if !strings.HasSuffix(capturedPath, ".env") {
-		t.Errorf("expected path to end with '.env', got %q", capturedPath)
+	expectedPath := "/home/user/.env.generated.1700000000.env"
+	if capturedPath != expectedPath {
+		t.Errorf("expected path %q, got %q", expectedPath, capturedPath)
 	}
 	if diff := cmp.Diff(generatedEnv, string(capturedData)); diff != "" {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
@@ -465,6 +597,7 @@ func TestDefaultConfigurer_WriteConfig_ErrorWhenGetwd(t *testing.T) {
 		prompter:         &mockPrompter{},
 		strategyRegistry: &mockStrategyRegistry{},
 		textFormatter:    testTextFormatter,
+		time:             &mockTime{},
 		files: &mockFiles{
 			getwd: func() (dir string, err error) {
 				return "", expectedErr
@@ -491,11 +624,12 @@ func TestDefaultConfigurer_WriteConfig_ErrorWhenWriteFile(t *testing.T) {
 		prompter:         &mockPrompter{},
 		strategyRegistry: &mockStrategyRegistry{},
 		textFormatter:    testTextFormatter,
+		time:             &mockTime{},
 		files: &mockFiles{
 			getwd: func() (dir string, err error) {
 				return "/home/user", nil
 			},
-			writeFile: func(path string, data []byte) error {
+			writeNewFile: func(path string, data []byte) error {
 				return expectedErr
 			},
 		},
@@ -504,7 +638,7 @@ func TestDefaultConfigurer_WriteConfig_ErrorWhenWriteFile(t *testing.T) {
 	err := configurer.WriteConfig(envVarRoot)
 
 	if err == nil {
-		t.Fatal("expected error when WriteFile fails, got nil")
+		t.Fatal("expected error when WriteNewFile fails, got nil")
 	}
 	if !errors.Is(err, ErrConfigFileWrite) {
 		t.Errorf("expected ErrConfigFileWrite, got: %v", err)
@@ -513,3 +647,578 @@ func TestDefaultConfigurer_WriteConfig_ErrorWhenWriteFile(t *testing.T) {
 		t.Errorf("expected error to wrap expectedErr, got: %v", err)
 	}
 }
+
+func TestDefaultConfigurer_WriteConfig_SameSecondDoesNotOverwrite(t *testing.T) {
+	existingFiles := map[string]bool{}
+	var writtenPaths []string
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    testTextFormatter,
+		time: &mockTime{
+			now: func() time.Time {
+				return time.Unix(1700000000, 0)
+			},
+		},
+		files: &mockFiles{
+			getwd: func() (dir string, err error) {
+				return "/home/user", nil
+			},
+			writeNewFile: func(path string, data []byte) error {
+				if existingFiles[path] {
+					return system.ErrFileAlreadyExists
+				}
+				existingFiles[path] = true
+				writtenPaths = append(writtenPaths, path)
+				return nil
+			},
+		},
+	}
+
+	if err := configurer.WriteConfig(envVarRoot); err != nil {
+		t.Fatalf("expected no error on first write, got %v", err)
+	}
+	if err := configurer.WriteConfig(envVarRoot); err != nil {
+		t.Fatalf("expected no error on second write, got %v", err)
+	}
+
+	expectedPaths := []string{
+		"/home/user/.env.generated.1700000000.env",
+		"/home/user/.env.generated.1700000000-1.env",
+	}
+	if diff := cmp.Diff(expectedPaths, writtenPaths); diff != "" {
+		t.Errorf("written paths mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultConfigurer_WriteConfig_WritesIntoOut(t *testing.T) {
+	var out strings.Builder
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    testTextFormatter,
+		files: &mockFiles{
+			writeNewFile: func(path string, data []byte) error {
+				t.Errorf("expected no file to be written, got %q", path)
+				return nil
+			},
+		},
+		out: &out,
+	}
+
+	err := configurer.WriteConfig(envVarRoot)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if diff := cmp.Diff(generatedEnv, out.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewConfigurer_FullFlowWithInMemoryIO(t *testing.T) {
+	answers := strings.NewReader("db.local\ns3cr3t\nMyServer\n")
+	var prompts strings.Builder
+	var out strings.Builder
+	configurer := NewConfigurer(NewIOPrompter(answers, &prompts), &mockFiles{}, &out, false)
+
+	root, err := configurer.ReadConfig(strings.NewReader(configJSON))
+	if err != nil {
+		t.Fatalf("expected no error reading config, got %v", err)
+	}
+	envVars, err := configurer.ProcessConfig(root)
+	if err != nil {
+		t.Fatalf("expected no error processing config, got %v", err)
+	}
+	err = configurer.WriteConfig(envVars)
+
+	if err != nil {
+		t.Fatalf("expected no error writing config, got %v", err)
+	}
+	for _, line := range []string{
+		`TEST_DATABASE_HOST="db.local"`,
+		`TEST_DATABASE_PASSWORD="s3cr3t"`,
+		`TEST_SERVER_NAME="MyServer"`,
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+	if !strings.Contains(prompts.String(), "Enter value for TEST_DATABASE_HOST (STRING): ") {
+		t.Errorf("expected the prompts to be written into the prompter's writer, got:\n%s", prompts.String())
+	}
+}
+
+func TestDefaultConfigurer_DiffConfig_ReportsAddedKey(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+		env: &mockEnv{
+			getAllEnvFunc: func() map[string]string {
+				return map[string]string{
+					"TEST_DATABASE_HOST":     "TEST_DATABASE_HOST#127.0.0.1#value",
+					"TEST_DATABASE_PASSWORD": "TEST_DATABASE_PASSWORD##value",
+				}
+			},
+		},
+	}
+
+	result := configurer.DiffConfig(envVarRoot)
+
+	expected := &EnvVarDiff{
+		Added: []EnvVarChange{
+			{Name: "TEST_SERVER_NAME", NewValue: "TEST_SERVER_NAME#MyServer#value"},
+		},
+		Changed: []EnvVarChange{},
+		Removed: []string{},
+	}
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultConfigurer_DiffConfig_ReportsChangedValue(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+		env: &mockEnv{
+			getAllEnvFunc: func() map[string]string {
+				return map[string]string{
+					"TEST_DATABASE_HOST":     "10.0.0.1",
+					"TEST_DATABASE_PASSWORD": "TEST_DATABASE_PASSWORD##value",
+					"TEST_SERVER_NAME":       "TEST_SERVER_NAME#MyServer#value",
+				}
+			},
+		},
+	}
+
+	result := configurer.DiffConfig(envVarRoot)
+
+	expected := &EnvVarDiff{
+		Added: []EnvVarChange{},
+		Changed: []EnvVarChange{
+			{Name: "TEST_DATABASE_HOST", OldValue: "10.0.0.1", NewValue: "TEST_DATABASE_HOST#127.0.0.1#value"},
+		},
+		Removed: []string{},
+	}
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultConfigurer_DiffConfig_ReportsRemovedKeys(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+		env: &mockEnv{
+			getAllEnvFunc: func() map[string]string {
+				return map[string]string{
+					"TEST_DATABASE_HOST":     "TEST_DATABASE_HOST#127.0.0.1#value",
+					"TEST_DATABASE_PASSWORD": "TEST_DATABASE_PASSWORD##value",
+					"TEST_SERVER_NAME":       "TEST_SERVER_NAME#MyServer#value",
+					"TEST_OLD_VAR_2":         "old",
+					"TEST_OLD_VAR_1":         "old",
+				}
+			},
+		},
+	}
+
+	result := configurer.DiffConfig(envVarRoot)
+
+	expected := &EnvVarDiff{
+		Added:   []EnvVarChange{},
+		Changed: []EnvVarChange{},
+		Removed: []string{"TEST_OLD_VAR_1", "TEST_OLD_VAR_2"},
+	}
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultConfigurer_DiffConfig_RedactsSensitiveValues(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+		env: &mockEnv{
+			getAllEnvFunc: func() map[string]string {
+				return map[string]string{
+					"TEST_DB_PASSWORD": "old-secret",
+				}
+			},
+		},
+	}
+	root := &EnvVarRoot{
+		Sections: []EnvVarSection{
+			{
+				Name: "TEST_DB",
+				Vars: []EnvVar{
+					{Name: "TEST_DB_PASSWORD", Type: "GENERATED", Value: "new-secret"},
+					{Name: "TEST_DB_TOKEN", Type: "GENERATED", Value: "token"},
+				},
+			},
+		},
+	}
+
+	result := configurer.DiffConfig(root)
+
+	expected := &EnvVarDiff{
+		Added: []EnvVarChange{
+			{Name: "TEST_DB_TOKEN", NewValue: redactedValue},
+		},
+		Changed: []EnvVarChange{
+			{Name: "TEST_DB_PASSWORD", OldValue: redactedValue, NewValue: redactedValue},
+		},
+		Removed: []string{},
+	}
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultConfigurer_DiffConfig_RedactsSensitiveNames(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{},
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+		env: &mockEnv{
+			getAllEnvFunc: func() map[string]string {
+				return map[string]string{
+					"TEST_SMTP_PASSWORD": "old-secret",
+					"TEST_SMTP_HOST":     "old.example.com",
+				}
+			},
+		},
+	}
+	root := &EnvVarRoot{
+		Sections: []EnvVarSection{
+			{
+				Name: "TEST_SMTP",
+				Vars: []EnvVar{
+					{Name: "TEST_SMTP_PASSWORD", Type: "STRING", Value: "new-secret"},
+					{Name: "TEST_SMTP_HOST", Type: "STRING", Value: "new.example.com"},
+				},
+			},
+		},
+	}
+
+	result := configurer.DiffConfig(root)
+
+	expected := &EnvVarDiff{
+		Added: []EnvVarChange{},
+		Changed: []EnvVarChange{
+			{Name: "TEST_SMTP_PASSWORD", OldValue: redactedValue, NewValue: redactedValue},
+			{Name: "TEST_SMTP_HOST", OldValue: "old.example.com", NewValue: "new.example.com"},
+		},
+		Removed: []string{},
+	}
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultConfigurer_LoadAnswers_Success(t *testing.T) {
+	configurer := &DefaultConfigurer{
+		files: &mockFiles{
+			readFile: func(path string) ([]byte, error) {
+				return []byte("# Answers\nTEST_DATABASE_PASSWORD=\"p@ss word\"\nexport TEST_SERVER_NAME=Server # comment\n\n"), nil
+			},
+		},
+	}
+
+	answers, err := configurer.LoadAnswers("answers.env")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := map[string]string{
+		"TEST_DATABASE_PASSWORD": "p@ss word",
+		"TEST_SERVER_NAME":       "Server",
+	}
+	if diff := cmp.Diff(expected, answers); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultConfigurer_LoadAnswers_ErrorWhenReadFile(t *testing.T) {
+	expectedErr := errors.New("read failed")
+	configurer := &DefaultConfigurer{
+		files: &mockFiles{
+			readFile: func(path string) ([]byte, error) {
+				return nil, expectedErr
+			},
+		},
+	}
+
+	_, err := configurer.LoadAnswers("answers.env")
+
+	if !errors.Is(err, ErrAnswersFileRead) {
+		t.Errorf("expected ErrAnswersFileRead, got: %v", err)
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected wrapped error %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestDefaultConfigurer_ProcessConfig_StrictWithCompleteAnswers(t *testing.T) {
+	configurer := (&DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: testStrategyRegistry,
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+	}).WithAnswers(map[string]string{"TEST_DATABASE_PASSWORD": "secret"}, true)
+
+	result, err := configurer.ProcessConfig(configRoot)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// The answer replaces the value of the strategy, and the other variables keep being acquired normally
+	got := result.Sections[0].Vars[1].Value
+	if got != "secret" {
+		t.Errorf("expected answered value %q, got %q", "secret", got)
+	}
+	got = result.Sections[0].Vars[0].Value
+	if got != "TEST_DATABASE_HOST#127.0.0.1#value" {
+		t.Errorf("expected acquired value %q, got %q", "TEST_DATABASE_HOST#127.0.0.1#value", got)
+	}
+}
+
+func TestDefaultConfigurer_ProcessConfig_StrictWithMissingRequiredAnswer(t *testing.T) {
+	acquired := false
+	configurer := (&DefaultConfigurer{
+		prompter: &mockPrompter{},
+		strategyRegistry: &mockStrategyRegistry{
+			getFunc: func(varType string) (AcquireStrategy, error) {
+				return &mockStrategy{
+					acquireFunc: func(varName string, defaultSpec *string) (string, error) {
+						acquired = true
+						return "value", nil
+					},
+				}, nil
+			},
+		},
+		textFormatter: &mockTextFormatter{},
+		files:         &mockFiles{},
+	}).WithAnswers(map[string]string{"TEST_SERVER_NAME": "Server"}, true)
+
+	_, err := configurer.ProcessConfig(configRoot)
+
+	if !errors.Is(err, ErrMissingAnswers) {
+		t.Fatalf("expected ErrMissingAnswers, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "TEST_DATABASE_PASSWORD") {
+		t.Errorf("expected error message to contain var name %q, got %q", "TEST_DATABASE_PASSWORD", err.Error())
+	}
+	if strings.Contains(err.Error(), "TEST_DATABASE_HOST") {
+		t.Errorf("expected defaulted var %q not to be listed, got %q", "TEST_DATABASE_HOST", err.Error())
+	}
+	if acquired {
+		t.Error("expected no variable to be acquired before failing")
+	}
+}
+
+func TestDefaultConfigurer_ProcessConfig_InvalidPortAnswer(t *testing.T) {
+	configurer := (&DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: NewStrategyRegistry(&mockPrompter{}, &mockFiles{}, false),
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+	}).WithAnswers(map[string]string{"TEST_WEB_PORT": "70000"}, true)
+	root := &ConfigRoot{
+		Prefix:   "TEST",
+		Sections: []ConfigSection{{Name: "WEB", Vars: []ConfigVar{{Name: "PORT", Type: "PORT"}}}},
+	}
+
+	_, err := configurer.ProcessConfig(root)
+
+	if !errors.Is(err, ErrInvalidAnswer) {
+		t.Fatalf("expected ErrInvalidAnswer, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "TEST_WEB_PORT") {
+		t.Errorf("expected error message to contain var name %q, got %q", "TEST_WEB_PORT", err.Error())
+	}
+}
+
+func TestDefaultConfigurer_ProcessConfig_NormalizesAnswers(t *testing.T) {
+	spec := "debug|info"
+	configurer := (&DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: NewStrategyRegistry(&mockPrompter{}, &mockFiles{}, false),
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+	}).WithAnswers(map[string]string{"TEST_WEB_PORT": " 8080 ", "TEST_WEB_LOG_LEVEL": "INFO"}, false)
+	root := &ConfigRoot{
+		Prefix: "TEST",
+		Sections: []ConfigSection{{Name: "WEB", Vars: []ConfigVar{
+			{Name: "PORT", Type: "PORT"},
+			{Name: "LOG_LEVEL", Type: "ENUM", Value: &spec},
+		}}},
+	}
+
+	result, err := configurer.ProcessConfig(root)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := result.Sections[0].Vars[0].Value; got != "8080" {
+		t.Errorf("expected port %q, got %q", "8080", got)
+	}
+	if got := result.Sections[0].Vars[1].Value; got != "info" {
+		t.Errorf("expected enum value %q, got %q", "info", got)
+	}
+}
+
+func TestDefaultConfigurer_ProcessConfig_StrictIgnoresExtraAnswers(t *testing.T) {
+	configurer := (&DefaultConfigurer{
+		prompter:         &mockPrompter{},
+		strategyRegistry: testStrategyRegistry,
+		textFormatter:    &mockTextFormatter{},
+		files:            &mockFiles{},
+	}).WithAnswers(map[string]string{"TEST_DATABASE_PASSWORD": "secret", "TEST_UNKNOWN_VAR": "ignored"}, true)
+
+	result, err := configurer.ProcessConfig(configRoot)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, section := range result.Sections {
+		for _, envVar := range section.Vars {
+			if envVar.Name == "TEST_UNKNOWN_VAR" {
+				t.Errorf("expected extra answer to be ignored, got var %q", envVar.Name)
+			}
+		}
+	}
+}
+
+func TestIsRequiredVar(t *testing.T) {
+	value := "value"
+	tests := []struct {
+		name           string
+		configVar      ConfigVar
+		nonInteractive bool
+		expected       bool
+	}{
+		{name: "without value", configVar: ConfigVar{Type: "STRING"}, nonInteractive: true, expected: true},
+		{name: "with value", configVar: ConfigVar{Type: "STRING", Value: &value}, nonInteractive: true, expected: false},
+		{name: "interactive string with value", configVar: ConfigVar{Type: "STRING", Value: &value}, expected: true},
+		{name: "constant", configVar: ConfigVar{Type: "CONSTANT", Value: &value}, expected: false},
+		{name: "generated", configVar: ConfigVar{Type: "GENERATED", Value: &value}, expected: false},
+		{name: "path with value", configVar: ConfigVar{Type: "path", Value: &value}, nonInteractive: true, expected: true},
+		{name: "number with range", configVar: ConfigVar{Type: "NUMBER", Value: &value}, nonInteractive: true, expected: true},
+		{name: "port with value", configVar: ConfigVar{Type: "PORT", Value: &value}, nonInteractive: true, expected: true},
+		{name: "enum with options", configVar: ConfigVar{Type: "enum", Value: &value}, nonInteractive: true, expected: true},
+		{name: "url with schemes", configVar: ConfigVar{Type: "URL", Value: &value}, nonInteractive: true, expected: true},
+		{name: "email with value", configVar: ConfigVar{Type: "EMAIL", Value: &value}, nonInteractive: true, expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := NewStrategyRegistry(&mockPrompter{}, &mockFiles{}, tt.nonInteractive).Get(tt.configVar.Type)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if got := isRequiredVar(tt.configVar, strategy); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLatestGeneratedConfig_ChoosesHighestTimestamp(t *testing.T) {
+	files := &mockFiles{
+		listFiles: func(path string) ([]os.FileInfo, error) {
+			return []os.FileInfo{
+				&mockFileInfo{name: ".env.generated.1700000100.env"},
+				// Timestamps are compared as numbers, so this older one must not win despite sorting last as text
+				&mockFileInfo{name: ".env.generated.999999999.env"},
+				&mockFileInfo{name: ".env.generated.1700000200.env"},
+				&mockFileInfo{name: ".env.generated.1700000200-2.env"},
+				&mockFileInfo{name: ".env.generated.1700000200-1.env"},
+				&mockFileInfo{name: ".env.generated.1700000000.env"},
+			}, nil
+		},
+	}
+
+	latest, err := latestGeneratedConfig(files, "/homelab")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if latest != "/homelab/.env.generated.1700000200-2.env" {
+		t.Errorf("expected %q, got %q", "/homelab/.env.generated.1700000200-2.env", latest)
+	}
+}
+
+func TestLatestGeneratedConfig_SkipsMalformedNames(t *testing.T) {
+	files := &mockFiles{
+		listFiles: func(path string) ([]os.FileInfo, error) {
+			return []os.FileInfo{
+				&mockFileInfo{name: ".env"},
+				&mockFileInfo{name: ".env.generated.1700000000.env"},
+				&mockFileInfo{name: ".env.generated.9999999999.env.bak"},
+				&mockFileInfo{name: ".env.generated.latest.env"},
+				&mockFileInfo{name: ".env.generated.9999999999-.env"},
+				&mockFileInfo{name: "x.env.generated.9999999999.env"},
+			}, nil
+		},
+	}
+
+	latest, err := latestGeneratedConfig(files, "/homelab")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if latest != "/homelab/.env.generated.1700000000.env" {
+		t.Errorf("expected %q, got %q", "/homelab/.env.generated.1700000000.env", latest)
+	}
+}
+
+func TestLatestGeneratedConfig_NoGeneratedFiles(t *testing.T) {
+	files := &mockFiles{
+		listFiles: func(path string) ([]os.FileInfo, error) {
+			return []os.FileInfo{&mockFileInfo{name: ".env"}, &mockFileInfo{name: ".env.generated.bad.env"}}, nil
+		},
+	}
+
+	_, err := latestGeneratedConfig(files, "/homelab")
+
+	if !errors.Is(err, ErrNoGeneratedConfig) {
+		t.Errorf("expected ErrNoGeneratedConfig, got: %v", err)
+	}
+}
+
+func TestLatestGeneratedConfig_ErrorWhenListFiles(t *testing.T) {
+	expectedErr := errors.New("permission denied")
+	files := &mockFiles{
+		listFiles: func(path string) ([]os.FileInfo, error) {
+			return nil, expectedErr
+		},
+	}
+
+	_, err := latestGeneratedConfig(files, "/homelab")
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestParseGeneratedConfigFilename_RoundTrip(t *testing.T) {
+	for _, attempt := range []int{0, 1, 42} {
+		name := generatedConfigFilename(1700000000, attempt)
+
+		timestamp, gotAttempt, ok := parseGeneratedConfigFilename(name)
+
+		if !ok || timestamp != 1700000000 || gotAttempt != attempt {
+			t.Errorf("expected (1700000000, %d, true) for %q, got (%d, %d, %v)", attempt, name, timestamp, gotAttempt, ok)
+		}
+	}
+}
@@ -19,13 +19,17 @@ type ConfigSection struct {
 
 // ConfigRoot represents the root structure of the JSON config file
 type ConfigRoot struct {
-	Prefix   string          `json:"prefix"`
-	Sections []ConfigSection `json:"sections"`
+	Prefix string `json:"prefix"`
+	// CharsetPools contains custom charsets for GENERATED variables, keyed by the name used in their specs (e.g.
+	// {"NOAMBIG": "abcdefghjkmnpqrstuvwxyz23456789"} for the spec "NOAMBIG:32")
+	CharsetPools map[string]string `json:"charsetPools"`
+	Sections     []ConfigSection   `json:"sections"`
 }
 
 // EnvVar represents a single environment variable with its metadata
 type EnvVar struct {
 	Name        string
+	Type        string
 	Description string
 	Value       string
 }
@@ -41,3 +45,21 @@ type EnvVarSection struct {
 type EnvVarRoot struct {
 	Sections []EnvVarSection
 }
+
+// EnvVarChange represents a variable whose value differs between the current environment and a processed configuration
+type EnvVarChange struct {
+	Name     string
+	OldValue string
+	NewValue string
+}
+
+// EnvVarDiff contains the differences between the current environment and a processed configuration
+type EnvVarDiff struct {
+	// Added contains the variables that exist in the processed configuration but not in the current environment
+	Added []EnvVarChange
+	// Changed contains the variables that exist in both places but with different values
+	Changed []EnvVarChange
+	// Removed contains the names of the variables that exist in the current environment but not in the processed
+	// configuration
+	Removed []string
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+// Promotion of generated .env files into the active .env file
+
+// dotenvBackupSuffix is added to the name of the active .env file to build the name of its backup
+const dotenvBackupSuffix = ".bak"
+
+// DotenvPromoter replaces the active .env file with a .env file generated by the configurer
+type DotenvPromoter struct {
+	prompter Prompter
+	files    system.FilesHandler
+}
+
+func NewDotenvPromoter() *DotenvPromoter {
+	return &DotenvPromoter{
+		prompter: NewConsolePrompter(),
+		files:    system.NewDefaultFilesHandler(),
+	}
+}
+
+// Promote copies generatedPath into dotenvPath, after the user confirms it. The current content of dotenvPath, if
+// it exists, is first backed up into the same path with a .bak suffix. If generatedPath is empty, the most recent
+// generated file of the working directory is promoted
+func (p *DotenvPromoter) Promote(generatedPath string, dotenvPath string) error {
+	if generatedPath == "" {
+		wd, err := p.files.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		latest, err := latestGeneratedConfig(p.files, wd)
+		if err != nil {
+			return err
+		}
+		generatedPath = latest
+	}
+
+	generated, err := p.files.ReadFile(generatedPath)
+	if err != nil {
+		return err
+	}
+	current, err := p.files.ReadFile(dotenvPath)
+	dotenvExists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	backupPath := dotenvPath + dotenvBackupSuffix
+	question := fmt.Sprintf("Replace %s with %s? [y/N]: ", dotenvPath, generatedPath)
+	if dotenvExists {
+		question = fmt.Sprintf("Replace %s with %s? The current file is backed up to %s [y/N]: ", dotenvPath, generatedPath, backupPath)
+	}
+	answer, err := p.prompter.Prompt(question)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains([]string{"y", "yes"}, strings.ToLower(strings.TrimSpace(answer))) {
+		p.prompter.Info("Not promoting the generated file")
+		return nil
+	}
+
+	if dotenvExists {
+		if err := p.files.WriteSecretFile(backupPath, current); err != nil {
+			return fmt.Errorf("%w %q: %w", ErrConfigFileWrite, backupPath, err)
+		}
+		slog.Info("Backed up the current .env file", "backupPath", backupPath)
+	}
+	if err := p.files.WriteSecretFile(dotenvPath, generated); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrConfigFileWrite, dotenvPath, err)
+	}
+
+	slog.Info("Promoted generated .env file", "generatedPath", generatedPath, "dotenvPath", dotenvPath)
+	return nil
+}
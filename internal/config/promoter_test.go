@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newPromoterFiles returns a mock whose files are stored in contents, keyed by their path
+func newPromoterFiles(contents map[string]string) *mockFiles {
+	return &mockFiles{
+		getwd: func() (string, error) { return "/homelab", nil },
+		readFile: func(path string) ([]byte, error) {
+			content, ok := contents[path]
+			if !ok {
+				return nil, fmt.Errorf("%w %q: %w", system.ErrFailedToReadFile, path, fs.ErrNotExist)
+			}
+			return []byte(content), nil
+		},
+		writeSecretFile: func(path string, data []byte) error {
+			contents[path] = string(data)
+			return nil
+		},
+		listFiles: func(path string) ([]os.FileInfo, error) {
+			return []os.FileInfo{
+				&mockFileInfo{name: ".env"},
+				&mockFileInfo{name: ".env.generated.1700000000.env"},
+				&mockFileInfo{name: ".env.generated.1700000100.env"},
+				&mockFileInfo{name: "docker-compose.yml"},
+			}, nil
+		},
+	}
+}
+
+func TestDotenvPromoter_Promote_BacksUpAndReplacesDotenv(t *testing.T) {
+	contents := map[string]string{
+		"/homelab/.env":                          "HOMELAB_A=old\n",
+		"/homelab/.env.generated.1700000000.env": "HOMELAB_A=first\n",
+	}
+	var prompted string
+	promoter := &DotenvPromoter{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				prompted = message
+				return "y", nil
+			},
+		},
+		files: newPromoterFiles(contents),
+	}
+
+	err := promoter.Promote("/homelab/.env.generated.1700000000.env", "/homelab/.env")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := map[string]string{
+		"/homelab/.env":                          "HOMELAB_A=first\n",
+		"/homelab/.env.bak":                      "HOMELAB_A=old\n",
+		"/homelab/.env.generated.1700000000.env": "HOMELAB_A=first\n",
+	}
+	if diff := cmp.Diff(expected, contents); diff != "" {
+		t.Errorf("files mismatch (-want +got):\n%s", diff)
+	}
+	if prompted == "" {
+		t.Error("expected the user to be asked for confirmation")
+	}
+}
+
+func TestDotenvPromoter_Promote_DefaultsToLatestGeneratedFile(t *testing.T) {
+	contents := map[string]string{
+		"/homelab/.env":                          "HOMELAB_A=old\n",
+		"/homelab/.env.generated.1700000000.env": "HOMELAB_A=first\n",
+		"/homelab/.env.generated.1700000100.env": "HOMELAB_A=latest\n",
+	}
+	promoter := &DotenvPromoter{
+		prompter: &mockPrompter{promptFunc: func(message string) (string, error) { return "yes", nil }},
+		files:    newPromoterFiles(contents),
+	}
+
+	err := promoter.Promote("", "/homelab/.env")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if contents["/homelab/.env"] != "HOMELAB_A=latest\n" {
+		t.Errorf("expected .env to contain the latest generated file, got %q", contents["/homelab/.env"])
+	}
+}
+
+func TestDotenvPromoter_Promote_WithoutCurrentDotenv(t *testing.T) {
+	contents := map[string]string{
+		"/homelab/.env.generated.1700000000.env": "HOMELAB_A=first\n",
+	}
+	promoter := &DotenvPromoter{
+		prompter: &mockPrompter{promptFunc: func(message string) (string, error) { return "y", nil }},
+		files:    newPromoterFiles(contents),
+	}
+
+	err := promoter.Promote("/homelab/.env.generated.1700000000.env", "/homelab/.env")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, ok := contents["/homelab/.env.bak"]; ok {
+		t.Error("expected no backup when there is no current .env file")
+	}
+	if contents["/homelab/.env"] != "HOMELAB_A=first\n" {
+		t.Errorf("expected .env to contain the generated file, got %q", contents["/homelab/.env"])
+	}
+}
+
+func TestDotenvPromoter_Promote_NotConfirmed(t *testing.T) {
+	contents := map[string]string{
+		"/homelab/.env":                          "HOMELAB_A=old\n",
+		"/homelab/.env.generated.1700000000.env": "HOMELAB_A=first\n",
+	}
+	promoter := &DotenvPromoter{
+		prompter: &mockPrompter{promptFunc: func(message string) (string, error) { return "n", nil }},
+		files:    newPromoterFiles(contents),
+	}
+
+	err := promoter.Promote("/homelab/.env.generated.1700000000.env", "/homelab/.env")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if contents["/homelab/.env"] != "HOMELAB_A=old\n" {
+		t.Errorf("expected .env to be untouched, got %q", contents["/homelab/.env"])
+	}
+	if _, ok := contents["/homelab/.env.bak"]; ok {
+		t.Error("expected no backup when the promotion is not confirmed")
+	}
+}
+
+func TestDotenvPromoter_Promote_NoGeneratedFile(t *testing.T) {
+	files := newPromoterFiles(map[string]string{})
+	files.listFiles = func(path string) ([]os.FileInfo, error) {
+		return []os.FileInfo{&mockFileInfo{name: ".env"}}, nil
+	}
+	promoter := &DotenvPromoter{prompter: &mockPrompter{}, files: files}
+
+	err := promoter.Promote("", "/homelab/.env")
+
+	if !errors.Is(err, ErrNoGeneratedConfig) {
+		t.Errorf("expected ErrNoGeneratedConfig, got: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"strings"
 )
 
@@ -14,6 +15,8 @@ import (
 // Prompter defines the interface for user interaction (prompting and displaying info)
 type Prompter interface {
 	Prompt(message string) (string, error)
+	// PromptSecret is like Prompt, but the input is not shown on the screen, so that it can be a password
+	PromptSecret(message string) (string, error)
 	Info(message string)
 }
 
@@ -25,13 +28,25 @@ var (
 type ConsolePrompter struct {
 	reader *bufio.Reader
 	writer io.Writer
+	// terminal is the terminal that the answers are typed into, whose echo is turned off to read secrets. Secrets are
+	// read like any other answer when it is nil
+	terminal *os.File
 }
 
 // NewConsolePrompter creates a new console-based prompter
 func NewConsolePrompter() *ConsolePrompter {
+	prompter := NewIOPrompter(os.Stdin, os.Stdout)
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		prompter.terminal = os.Stdin
+	}
+	return prompter
+}
+
+// NewIOPrompter creates a prompter that reads the answers from reader and writes the messages into writer
+func NewIOPrompter(reader io.Reader, writer io.Writer) *ConsolePrompter {
 	return &ConsolePrompter{
-		reader: bufio.NewReader(os.Stdin),
-		writer: os.Stdout,
+		reader: bufio.NewReader(reader),
+		writer: writer,
 	}
 }
 
@@ -45,6 +60,36 @@ func (p *ConsolePrompter) Prompt(message string) (string, error) {
 	return strings.TrimSpace(input), nil
 }
 
+// PromptSecret displays a message and reads user input without echoing it, when the input is a terminal. The echo is
+// turned off with stty, so the input is shown if stty is not available. Only the line ending is removed from the input,
+// because the spaces around a password are part of it
+func (p *ConsolePrompter) PromptSecret(message string) (string, error) {
+	fmt.Fprint(p.writer, message)
+	if p.terminal != nil && setTerminalEcho(p.terminal, false) == nil {
+		defer func() {
+			_ = setTerminalEcho(p.terminal, true)
+			// The newline typed by the user was not echoed either
+			fmt.Fprintln(p.writer)
+		}()
+	}
+	input, err := p.reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrPrompterRead, err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(input, "\n"), "\r"), nil
+}
+
+// setTerminalEcho turns on or off the echo of the characters typed into terminal
+func setTerminalEcho(terminal *os.File, on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = terminal
+	return cmd.Run()
+}
+
 // Info displays an informational message
 func (p *ConsolePrompter) Info(message string) {
 	fmt.Fprintln(p.writer, message)
@@ -141,6 +141,42 @@ func TestConsolePrompter_Prompt_ReadError(t *testing.T) {
 	}
 }
 
+func TestConsolePrompter_PromptSecret_WithoutTerminal(t *testing.T) {
+	var output bytes.Buffer
+	prompter := &ConsolePrompter{
+		reader: bufio.NewReader(strings.NewReader("s3cret\n")),
+		writer: &output,
+	}
+
+	result, err := prompter.PromptSecret("Password: ")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if result != "s3cret" {
+		t.Errorf("expected result %q, got %q", "s3cret", result)
+	}
+	if output.String() != "Password: " {
+		t.Errorf("expected output %q, got %q", "Password: ", output.String())
+	}
+}
+
+func TestConsolePrompter_PromptSecret_KeepsSurroundingSpaces(t *testing.T) {
+	prompter := &ConsolePrompter{
+		reader: bufio.NewReader(strings.NewReader("  s3cret \r\n")),
+		writer: &bytes.Buffer{},
+	}
+
+	result, err := prompter.PromptSecret("Password: ")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if result != "  s3cret " {
+		t.Errorf("expected result %q, got %q", "  s3cret ", result)
+	}
+}
+
 func TestConsolePrompter_Info_Success(t *testing.T) {
 	var output bytes.Buffer
 	prompter := &ConsolePrompter{
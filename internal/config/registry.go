@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 )
 
 // Variable acquisition strategy registration and lookup
@@ -23,18 +25,34 @@ var (
 	ErrVarTypeNotSupported = errors.New("unsupported variable type")
 )
 
-// NewDefaultStrategyRegistry creates a new registry with default strategies
-func NewDefaultStrategyRegistry() *DefaultStrategyRegistry {
+// NewDefaultStrategyRegistry creates a new registry with default strategies. If nonInteractive is true, the
+// strategies that support it use the config's value instead of prompting the user
+func NewDefaultStrategyRegistry(nonInteractive bool) *DefaultStrategyRegistry {
+	return NewStrategyRegistry(NewConsolePrompter(), system.NewDefaultFilesHandler(), nonInteractive)
+}
+
+// NewStrategyRegistry creates a registry with the default strategies, which interact with the user through prompter
+// and create directories through files
+func NewStrategyRegistry(prompter Prompter, files system.FilesHandler, nonInteractive bool) *DefaultStrategyRegistry {
 	registry := &DefaultStrategyRegistry{
 		strategies: make(map[string]AcquireStrategy),
 	}
 
 	// Register default strategies
-	registry.Register("CONSTANT", NewConstantStrategy())
-	registry.Register("GENERATED", NewGeneratedStrategy())
-	registry.Register("IP", NewIPStrategy())
-	registry.Register("STRING", NewStringStrategy())
-	registry.Register("PATH", NewPathStrategy())
+	env := system.NewDefaultEnv()
+	commands := system.NewDefaultCommands()
+	registry.Register("CONSTANT", &ConstantStrategy{prompter: prompter})
+	registry.Register("GENERATED", &GeneratedStrategy{prompter: prompter, env: env})
+	registry.Register("COMMAND", &CommandStrategy{prompter: prompter, env: env, commands: commands})
+	registry.Register("IP", &IPStrategy{prompter: prompter, env: env, nonInteractive: nonInteractive})
+	registry.Register("STRING", &StringStrategy{prompter: prompter, env: env, nonInteractive: nonInteractive})
+	registry.Register("EMAIL", &EmailStrategy{prompter: prompter, env: env})
+	registry.Register("URL", &URLStrategy{prompter: prompter, env: env})
+	registry.Register("BOOL", &BoolStrategy{prompter: prompter, env: env, nonInteractive: nonInteractive})
+	registry.Register("NUMBER", &NumberStrategy{prompter: prompter, env: env})
+	registry.Register("ENUM", &EnumStrategy{prompter: prompter, env: env})
+	registry.Register("PORT", &PortStrategy{prompter: prompter, env: env})
+	registry.Register("PATH", &PathStrategy{prompter: prompter, env: env, files: files})
 
 	return registry
 }
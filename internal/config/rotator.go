@@ -0,0 +1,130 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/dotenv"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+// Rotation of GENERATED secrets
+
+var (
+	ErrVarNotFound     = errors.New("variable not found in config")
+	ErrVarNotRotatable = errors.New("only GENERATED variables can be rotated")
+	ErrVarNotInDotenv  = errors.New("variable not found in .env file")
+)
+
+// SecretRotator regenerates GENERATED secrets and updates them in a .env file
+type SecretRotator struct {
+	prompter     Prompter
+	files        system.FilesHandler
+	dockerRunner docker.Runner
+}
+
+func NewSecretRotator(dockerRunner docker.Runner) *SecretRotator {
+	return &SecretRotator{
+		prompter:     NewConsolePrompter(),
+		files:        system.NewDefaultFilesHandler(),
+		dockerRunner: dockerRunner,
+	}
+}
+
+// Rotate generates a new value for the GENERATED variable varName and replaces its value in the .env file at
+// dotenvPath. If services is not empty, the user is asked for confirmation before the services are restarted so
+// that they pick up the new value
+func (r *SecretRotator) Rotate(configRoot *ConfigRoot, varName string, dotenvPath string, services []string) error {
+	configVar, err := findConfigVar(configRoot, varName)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(configVar.Type, "GENERATED") {
+		return fmt.Errorf("%w: %q has type %q", ErrVarNotRotatable, varName, configVar.Type)
+	}
+	if configVar.Value == nil {
+		return fmt.Errorf("%w: %q", ErrNilDefaultSpec, varName)
+	}
+
+	customPools, err := normalizeCharsetPools(configRoot.CharsetPools)
+	if err != nil {
+		return err
+	}
+	pool, length, err := parseGeneratedSpec(*configVar.Value, customPools)
+	if err != nil {
+		return fmt.Errorf("%w %q: %w", ErrCantParseDefaultSpec, varName, err)
+	}
+	generated, err := generateSecret(pool, length)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCantGenerateSecret, err)
+	}
+
+	if err := r.replaceDotenvValue(dotenvPath, varName, generated); err != nil {
+		return err
+	}
+	r.prompter.Info(fmt.Sprintf("Generated a new secret value of length %d for %s and updated %s", length, varName, dotenvPath))
+
+	if len(services) == 0 {
+		return nil
+	}
+	answer, err := r.prompter.Prompt(fmt.Sprintf("Restart services %s now? [y/N]: ", strings.Join(services, ", ")))
+	if err != nil {
+		return err
+	}
+	if !slices.Contains([]string{"y", "yes"}, strings.ToLower(answer)) {
+		r.prompter.Info("Not restarting services. Restart them manually so that they use the new value")
+		return nil
+	}
+
+	slog.Info("Restarting services", "services", services)
+	if err := r.dockerRunner.ComposeStop(services); err != nil {
+		return fmt.Errorf("failed to stop services: %w", err)
+	}
+	if err := r.dockerRunner.ComposeStart(services); err != nil {
+		return fmt.Errorf("failed to start services: %w", err)
+	}
+	return nil
+}
+
+// findConfigVar finds a variable in the configuration by its full name (PREFIX_SECTION_NAME)
+func findConfigVar(configRoot *ConfigRoot, varName string) (*ConfigVar, error) {
+	for _, section := range configRoot.Sections {
+		for _, configVar := range section.Vars {
+			if fmt.Sprintf("%s_%s_%s", configRoot.Prefix, section.Name, configVar.Name) == varName {
+				return &configVar, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrVarNotFound, varName)
+}
+
+// replaceDotenvValue replaces the value of a variable in a .env file, keeping the rest of the file untouched
+func (r *SecretRotator) replaceDotenvValue(dotenvPath string, varName string, value string) error {
+	data, err := r.files.ReadFile(dotenvPath)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := dotenv.Parse(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %w", dotenvPath, err)
+	}
+	if _, found := parsed.Get(varName); !found {
+		return fmt.Errorf("%w: %q", ErrVarNotInDotenv, varName)
+	}
+	parsed.Set(varName, value)
+
+	var content bytes.Buffer
+	if err := parsed.Write(&content); err != nil {
+		return err
+	}
+	if err := r.files.WriteSecretFile(dotenvPath, content.Bytes()); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrConfigFileWrite, dotenvPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var rotatorPasswordSpec = "ALPHA:16"
+var rotatorHostValue = "localhost"
+var rotatorConfigRoot = &ConfigRoot{
+	Prefix: "TEST",
+	Sections: []ConfigSection{
+		{
+			Name: "DB",
+			Vars: []ConfigVar{
+				{Name: "HOST", Type: "CONSTANT", Value: &rotatorHostValue},
+				{Name: "PASSWORD", Type: "GENERATED", Value: &rotatorPasswordSpec},
+			},
+		},
+	},
+}
+var rotatorDotenv = `# Database host
+TEST_DB_HOST="localhost"
+# Database password
+TEST_DB_PASSWORD="old-password"
+`
+
+func TestSecretRotator_Rotate_GeneratesNewValueAndUpdatesDotenv(t *testing.T) {
+	var capturedPath string
+	var capturedData []byte
+	rotator := &SecretRotator{
+		prompter: &mockPrompter{},
+		files: &mockFiles{
+			readFile: func(path string) ([]byte, error) {
+				return []byte(rotatorDotenv), nil
+			},
+			writeSecretFile: func(path string, data []byte) error {
+				capturedPath = path
+				capturedData = data
+				return nil
+			},
+		},
+		dockerRunner: &mockDockerRunner{},
+	}
+
+	err := rotator.Rotate(rotatorConfigRoot, "TEST_DB_PASSWORD", "/home/user/.env", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedPath != "/home/user/.env" {
+		t.Errorf("expected path %q, got %q", "/home/user/.env", capturedPath)
+	}
+	lines := strings.Split(string(capturedData), "\n")
+	if !regexp.MustCompile(`^TEST_DB_PASSWORD="[A-Za-z0-9]{16}"$`).MatchString(lines[3]) {
+		t.Errorf("expected a new 16 characters value for TEST_DB_PASSWORD, got line %q", lines[3])
+	}
+	// The rest of the file must be kept untouched
+	expectedLines := strings.Split(rotatorDotenv, "\n")
+	expectedLines[3] = lines[3]
+	if diff := cmp.Diff(expectedLines, lines); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSecretRotator_Rotate_UsesCustomCharsetPool(t *testing.T) {
+	spec := "HEXLOWER:32"
+	root := &ConfigRoot{
+		Prefix:       "TEST",
+		CharsetPools: map[string]string{"HEXLOWER": "0123456789abcdef"},
+		Sections: []ConfigSection{
+			{Name: "DB", Vars: []ConfigVar{{Name: "PASSWORD", Type: "GENERATED", Value: &spec}}},
+		},
+	}
+	var capturedData []byte
+	rotator := &SecretRotator{
+		prompter: &mockPrompter{},
+		files: &mockFiles{
+			readFile: func(path string) ([]byte, error) {
+				return []byte(rotatorDotenv), nil
+			},
+			writeSecretFile: func(path string, data []byte) error {
+				capturedData = data
+				return nil
+			},
+		},
+		dockerRunner: &mockDockerRunner{},
+	}
+
+	err := rotator.Rotate(root, "TEST_DB_PASSWORD", "/home/user/.env", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	lines := strings.Split(string(capturedData), "\n")
+	if !regexp.MustCompile(`^TEST_DB_PASSWORD="[0-9a-f]{32}"$`).MatchString(lines[3]) {
+		t.Errorf("expected a new 32 characters hex value for TEST_DB_PASSWORD, got line %q", lines[3])
+	}
+}
+
+func TestSecretRotator_Rotate_RestartsServicesWhenConfirmed(t *testing.T) {
+	var stoppedServices []string
+	var startedServices []string
+	rotator := &SecretRotator{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				return "y", nil
+			},
+		},
+		files: &mockFiles{
+			readFile: func(path string) ([]byte, error) {
+				return []byte(rotatorDotenv), nil
+			},
+		},
+		dockerRunner: &mockDockerRunner{
+			composeStop: func(services []string) error {
+				stoppedServices = services
+				return nil
+			},
+			composeStart: func(services []string) error {
+				startedServices = services
+				return nil
+			},
+		},
+	}
+
+	err := rotator.Rotate(rotatorConfigRoot, "TEST_DB_PASSWORD", "/home/user/.env", []string{"db"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"db"}, stoppedServices); diff != "" {
+		t.Errorf("stopped services mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"db"}, startedServices); diff != "" {
+		t.Errorf("started services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSecretRotator_Rotate_DoesNotRestartWithoutConfirmation(t *testing.T) {
+	restarted := false
+	rotator := &SecretRotator{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				return "n", nil
+			},
+		},
+		files: &mockFiles{
+			readFile: func(path string) ([]byte, error) {
+				return []byte(rotatorDotenv), nil
+			},
+		},
+		dockerRunner: &mockDockerRunner{
+			composeStop: func(services []string) error {
+				restarted = true
+				return nil
+			},
+			composeStart: func(services []string) error {
+				restarted = true
+				return nil
+			},
+		},
+	}
+
+	err := rotator.Rotate(rotatorConfigRoot, "TEST_DB_PASSWORD", "/home/user/.env", []string{"db"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if restarted {
+		t.Errorf("expected services not to be restarted without confirmation")
+	}
+}
+
+func TestSecretRotator_Rotate_ErrorWhenVarIsNotGenerated(t *testing.T) {
+	written := false
+	rotator := &SecretRotator{
+		prompter: &mockPrompter{},
+		files: &mockFiles{
+			writeSecretFile: func(path string, data []byte) error {
+				written = true
+				return nil
+			},
+		},
+		dockerRunner: &mockDockerRunner{},
+	}
+
+	err := rotator.Rotate(rotatorConfigRoot, "TEST_DB_HOST", "/home/user/.env", nil)
+
+	if !errors.Is(err, ErrVarNotRotatable) {
+		t.Errorf("expected ErrVarNotRotatable, got: %v", err)
+	}
+	if written {
+		t.Errorf("expected .env not to be written")
+	}
+}
+
+func TestSecretRotator_Rotate_ErrorWhenVarNotInConfig(t *testing.T) {
+	rotator := &SecretRotator{
+		prompter:     &mockPrompter{},
+		files:        &mockFiles{},
+		dockerRunner: &mockDockerRunner{},
+	}
+
+	err := rotator.Rotate(rotatorConfigRoot, "TEST_DB_UNKNOWN", "/home/user/.env", nil)
+
+	if !errors.Is(err, ErrVarNotFound) {
+		t.Errorf("expected ErrVarNotFound, got: %v", err)
+	}
+}
+
+func TestSecretRotator_Rotate_ErrorWhenVarNotInDotenv(t *testing.T) {
+	rotator := &SecretRotator{
+		prompter: &mockPrompter{},
+		files: &mockFiles{
+			readFile: func(path string) ([]byte, error) {
+				return []byte("TEST_DB_HOST=\"localhost\"\n"), nil
+			},
+		},
+		dockerRunner: &mockDockerRunner{},
+	}
+
+	err := rotator.Rotate(rotatorConfigRoot, "TEST_DB_PASSWORD", "/home/user/.env", nil)
+
+	if !errors.Is(err, ErrVarNotInDotenv) {
+		t.Errorf("expected ErrVarNotInDotenv, got: %v", err)
+	}
+}
+
+func TestSecretRotator_Rotate_KeepsExportPrefixAndInlineComment(t *testing.T) {
+	var capturedData []byte
+	rotator := &SecretRotator{
+		prompter: &mockPrompter{},
+		files: &mockFiles{
+			readFile: func(path string) ([]byte, error) {
+				return []byte("export TEST_DB_PASSWORD=\"old-password\" # rotated yearly\n"), nil
+			},
+			writeSecretFile: func(path string, data []byte) error {
+				capturedData = data
+				return nil
+			},
+		},
+		dockerRunner: &mockDockerRunner{},
+	}
+
+	err := rotator.Rotate(rotatorConfigRoot, "TEST_DB_PASSWORD", "/home/user/.env", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !regexp.MustCompile(`^export TEST_DB_PASSWORD="[A-Za-z0-9]{16}" # rotated yearly\n$`).Match(capturedData) {
+		t.Errorf("expected the export prefix and inline comment to be kept, got %q", capturedData)
+	}
+}
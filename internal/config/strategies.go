@@ -1,11 +1,16 @@
 package config
 
 import (
+	"bytes"
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"math/big"
 	"net"
+	"net/mail"
+	"net/url"
 	"slices"
 	"strconv"
 	"strings"
@@ -18,14 +23,27 @@ import (
 // AcquireStrategy defines the interface for acquiring environment variable values
 type AcquireStrategy interface {
 	Acquire(varName string, defaultSpec *string) (string, error)
+	// Validate checks a value that was not entered at the prompt, such as an answer of an answers file, the same way
+	// Acquire checks the user's input. It returns the value as Acquire would store it
+	Validate(varName string, value string, defaultSpec *string) (string, error)
+	// UsesDefault returns whether Acquire uses the value of the variable in the config file without prompting the user.
+	// The value of some strategies is not a default, such as the range of a NUMBER variable
+	UsesDefault() bool
 }
 
 var (
 	ErrNilDefaultSpec       = errors.New("default spec must not be nil")
 	ErrCantParseDefaultSpec = errors.New("unable to parse default spec")
 	ErrCantGenerateSecret   = errors.New("unable to generate secret")
+	ErrInvalidCharsetPool   = errors.New("invalid charset pool")
+	ErrCommandFailed        = errors.New("command to acquire value failed")
 )
 
+// retryMessage tells the user why an answer is not valid, before prompting them again
+func retryMessage(err error) string {
+	return fmt.Sprintf("Invalid value: %v. Please try again.", err)
+}
+
 // ConstantStrategy returns a constant value
 type ConstantStrategy struct {
 	prompter Prompter
@@ -45,10 +63,20 @@ func (s *ConstantStrategy) Acquire(varName string, defaultSpec *string) (string,
 	return *defaultSpec, nil
 }
 
+func (s *ConstantStrategy) UsesDefault() bool {
+	return true
+}
+
+func (s *ConstantStrategy) Validate(_ string, value string, _ *string) (string, error) {
+	return value, nil
+}
+
 // GeneratedStrategy generates a random secret value
 type GeneratedStrategy struct {
 	prompter Prompter
 	env      system.Env
+	// customPools contains the custom charsets of the config file, keyed by their names in upper case
+	customPools map[string]string
 }
 
 func NewGeneratedStrategy() *GeneratedStrategy {
@@ -58,6 +86,9 @@ func NewGeneratedStrategy() *GeneratedStrategy {
 var charsetPools = map[string]string{
 	"ALL":   "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789%&*+-.:<>^_|~",
 	"ALPHA": "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	// SAFE excludes the characters that are easily mistaken for one another (0/O and 1/l/I), for values that a human
+	// may have to transcribe
+	"SAFE": "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789",
 }
 
 func (s *GeneratedStrategy) Acquire(varName string, defaultSpec *string) (string, error) {
@@ -69,12 +100,11 @@ func (s *GeneratedStrategy) Acquire(varName string, defaultSpec *string) (string
 		return val, nil
 	}
 
-	charsetName, length, err := parseGeneratedSpec(*defaultSpec)
+	pool, length, err := parseGeneratedSpec(*defaultSpec, s.customPools)
 	if err != nil {
 		return "", fmt.Errorf("%w %q: %w", ErrCantParseDefaultSpec, varName, err)
 	}
 
-	pool := charsetPools[charsetName]
 	generated, err := generateSecret(pool, length)
 	if err != nil {
 		return "", fmt.Errorf("%w: %w", ErrCantGenerateSecret, err)
@@ -84,15 +114,39 @@ func (s *GeneratedStrategy) Acquire(varName string, defaultSpec *string) (string
 	return generated, nil
 }
 
-func parseGeneratedSpec(spec string) (string, int, error) {
+func (s *GeneratedStrategy) UsesDefault() bool {
+	return true
+}
+
+// Validate accepts any non-empty value, so that a known secret can be given instead of generating one
+func (s *GeneratedStrategy) Validate(_ string, value string, _ *string) (string, error) {
+	if value == "" {
+		return "", errors.New("value cannot be empty")
+	}
+	return value, nil
+}
+
+// SetCharsetPools makes the strategy accept the custom charset pools, which must have been normalized with
+// normalizeCharsetPools, in its specs
+func (s *GeneratedStrategy) SetCharsetPools(pools map[string]string) {
+	s.customPools = pools
+}
+
+// parseGeneratedSpec parses the SET:LENGTH spec of a GENERATED variable, returning the characters of the set. The set
+// is either a built-in charset or one of customPools
+func parseGeneratedSpec(spec string, customPools map[string]string) (string, int, error) {
 	parts := strings.SplitN(spec, ":", 2)
 	if len(parts) != 2 {
 		return "", 0, errors.New("invalid format, expected SET:LENGTH")
 	}
 
 	charsetName := strings.TrimSpace(strings.ToUpper(parts[0]))
-	if _, ok := charsetPools[charsetName]; !ok {
-		return "", 0, fmt.Errorf("invalid charset %q, must be ALL or ALPHA", charsetName)
+	pool, ok := charsetPools[charsetName]
+	if !ok {
+		pool, ok = customPools[charsetName]
+	}
+	if !ok {
+		return "", 0, fmt.Errorf("invalid charset %q, must be ALL, ALPHA, SAFE or a charset pool of the config file", charsetName)
 	}
 
 	length, err := strconv.Atoi(strings.TrimSpace(parts[1]))
@@ -103,7 +157,37 @@ func parseGeneratedSpec(spec string) (string, int, error) {
 		return "", 0, fmt.Errorf("length must be between 1 and 1024, got %d", length)
 	}
 
-	return charsetName, length, nil
+	return pool, length, nil
+}
+
+// normalizeCharsetPools validates the custom charset pools of the config file and returns them keyed by their names in
+// upper case, which is how the specs are matched against them. A pool can't replace a built-in charset, and its
+// characters must be in the ALL charset, which leaves out the ones that docker compose or the quoting of the .env file
+// would interpret, such as '$', '\' or '#'
+func normalizeCharsetPools(pools map[string]string) (map[string]string, error) {
+	normalized := make(map[string]string, len(pools))
+	for name, pool := range pools {
+		key := strings.TrimSpace(strings.ToUpper(name))
+		if key == "" || strings.Contains(key, ":") {
+			return nil, fmt.Errorf("%w %q: name must not be empty or contain ':'", ErrInvalidCharsetPool, name)
+		}
+		if _, ok := charsetPools[key]; ok {
+			return nil, fmt.Errorf("%w %q: name is reserved for a built-in charset", ErrInvalidCharsetPool, name)
+		}
+		if _, ok := normalized[key]; ok {
+			return nil, fmt.Errorf("%w %q: name is defined more than once", ErrInvalidCharsetPool, name)
+		}
+		if pool == "" {
+			return nil, fmt.Errorf("%w %q: charset must not be empty", ErrInvalidCharsetPool, name)
+		}
+		for _, ch := range pool {
+			if !strings.ContainsRune(charsetPools["ALL"], ch) {
+				return nil, fmt.Errorf("%w %q: charset must only contain characters of the ALL charset, got %q", ErrInvalidCharsetPool, name, ch)
+			}
+		}
+		normalized[key] = pool
+	}
+	return normalized, nil
 }
 
 func generateSecret(charset string, length int) (string, error) {
@@ -121,76 +205,689 @@ func generateSecret(charset string, length int) (string, error) {
 	return string(result), nil
 }
 
+// CommandStrategy uses the output of a shell command as the value, such as "openssl rand -hex 16". The default spec is
+// the command, and the surrounding whitespace of its standard output is removed
+type CommandStrategy struct {
+	prompter Prompter
+	env      system.Env
+	commands system.Commands
+}
+
+func NewCommandStrategy() *CommandStrategy {
+	return &CommandStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv(), commands: system.NewDefaultCommands()}
+}
+
+func (s *CommandStrategy) Acquire(varName string, defaultSpec *string) (string, error) {
+	if defaultSpec == nil {
+		return "", fmt.Errorf("%w: %q", ErrNilDefaultSpec, varName)
+	}
+	if val, exists := s.env.GetEnv(varName); exists == true {
+		s.prompter.Info("Not overriding already existing environment variable " + varName)
+		return val, nil
+	}
+
+	command := strings.TrimSpace(*defaultSpec)
+	if command == "" {
+		return "", fmt.Errorf("%w %q: command cannot be empty", ErrCantParseDefaultSpec, varName)
+	}
+
+	slog.Info("Running command to acquire value", "varName", varName, "command", command)
+	var stdout bytes.Buffer
+	if err := s.commands.ExecShellCommandWithStdout(command, &stdout).Run(); err != nil {
+		return "", fmt.Errorf("%w %q: %w", ErrCommandFailed, command, err)
+	}
+
+	value := strings.TrimSpace(stdout.String())
+	if value == "" {
+		return "", fmt.Errorf("%w %q: command produced no output", ErrCommandFailed, command)
+	}
+	return value, nil
+}
+
+func (s *CommandStrategy) UsesDefault() bool {
+	return true
+}
+
+// Validate accepts any non-empty value, which is used instead of running the command
+func (s *CommandStrategy) Validate(_ string, value string, _ *string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("value cannot be empty")
+	}
+	return value, nil
+}
+
 // IPStrategy prompts the user for a valid IP address
 type IPStrategy struct {
 	prompter Prompter
 	env      system.Env
+	// nonInteractive makes the strategy use the default spec as the value, instead of prompting the user. The user
+	// is only prompted when there is no default spec
+	nonInteractive bool
 }
 
-func NewIPStrategy() *IPStrategy {
-	return &IPStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv()}
+func NewIPStrategy(nonInteractive bool) *IPStrategy {
+	return &IPStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv(), nonInteractive: nonInteractive}
 }
 
-func (s *IPStrategy) Acquire(varName string, _ *string) (string, error) {
+func (s *IPStrategy) Acquire(varName string, defaultSpec *string) (string, error) {
 	if val, exists := s.env.GetEnv(varName); exists == true {
 		s.prompter.Info("Not overriding already existing environment variable " + varName)
 		return val, nil
 	}
 
+	if s.nonInteractive && defaultSpec != nil {
+		value := strings.TrimSpace(*defaultSpec)
+		if net.ParseIP(value) == nil {
+			return "", fmt.Errorf("%w %q: invalid IP address %q", ErrCantParseDefaultSpec, varName, value)
+		}
+		s.prompter.Info(fmt.Sprintf("Defaulting to: %s", value))
+		return value, nil
+	}
+
 	for {
 		input, err := s.prompter.Prompt(fmt.Sprintf("Enter value for %s (IP): ", varName))
 		if err != nil {
 			return "", err
 		}
 
-		input = strings.TrimSpace(input)
-		if input == "" {
-			s.prompter.Info("IP address cannot be empty. Please try again.")
+		value, err := parseIP(input)
+		if err != nil {
+			s.prompter.Info(retryMessage(err))
 			continue
 		}
 
-		if net.ParseIP(input) == nil {
-			s.prompter.Info("Invalid IP address. Please enter a valid IPv4 or IPv6 address.")
-			continue
-		}
+		return value, nil
+	}
+}
 
-		return input, nil
+func (s *IPStrategy) UsesDefault() bool {
+	return s.nonInteractive
+}
+
+func (s *IPStrategy) Validate(_ string, value string, _ *string) (string, error) {
+	return parseIP(value)
+}
+
+// parseIP validates an IP address
+func parseIP(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errors.New("IP address cannot be empty")
+	}
+	if net.ParseIP(input) == nil {
+		return "", fmt.Errorf("invalid IP address %q, must be IPv4 or IPv6", input)
 	}
+	return input, nil
 }
 
 // StringStrategy prompts the user for a non-empty string
 type StringStrategy struct {
 	prompter Prompter
 	env      system.Env
+	// nonInteractive makes the strategy use the default spec as the value, instead of prompting the user. The user
+	// is only prompted when there is no default spec
+	nonInteractive bool
 }
 
-func NewStringStrategy() *StringStrategy {
-	return &StringStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv()}
+func NewStringStrategy(nonInteractive bool) *StringStrategy {
+	return &StringStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv(), nonInteractive: nonInteractive}
 }
 
-func (s *StringStrategy) Acquire(varName string, _ *string) (string, error) {
+func (s *StringStrategy) Acquire(varName string, defaultSpec *string) (string, error) {
 	// Check if already set in environment
 	if val, exists := s.env.GetEnv(varName); exists == true {
 		s.prompter.Info("Not overriding already existing environment variable " + varName)
 		return val, nil
 	}
 
+	if s.nonInteractive && defaultSpec != nil {
+		value := strings.TrimSpace(*defaultSpec)
+		if value == "" {
+			return "", fmt.Errorf("%w %q: value cannot be empty", ErrCantParseDefaultSpec, varName)
+		}
+		s.prompter.Info(fmt.Sprintf("Defaulting to: %s", value))
+		return value, nil
+	}
+
 	for {
 		input, err := s.prompter.Prompt(fmt.Sprintf("Enter value for %s (STRING): ", varName))
 		if err != nil {
 			return "", err
 		}
 
-		input = strings.TrimSpace(input)
-		if input == "" {
-			s.prompter.Info("Value cannot be empty. Please enter a non-empty string.")
+		value, err := parseString(input)
+		if err != nil {
+			s.prompter.Info(retryMessage(err))
 			continue
 		}
 
-		return input, nil
+		return value, nil
 	}
 }
 
+func (s *StringStrategy) UsesDefault() bool {
+	return s.nonInteractive
+}
+
+func (s *StringStrategy) Validate(_ string, value string, _ *string) (string, error) {
+	return parseString(value)
+}
+
+// parseString validates a non-empty string
+func parseString(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errors.New("value cannot be empty")
+	}
+	return input, nil
+}
+
+// EmailStrategy prompts the user for an email address, such as the address of an administrator. If the user enters a
+// name too (e.g. "Admin <admin@example.com>"), only the address is stored
+type EmailStrategy struct {
+	prompter Prompter
+	env      system.Env
+}
+
+func NewEmailStrategy() *EmailStrategy {
+	return &EmailStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv()}
+}
+
+func (s *EmailStrategy) Acquire(varName string, _ *string) (string, error) {
+	if val, exists := s.env.GetEnv(varName); exists == true {
+		s.prompter.Info("Not overriding already existing environment variable " + varName)
+		return val, nil
+	}
+
+	for {
+		input, err := s.prompter.Prompt(fmt.Sprintf("Enter value for %s (EMAIL): ", varName))
+		if err != nil {
+			return "", err
+		}
+
+		value, err := parseEmail(input)
+		if err != nil {
+			s.prompter.Info(retryMessage(err))
+			continue
+		}
+
+		return value, nil
+	}
+}
+
+func (s *EmailStrategy) UsesDefault() bool {
+	return false
+}
+
+func (s *EmailStrategy) Validate(_ string, value string, _ *string) (string, error) {
+	return parseEmail(value)
+}
+
+// parseEmail validates an email address, returning only its address part
+func parseEmail(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errors.New("email address cannot be empty")
+	}
+	address, err := mail.ParseAddress(input)
+	if err != nil {
+		return "", fmt.Errorf("invalid email address %q", input)
+	}
+	return address.Address, nil
+}
+
+// URLStrategy prompts the user for an http or https URL, such as the address of a webhook. The default spec, if any,
+// restricts the allowed schemes, separated by pipes (e.g. "https")
+type URLStrategy struct {
+	prompter Prompter
+	env      system.Env
+}
+
+func NewURLStrategy() *URLStrategy {
+	return &URLStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv()}
+}
+
+// urlSchemes are the schemes accepted by URL variables
+var urlSchemes = []string{"http", "https"}
+
+func (s *URLStrategy) Acquire(varName string, defaultSpec *string) (string, error) {
+	if val, exists := s.env.GetEnv(varName); exists == true {
+		s.prompter.Info("Not overriding already existing environment variable " + varName)
+		return val, nil
+	}
+
+	schemes, err := s.schemes(varName, defaultSpec)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		input, err := s.prompter.Prompt(fmt.Sprintf("Enter value for %s (URL): ", varName))
+		if err != nil {
+			return "", err
+		}
+
+		value, err := parseURL(input, schemes)
+		if err != nil {
+			s.prompter.Info(retryMessage(err))
+			continue
+		}
+
+		return value, nil
+	}
+}
+
+func (s *URLStrategy) UsesDefault() bool {
+	return false
+}
+
+func (s *URLStrategy) Validate(varName string, value string, defaultSpec *string) (string, error) {
+	schemes, err := s.schemes(varName, defaultSpec)
+	if err != nil {
+		return "", err
+	}
+	return parseURL(value, schemes)
+}
+
+// schemes returns the schemes allowed by the default spec of a URL variable
+func (s *URLStrategy) schemes(varName string, defaultSpec *string) ([]string, error) {
+	if defaultSpec == nil {
+		return urlSchemes, nil
+	}
+	schemes, err := parseURLSpec(*defaultSpec)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrCantParseDefaultSpec, varName, err)
+	}
+	return schemes, nil
+}
+
+// parseURL validates a URL with one of schemes
+func parseURL(input string, schemes []string) (string, error) {
+	input = strings.TrimSpace(input)
+	parsed, err := url.ParseRequestURI(input)
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("invalid URL %q, expected a URL such as %s://example.com", input, schemes[0])
+	}
+	if !slices.Contains(schemes, strings.ToLower(parsed.Scheme)) {
+		return "", fmt.Errorf("invalid URL scheme %q, must be %s", parsed.Scheme, strings.Join(schemes, " or "))
+	}
+	return input, nil
+}
+
+// parseURLSpec parses the pipe-separated allowed schemes of a URL variable, which must be a subset of urlSchemes. An
+// empty spec allows all of them
+func parseURLSpec(spec string) ([]string, error) {
+	var schemes []string
+	for _, scheme := range strings.Split(spec, "|") {
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		if scheme == "" {
+			continue
+		}
+		if !slices.Contains(urlSchemes, scheme) {
+			return nil, fmt.Errorf("invalid scheme %q, must be http or https", scheme)
+		}
+		schemes = append(schemes, scheme)
+	}
+	if len(schemes) == 0 {
+		return urlSchemes, nil
+	}
+	return schemes, nil
+}
+
+// BoolStrategy prompts the user for a boolean, accepting yes/no, true/false and 1/0 in any case. The value is stored
+// as "true" or "false". The default spec, if any, is the value used when the user enters nothing
+type BoolStrategy struct {
+	prompter Prompter
+	env      system.Env
+	// nonInteractive makes the strategy use the default spec as the value, instead of prompting the user. The user
+	// is only prompted when there is no default spec
+	nonInteractive bool
+}
+
+func NewBoolStrategy(nonInteractive bool) *BoolStrategy {
+	return &BoolStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv(), nonInteractive: nonInteractive}
+}
+
+// boolValues maps the accepted answers of a BOOL variable to the values they are stored as
+var boolValues = map[string]string{
+	"yes": "true", "true": "true", "1": "true",
+	"no": "false", "false": "false", "0": "false",
+}
+
+func (s *BoolStrategy) Acquire(varName string, defaultSpec *string) (string, error) {
+	if val, exists := s.env.GetEnv(varName); exists == true {
+		s.prompter.Info("Not overriding already existing environment variable " + varName)
+		return val, nil
+	}
+
+	defaultValue := ""
+	if defaultSpec != nil {
+		var ok bool
+		defaultValue, ok = parseBool(*defaultSpec)
+		if !ok {
+			return "", fmt.Errorf("%w %q: invalid boolean %q, must be yes, no, true, false, 1 or 0", ErrCantParseDefaultSpec, varName, *defaultSpec)
+		}
+		if s.nonInteractive {
+			s.prompter.Info(fmt.Sprintf("Defaulting to: %s", defaultValue))
+			return defaultValue, nil
+		}
+	}
+
+	message := fmt.Sprintf("Enter value for %s (BOOL): ", varName)
+	if defaultValue != "" {
+		message = fmt.Sprintf("Enter value for %s (BOOL) [%s]: ", varName, defaultValue)
+	}
+	for {
+		input, err := s.prompter.Prompt(message)
+		if err != nil {
+			return "", err
+		}
+
+		if strings.TrimSpace(input) == "" && defaultValue != "" {
+			return defaultValue, nil
+		}
+
+		value, ok := parseBool(input)
+		if !ok {
+			s.prompter.Info(retryMessage(errInvalidBool))
+			continue
+		}
+
+		return value, nil
+	}
+}
+
+func (s *BoolStrategy) UsesDefault() bool {
+	return s.nonInteractive
+}
+
+func (s *BoolStrategy) Validate(_ string, value string, defaultSpec *string) (string, error) {
+	if strings.TrimSpace(value) == "" && defaultSpec != nil {
+		value = *defaultSpec
+	}
+	parsed, ok := parseBool(value)
+	if !ok {
+		return "", errInvalidBool
+	}
+	return parsed, nil
+}
+
+// errInvalidBool is returned when the value of a BOOL variable is not a boolean
+var errInvalidBool = errors.New("invalid boolean, must be yes, no, true, false, 1 or 0")
+
+// parseBool normalizes an answer of a BOOL variable to "true" or "false". It returns false if the answer is not a
+// boolean
+func parseBool(input string) (string, bool) {
+	value, ok := boolValues[strings.ToLower(strings.TrimSpace(input))]
+	return value, ok
+}
+
+// NumberStrategy prompts the user for an integer, such as a port. The default spec is an optional range, MIN:MAX, where
+// either bound can be left empty (e.g. "1:65535" or "1:")
+type NumberStrategy struct {
+	prompter Prompter
+	env      system.Env
+}
+
+func NewNumberStrategy() *NumberStrategy {
+	return &NumberStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv()}
+}
+
+func (s *NumberStrategy) Acquire(varName string, defaultSpec *string) (string, error) {
+	if val, exists := s.env.GetEnv(varName); exists == true {
+		s.prompter.Info("Not overriding already existing environment variable " + varName)
+		return val, nil
+	}
+
+	minValue, maxValue, err := s.numberRange(varName, defaultSpec)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		input, err := s.prompter.Prompt(fmt.Sprintf("Enter value for %s (NUMBER): ", varName))
+		if err != nil {
+			return "", err
+		}
+
+		value, err := parseNumber(input, minValue, maxValue)
+		if err != nil {
+			s.prompter.Info(retryMessage(err))
+			continue
+		}
+
+		return value, nil
+	}
+}
+
+func (s *NumberStrategy) UsesDefault() bool {
+	return false
+}
+
+func (s *NumberStrategy) Validate(varName string, value string, defaultSpec *string) (string, error) {
+	minValue, maxValue, err := s.numberRange(varName, defaultSpec)
+	if err != nil {
+		return "", err
+	}
+	return parseNumber(value, minValue, maxValue)
+}
+
+// numberRange returns the range allowed by the default spec of a NUMBER variable
+func (s *NumberStrategy) numberRange(varName string, defaultSpec *string) (int, int, error) {
+	if defaultSpec == nil {
+		return math.MinInt, math.MaxInt, nil
+	}
+	minValue, maxValue, err := parseNumberSpec(*defaultSpec)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w %q: %w", ErrCantParseDefaultSpec, varName, err)
+	}
+	return minValue, maxValue, nil
+}
+
+// parseNumber validates an integer between minValue and maxValue
+func parseNumber(input string, minValue, maxValue int) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errors.New("number cannot be empty")
+	}
+	number, err := strconv.Atoi(input)
+	if err != nil {
+		return "", fmt.Errorf("invalid number %q, must be an integer", input)
+	}
+	if number < minValue || number > maxValue {
+		return "", fmt.Errorf("number %d is out of range, must be %s", number, describeNumberRange(minValue, maxValue))
+	}
+	return strconv.Itoa(number), nil
+}
+
+// parseNumberSpec parses the MIN:MAX range of a NUMBER variable. A missing bound is returned as math.MinInt or
+// math.MaxInt, and an empty spec allows any integer
+func parseNumberSpec(spec string) (int, int, error) {
+	minValue, maxValue := math.MinInt, math.MaxInt
+	if strings.TrimSpace(spec) == "" {
+		return minValue, maxValue, nil
+	}
+
+	parts := strings.Split(spec, ":")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("invalid format, expected MIN:MAX")
+	}
+
+	var err error
+	if minPart := strings.TrimSpace(parts[0]); minPart != "" {
+		if minValue, err = strconv.Atoi(minPart); err != nil {
+			return 0, 0, fmt.Errorf("invalid minimum: %w", err)
+		}
+	}
+	if maxPart := strings.TrimSpace(parts[1]); maxPart != "" {
+		if maxValue, err = strconv.Atoi(maxPart); err != nil {
+			return 0, 0, fmt.Errorf("invalid maximum: %w", err)
+		}
+	}
+	if minValue > maxValue {
+		return 0, 0, fmt.Errorf("minimum %d is greater than maximum %d", minValue, maxValue)
+	}
+
+	return minValue, maxValue, nil
+}
+
+// describeNumberRange describes the range of a NUMBER variable to the user, such as "between 1 and 65535"
+func describeNumberRange(minValue, maxValue int) string {
+	switch {
+	case minValue == math.MinInt:
+		return fmt.Sprintf("less than or equal to %d", maxValue)
+	case maxValue == math.MaxInt:
+		return fmt.Sprintf("greater than or equal to %d", minValue)
+	default:
+		return fmt.Sprintf("between %d and %d", minValue, maxValue)
+	}
+}
+
+// EnumStrategy prompts the user for one of a fixed set of values, such as a log level. The default spec is the list of
+// allowed values, separated by pipes (e.g. "debug|info|warn|error")
+type EnumStrategy struct {
+	prompter Prompter
+	env      system.Env
+}
+
+func NewEnumStrategy() *EnumStrategy {
+	return &EnumStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv()}
+}
+
+func (s *EnumStrategy) Acquire(varName string, defaultSpec *string) (string, error) {
+	if defaultSpec == nil {
+		return "", fmt.Errorf("%w: %q", ErrNilDefaultSpec, varName)
+	}
+	if val, exists := s.env.GetEnv(varName); exists == true {
+		s.prompter.Info("Not overriding already existing environment variable " + varName)
+		return val, nil
+	}
+
+	options, err := parseEnumSpec(*defaultSpec)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %w", ErrCantParseDefaultSpec, varName, err)
+	}
+
+	message := fmt.Sprintf("Enter value for %s (%s): ", varName, strings.Join(options, "|"))
+	for {
+		input, err := s.prompter.Prompt(message)
+		if err != nil {
+			return "", err
+		}
+
+		value, err := parseEnum(input, options)
+		if err != nil {
+			s.prompter.Info(retryMessage(err))
+			continue
+		}
+
+		return value, nil
+	}
+}
+
+func (s *EnumStrategy) UsesDefault() bool {
+	return false
+}
+
+func (s *EnumStrategy) Validate(varName string, value string, defaultSpec *string) (string, error) {
+	if defaultSpec == nil {
+		return "", fmt.Errorf("%w: %q", ErrNilDefaultSpec, varName)
+	}
+	options, err := parseEnumSpec(*defaultSpec)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %w", ErrCantParseDefaultSpec, varName, err)
+	}
+	return parseEnum(value, options)
+}
+
+// parseEnum validates one of the allowed values of an ENUM variable
+func parseEnum(input string, options []string) (string, error) {
+	input = strings.TrimSpace(input)
+	// The value is stored as written in the spec, regardless of the case the user entered it in
+	index := slices.IndexFunc(options, func(option string) bool {
+		return strings.EqualFold(option, input)
+	})
+	if index == -1 {
+		return "", fmt.Errorf("invalid value %q, must be one of: %s", input, strings.Join(options, ", "))
+	}
+	return options[index], nil
+}
+
+// parseEnumSpec parses the pipe-separated allowed values of an ENUM variable, trimming the whitespace around each one
+func parseEnumSpec(spec string) ([]string, error) {
+	var options []string
+	for _, option := range strings.Split(spec, "|") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		options = append(options, option)
+	}
+	if len(options) == 0 {
+		return nil, errors.New("no allowed values, expected a list such as a|b|c")
+	}
+	return options, nil
+}
+
+// PortStrategy prompts the user for a TCP port, which can't be shared by two variables
+type PortStrategy struct {
+	prompter Prompter
+	env      system.Env
+	// alreadyUsedPorts contains the ports that have already been used with this instance of the Port strategy. Two
+	// services listening on the same port of the host would make one of them fail to start
+	alreadyUsedPorts []int
+}
+
+func NewPortStrategy() *PortStrategy {
+	return &PortStrategy{prompter: NewConsolePrompter(), env: system.NewDefaultEnv()}
+}
+
+func (s *PortStrategy) Acquire(varName string, _ *string) (string, error) {
+	if val, exists := s.env.GetEnv(varName); exists == true {
+		s.prompter.Info("Not overriding already existing environment variable " + varName)
+		return val, nil
+	}
+
+	for {
+		input, err := s.prompter.Prompt(fmt.Sprintf("Enter value for %s (PORT): ", varName))
+		if err != nil {
+			return "", err
+		}
+
+		value, err := s.usePort(input)
+		if err != nil {
+			s.prompter.Info(retryMessage(err))
+			continue
+		}
+
+		return value, nil
+	}
+}
+
+func (s *PortStrategy) UsesDefault() bool {
+	return false
+}
+
+// Validate checks the port like Acquire does, so an answered port can't be reused by the other PORT variables either
+func (s *PortStrategy) Validate(_ string, value string, _ *string) (string, error) {
+	return s.usePort(value)
+}
+
+// usePort validates a port that has not been used yet, and marks it as used
+func (s *PortStrategy) usePort(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	port, err := strconv.Atoi(input)
+	if err != nil || port < 1 || port > 65535 {
+		return "", fmt.Errorf("invalid port %q, must be a number between 1 and 65535", input)
+	}
+	if slices.Contains(s.alreadyUsedPorts, port) {
+		return "", fmt.Errorf("port cannot be reused: %d", port)
+	}
+	s.alreadyUsedPorts = append(s.alreadyUsedPorts, port)
+	return strconv.Itoa(port), nil
+}
+
 // PathStrategy prompts the user for a directory path, creating it if needed
 type PathStrategy struct {
 	prompter Prompter
@@ -221,42 +918,59 @@ func (s *PathStrategy) Acquire(varName string, _ *string) (string, error) {
 			return "", err
 		}
 
-		input = strings.TrimSpace(input)
-		if input == "" {
-			s.prompter.Info("Path cannot be empty. Please enter a directory path.")
+		value, err := s.usePath(input)
+		if err != nil {
+			s.prompter.Info(retryMessage(err))
 			continue
 		}
 
-		if strings.HasPrefix(input, "~") {
-			s.prompter.Info("Homedir ('~') expansion is not supported. Please enter a valid directory path.")
-			continue
-		}
+		return value, nil
+	}
+}
 
-		absPath, err := s.files.GetAbsPath(input)
-		if err != nil {
-			s.prompter.Info(fmt.Sprintf("Invalid path: %v. Please try again.", err))
-			continue
-		}
+func (s *PathStrategy) UsesDefault() bool {
+	return false
+}
 
-		// Check if the directory exists. If it does, we continue, and if it doesn't, we try to create it. If directory
-		// creation fails, it can be due to an error such as insufficient permissions, so we let the user try again
-		// with another directory
-		if err := s.files.EnsureDirExists(absPath); err != nil {
-			if err := s.files.CreateDirIfNotExists(absPath); err != nil {
-				s.prompter.Info(fmt.Sprintf("Invalid path: %v. Please try again.", err))
-				continue
-			}
-			s.prompter.Info(fmt.Sprintf("Created directory: %s", absPath))
-		} else {
-			s.prompter.Info(fmt.Sprintf("Directory exists: %s", absPath))
-		}
+// Validate checks the path like Acquire does, creating the directory if needed, so an answered path can't be reused by
+// the other PATH variables either
+func (s *PathStrategy) Validate(_ string, value string, _ *string) (string, error) {
+	return s.usePath(value)
+}
 
-		if slices.Contains(s.alreadyUsedPaths, absPath) {
-			s.prompter.Info(fmt.Sprintf("Path cannot be reused: %q. Please try again.", absPath))
-			continue
+// usePath validates a directory path that has not been used yet, creating the directory if needed, and marks it as
+// used
+func (s *PathStrategy) usePath(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errors.New("path cannot be empty")
+	}
+
+	if strings.HasPrefix(input, "~") {
+		return "", errors.New("homedir ('~') expansion is not supported")
+	}
+
+	absPath, err := s.files.GetAbsPath(input)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+
+	// Check if the directory exists. If it does, we continue, and if it doesn't, we try to create it. If directory
+	// creation fails, it can be due to an error such as insufficient permissions, so we let the user try again
+	// with another directory
+	if err := s.files.EnsureDirExists(absPath); err != nil {
+		if err := s.files.CreateDirIfNotExists(absPath); err != nil {
+			return "", fmt.Errorf("invalid path: %w", err)
 		}
-		s.alreadyUsedPaths = append(s.alreadyUsedPaths, absPath)
+		s.prompter.Info(fmt.Sprintf("Created directory: %s", absPath))
+	} else {
+		s.prompter.Info(fmt.Sprintf("Directory exists: %s", absPath))
+	}
 
-		return absPath, nil
+	if slices.Contains(s.alreadyUsedPaths, absPath) {
+		return "", fmt.Errorf("path cannot be reused: %q", absPath)
 	}
+	s.alreadyUsedPaths = append(s.alreadyUsedPaths, absPath)
+
+	return absPath, nil
 }
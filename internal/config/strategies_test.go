@@ -2,8 +2,15 @@ package config
 
 import (
 	"errors"
+	"io"
+	"math"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestConstantStrategy_Acquire_Success(t *testing.T) {
@@ -177,6 +184,120 @@ func TestGeneratedStrategy_Acquire_GeneratesAllSecret(t *testing.T) {
 	}
 }
 
+func TestGeneratedStrategy_Acquire_GeneratesSafeSecret(t *testing.T) {
+	strategy := &GeneratedStrategy{
+		prompter: &mockPrompter{},
+		env:      &mockEnv{},
+	}
+	defaultSpec := "SAFE:1024"
+
+	result, err := strategy.Acquire("TEST_VAR", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result) != 1024 {
+		t.Errorf("expected result length 1024, got %d", len(result))
+	}
+	if strings.ContainsAny(result, "0O1lI") {
+		t.Errorf("expected no ambiguous characters, got %q", result)
+	}
+	for _, ch := range result {
+		if !strings.ContainsRune(charsetPools["ALPHA"], ch) {
+			t.Errorf("expected only alphanumeric characters, found %q", ch)
+		}
+	}
+}
+
+func TestCharsetPools_SafeExcludesAmbiguousCharacters(t *testing.T) {
+	for _, ch := range charsetPools["ALPHA"] {
+		excluded := strings.ContainsRune("0O1lI", ch)
+		if strings.ContainsRune(charsetPools["SAFE"], ch) == excluded {
+			t.Errorf("expected SAFE to contain %q: %v", ch, !excluded)
+		}
+	}
+}
+
+func TestGeneratedStrategy_Acquire_GeneratesCustomPoolSecret(t *testing.T) {
+	pool := "abcdefghjkmnpqrstuvwxyz23456789"
+	strategy := &GeneratedStrategy{
+		prompter:    &mockPrompter{},
+		env:         &mockEnv{},
+		customPools: map[string]string{"NOAMBIG": pool},
+	}
+	defaultSpec := "noambig:256"
+
+	result, err := strategy.Acquire("TEST_VAR", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result) != 256 {
+		t.Errorf("expected result length 256, got %d", len(result))
+	}
+	for _, ch := range result {
+		if !strings.ContainsRune(pool, ch) {
+			t.Errorf("unexpected character %q in generated secret", ch)
+		}
+	}
+}
+
+func TestGeneratedStrategy_Acquire_UnknownCustomPool(t *testing.T) {
+	strategy := &GeneratedStrategy{
+		prompter:    &mockPrompter{},
+		env:         &mockEnv{},
+		customPools: map[string]string{"NOAMBIG": "abc"},
+	}
+	defaultSpec := "HEX:32"
+
+	_, err := strategy.Acquire("VAR", &defaultSpec)
+
+	if !errors.Is(err, ErrCantParseDefaultSpec) {
+		t.Errorf("expected ErrCantParseDefaultSpec, got: %v", err)
+	}
+}
+
+func TestNormalizeCharsetPools(t *testing.T) {
+	pools, err := normalizeCharsetPools(map[string]string{" noAmbig ": "abc234", "DIGITS": "0123456789"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := map[string]string{"NOAMBIG": "abc234", "DIGITS": "0123456789"}
+	if diff := cmp.Diff(expected, pools); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNormalizeCharsetPools_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		pools map[string]string
+	}{
+		{"empty_name", map[string]string{" ": "abc"}},
+		{"name_with_colon", map[string]string{"A:B": "abc"}},
+		{"built_in_name", map[string]string{"alpha": "abc"}},
+		{"duplicated_name", map[string]string{"pool": "abc", "POOL": "def"}},
+		{"empty_charset", map[string]string{"EMPTY": ""}},
+		{"non_ascii_charset", map[string]string{"ACCENTS": "áéí"}},
+		{"non_printable_charset", map[string]string{"CONTROL": "ab\n"}},
+		{"dollar_charset", map[string]string{"DOLLAR": "ab$"}},
+		{"backslash_charset", map[string]string{"BACKSLASH": `ab\`}},
+		{"space_charset", map[string]string{"SPACE": "a b"}},
+		{"quote_charset", map[string]string{"QUOTES": "a'`\"#"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := normalizeCharsetPools(tt.pools)
+
+			if !errors.Is(err, ErrInvalidCharsetPool) {
+				t.Errorf("expected ErrInvalidCharsetPool, got: %v", err)
+			}
+		})
+	}
+}
+
 func TestGeneratedStrategy_Acquire_DifferentLengths(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -256,6 +377,7 @@ func TestGeneratedStrategy_Acquire_CaseInsensitiveCharset(t *testing.T) {
 		{"lowercase_alpha", "alpha:16"},
 		{"lowercase_all", "all:16"},
 		{"mixed_case", "AlPhA:16"},
+		{"lowercase_safe", "safe:16"},
 		{"uppercase", "ALL:16"},
 	}
 
@@ -328,6 +450,134 @@ func TestGeneratedStrategy_Acquire_GeneratesUniqueValues(t *testing.T) {
 	}
 }
 
+func TestCommandStrategy_Acquire_NilDefaultSpec(t *testing.T) {
+	strategy := &CommandStrategy{prompter: &mockPrompter{}, env: &mockEnv{}, commands: &mockCommands{}}
+
+	_, err := strategy.Acquire("VAR_NAME", nil)
+
+	if !errors.Is(err, ErrNilDefaultSpec) {
+		t.Errorf("expected ErrNilDefaultSpec, got: %v", err)
+	}
+}
+
+func TestCommandStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
+	existingValue := "existing"
+	commandRun := false
+	strategy := &CommandStrategy{
+		prompter: &mockPrompter{},
+		env: &mockEnv{
+			getEnvFunc: func(varName string) (string, bool) {
+				return existingValue, true
+			},
+		},
+		commands: &mockCommands{
+			execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+				commandRun = true
+				return &mockRunnableCommand{}
+			},
+		},
+	}
+	defaultSpec := "openssl rand -hex 16"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != existingValue {
+		t.Errorf("expected result %q, got %q", existingValue, result)
+	}
+	if commandRun {
+		t.Error("expected the command not to be run")
+	}
+}
+
+func TestCommandStrategy_Acquire_ReturnsTrimmedOutput(t *testing.T) {
+	var capturedCmd string
+	strategy := &CommandStrategy{
+		prompter: &mockPrompter{},
+		env:      &mockEnv{},
+		commands: &mockCommands{
+			execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+				capturedCmd = cmd
+				return &mockRunnableCommand{runFunc: func() error {
+					_, err := io.WriteString(stdout, "  3f9a1c2b7d4e5f60a1b2c3d4e5f6a7b8\n\n")
+					return err
+				}}
+			},
+		},
+	}
+	defaultSpec := " openssl rand -hex 16 "
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "3f9a1c2b7d4e5f60a1b2c3d4e5f6a7b8" {
+		t.Errorf("expected result %q, got %q", "3f9a1c2b7d4e5f60a1b2c3d4e5f6a7b8", result)
+	}
+	if capturedCmd != "openssl rand -hex 16" {
+		t.Errorf("expected command %q, got %q", "openssl rand -hex 16", capturedCmd)
+	}
+}
+
+func TestCommandStrategy_Acquire_CommandFails(t *testing.T) {
+	expectedErr := errors.New("exit status 1")
+	strategy := &CommandStrategy{
+		prompter: &mockPrompter{},
+		env:      &mockEnv{},
+		commands: &mockCommands{
+			execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+				return &mockRunnableCommand{runFunc: func() error { return expectedErr }}
+			},
+		},
+	}
+	defaultSpec := "cat /sys/class/dmi/id/product_uuid"
+
+	_, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if !errors.Is(err, ErrCommandFailed) {
+		t.Errorf("expected ErrCommandFailed, got: %v", err)
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+func TestCommandStrategy_Acquire_NoOutput(t *testing.T) {
+	strategy := &CommandStrategy{
+		prompter: &mockPrompter{},
+		env:      &mockEnv{},
+		commands: &mockCommands{
+			execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+				return &mockRunnableCommand{runFunc: func() error {
+					_, err := io.WriteString(stdout, " \n")
+					return err
+				}}
+			},
+		},
+	}
+	defaultSpec := "true"
+
+	_, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if !errors.Is(err, ErrCommandFailed) {
+		t.Errorf("expected ErrCommandFailed, got: %v", err)
+	}
+}
+
+func TestCommandStrategy_Acquire_EmptyCommand(t *testing.T) {
+	strategy := &CommandStrategy{prompter: &mockPrompter{}, env: &mockEnv{}, commands: &mockCommands{}}
+	defaultSpec := "   "
+
+	_, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if !errors.Is(err, ErrCantParseDefaultSpec) {
+		t.Errorf("expected ErrCantParseDefaultSpec, got: %v", err)
+	}
+}
+
 func TestIPStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
 	existingIP := "192.168.1.100"
 	strategy := &IPStrategy{
@@ -537,7 +787,7 @@ func TestIPStrategy_Acquire_EmptyInput_RetriesUntilValid(t *testing.T) {
 	if len(capturedInfoMessages) != 2 {
 		t.Fatalf("expected 2 info messages, got %d", len(capturedInfoMessages))
 	}
-	expectedMessage := "IP address cannot be empty. Please try again."
+	expectedMessage := "Invalid value: IP address cannot be empty. Please try again."
 	for i, msg := range capturedInfoMessages {
 		if msg != expectedMessage {
 			t.Errorf("info message %d: expected %q, got %q", i, expectedMessage, msg)
@@ -588,10 +838,9 @@ func TestIPStrategy_Acquire_InvalidIP_RetriesUntilValid(t *testing.T) {
 	if len(capturedInfoMessages) != 5 {
 		t.Fatalf("expected 5 info messages, got %d", len(capturedInfoMessages))
 	}
-	expectedMessage := "Invalid IP address. Please enter a valid IPv4 or IPv6 address."
 	for i, msg := range capturedInfoMessages {
-		if msg != expectedMessage {
-			t.Errorf("info message %d: expected %q, got %q", i, expectedMessage, msg)
+		if !strings.HasPrefix(msg, "Invalid value: invalid IP address") || !strings.HasSuffix(msg, ". Please try again.") {
+			t.Errorf("info message %d: expected an invalid IP address message, got %q", i, msg)
 		}
 	}
 }
@@ -618,6 +867,71 @@ func TestIPStrategy_Acquire_IgnoresDefaultSpec(t *testing.T) {
 	}
 }
 
+func TestIPStrategy_Acquire_NonInteractive_UsesDefaultSpec(t *testing.T) {
+	defaultSpec := "10.0.0.1"
+	strategy := &IPStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				t.Fatalf("expected no prompt in non-interactive mode, got prompt %q", message)
+				return "", nil
+			},
+		},
+		env:            &mockEnv{},
+		nonInteractive: true,
+	}
+
+	result, err := strategy.Acquire("IP_VAR", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != defaultSpec {
+		t.Errorf("expected result %q, got %q", defaultSpec, result)
+	}
+}
+
+func TestIPStrategy_Acquire_NonInteractive_PromptsWithoutDefaultSpec(t *testing.T) {
+	promptedValue := "192.168.1.1"
+	promptCount := 0
+	strategy := &IPStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				promptCount++
+				return promptedValue, nil
+			},
+		},
+		env:            &mockEnv{},
+		nonInteractive: true,
+	}
+
+	result, err := strategy.Acquire("IP_VAR", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if promptCount != 1 {
+		t.Errorf("expected 1 prompt, got %d", promptCount)
+	}
+	if result != promptedValue {
+		t.Errorf("expected result %q, got %q", promptedValue, result)
+	}
+}
+
+func TestIPStrategy_Acquire_NonInteractive_InvalidDefaultSpec(t *testing.T) {
+	defaultSpec := "not-an-ip"
+	strategy := &IPStrategy{
+		prompter:       &mockPrompter{},
+		env:            &mockEnv{},
+		nonInteractive: true,
+	}
+
+	_, err := strategy.Acquire("IP_VAR", &defaultSpec)
+
+	if !errors.Is(err, ErrCantParseDefaultSpec) {
+		t.Errorf("expected ErrCantParseDefaultSpec, got: %v", err)
+	}
+}
+
 func TestStringStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
 	existingValue := "val"
 	strategy := &StringStrategy{
@@ -694,7 +1008,7 @@ func TestStringStrategy_Acquire_EmptyInput_RetriesUntilValid(t *testing.T) {
 	if callCount != 3 {
 		t.Errorf("expected 3 prompt calls, got %d", callCount)
 	}
-	expectedMessageWhenValueIsEmpty := "Value cannot be empty"
+	expectedMessageWhenValueIsEmpty := "value cannot be empty"
 	emptyMessages := 0
 	for _, msg := range capturedInfoMessages {
 		if strings.Contains(msg, expectedMessageWhenValueIsEmpty) {
@@ -789,6 +1103,1080 @@ func TestStringStrategy_Acquire_IgnoresDefaultSpec(t *testing.T) {
 	}
 }
 
+func TestStringStrategy_Acquire_NonInteractive_UsesDefaultSpec(t *testing.T) {
+	defaultSpec := "default"
+	strategy := &StringStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				t.Fatalf("expected no prompt in non-interactive mode, got prompt %q", message)
+				return "", nil
+			},
+		},
+		env:            &mockEnv{},
+		nonInteractive: true,
+	}
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != defaultSpec {
+		t.Errorf("expected result %q, got %q", defaultSpec, result)
+	}
+}
+
+func TestStringStrategy_Acquire_NonInteractive_PromptsWithoutDefaultSpec(t *testing.T) {
+	promptedValue := "val"
+	promptCount := 0
+	strategy := &StringStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				promptCount++
+				return promptedValue, nil
+			},
+		},
+		env:            &mockEnv{},
+		nonInteractive: true,
+	}
+
+	result, err := strategy.Acquire("VAR_NAME", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if promptCount != 1 {
+		t.Errorf("expected 1 prompt, got %d", promptCount)
+	}
+	if result != promptedValue {
+		t.Errorf("expected result %q, got %q", promptedValue, result)
+	}
+}
+
+func TestStringStrategy_Acquire_NonInteractive_EmptyDefaultSpec(t *testing.T) {
+	defaultSpec := "  "
+	strategy := &StringStrategy{
+		prompter:       &mockPrompter{},
+		env:            &mockEnv{},
+		nonInteractive: true,
+	}
+
+	_, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if !errors.Is(err, ErrCantParseDefaultSpec) {
+		t.Errorf("expected ErrCantParseDefaultSpec, got: %v", err)
+	}
+}
+
+func TestEmailStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
+	existingValue := "admin@example.com"
+	strategy := &EmailStrategy{
+		prompter: &mockPrompter{},
+		env: &mockEnv{
+			getEnvFunc: func(varName string) (string, bool) {
+				return existingValue, true
+			},
+		},
+	}
+
+	result, err := strategy.Acquire("VAR_NAME", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != existingValue {
+		t.Errorf("expected result %q, got %q", existingValue, result)
+	}
+}
+
+func TestEmailStrategy_Acquire_PrompterError(t *testing.T) {
+	expectedError := errors.New("prompter read failed")
+	strategy := &EmailStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				return "", expectedError
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	_, err := strategy.Acquire("VAR_NAME", nil)
+
+	if !errors.Is(err, expectedError) {
+		t.Errorf("expected error to be %v, got: %v", expectedError, err)
+	}
+}
+
+func TestEmailStrategy_Acquire_Success(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{name: "address", in: "admin@example.com", out: "admin@example.com"},
+		{name: "subdomain and tag", in: "admin+homelab@mail.example.org", out: "admin+homelab@mail.example.org"},
+		{name: "with name", in: "Admin <admin@example.com>", out: "admin@example.com"},
+		{name: "spaces", in: "   admin@example.com  ", out: "admin@example.com"},
+		{name: "tabs and newline", in: "\t\n admin@example.com \t\n", out: "admin@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var capturedPrompt string
+			strategy := &EmailStrategy{
+				prompter: &mockPrompter{
+					promptFunc: func(message string) (string, error) {
+						capturedPrompt = message
+						return tt.in, nil
+					},
+				},
+				env: &mockEnv{},
+			}
+
+			result, err := strategy.Acquire("VAR_NAME", nil)
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result != tt.out {
+				t.Errorf("expected result %q, got %q", tt.out, result)
+			}
+			expectedPrompt := "Enter value for VAR_NAME (EMAIL): "
+			if capturedPrompt != expectedPrompt {
+				t.Errorf("expected prompt to be %q, got %q", expectedPrompt, capturedPrompt)
+			}
+		})
+	}
+}
+
+func TestEmailStrategy_Acquire_InvalidInput_RetriesUntilValid(t *testing.T) {
+	inputs := []string{"", "   ", "admin", "admin@", "@example.com", "admin example.com", "admin@example.com"}
+	var capturedInfoMessages []string
+	strategy := &EmailStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				input := inputs[0]
+				inputs = inputs[1:]
+				return input, nil
+			},
+			infoFunc: func(message string) {
+				capturedInfoMessages = append(capturedInfoMessages, message)
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	result, err := strategy.Acquire("VAR_NAME", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "admin@example.com" {
+		t.Errorf("expected result %q, got %q", "admin@example.com", result)
+	}
+	expectedMessages := []string{
+		"Invalid value: email address cannot be empty. Please try again.",
+		"Invalid value: email address cannot be empty. Please try again.",
+		`Invalid value: invalid email address "admin". Please try again.`,
+		`Invalid value: invalid email address "admin@". Please try again.`,
+		`Invalid value: invalid email address "@example.com". Please try again.`,
+		`Invalid value: invalid email address "admin example.com". Please try again.`,
+	}
+	if diff := cmp.Diff(expectedMessages, capturedInfoMessages); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestURLStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
+	existingValue := "ftp://example.com"
+	strategy := &URLStrategy{
+		prompter: &mockPrompter{},
+		env: &mockEnv{
+			getEnvFunc: func(varName string) (string, bool) {
+				return existingValue, true
+			},
+		},
+	}
+	defaultSpec := "https"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != existingValue {
+		t.Errorf("expected result %q, got %q", existingValue, result)
+	}
+}
+
+func TestURLStrategy_Acquire_PrompterError(t *testing.T) {
+	expectedError := errors.New("prompter read failed")
+	strategy := &URLStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				return "", expectedError
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	_, err := strategy.Acquire("VAR_NAME", nil)
+
+	if !errors.Is(err, expectedError) {
+		t.Errorf("expected error to be %v, got: %v", expectedError, err)
+	}
+}
+
+func TestURLStrategy_Acquire_Success(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{name: "http", in: "http://192.168.1.10:8080/hook", out: "http://192.168.1.10:8080/hook"},
+		{name: "https with query", in: "https://ntfy.example.com/topic?priority=high", out: "https://ntfy.example.com/topic?priority=high"},
+		{name: "upper case scheme", in: "HTTPS://example.com", out: "HTTPS://example.com"},
+		{name: "spaces", in: "  https://example.com \n", out: "https://example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var capturedPrompt string
+			strategy := &URLStrategy{
+				prompter: &mockPrompter{
+					promptFunc: func(message string) (string, error) {
+						capturedPrompt = message
+						return tt.in, nil
+					},
+				},
+				env: &mockEnv{},
+			}
+
+			result, err := strategy.Acquire("VAR_NAME", nil)
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result != tt.out {
+				t.Errorf("expected result %q, got %q", tt.out, result)
+			}
+			expectedPrompt := "Enter value for VAR_NAME (URL): "
+			if capturedPrompt != expectedPrompt {
+				t.Errorf("expected prompt to be %q, got %q", expectedPrompt, capturedPrompt)
+			}
+		})
+	}
+}
+
+func TestURLStrategy_Acquire_InvalidInput_RetriesUntilValid(t *testing.T) {
+	inputs := []string{"", "example.com", "/hook", "https://", "ftp://example.com", "https://example.com"}
+	var capturedInfoMessages []string
+	strategy := &URLStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				input := inputs[0]
+				inputs = inputs[1:]
+				return input, nil
+			},
+			infoFunc: func(message string) {
+				capturedInfoMessages = append(capturedInfoMessages, message)
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	result, err := strategy.Acquire("VAR_NAME", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "https://example.com" {
+		t.Errorf("expected result %q, got %q", "https://example.com", result)
+	}
+	expectedMessages := []string{
+		`Invalid value: invalid URL "", expected a URL such as http://example.com. Please try again.`,
+		`Invalid value: invalid URL "example.com", expected a URL such as http://example.com. Please try again.`,
+		`Invalid value: invalid URL "/hook", expected a URL such as http://example.com. Please try again.`,
+		`Invalid value: invalid URL "https://", expected a URL such as http://example.com. Please try again.`,
+		`Invalid value: invalid URL scheme "ftp", must be http or https. Please try again.`,
+	}
+	if diff := cmp.Diff(expectedMessages, capturedInfoMessages); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestURLStrategy_Acquire_DefaultSpecRestrictsSchemes(t *testing.T) {
+	inputs := []string{"http://example.com", "https://example.com"}
+	var capturedInfoMessages []string
+	strategy := &URLStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				input := inputs[0]
+				inputs = inputs[1:]
+				return input, nil
+			},
+			infoFunc: func(message string) {
+				capturedInfoMessages = append(capturedInfoMessages, message)
+			},
+		},
+		env: &mockEnv{},
+	}
+	defaultSpec := " HTTPS "
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "https://example.com" {
+		t.Errorf("expected result %q, got %q", "https://example.com", result)
+	}
+	expectedMessages := []string{`Invalid value: invalid URL scheme "http", must be https. Please try again.`}
+	if diff := cmp.Diff(expectedMessages, capturedInfoMessages); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestURLStrategy_Acquire_InvalidDefaultSpec(t *testing.T) {
+	strategy := &URLStrategy{prompter: &mockPrompter{}, env: &mockEnv{}}
+	defaultSpec := "https|ftp"
+
+	_, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if !errors.Is(err, ErrCantParseDefaultSpec) {
+		t.Errorf("expected ErrCantParseDefaultSpec, got: %v", err)
+	}
+}
+
+func TestParseURLSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected []string
+	}{
+		{"empty", "", []string{"http", "https"}},
+		{"only_pipes", " | ", []string{"http", "https"}},
+		{"https", "https", []string{"https"}},
+		{"both_trimmed", " http | HTTPS ", []string{"http", "https"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemes, err := parseURLSpec(tt.spec)
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, schemes); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBoolStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
+	existingValue := "yes"
+	strategy := &BoolStrategy{
+		prompter: &mockPrompter{},
+		env: &mockEnv{
+			getEnvFunc: func(varName string) (string, bool) {
+				return existingValue, true
+			},
+		},
+	}
+
+	result, err := strategy.Acquire("VAR_NAME", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != existingValue {
+		t.Errorf("expected result %q, got %q", existingValue, result)
+	}
+}
+
+func TestBoolStrategy_Acquire_Normalizes(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"yes", "true"},
+		{"YES", "true"},
+		{"true", "true"},
+		{"True", "true"},
+		{"1", "true"},
+		{"no", "false"},
+		{"No", "false"},
+		{"false", "false"},
+		{"FALSE", "false"},
+		{"0", "false"},
+		{"  yes  ", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			strategy := &BoolStrategy{
+				prompter: &mockPrompter{
+					promptFunc: func(message string) (string, error) {
+						return tt.in, nil
+					},
+				},
+				env: &mockEnv{},
+			}
+
+			result, err := strategy.Acquire("VAR_NAME", nil)
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result != tt.out {
+				t.Errorf("expected result %q, got %q", tt.out, result)
+			}
+		})
+	}
+}
+
+func TestBoolStrategy_Acquire_InvalidInput_RetriesUntilValid(t *testing.T) {
+	inputs := []string{"", "maybe", "2", "no"}
+	var capturedInfoMessages []string
+	strategy := &BoolStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				input := inputs[0]
+				inputs = inputs[1:]
+				return input, nil
+			},
+			infoFunc: func(message string) {
+				capturedInfoMessages = append(capturedInfoMessages, message)
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	result, err := strategy.Acquire("VAR_NAME", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "false" {
+		t.Errorf("expected result %q, got %q", "false", result)
+	}
+	if len(capturedInfoMessages) != 3 {
+		t.Errorf("expected 3 invalid boolean messages, got %q", capturedInfoMessages)
+	}
+}
+
+func TestBoolStrategy_Acquire_EmptyInputPicksDefault(t *testing.T) {
+	var capturedPrompt string
+	strategy := &BoolStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				capturedPrompt = message
+				return "  ", nil
+			},
+		},
+		env: &mockEnv{},
+	}
+	defaultSpec := "Yes"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "true" {
+		t.Errorf("expected result %q, got %q", "true", result)
+	}
+	expectedPrompt := "Enter value for VAR_NAME (BOOL) [true]: "
+	if capturedPrompt != expectedPrompt {
+		t.Errorf("expected prompt to be %q, got %q", expectedPrompt, capturedPrompt)
+	}
+}
+
+func TestBoolStrategy_Acquire_InputOverridesDefault(t *testing.T) {
+	strategy := &BoolStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				return "0", nil
+			},
+		},
+		env: &mockEnv{},
+	}
+	defaultSpec := "true"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "false" {
+		t.Errorf("expected result %q, got %q", "false", result)
+	}
+}
+
+func TestBoolStrategy_Acquire_InvalidDefaultSpec(t *testing.T) {
+	strategy := &BoolStrategy{
+		prompter: &mockPrompter{},
+		env:      &mockEnv{},
+	}
+	defaultSpec := "sometimes"
+
+	_, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if !errors.Is(err, ErrCantParseDefaultSpec) {
+		t.Errorf("expected ErrCantParseDefaultSpec, got: %v", err)
+	}
+}
+
+func TestBoolStrategy_Acquire_NonInteractive_UsesDefaultSpec(t *testing.T) {
+	strategy := &BoolStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				t.Errorf("expected no prompt, got %q", message)
+				return "", nil
+			},
+		},
+		env:            &mockEnv{},
+		nonInteractive: true,
+	}
+	defaultSpec := "no"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "false" {
+		t.Errorf("expected result %q, got %q", "false", result)
+	}
+}
+
+func TestNumberStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
+	existingValue := "8080"
+	strategy := &NumberStrategy{
+		prompter: &mockPrompter{},
+		env: &mockEnv{
+			getEnvFunc: func(varName string) (string, bool) {
+				return existingValue, true
+			},
+		},
+	}
+	defaultSpec := "1:65535"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != existingValue {
+		t.Errorf("expected result %q, got %q", existingValue, result)
+	}
+}
+
+func TestNumberStrategy_Acquire_PrompterError(t *testing.T) {
+	expectedError := errors.New("prompter read failed")
+	strategy := &NumberStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				return "", expectedError
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	_, err := strategy.Acquire("VAR_NAME", nil)
+
+	if !errors.Is(err, expectedError) {
+		t.Errorf("expected error to be %v, got: %v", expectedError, err)
+	}
+}
+
+func TestNumberStrategy_Acquire_Success(t *testing.T) {
+	var capturedPrompt string
+	strategy := &NumberStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				capturedPrompt = message
+				return "  8080 ", nil
+			},
+		},
+		env: &mockEnv{},
+	}
+	defaultSpec := "1:65535"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "8080" {
+		t.Errorf("expected result %q, got %q", "8080", result)
+	}
+	expectedPrompt := "Enter value for VAR_NAME (NUMBER): "
+	if capturedPrompt != expectedPrompt {
+		t.Errorf("expected prompt to be %q, got %q", expectedPrompt, capturedPrompt)
+	}
+}
+
+func TestNumberStrategy_Acquire_InvalidInput_RetriesUntilValid(t *testing.T) {
+	inputs := []string{"", "abc", "12.5", "0", "65536", "443"}
+	var capturedInfoMessages []string
+	strategy := &NumberStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				input := inputs[0]
+				inputs = inputs[1:]
+				return input, nil
+			},
+			infoFunc: func(message string) {
+				capturedInfoMessages = append(capturedInfoMessages, message)
+			},
+		},
+		env: &mockEnv{},
+	}
+	defaultSpec := "1:65535"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "443" {
+		t.Errorf("expected result %q, got %q", "443", result)
+	}
+	expectedMessages := []string{
+		"Invalid value: number cannot be empty. Please try again.",
+		`Invalid value: invalid number "abc", must be an integer. Please try again.`,
+		`Invalid value: invalid number "12.5", must be an integer. Please try again.`,
+		"Invalid value: number 0 is out of range, must be between 1 and 65535. Please try again.",
+		"Invalid value: number 65536 is out of range, must be between 1 and 65535. Please try again.",
+	}
+	if strings.Join(capturedInfoMessages, "\n") != strings.Join(expectedMessages, "\n") {
+		t.Errorf("expected messages %q, got %q", expectedMessages, capturedInfoMessages)
+	}
+}
+
+func TestNumberStrategy_Acquire_InvalidFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"no_colon", "1-65535"},
+		{"multiple_colons", "1:10:100"},
+		{"min_not_a_number", "a:10"},
+		{"max_not_a_number", "1:b"},
+		{"min_float_number", "1.5:10"},
+		{"min_greater_than_max", "10:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := &NumberStrategy{
+				prompter: &mockPrompter{},
+				env:      &mockEnv{},
+			}
+
+			_, err := strategy.Acquire("VAR", &tt.spec)
+
+			if !errors.Is(err, ErrCantParseDefaultSpec) {
+				t.Errorf("expected ErrCantParseDefaultSpec, got: %v", err)
+			}
+			if err != nil && !strings.Contains(err.Error(), "VAR") {
+				t.Errorf("expected error message to contain var name %q, got %q", "VAR", err.Error())
+			}
+		})
+	}
+}
+
+func TestParseNumberSpec(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		expectedMin int
+		expectedMax int
+	}{
+		{"empty", "", math.MinInt, math.MaxInt},
+		{"both_bounds", "1:65535", 1, 65535},
+		{"only_min", "1:", 1, math.MaxInt},
+		{"only_max", ":100", math.MinInt, 100},
+		{"no_bounds", ":", math.MinInt, math.MaxInt},
+		{"negative_bounds", "-10:-1", -10, -1},
+		{"single_value", "5:5", 5, 5},
+		{"whitespace", " 1 : 10 ", 1, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minValue, maxValue, err := parseNumberSpec(tt.spec)
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if minValue != tt.expectedMin || maxValue != tt.expectedMax {
+				t.Errorf("expected range %d:%d, got %d:%d", tt.expectedMin, tt.expectedMax, minValue, maxValue)
+			}
+		})
+	}
+}
+
+func TestDescribeNumberRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		minValue int
+		maxValue int
+		expected string
+	}{
+		{"both_bounds", 1, 65535, "between 1 and 65535"},
+		{"only_min", 1, math.MaxInt, "greater than or equal to 1"},
+		{"only_max", math.MinInt, 100, "less than or equal to 100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeNumberRange(tt.minValue, tt.maxValue); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEnumStrategy_Acquire_NilDefaultSpec(t *testing.T) {
+	strategy := &EnumStrategy{prompter: &mockPrompter{}, env: &mockEnv{}}
+
+	_, err := strategy.Acquire("VAR_NAME", nil)
+
+	if !errors.Is(err, ErrNilDefaultSpec) {
+		t.Errorf("expected ErrNilDefaultSpec, got: %v", err)
+	}
+}
+
+func TestEnumStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
+	existingValue := "warn"
+	strategy := &EnumStrategy{
+		prompter: &mockPrompter{},
+		env: &mockEnv{
+			getEnvFunc: func(varName string) (string, bool) {
+				return existingValue, true
+			},
+		},
+	}
+	defaultSpec := "debug|info|warn|error"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != existingValue {
+		t.Errorf("expected result %q, got %q", existingValue, result)
+	}
+}
+
+func TestEnumStrategy_Acquire_PrompterError(t *testing.T) {
+	expectedError := errors.New("prompter read failed")
+	strategy := &EnumStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				return "", expectedError
+			},
+		},
+		env: &mockEnv{},
+	}
+	defaultSpec := "debug|info"
+
+	_, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if !errors.Is(err, expectedError) {
+		t.Errorf("expected error to be %v, got: %v", expectedError, err)
+	}
+}
+
+func TestEnumStrategy_Acquire_Success(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"exact", "info", "info"},
+		{"different_case", "WARN", "warn"},
+		{"whitespace", "  error ", "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var capturedPrompt string
+			strategy := &EnumStrategy{
+				prompter: &mockPrompter{
+					promptFunc: func(message string) (string, error) {
+						capturedPrompt = message
+						return tt.input, nil
+					},
+				},
+				env: &mockEnv{},
+			}
+			defaultSpec := "debug|info|warn|error"
+
+			result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected result %q, got %q", tt.expected, result)
+			}
+			expectedPrompt := "Enter value for VAR_NAME (debug|info|warn|error): "
+			if capturedPrompt != expectedPrompt {
+				t.Errorf("expected prompt to be %q, got %q", expectedPrompt, capturedPrompt)
+			}
+		})
+	}
+}
+
+func TestEnumStrategy_Acquire_InvalidInput_RetriesUntilValid(t *testing.T) {
+	inputs := []string{"", "verbose", "inf", "Debug"}
+	var capturedInfoMessages []string
+	strategy := &EnumStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				input := inputs[0]
+				inputs = inputs[1:]
+				return input, nil
+			},
+			infoFunc: func(message string) {
+				capturedInfoMessages = append(capturedInfoMessages, message)
+			},
+		},
+		env: &mockEnv{},
+	}
+	defaultSpec := "debug|info|warn|error"
+
+	result, err := strategy.Acquire("VAR_NAME", &defaultSpec)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "debug" {
+		t.Errorf("expected result %q, got %q", "debug", result)
+	}
+	expectedMessages := []string{
+		`Invalid value: invalid value "", must be one of: debug, info, warn, error. Please try again.`,
+		`Invalid value: invalid value "verbose", must be one of: debug, info, warn, error. Please try again.`,
+		`Invalid value: invalid value "inf", must be one of: debug, info, warn, error. Please try again.`,
+	}
+	if strings.Join(capturedInfoMessages, "\n") != strings.Join(expectedMessages, "\n") {
+		t.Errorf("expected messages %q, got %q", expectedMessages, capturedInfoMessages)
+	}
+}
+
+func TestEnumStrategy_Acquire_NoOptions(t *testing.T) {
+	for _, spec := range []string{"", "   ", "|", " | | "} {
+		t.Run(spec, func(t *testing.T) {
+			strategy := &EnumStrategy{prompter: &mockPrompter{}, env: &mockEnv{}}
+
+			_, err := strategy.Acquire("VAR", &spec)
+
+			if !errors.Is(err, ErrCantParseDefaultSpec) {
+				t.Errorf("expected ErrCantParseDefaultSpec, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseEnumSpec_TrimsOptions(t *testing.T) {
+	options, err := parseEnumSpec(" debug | info|warn ||  error ")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []string{"debug", "info", "warn", "error"}
+	if !slices.Equal(options, expected) {
+		t.Errorf("expected options %q, got %q", expected, options)
+	}
+}
+
+func TestPortStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
+	existingValue := "8080"
+	strategy := &PortStrategy{
+		prompter: &mockPrompter{},
+		env: &mockEnv{
+			getEnvFunc: func(varName string) (string, bool) {
+				return existingValue, true
+			},
+		},
+	}
+
+	result, err := strategy.Acquire("PORT_VAR", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != existingValue {
+		t.Errorf("expected result %q, got %q", existingValue, result)
+	}
+}
+
+func TestPortStrategy_Acquire_PrompterError(t *testing.T) {
+	expectedError := errors.New("prompter read failed")
+	strategy := &PortStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				return "", expectedError
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	_, err := strategy.Acquire("PORT_VAR", nil)
+
+	if !errors.Is(err, expectedError) {
+		t.Errorf("expected error to be %v, got: %v", expectedError, err)
+	}
+}
+
+func TestPortStrategy_Acquire_Success(t *testing.T) {
+	var capturedPrompt string
+	strategy := &PortStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				capturedPrompt = message
+				return " 8443  ", nil
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	result, err := strategy.Acquire("PORT_VAR", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "8443" {
+		t.Errorf("expected result %q, got %q", "8443", result)
+	}
+	expectedPrompt := "Enter value for PORT_VAR (PORT): "
+	if capturedPrompt != expectedPrompt {
+		t.Errorf("expected prompt to be %q, got %q", expectedPrompt, capturedPrompt)
+	}
+	if !slices.Equal(strategy.alreadyUsedPorts, []int{8443}) {
+		t.Errorf("expected used ports %v, got %v", []int{8443}, strategy.alreadyUsedPorts)
+	}
+}
+
+func TestPortStrategy_Acquire_InvalidInput_RetriesUntilValid(t *testing.T) {
+	inputs := []string{"", "http", "0", "65536", "80.5", "65535"}
+	var capturedInfoMessages []string
+	strategy := &PortStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				input := inputs[0]
+				inputs = inputs[1:]
+				return input, nil
+			},
+			infoFunc: func(message string) {
+				capturedInfoMessages = append(capturedInfoMessages, message)
+			},
+		},
+		env: &mockEnv{},
+	}
+
+	result, err := strategy.Acquire("PORT_VAR", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "65535" {
+		t.Errorf("expected result %q, got %q", "65535", result)
+	}
+	if len(capturedInfoMessages) != 5 {
+		t.Errorf("expected 5 invalid port messages, got %q", capturedInfoMessages)
+	}
+}
+
+func TestPortStrategy_Acquire_PortReused_RetriesUntilValid(t *testing.T) {
+	callCount := 0
+	var capturedInfoMessages []string
+	alreadyUsedPort := 8080
+	validPort := "8081"
+	strategy := &PortStrategy{
+		prompter: &mockPrompter{
+			promptFunc: func(message string) (string, error) {
+				callCount++
+				if callCount <= 2 {
+					return strconv.Itoa(alreadyUsedPort), nil
+				}
+				return validPort, nil
+			},
+			infoFunc: func(message string) {
+				capturedInfoMessages = append(capturedInfoMessages, message)
+			},
+		},
+		env:              &mockEnv{},
+		alreadyUsedPorts: []int{alreadyUsedPort},
+	}
+
+	result, err := strategy.Acquire("PORT_VAR", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != validPort {
+		t.Errorf("expected result %q, got %q", validPort, result)
+	}
+	if callCount != 3 {
+		t.Errorf("expected 3 prompt calls, got %d", callCount)
+	}
+	portReusedMessagesShown := 0
+	for _, msg := range capturedInfoMessages {
+		if strings.Contains(msg, "port cannot be reused:") {
+			portReusedMessagesShown++
+		}
+	}
+	if portReusedMessagesShown != 2 {
+		t.Errorf("expected 2 port reused messages, got %d", portReusedMessagesShown)
+	}
+}
+
+func TestPortStrategy_Validate_Success(t *testing.T) {
+	strategy := &PortStrategy{prompter: &mockPrompter{}, env: &mockEnv{}}
+
+	result, err := strategy.Validate("PORT_VAR", " 8080 ", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != "8080" {
+		t.Errorf("expected result %q, got %q", "8080", result)
+	}
+	if !slices.Contains(strategy.alreadyUsedPorts, 8080) {
+		t.Errorf("expected port 8080 to be marked as used, got %v", strategy.alreadyUsedPorts)
+	}
+}
+
+func TestPortStrategy_Validate_InvalidValues(t *testing.T) {
+	tests := []string{"", "abc", "0", "65536"}
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			strategy := &PortStrategy{prompter: &mockPrompter{}, env: &mockEnv{}}
+
+			_, err := strategy.Validate("PORT_VAR", value, nil)
+
+			if err == nil {
+				t.Errorf("expected an error for port %q", value)
+			}
+		})
+	}
+}
+
+func TestPortStrategy_Validate_PortReused(t *testing.T) {
+	strategy := &PortStrategy{prompter: &mockPrompter{}, env: &mockEnv{}, alreadyUsedPorts: []int{8080}}
+
+	_, err := strategy.Validate("PORT_VAR", "8080", nil)
+
+	if err == nil || err.Error() != "port cannot be reused: 8080" {
+		t.Errorf("expected port reused error, got %v", err)
+	}
+}
+
 func TestPathStrategy_Acquire_AlreadySetInEnv(t *testing.T) {
 	existingPath := "/home/user/data"
 	strategy := &PathStrategy{
@@ -876,7 +2264,7 @@ func TestPathStrategy_Acquire_EmptyInput_RetriesUntilValid(t *testing.T) {
 	if callCount != 3 {
 		t.Errorf("expected 3 prompt calls, got %d", callCount)
 	}
-	expectedMessageWhenPathIsEmpty := "Path cannot be empty"
+	expectedMessageWhenPathIsEmpty := "path cannot be empty"
 	emptyMessages := 0
 	for _, msg := range capturedInfoMessages {
 		if strings.Contains(msg, expectedMessageWhenPathIsEmpty) {
@@ -927,7 +2315,7 @@ func TestPathStrategy_Acquire_HomedirExpansionNotSupported(t *testing.T) {
 	if callCount != 2 {
 		t.Errorf("expected 2 prompt calls, got %d", callCount)
 	}
-	expectedMessageWhenHomedirCharIntroduced := "Homedir ('~') expansion is not supported"
+	expectedMessageWhenHomedirCharIntroduced := "homedir ('~') expansion is not supported"
 	found := false
 	for _, msg := range capturedInfoMessages {
 		if strings.Contains(msg, expectedMessageWhenHomedirCharIntroduced) {
@@ -1019,7 +2407,7 @@ func TestPathStrategy_Acquire_CreateDirError_RetriesUntilValid(t *testing.T) {
 	}
 	errorMessagesShown := 0
 	for _, msg := range capturedInfoMessages {
-		if strings.Contains(msg, "Invalid path:") {
+		if strings.Contains(msg, "invalid path:") {
 			errorMessagesShown++
 		}
 	}
@@ -1068,7 +2456,7 @@ func TestPathStrategy_Acquire_PathReused_RetriesUntilValid(t *testing.T) {
 	}
 	pathReusedMessagesShown := 0
 	for _, msg := range capturedInfoMessages {
-		if strings.Contains(msg, "Path cannot be reused:") {
+		if strings.Contains(msg, "path cannot be reused:") {
 			pathReusedMessagesShown++
 		}
 	}
@@ -1,9 +1,60 @@
 package config
 
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/docker"
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+// mockRunnableCommand is a simple mock for RunnableCommand
+type mockRunnableCommand struct {
+	runFunc func() error
+}
+
+func (m *mockRunnableCommand) Run() error {
+	if m.runFunc != nil {
+		return m.runFunc()
+	}
+	return nil
+}
+
+// mockCommands is a mock implementation of system.Commands for testing
+type mockCommands struct {
+	execShellCommandStdout func(cmd string, stdout io.Writer) system.RunnableCommand
+}
+
+func (m *mockCommands) ExecCommand(name string, arg ...string) system.RunnableCommand {
+	return &mockRunnableCommand{}
+}
+func (m *mockCommands) ExecShellCommand(cmd string) system.RunnableCommand {
+	return &mockRunnableCommand{}
+}
+func (m *mockCommands) ExecShellCommandContext(ctx context.Context, cmd string) system.RunnableCommand {
+	return &mockRunnableCommand{}
+}
+func (m *mockCommands) ExecShellCommandWithStderr(cmd string, stderr io.Writer) system.RunnableCommand {
+	return &mockRunnableCommand{}
+}
+func (m *mockCommands) ExecShellCommandWithStdout(cmd string, stdout io.Writer) system.RunnableCommand {
+	if m.execShellCommandStdout != nil {
+		return m.execShellCommandStdout(cmd, stdout)
+	}
+	return &mockRunnableCommand{}
+}
+func (m *mockCommands) ExecShellCommandContextWithStdout(ctx context.Context, cmd string, stdout io.Writer) system.RunnableCommand {
+	return m.ExecShellCommandWithStdout(cmd, stdout)
+}
+func (m *mockCommands) LookPath(name string) (string, error) { return "", nil }
+
 // mockPrompter is a mock implementation of Prompter for testing
 type mockPrompter struct {
-	promptFunc func(message string) (string, error)
-	infoFunc   func(message string)
+	promptFunc       func(message string) (string, error)
+	promptSecretFunc func(message string) (string, error)
+	infoFunc         func(message string)
 }
 
 func (m *mockPrompter) Prompt(message string) (string, error) {
@@ -12,6 +63,12 @@ func (m *mockPrompter) Prompt(message string) (string, error) {
 	}
 	return "", nil
 }
+func (m *mockPrompter) PromptSecret(message string) (string, error) {
+	if m.promptSecretFunc != nil {
+		return m.promptSecretFunc(message)
+	}
+	return "", nil
+}
 func (m *mockPrompter) Info(message string) {
 	if m.infoFunc != nil {
 		m.infoFunc(message)
@@ -19,7 +76,8 @@ func (m *mockPrompter) Info(message string) {
 }
 
 type mockEnv struct {
-	getEnvFunc func(varName string) (string, bool)
+	getEnvFunc    func(varName string) (string, bool)
+	getAllEnvFunc func() map[string]string
 }
 
 // GetEnv assumes by default that the variable does NOT exist
@@ -30,6 +88,15 @@ func (m *mockEnv) GetEnv(varName string) (string, bool) {
 	return "", false
 }
 func (m *mockEnv) GetRequiredEnv(varName string) (string, error) { return "", nil }
+func (m *mockEnv) GetBoolEnv(varName string) (bool, bool)        { return false, false }
+func (m *mockEnv) GetIntEnv(varName string) (int, bool, error)   { return 0, false, nil }
+func (m *mockEnv) GetAllEnv() map[string]string {
+	if m.getAllEnvFunc != nil {
+		return m.getAllEnvFunc()
+	}
+	return map[string]string{}
+}
+func (m *mockEnv) ListByPrefix(prefix string) map[string]string { return map[string]string{} }
 
 type mockFiles struct {
 	createDirIfNotExists func(path string) error
@@ -37,6 +104,10 @@ type mockFiles struct {
 	getAbsPath           func(path string) (string, error)
 	getwd                func() (string, error)
 	writeFile            func(path string, data []byte) error
+	writeSecretFile      func(path string, data []byte) error
+	writeNewFile         func(path string, data []byte) error
+	readFile             func(path string) ([]byte, error)
+	listFiles            func(path string) ([]os.FileInfo, error)
 }
 
 func (m *mockFiles) CreateDirIfNotExists(path string) error {
@@ -58,6 +129,9 @@ func (m *mockFiles) EmptyDir(path string) error {
 func (m *mockFiles) CopyDir(srcPath string, dstPath string) error {
 	return nil
 }
+func (m *mockFiles) CopyDirWithOptions(srcPath string, dstPath string, opts system.CopyDirOptions) ([]string, error) {
+	return nil, nil
+}
 func (m *mockFiles) Getwd() (dir string, err error) {
 	if m.getwd != nil {
 		return m.getwd()
@@ -70,12 +144,55 @@ func (m *mockFiles) WriteFile(path string, data []byte) error {
 	}
 	return nil
 }
+func (m *mockFiles) WriteSecretFile(path string, data []byte) error {
+	if m.writeSecretFile != nil {
+		return m.writeSecretFile(path, data)
+	}
+	return nil
+}
+func (m *mockFiles) WriteNewFile(path string, data []byte) error {
+	if m.writeNewFile != nil {
+		return m.writeNewFile(path, data)
+	}
+	return nil
+}
+func (m *mockFiles) CreateNewFile(path string) (io.WriteCloser, error) {
+	return nil, nil
+}
+func (m *mockFiles) ReadFile(path string) ([]byte, error) {
+	if m.readFile != nil {
+		return m.readFile(path)
+	}
+	return nil, nil
+}
 func (m *mockFiles) GetAbsPath(path string) (string, error) {
 	if m.getAbsPath != nil {
 		return m.getAbsPath(path)
 	}
 	return "", nil
 }
+func (m *mockFiles) ListFiles(path string) ([]os.FileInfo, error) {
+	if m.listFiles != nil {
+		return m.listFiles(path)
+	}
+	return nil, nil
+}
+func (m *mockFiles) RemoveFile(path string) error { return nil }
+func (m *mockFiles) DirSize(path string) (int64, error) {
+	return 0, nil
+}
+
+// mockFileInfo is a mock implementation of os.FileInfo for testing
+type mockFileInfo struct {
+	name string
+}
+
+func (m *mockFileInfo) Name() string       { return m.name }
+func (m *mockFileInfo) Size() int64        { return 0 }
+func (m *mockFileInfo) Mode() os.FileMode  { return 0 }
+func (m *mockFileInfo) ModTime() time.Time { return time.Time{} }
+func (m *mockFileInfo) IsDir() bool        { return false }
+func (m *mockFileInfo) Sys() interface{}   { return nil }
 
 type mockStrategyRegistry struct {
 	getFunc func(varType string) (AcquireStrategy, error)
@@ -91,7 +208,10 @@ func (m *mockStrategyRegistry) Register(typeName string, strategy AcquireStrateg
 
 // mockStrategy is a mock implementation of AcquireStrategy for testing
 type mockStrategy struct {
-	acquireFunc func(varName string, defaultSpec *string) (string, error)
+	acquireFunc  func(varName string, defaultSpec *string) (string, error)
+	validateFunc func(varName string, value string, defaultSpec *string) (string, error)
+	// promptsForValue makes UsesDefault return false
+	promptsForValue bool
 }
 
 func (m *mockStrategy) Acquire(varName string, defaultSpec *string) (string, error) {
@@ -100,6 +220,15 @@ func (m *mockStrategy) Acquire(varName string, defaultSpec *string) (string, err
 	}
 	return "mock-value", nil
 }
+func (m *mockStrategy) Validate(varName string, value string, defaultSpec *string) (string, error) {
+	if m.validateFunc != nil {
+		return m.validateFunc(varName, value, defaultSpec)
+	}
+	return value, nil
+}
+func (m *mockStrategy) UsesDefault() bool {
+	return !m.promptsForValue
+}
 
 type mockTextFormatter struct {
 	formatDotenvKeyValue func(key string, value string) (string, error)
@@ -117,3 +246,54 @@ func (m *mockTextFormatter) FormatDotenvKeyValue(key string, value string) (stri
 func (m *mockTextFormatter) QuoteForPOSIXShell(text string) string {
 	return text
 }
+
+type mockDockerRunner struct {
+	composeStart func(services []string) error
+	composeStop  func(services []string) error
+}
+
+func (m *mockDockerRunner) ComposeStart(services []string) error {
+	if m.composeStart != nil {
+		return m.composeStart(services)
+	}
+	return nil
+}
+func (m *mockDockerRunner) ComposeStop(services []string) error {
+	if m.composeStop != nil {
+		return m.composeStop(services)
+	}
+	return nil
+}
+func (m *mockDockerRunner) ComposeValidate() error { return nil }
+func (m *mockDockerRunner) ComposePs(services []string) ([]docker.ServiceStatus, error) {
+	return nil, nil
+}
+func (m *mockDockerRunner) ComposeServices() ([]string, error) {
+	return nil, nil
+}
+func (m *mockDockerRunner) WaitUntilServicesAreHealthy(services []string, timeout time.Duration) error {
+	return nil
+}
+func (m *mockDockerRunner) WaitUntilContainerHealthy(container string) error {
+	return nil
+}
+func (m *mockDockerRunner) ContainerLogs(ctx context.Context, name string, tail int, follow bool) error {
+	return nil
+}
+func (m *mockDockerRunner) ContainerExec(container string, cmd string) error { return nil }
+func (m *mockDockerRunner) WaitUntilContainerExecIsSuccessful(container string, cmd string, timeout time.Duration) error {
+	return nil
+}
+
+type mockTime struct {
+	now func() time.Time
+}
+
+func (m *mockTime) Sleep(d time.Duration)                  {}
+func (m *mockTime) After(d time.Duration) <-chan time.Time { return nil }
+func (m *mockTime) Now() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Time{}
+}
@@ -2,22 +2,82 @@ package docker
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 )
 
 // BuildDockerComposeCommandStr builds a Docker Compose command in a way that's safe to use. For example, it sets the
 // environment variables that specify which user and group are running the command. The resulting command is
-// `docker compose ${cmd}`, where `${cmd} is the value of this function's `cmd` input argument
+// `docker compose ${cmd}`, where `${cmd} is the value of this function's `cmd` input argument.
+// The `docker compose` invocation can be replaced with the HOMELAB_COMPOSE_CMD environment variable (for example,
+// with `docker-compose` on systems that still use Docker Compose v1).
+// The user and group default to the ones running this process, and can be overridden with the HOMELAB_OVERRIDE_UID
+// and HOMELAB_OVERRIDE_GID environment variables (for example, on NAS systems where the containers' user is not the
+// user invoking this program)
 func BuildDockerComposeCommandStr(cmd string) string {
-	uid := os.Getuid()
-	gid := os.Getgid()
+	return buildDockerComposeCommandStrWithEnv(system.NewDefaultEnv(), ProcessUserIDs{}, cmd)
+}
+
+// UserIDProvider provides the user and group that run docker compose, unless they are overridden
+type UserIDProvider interface {
+	// UID returns the ID of the user
+	UID() int
+	// GID returns the ID of the group
+	GID() int
+}
+
+// ProcessUserIDs provides the user and group that run this process
+type ProcessUserIDs struct{}
+
+func (ProcessUserIDs) UID() int { return os.Getuid() }
+func (ProcessUserIDs) GID() int { return os.Getgid() }
+
+func buildDockerComposeCommandStrWithEnv(env system.Env, ids UserIDProvider, cmd string) string {
+	uid := getIDOverride(env, "HOMELAB_OVERRIDE_UID", ids.UID())
+	gid := getIDOverride(env, "HOMELAB_OVERRIDE_GID", ids.GID())
 
 	var cmdParts []string
 	cmdParts = append(cmdParts, fmt.Sprintf("HOMELAB_GENERAL_UID=%d", uid))
 	cmdParts = append(cmdParts, fmt.Sprintf("HOMELAB_GENERAL_GID=%d", gid))
-	cmdParts = append(cmdParts, "docker compose")
+	cmdParts = append(cmdParts, getComposeCmd(env))
 	cmdParts = append(cmdParts, cmd)
 
 	return strings.Join(cmdParts, " ")
 }
+
+// defaultComposeCmd is the invocation of Docker Compose used when HOMELAB_COMPOSE_CMD is not defined
+const defaultComposeCmd = "docker compose"
+
+// getComposeCmd returns the invocation of Docker Compose, which is the value of the HOMELAB_COMPOSE_CMD environment
+// variable if it is defined and not blank, or defaultComposeCmd otherwise
+func getComposeCmd(env system.Env) string {
+	value, exists := env.GetEnv("HOMELAB_COMPOSE_CMD")
+	if !exists {
+		return defaultComposeCmd
+	}
+	composeCmd := strings.TrimSpace(value)
+	if composeCmd == "" {
+		slog.Warn("Ignoring empty compose command override", "varName", "HOMELAB_COMPOSE_CMD", "default", defaultComposeCmd)
+		return defaultComposeCmd
+	}
+	return composeCmd
+}
+
+// getIDOverride returns the value of the varName environment variable as a user or group ID. If the variable is not
+// defined, or it is not a valid ID, it returns defaultID
+func getIDOverride(env system.Env, varName string, defaultID int) int {
+	value, exists := env.GetEnv(varName)
+	if !exists {
+		return defaultID
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || id < 0 {
+		slog.Warn("Ignoring invalid ID override", "varName", varName, "value", value, "default", defaultID)
+		return defaultID
+	}
+	return id
+}
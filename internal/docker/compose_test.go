@@ -31,3 +31,89 @@ func TestBuildDockerComposeCommandStr_EmptyCommand(t *testing.T) {
 		t.Errorf("expected command %q, got %q", expectedCmd, result)
 	}
 }
+
+func TestBuildDockerComposeCommandStrWithEnv_UsesOverrides(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			switch varName {
+			case "HOMELAB_OVERRIDE_UID":
+				return "1026", true
+			case "HOMELAB_OVERRIDE_GID":
+				return "100", true
+			}
+			return "", false
+		},
+	}
+
+	result := buildDockerComposeCommandStrWithEnv(env, &mockUserIDs{uid: 1000, gid: 1001}, "up -d")
+
+	expectedCmd := "HOMELAB_GENERAL_UID=1026 HOMELAB_GENERAL_GID=100 docker compose up -d"
+	if result != expectedCmd {
+		t.Errorf("expected command %q, got %q", expectedCmd, result)
+	}
+}
+
+func TestBuildDockerComposeCommandStrWithEnv_UsesProvidedIDsWhenNotOverridden(t *testing.T) {
+	env := &mockEnv{}
+
+	result := buildDockerComposeCommandStrWithEnv(env, &mockUserIDs{uid: 1000, gid: 1001}, "up -d")
+
+	expectedCmd := "HOMELAB_GENERAL_UID=1000 HOMELAB_GENERAL_GID=1001 docker compose up -d"
+	if result != expectedCmd {
+		t.Errorf("expected command %q, got %q", expectedCmd, result)
+	}
+}
+
+func TestBuildDockerComposeCommandStrWithEnv_IgnoresInvalidOverride(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			if varName == "HOMELAB_OVERRIDE_UID" {
+				return "not-a-number", true
+			}
+			return "", false
+		},
+	}
+
+	result := buildDockerComposeCommandStrWithEnv(env, &mockUserIDs{uid: 1000, gid: 1001}, "up -d")
+
+	expectedCmd := "HOMELAB_GENERAL_UID=1000 HOMELAB_GENERAL_GID=1001 docker compose up -d"
+	if result != expectedCmd {
+		t.Errorf("expected command %q, got %q", expectedCmd, result)
+	}
+}
+
+func TestBuildDockerComposeCommandStrWithEnv_UsesCustomComposeCmd(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			if varName == "HOMELAB_COMPOSE_CMD" {
+				return "docker-compose", true
+			}
+			return "", false
+		},
+	}
+
+	result := buildDockerComposeCommandStrWithEnv(env, &mockUserIDs{uid: 1000, gid: 1001}, "up -d")
+
+	expectedCmd := "HOMELAB_GENERAL_UID=1000 HOMELAB_GENERAL_GID=1001 docker-compose up -d"
+	if result != expectedCmd {
+		t.Errorf("expected command %q, got %q", expectedCmd, result)
+	}
+}
+
+func TestBuildDockerComposeCommandStrWithEnv_IgnoresBlankComposeCmd(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			if varName == "HOMELAB_COMPOSE_CMD" {
+				return "   ", true
+			}
+			return "", false
+		},
+	}
+
+	result := buildDockerComposeCommandStrWithEnv(env, &mockUserIDs{uid: 1000, gid: 1001}, "up -d")
+
+	expectedCmd := "HOMELAB_GENERAL_UID=1000 HOMELAB_GENERAL_GID=1001 docker compose up -d"
+	if result != expectedCmd {
+		t.Errorf("expected command %q, got %q", expectedCmd, result)
+	}
+}
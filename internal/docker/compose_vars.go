@@ -0,0 +1,65 @@
+package docker
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+var (
+	ErrComposeVarsMissing = errors.New("variables referenced by docker compose file are not defined")
+)
+
+// composeVarRegexp matches the variable interpolations of a docker compose file: $VAR, ${VAR} and ${VAR<modifier>...},
+// where <modifier> is one of :-, -, :?, ?, :+ or +. The second group captures the modifier, if any
+var composeVarRegexp = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)(:?[-?+])?[^}]*\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// injectedComposeVars are the variables that BuildDockerComposeCommandStr sets on every docker compose command, so
+// they don't need to be defined in the environment
+var injectedComposeVars = []string{"HOMELAB_GENERAL_UID", "HOMELAB_GENERAL_GID"}
+
+// FindComposeVarReferences returns the sorted, unique names of the variables that the content of a docker compose file
+// needs to be defined. Variables that have a default value (${VAR:-default} or ${VAR-default}) or an alternative
+// value (${VAR:+alt} or ${VAR+alt}) are not required, so they are not returned. Commented lines are ignored
+func FindComposeVarReferences(content string) []string {
+	var names []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		// "$$" is an escaped "$", which is not interpolated
+		line = strings.ReplaceAll(line, "$$", "")
+		for _, match := range composeVarRegexp.FindAllStringSubmatch(line, -1) {
+			name, modifier := match[1], match[2]
+			if name == "" {
+				name = match[3]
+			}
+			if strings.HasSuffix(modifier, "-") || strings.HasSuffix(modifier, "+") {
+				continue
+			}
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// FindMissingComposeVars returns the sorted names of the variables required by the content of a docker compose file
+// that are not defined in the environment
+func FindMissingComposeVars(env system.Env, content string) []string {
+	missing := []string{}
+	for _, name := range FindComposeVarReferences(content) {
+		if slices.Contains(injectedComposeVars, name) {
+			continue
+		}
+		if _, exists := env.GetEnv(name); !exists {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
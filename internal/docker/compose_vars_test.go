@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var sampleComposeFile = `services:
+  web:
+    container_name: ${HOMELAB_WEB_CONTAINER_NAME}
+    image: nginx:${HOMELAB_WEB_VERSION:-latest}
+    user: ${HOMELAB_GENERAL_UID}:${HOMELAB_GENERAL_GID}
+    # image: ${HOMELAB_COMMENTED_OUT}
+    environment:
+      - TZ=$HOMELAB_GENERAL_TIMEZONE
+      - ESCAPED=$${NOT_A_VAR}
+      - PASSWORD=${HOMELAB_WEB_PASSWORD:?password is required}
+`
+
+func TestFindComposeVarReferences_ReturnsRequiredVars(t *testing.T) {
+	names := FindComposeVarReferences(sampleComposeFile)
+
+	expected := []string{
+		"HOMELAB_GENERAL_GID",
+		"HOMELAB_GENERAL_TIMEZONE",
+		"HOMELAB_GENERAL_UID",
+		"HOMELAB_WEB_CONTAINER_NAME",
+		"HOMELAB_WEB_PASSWORD",
+	}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFindMissingComposeVars_ReportsUndefinedVar(t *testing.T) {
+	content := `services:
+  web:
+    container_name: ${HOMELAB_DEFINED}
+    image: ${HOMELAB_UNDEFINED}
+`
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			if varName == "HOMELAB_DEFINED" {
+				return "web", true
+			}
+			return "", false
+		},
+	}
+
+	missing := FindMissingComposeVars(env, content)
+
+	expected := []string{"HOMELAB_UNDEFINED"}
+	if diff := cmp.Diff(expected, missing); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFindMissingComposeVars_IgnoresInjectedVars(t *testing.T) {
+	env := &mockEnv{
+		getEnvFunc: func(varName string) (string, bool) {
+			return "value", varName != "HOMELAB_GENERAL_UID" && varName != "HOMELAB_GENERAL_GID"
+		},
+	}
+
+	missing := FindMissingComposeVars(env, sampleComposeFile)
+
+	if len(missing) != 0 {
+		t.Errorf("expected no missing vars, got %v", missing)
+	}
+}
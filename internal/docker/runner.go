@@ -1,12 +1,17 @@
 package docker
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/davidsilvasanmartin/auto-homelab/internal/format"
 	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
 )
 
@@ -14,32 +19,91 @@ import (
 type Runner interface {
 	ComposeStart(services []string) error
 	ComposeStop(services []string) error
+	ComposeValidate() error
+	ComposePs(services []string) ([]ServiceStatus, error)
+	ComposeServices() ([]string, error)
+	WaitUntilServicesAreHealthy(services []string, timeout time.Duration) error
+	ContainerLogs(ctx context.Context, name string, tail int, follow bool) error
 	ContainerExec(container string, cmd string) error
-	WaitUntilContainerExecIsSuccessful(container string, cmd string) error
+	WaitUntilContainerExecIsSuccessful(container string, cmd string, timeout time.Duration) error
+	WaitUntilContainerHealthy(container string) error
 }
 
 var (
-	ErrTooManyRetries = errors.New("too many retries")
+	ErrTooManyRetries       = errors.New("too many retries")
+	ErrComposeConfigInvalid = errors.New("invalid docker compose configuration")
+	ErrNoHealthCheck        = errors.New("container has no health check")
 )
 
+const (
+	// containerWaitMaxRetries is the number of times a container is checked when waiting for it to be ready
+	containerWaitMaxRetries = 30
+	// containerWaitRetryInterval is the time between two checks of a container when waiting for it to be ready
+	containerWaitRetryInterval = 1 * time.Second
+)
+
+// ServiceStatus is the state of the container of a docker compose service, as reported by docker compose ps
+type ServiceStatus struct {
+	Service string
+	State   string
+	// ExitCode is the exit code of the container, which is only meaningful when it has exited
+	ExitCode int
+	// Health is the health of the container, or an empty string if it has no health check
+	Health string
+}
+
+// serviceHealthPollInterval is the time between two checks of the health of the services
+const serviceHealthPollInterval = 2 * time.Second
+
 // SystemRunner implements the Docker Runner using system commands calls
 type SystemRunner struct {
-	commands                     system.Commands
-	files                        system.FilesHandler
-	time                         system.Time
+	commands system.Commands
+	files    system.FilesHandler
+	time     system.Time
+	env      system.Env
+	// ids provides the user and group injected into docker compose commands
+	ids UserIDProvider
+	// buildDockerComposeCommandStr replaces the building of docker compose commands from env and ids, when it is not nil
 	buildDockerComposeCommandStr func(cmd string) string
+	// extraRequiredFiles are files that must exist in the working directory, in addition to docker-compose.yml
+	// and .env, before running docker compose. For example, files referenced by env_file directives
+	extraRequiredFiles []string
+	// envFiles are the .env files passed to docker compose with --env-file, in order, instead of the .env file of the
+	// working directory
+	envFiles []string
 }
 
 // NewSystemRunner creates a new Docker SystemRunner
 func NewSystemRunner() *SystemRunner {
 	return &SystemRunner{
-		commands:                     system.NewDefaultCommands(),
-		files:                        system.NewDefaultFilesHandler(),
-		time:                         system.NewDefaultTime(),
-		buildDockerComposeCommandStr: BuildDockerComposeCommandStr,
+		commands: system.NewDefaultCommands(),
+		files:    system.NewDefaultFilesHandler(),
+		time:     system.NewDefaultTime(),
+		env:      system.NewDefaultEnv(),
+		ids:      ProcessUserIDs{},
 	}
 }
 
+// WithUserIDs replaces the provider of the user and group injected into docker compose commands, which are the ones
+// running this process by default
+func (r *SystemRunner) WithUserIDs(ids UserIDProvider) *SystemRunner {
+	r.ids = ids
+	return r
+}
+
+// WithRequiredFiles adds files that must exist in the working directory before running docker compose
+func (r *SystemRunner) WithRequiredFiles(filenames ...string) *SystemRunner {
+	r.extraRequiredFiles = append(r.extraRequiredFiles, filenames...)
+	return r
+}
+
+// WithEnvFiles makes docker compose interpolate the variables of the .env files at paths instead of the ones of the
+// .env file in the working directory. The values of a file override the ones of the files before it
+func (r *SystemRunner) WithEnvFiles(paths ...string) *SystemRunner {
+	r.envFiles = append(r.envFiles, paths...)
+	return r
+}
+
 // ComposeStart starts services by using the system's docker compose command
 func (r *SystemRunner) ComposeStart(services []string) error {
 	allArgs := append([]string{"up", "-d"}, services...)
@@ -52,35 +116,259 @@ func (r *SystemRunner) ComposeStop(services []string) error {
 	return r.executeComposeCommand(allArgs...)
 }
 
-func (r *SystemRunner) executeComposeCommand(args ...string) error {
-	// The docker compose command will automatically read the .env file,
-	if err := r.files.EnsureFilesInWD("docker-compose.yml", ".env"); err != nil {
+// ComposeValidate validates the docker compose file (including the interpolation of the variables in .env) by using
+// the system's docker compose command. The errors reported by docker compose are included in the returned error
+func (r *SystemRunner) ComposeValidate() error {
+	fullCmd, err := r.buildComposeCommand("config", "-q")
+	if err != nil {
 		return err
 	}
 
-	fullCmd := r.buildDockerComposeCommandStr(strings.Join(args, " "))
+	var stderr bytes.Buffer
+	cmd := r.commands.ExecShellCommandWithStderr(fullCmd, &stderr)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrComposeConfigInvalid, strings.TrimSpace(stderr.String()), err)
+	}
+
+	return nil
+}
+
+// ComposePs returns the state of the containers of the services by using the system's docker compose command.
+// Stopped containers are included. If no service is provided, the containers of all services are returned
+func (r *SystemRunner) ComposePs(services []string) ([]ServiceStatus, error) {
+	allArgs := append([]string{"ps", "--all", "--format", "'{{.Service}} {{.State}} {{.ExitCode}} {{.Health}}'"}, services...)
+	fullCmd, err := r.buildComposeCommand(allArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	cmd := r.commands.ExecShellCommandWithStdout(fullCmd, &stdout)
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	var statuses []ServiceStatus
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 && len(fields) != 4 {
+			slog.Warn("Ignoring unexpected line in docker compose ps output", "line", line)
+			continue
+		}
+		exitCode, err := strconv.Atoi(fields[2])
+		if err != nil {
+			slog.Warn("Ignoring unexpected line in docker compose ps output", "line", line)
+			continue
+		}
+		status := ServiceStatus{Service: fields[0], State: fields[1], ExitCode: exitCode}
+		if len(fields) == 4 {
+			status.Health = fields[3]
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// ComposeServices returns the names of the services defined in the docker compose file by using the system's docker
+// compose command
+func (r *SystemRunner) ComposeServices() ([]string, error) {
+	fullCmd, err := r.buildComposeCommand("config", "--services")
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	cmd := r.commands.ExecShellCommandWithStdout(fullCmd, &stdout)
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(stdout.String()), nil
+}
+
+// ContainerLogs shows the logs of a service by using the system's docker compose command. If tail is greater than
+// zero, only the last tail lines are shown. If follow is true, the logs are streamed until the context is done.
+// If name is empty, the logs of all services are shown
+func (r *SystemRunner) ContainerLogs(ctx context.Context, name string, tail int, follow bool) error {
+	allArgs := []string{"logs"}
+	if tail > 0 {
+		allArgs = append(allArgs, "--tail", strconv.Itoa(tail))
+	}
+	if follow {
+		allArgs = append(allArgs, "-f")
+	}
+	if name != "" {
+		allArgs = append(allArgs, name)
+	}
+
+	fullCmd, err := r.buildComposeCommand(allArgs...)
+	if err != nil {
+		return err
+	}
+	cmd := r.commands.ExecShellCommandContext(ctx, fullCmd)
+
+	return cmd.Run()
+}
+
+func (r *SystemRunner) executeComposeCommand(args ...string) error {
+	fullCmd, err := r.buildComposeCommand(args...)
+	if err != nil {
+		return err
+	}
 	cmd := r.commands.ExecShellCommand(fullCmd)
 
 	return cmd.Run()
 }
 
+// buildComposeCommand checks that the files docker compose needs are present and builds the full command
+func (r *SystemRunner) buildComposeCommand(args ...string) (string, error) {
+	requiredFiles := []string{"docker-compose.yml"}
+	if len(r.envFiles) == 0 {
+		// The docker compose command will automatically read the .env file,
+		requiredFiles = append(requiredFiles, ".env")
+	}
+	requiredFiles = append(requiredFiles, r.extraRequiredFiles...)
+	if err := r.files.EnsureFilesInWD(requiredFiles...); err != nil {
+		return "", err
+	}
+
+	r.warnIfUserVarsAreDefined()
+
+	// --env-file is an option of docker compose itself, so it goes before the subcommand
+	var composeArgs []string
+	textFormatter := format.NewDefaultTextFormatter()
+	for _, path := range r.envFiles {
+		composeArgs = append(composeArgs, "--env-file", textFormatter.QuoteForPOSIXShell(path))
+	}
+	cmd := strings.Join(append(composeArgs, args...), " ")
+	if r.buildDockerComposeCommandStr != nil {
+		return r.buildDockerComposeCommandStr(cmd), nil
+	}
+	return buildDockerComposeCommandStrWithEnv(r.env, r.ids, cmd), nil
+}
+
+// warnIfUserVarsAreDefined warns the user when the variables that BuildDockerComposeCommandStr injects are also
+// defined in the environment (typically in .env). Docker compose gives precedence to the variables of the shell
+// over the ones in .env, so the values in .env are silently ignored. The injected value is the current user's, or
+// its HOMELAB_OVERRIDE_UID or HOMELAB_OVERRIDE_GID override
+func (r *SystemRunner) warnIfUserVarsAreDefined() {
+	injectedIDs := []struct {
+		varName  string
+		injected func() int
+	}{
+		{"HOMELAB_GENERAL_UID", func() int { return getIDOverride(r.env, "HOMELAB_OVERRIDE_UID", r.ids.UID()) }},
+		{"HOMELAB_GENERAL_GID", func() int { return getIDOverride(r.env, "HOMELAB_OVERRIDE_GID", r.ids.GID()) }},
+	}
+	for _, id := range injectedIDs {
+		if value, exists := r.env.GetEnv(id.varName); exists {
+			slog.Warn("Variable is defined in the environment but will be overridden with the injected value",
+				"varName", id.varName, "value", value, "injected", id.injected())
+		}
+	}
+}
+
 func (r *SystemRunner) ContainerExec(container string, cmd string) error {
 	fullCmd := fmt.Sprintf("docker container exec %s %s", container, cmd)
 	systemCmd := r.commands.ExecShellCommand(fullCmd)
 	return systemCmd.Run()
 }
 
-func (r *SystemRunner) WaitUntilContainerExecIsSuccessful(container string, cmd string) error {
-	slog.Debug("Waiting until docker container exec command is successful", "container", container, "cmd", cmd)
-	maxRetries := 30
-	retryInterval := 1 * time.Second
+// WaitUntilContainerExecIsSuccessful runs a command in a container until it succeeds, once every second. It is tried
+// until timeout has passed or, when timeout is zero, for containerWaitMaxRetries seconds. The time the command takes to
+// run counts towards the timeout
+func (r *SystemRunner) WaitUntilContainerExecIsSuccessful(container string, cmd string, timeout time.Duration) error {
+	slog.Debug("Waiting until docker container exec command is successful", "container", container, "cmd", cmd, "timeout", timeout)
+	if timeout <= 0 {
+		timeout = containerWaitMaxRetries * containerWaitRetryInterval
+	}
+	deadline := r.time.Now().Add(timeout)
+	for attempts := 1; ; attempts++ {
+		if err := r.ContainerExec(container, cmd); err == nil {
+			return nil
+		}
+		remaining := deadline.Sub(r.time.Now())
+		if remaining <= 0 {
+			return fmt.Errorf("%w: docker container exec command %q failed %d times in %s on container %s", ErrTooManyRetries, cmd, attempts, timeout, container)
+		}
+		r.time.Sleep(min(containerWaitRetryInterval, remaining))
+	}
+}
+
+// WaitUntilContainerHealthy polls the health status that docker reports for a container until it is "healthy". It is
+// an alternative to WaitUntilContainerExecIsSuccessful for the containers that define a health check. Failing to
+// inspect the container, for example because it is still being created, is retried. ErrNoHealthCheck is returned
+// straight away if the container has no health check
+func (r *SystemRunner) WaitUntilContainerHealthy(container string) error {
+	slog.Debug("Waiting until docker container is healthy", "container", container)
+	fullCmd := fmt.Sprintf("docker container inspect -f '{{if .State.Health}}{{.State.Health.Status}}{{end}}' %s", container)
+
+	status := ""
+	for i := 0; i < containerWaitMaxRetries; i++ {
+		var stdout bytes.Buffer
+		cmd := r.commands.ExecShellCommandWithStdout(fullCmd, &stdout)
+		if err := cmd.Run(); err == nil {
+			status = strings.TrimSpace(stdout.String())
+			switch status {
+			case "healthy":
+				return nil
+			case "":
+				return fmt.Errorf("%w: %s", ErrNoHealthCheck, container)
+			}
+		}
+		r.time.Sleep(containerWaitRetryInterval)
+	}
+
+	return fmt.Errorf("%w: container %s was not healthy after %d checks (last status %q)", ErrTooManyRetries, container, containerWaitMaxRetries, status)
+}
 
+// WaitUntilServicesAreHealthy polls docker compose ps until the containers of the services are running and, for the
+// ones that have a health check, healthy. Containers that exited successfully, such as the ones that run a one-off
+// task, are done and not waited for. If no service is provided, all the containers of docker compose are waited
+// for. An error is returned if the services are not healthy within the timeout
+func (r *SystemRunner) WaitUntilServicesAreHealthy(services []string, timeout time.Duration) error {
+	slog.Debug("Waiting until services are healthy", "services", services, "timeout", timeout)
+	maxRetries := max(1, int(timeout/serviceHealthPollInterval))
+
+	var pending []string
 	for i := 0; i < maxRetries; i++ {
-		if err := r.ContainerExec(container, cmd); err == nil {
+		statuses, err := r.ComposePs(services)
+		if err != nil {
+			return err
+		}
+		pending = unhealthyServices(statuses, services)
+		if len(pending) == 0 {
 			return nil
 		}
-		r.time.Sleep(retryInterval)
+		slog.Debug("Services are not healthy yet", "services", pending)
+		r.time.Sleep(serviceHealthPollInterval)
 	}
 
-	return fmt.Errorf("%w: docker container exec command %q retried %d times on container %s", ErrTooManyRetries, cmd, maxRetries, container)
+	return fmt.Errorf("%w: services %v were not healthy after %s", ErrTooManyRetries, pending, timeout)
+}
+
+// unhealthyServices returns the services whose containers are not running or not healthy. A container that exited
+// with code 0 has finished its work, so it is not unhealthy. A requested service without container is not healthy
+// either
+func unhealthyServices(statuses []ServiceStatus, services []string) []string {
+	var unhealthy []string
+	for _, status := range statuses {
+		if status.State == "exited" && status.ExitCode == 0 {
+			continue
+		}
+		if status.State != "running" || (status.Health != "" && status.Health != "healthy") {
+			unhealthy = append(unhealthy, status.Service)
+		}
+	}
+	for _, service := range services {
+		found := slices.ContainsFunc(statuses, func(status ServiceStatus) bool {
+			return status.Service == service
+		})
+		if !found {
+			unhealthy = append(unhealthy, service)
+		}
+	}
+	return unhealthy
 }
@@ -1,12 +1,19 @@
 package docker
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+	"github.com/google/go-cmp/cmp"
 )
 
 // mockRunnableCommand is a simple mock for RunnableCommand
@@ -22,7 +29,10 @@ func (m *mockRunnableCommand) Run() error {
 }
 
 type mockCommands struct {
-	execShellCommand func(cmd string) system.RunnableCommand
+	execShellCommand        func(cmd string) system.RunnableCommand
+	execShellCommandContext func(ctx context.Context, cmd string) system.RunnableCommand
+	execShellCommandStderr  func(cmd string, stderr io.Writer) system.RunnableCommand
+	execShellCommandStdout  func(cmd string, stdout io.Writer) system.RunnableCommand
 }
 
 func (m *mockCommands) ExecCommand(name string, arg ...string) system.RunnableCommand {
@@ -34,6 +44,31 @@ func (m *mockCommands) ExecShellCommand(cmd string) system.RunnableCommand {
 	}
 	return nil
 }
+func (m *mockCommands) ExecShellCommandContext(ctx context.Context, cmd string) system.RunnableCommand {
+	if m.execShellCommandContext != nil {
+		return m.execShellCommandContext(ctx, cmd)
+	}
+	return nil
+}
+func (m *mockCommands) ExecShellCommandWithStderr(cmd string, stderr io.Writer) system.RunnableCommand {
+	if m.execShellCommandStderr != nil {
+		return m.execShellCommandStderr(cmd, stderr)
+	}
+	return nil
+}
+func (m *mockCommands) ExecShellCommandWithStdout(cmd string, stdout io.Writer) system.RunnableCommand {
+	if m.execShellCommandStdout != nil {
+		return m.execShellCommandStdout(cmd, stdout)
+	}
+	return nil
+}
+func (m *mockCommands) ExecShellCommandContextWithStdout(ctx context.Context, cmd string, stdout io.Writer) system.RunnableCommand {
+	if m.execShellCommandContext != nil {
+		return m.execShellCommandContext(ctx, cmd)
+	}
+	return m.ExecShellCommandWithStdout(cmd, stdout)
+}
+func (m *mockCommands) LookPath(name string) (string, error) { return "", nil }
 
 type mockFiles struct {
 	ensureFilesInWD func(filenames ...string) error
@@ -57,16 +92,56 @@ func (m *mockFiles) EmptyDir(path string) error {
 func (m *mockFiles) CopyDir(srcPath string, dstPath string) error {
 	return nil
 }
-func (m *mockFiles) Getwd() (dir string, err error)           { return "", nil }
-func (m *mockFiles) WriteFile(path string, data []byte) error { return nil }
-func (m *mockFiles) GetAbsPath(path string) (string, error)   { return "", nil }
+func (m *mockFiles) CopyDirWithOptions(srcPath string, dstPath string, opts system.CopyDirOptions) ([]string, error) {
+	return nil, nil
+}
+func (m *mockFiles) Getwd() (dir string, err error)                    { return "", nil }
+func (m *mockFiles) WriteFile(path string, data []byte) error          { return nil }
+func (m *mockFiles) WriteSecretFile(path string, data []byte) error    { return nil }
+func (m *mockFiles) WriteNewFile(path string, data []byte) error       { return nil }
+func (m *mockFiles) CreateNewFile(path string) (io.WriteCloser, error) { return nil, nil }
+func (m *mockFiles) ReadFile(path string) ([]byte, error)              { return nil, nil }
+func (m *mockFiles) GetAbsPath(path string) (string, error)            { return "", nil }
+func (m *mockFiles) ListFiles(path string) ([]os.FileInfo, error)      { return nil, nil }
+func (m *mockFiles) RemoveFile(path string) error                      { return nil }
+func (m *mockFiles) DirSize(path string) (int64, error)                { return 0, nil }
+
+// mockTime is a clock that only moves forward when Sleep is called
+type mockTime struct {
+	now time.Time
+}
+
+func (t *mockTime) Sleep(d time.Duration) { t.now = t.now.Add(d) }
+
+func (t *mockTime) Now() time.Time { return t.now }
+
+func (t *mockTime) After(d time.Duration) <-chan time.Time { return nil }
+
+type mockEnv struct {
+	getEnvFunc func(varName string) (string, bool)
+}
 
-type mockTime struct{}
+// GetEnv assumes by default that the variable does NOT exist
+func (m *mockEnv) GetEnv(varName string) (string, bool) {
+	if m.getEnvFunc != nil {
+		return m.getEnvFunc(varName)
+	}
+	return "", false
+}
+func (m *mockEnv) GetRequiredEnv(varName string) (string, error) { return "", nil }
+func (m *mockEnv) GetBoolEnv(varName string) (bool, bool)        { return false, false }
+func (m *mockEnv) GetIntEnv(varName string) (int, bool, error)   { return 0, false, nil }
+func (m *mockEnv) GetAllEnv() map[string]string                  { return map[string]string{} }
+func (m *mockEnv) ListByPrefix(prefix string) map[string]string  { return map[string]string{} }
 
-func (t *mockTime) Sleep(d time.Duration) {
-	// noop
+type mockUserIDs struct {
+	uid int
+	gid int
 }
 
+func (m *mockUserIDs) UID() int { return m.uid }
+func (m *mockUserIDs) GID() int { return m.gid }
+
 func mockBuildDockerComposeCommandStr(cmd string) string {
 	return "docker compose " + cmd
 }
@@ -83,6 +158,7 @@ func TestSystemRunner_ComposeStart_NoServices(t *testing.T) {
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
@@ -97,6 +173,43 @@ func TestSystemRunner_ComposeStart_NoServices(t *testing.T) {
 	}
 }
 
+func TestSystemRunner_ComposeStart_WithEnvFiles(t *testing.T) {
+	var capturedCmd string
+	var capturedRequiredFiles []string
+	commands := &mockCommands{
+		execShellCommand: func(cmd string) system.RunnableCommand {
+			capturedCmd = cmd
+			return &mockRunnableCommand{}
+		},
+	}
+	runner := (&SystemRunner{
+		commands: commands,
+		files: &mockFiles{
+			ensureFilesInWD: func(filenames ...string) error {
+				capturedRequiredFiles = filenames
+				return nil
+			},
+		},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}).WithEnvFiles("/etc/homelab/base.env", "/etc/homelab/host's.env")
+
+	err := runner.ComposeStart([]string{"service"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := `docker compose --env-file '/etc/homelab/base.env' --env-file '/etc/homelab/host'"'"'s.env' up -d service`
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command to be %q, got %q", expectedCmd, capturedCmd)
+	}
+	// The .env file of the working directory is not used, so it is not required
+	if diff := cmp.Diff([]string{"docker-compose.yml"}, capturedRequiredFiles); diff != "" {
+		t.Errorf("required files mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestSystemRunner_ComposeStart_OneService(t *testing.T) {
 	var capturedCmd string
 	commands := &mockCommands{
@@ -109,6 +222,7 @@ func TestSystemRunner_ComposeStart_OneService(t *testing.T) {
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
@@ -135,6 +249,7 @@ func TestSystemRunner_ComposeStart_MultipleServices(t *testing.T) {
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
@@ -149,6 +264,169 @@ func TestSystemRunner_ComposeStart_MultipleServices(t *testing.T) {
 	}
 }
 
+func TestSystemRunner_ComposeStart_ValidatesDefaultRequiredFiles(t *testing.T) {
+	var capturedFilenames []string
+	runner := &SystemRunner{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{}
+			},
+		},
+		files: &mockFiles{
+			ensureFilesInWD: func(filenames ...string) error {
+				capturedFilenames = filenames
+				return nil
+			},
+		},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.ComposeStart([]string{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedFilenames := []string{"docker-compose.yml", ".env"}
+	if diff := cmp.Diff(expectedFilenames, capturedFilenames); diff != "" {
+		t.Errorf("filenames mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSystemRunner_ComposeStart_ValidatesExtraRequiredFiles(t *testing.T) {
+	var capturedFilenames []string
+	runner := (&SystemRunner{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{}
+			},
+		},
+		files: &mockFiles{
+			ensureFilesInWD: func(filenames ...string) error {
+				capturedFilenames = filenames
+				return nil
+			},
+		},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}).WithRequiredFiles("immich.env", "paperless.env")
+
+	err := runner.ComposeStart([]string{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedFilenames := []string{"docker-compose.yml", ".env", "immich.env", "paperless.env"}
+	if diff := cmp.Diff(expectedFilenames, capturedFilenames); diff != "" {
+		t.Errorf("filenames mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSystemRunner_ComposeStart_MissingExtraRequiredFile(t *testing.T) {
+	expectedErr := errors.New("required file not found: immich.env")
+	commandExecuted := false
+	runner := (&SystemRunner{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				commandExecuted = true
+				return &mockRunnableCommand{}
+			},
+		},
+		files: &mockFiles{
+			ensureFilesInWD: func(filenames ...string) error {
+				return expectedErr
+			},
+		},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}).WithRequiredFiles("immich.env")
+
+	err := runner.ComposeStart([]string{})
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap expectedErr, got: %v", err)
+	}
+	if commandExecuted {
+		t.Errorf("expected docker compose not to be executed")
+	}
+}
+
+func TestSystemRunner_ComposeStart_WarnsWhenUserVarsAreDefined(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+	runner := &SystemRunner{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{}
+			},
+		},
+		files: &mockFiles{},
+		time:  &mockTime{},
+		env: &mockEnv{
+			getEnvFunc: func(varName string) (string, bool) {
+				switch varName {
+				case "HOMELAB_GENERAL_UID":
+					return "1000", true
+				case "HOMELAB_OVERRIDE_UID":
+					return "1026", true
+				}
+				return "", false
+			},
+		},
+		ids:                          &mockUserIDs{uid: 1001, gid: 100},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.ComposeStart([]string{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	output := logs.String()
+	if !strings.Contains(output, "level=WARN") || !strings.Contains(output, "varName=HOMELAB_GENERAL_UID") {
+		t.Errorf("expected a warning about HOMELAB_GENERAL_UID, got logs: %q", output)
+	}
+	// The override is injected instead of the current user's ID
+	if !strings.Contains(output, "injected=1026") {
+		t.Errorf("expected the warning to contain the injected value 1026, got logs: %q", output)
+	}
+	if strings.Contains(output, "varName=HOMELAB_GENERAL_GID") {
+		t.Errorf("expected no warning about HOMELAB_GENERAL_GID, got logs: %q", output)
+	}
+}
+
+func TestSystemRunner_ComposeStart_NoWarningWhenUserVarsAreNotDefined(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+	runner := &SystemRunner{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				return &mockRunnableCommand{}
+			},
+		},
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.ComposeStart([]string{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if strings.Contains(logs.String(), "level=WARN") {
+		t.Errorf("expected no warnings, got logs: %q", logs.String())
+	}
+}
+
 func TestSystemRunner_ComposeStop_NoServices(t *testing.T) {
 	var capturedCmd string
 	commands := &mockCommands{
@@ -161,6 +439,7 @@ func TestSystemRunner_ComposeStop_NoServices(t *testing.T) {
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
@@ -187,6 +466,7 @@ func TestSystemRunner_ComposeStop_OneService(t *testing.T) {
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
@@ -213,6 +493,7 @@ func TestSystemRunner_ComposeStop_MultipleServices(t *testing.T) {
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
@@ -227,10 +508,10 @@ func TestSystemRunner_ComposeStop_MultipleServices(t *testing.T) {
 	}
 }
 
-func TestSystemRunner_ContainerExec_ExecutesCorrectCommand(t *testing.T) {
+func TestSystemRunner_ComposeValidate_ExecutesCorrectCommand(t *testing.T) {
 	var capturedCmd string
 	commands := &mockCommands{
-		execShellCommand: func(cmd string) system.RunnableCommand {
+		execShellCommandStderr: func(cmd string, stderr io.Writer) system.RunnableCommand {
 			capturedCmd = cmd
 			return &mockRunnableCommand{}
 		},
@@ -239,28 +520,30 @@ func TestSystemRunner_ContainerExec_ExecutesCorrectCommand(t *testing.T) {
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
-	err := runner.ContainerExec("cont", "echo hello")
+	err := runner.ComposeValidate()
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
-	expectedCmd := "docker container exec cont echo hello"
+	expectedCmd := "docker compose config -q"
 	if capturedCmd != expectedCmd {
-		t.Errorf("wrong command issued %q, expected %q", capturedCmd, expectedCmd)
+		t.Errorf("expected command to be %q, got %q", expectedCmd, capturedCmd)
 	}
 }
 
-func TestSystemRunner_WaitUntilContainerExecIsSuccessful_SucceedsImmediately(t *testing.T) {
-	var callCount int
+func TestSystemRunner_ComposeValidate_FailurePropagatesWithStderr(t *testing.T) {
+	expectedErr := errors.New("exit status 15")
+	stderrOutput := "invalid interpolation format for services.web.image"
 	commands := &mockCommands{
-		execShellCommand: func(cmd string) system.RunnableCommand {
-			callCount++
+		execShellCommandStderr: func(cmd string, stderr io.Writer) system.RunnableCommand {
 			return &mockRunnableCommand{
 				runFunc: func() error {
-					return nil // Success on the first try
+					fmt.Fprintln(stderr, stderrOutput)
+					return expectedErr
 				},
 			}
 		},
@@ -269,109 +552,747 @@ func TestSystemRunner_WaitUntilContainerExecIsSuccessful_SucceedsImmediately(t *
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.ComposeValidate()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrComposeConfigInvalid) {
+		t.Errorf("expected ErrComposeConfigInvalid, got: %v", err)
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap expectedErr, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), stderrOutput) {
+		t.Errorf("expected error message to contain stderr %q, got %q", stderrOutput, err.Error())
+	}
+}
+
+func TestSystemRunner_ComposeValidate_MissingFiles(t *testing.T) {
+	expectedErr := errors.New("missing files")
+	runner := &SystemRunner{
+		commands: &mockCommands{},
+		files: &mockFiles{
+			ensureFilesInWD: func(filenames ...string) error {
+				return expectedErr
+			},
+		},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.ComposeValidate()
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap expectedErr, got: %v", err)
+	}
+}
+
+func TestSystemRunner_ContainerLogs_WithTail(t *testing.T) {
+	var capturedCmd string
+	commands := &mockCommands{
+		execShellCommandContext: func(ctx context.Context, cmd string) system.RunnableCommand {
+			capturedCmd = cmd
+			return &mockRunnableCommand{}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
-	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd")
+	err := runner.ContainerLogs(context.Background(), "service", 50, false)
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
-	if callCount != 1 {
-		t.Errorf("expected 1 exec call, got: %d", callCount)
+	expectedCmd := "docker compose logs --tail 50 service"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command to be %q, got %q", expectedCmd, capturedCmd)
 	}
 }
 
-func TestSystemRunner_WaitUntilContainerExecIsSuccessful_SucceedsAfterRetries(t *testing.T) {
-	var callCount int
+func TestSystemRunner_ContainerLogs_WithoutTail(t *testing.T) {
+	var capturedCmd string
 	commands := &mockCommands{
-		execShellCommand: func(cmd string) system.RunnableCommand {
-			callCount++
-			return &mockRunnableCommand{
-				runFunc: func() error {
-					if callCount < 5 {
-						return fmt.Errorf("not ready yet")
-					}
-					return nil // Success on the 5th try
-				},
-			}
+		execShellCommandContext: func(ctx context.Context, cmd string) system.RunnableCommand {
+			capturedCmd = cmd
+			return &mockRunnableCommand{}
 		},
 	}
 	runner := &SystemRunner{
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
-	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd")
+	err := runner.ContainerLogs(context.Background(), "service", 0, false)
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
-	if callCount != 5 {
-		t.Errorf("expected 5 exec calls, got: %d", callCount)
+	expectedCmd := "docker compose logs service"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command to be %q, got %q", expectedCmd, capturedCmd)
 	}
 }
 
-func TestSystemRunner_WaitUntilContainerExecIsSuccessful_ExhaustsRetries(t *testing.T) {
-	var callCount int
+func TestSystemRunner_ContainerLogs_AllServices(t *testing.T) {
+	var capturedCmd string
 	commands := &mockCommands{
-		execShellCommand: func(cmd string) system.RunnableCommand {
-			callCount++
-			return &mockRunnableCommand{
-				runFunc: func() error {
-					return fmt.Errorf("always fails")
-				},
-			}
+		execShellCommandContext: func(ctx context.Context, cmd string) system.RunnableCommand {
+			capturedCmd = cmd
+			return &mockRunnableCommand{}
 		},
 	}
 	runner := &SystemRunner{
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
-	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd")
+	err := runner.ContainerLogs(context.Background(), "", 10, false)
 
-	if err == nil {
-		t.Fatal("expected error, got nil")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
 	}
-	if !errors.Is(err, ErrTooManyRetries) {
-		t.Errorf("expected ErrTooManyRetries, got: %v", err)
+	expectedCmd := "docker compose logs --tail 10"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command to be %q, got %q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestSystemRunner_ContainerLogs_WithFollow(t *testing.T) {
+	var capturedCmd string
+	commands := &mockCommands{
+		execShellCommandContext: func(ctx context.Context, cmd string) system.RunnableCommand {
+			capturedCmd = cmd
+			return &mockRunnableCommand{}
+		},
 	}
-	if callCount != 30 {
-		t.Errorf("expected %d exec calls, got: %d", 30, callCount)
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.ContainerLogs(context.Background(), "service", 0, true)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "docker compose logs -f service"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command to be %q, got %q", expectedCmd, capturedCmd)
 	}
 }
 
-func TestSystemRunner_WaitUntilContainerExecIsSuccessful_ExecutesCorrectCommand(t *testing.T) {
+func TestSystemRunner_ContainerLogs_WithTailAndFollow(t *testing.T) {
 	var capturedCmd string
 	commands := &mockCommands{
-		execShellCommand: func(cmd string) system.RunnableCommand {
-			return &mockRunnableCommand{
-				runFunc: func() error {
-					capturedCmd = cmd
-					return nil // Success on the first try
-				},
-			}
+		execShellCommandContext: func(ctx context.Context, cmd string) system.RunnableCommand {
+			capturedCmd = cmd
+			return &mockRunnableCommand{}
 		},
 	}
 	runner := &SystemRunner{
 		commands:                     commands,
 		files:                        &mockFiles{},
 		time:                         &mockTime{},
+		env:                          &mockEnv{},
 		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
 	}
 
-	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd")
+	err := runner.ContainerLogs(context.Background(), "service", 20, true)
 
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
-	expectedCmd := "docker container exec test-container test-cmd"
+	expectedCmd := "docker compose logs --tail 20 -f service"
 	if capturedCmd != expectedCmd {
-		t.Errorf("wrong command issued %q, expected %q", capturedCmd, expectedCmd)
+		t.Errorf("expected command to be %q, got %q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestSystemRunner_ContainerLogs_PassesContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var capturedCtx context.Context
+	commands := &mockCommands{
+		execShellCommandContext: func(ctx context.Context, cmd string) system.RunnableCommand {
+			capturedCtx = ctx
+			return &mockRunnableCommand{}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.ContainerLogs(ctx, "service", 0, true)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedCtx != ctx {
+		t.Errorf("expected the context to be passed to the command")
+	}
+}
+
+func TestSystemRunner_ContainerExec_ExecutesCorrectCommand(t *testing.T) {
+	var capturedCmd string
+	commands := &mockCommands{
+		execShellCommand: func(cmd string) system.RunnableCommand {
+			capturedCmd = cmd
+			return &mockRunnableCommand{}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.ContainerExec("cont", "echo hello")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "docker container exec cont echo hello"
+	if capturedCmd != expectedCmd {
+		t.Errorf("wrong command issued %q, expected %q", capturedCmd, expectedCmd)
+	}
+}
+
+func TestSystemRunner_WaitUntilContainerExecIsSuccessful_SucceedsImmediately(t *testing.T) {
+	var callCount int
+	commands := &mockCommands{
+		execShellCommand: func(cmd string) system.RunnableCommand {
+			callCount++
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					return nil // Success on the first try
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd", 0)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if callCount != 1 {
+		t.Errorf("expected 1 exec call, got: %d", callCount)
+	}
+}
+
+func TestSystemRunner_WaitUntilContainerExecIsSuccessful_SucceedsAfterRetries(t *testing.T) {
+	var callCount int
+	commands := &mockCommands{
+		execShellCommand: func(cmd string) system.RunnableCommand {
+			callCount++
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					if callCount < 5 {
+						return fmt.Errorf("not ready yet")
+					}
+					return nil // Success on the 5th try
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd", 0)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if callCount != 5 {
+		t.Errorf("expected 5 exec calls, got: %d", callCount)
+	}
+}
+
+func TestSystemRunner_WaitUntilContainerExecIsSuccessful_ExhaustsRetries(t *testing.T) {
+	var callCount int
+	commands := &mockCommands{
+		execShellCommand: func(cmd string) system.RunnableCommand {
+			callCount++
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					return fmt.Errorf("always fails")
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd", 0)
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrTooManyRetries) {
+		t.Errorf("expected ErrTooManyRetries, got: %v", err)
+	}
+	// One call every second, from the start until the deadline, both included
+	if callCount != 31 {
+		t.Errorf("expected %d exec calls, got: %d", 31, callCount)
+	}
+}
+
+func TestSystemRunner_WaitUntilContainerExecIsSuccessful_RetriesForTimeout(t *testing.T) {
+	var callCount int
+	commands := &mockCommands{
+		execShellCommand: func(cmd string) system.RunnableCommand {
+			callCount++
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					return fmt.Errorf("always fails")
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd", 2*time.Minute)
+
+	if !errors.Is(err, ErrTooManyRetries) {
+		t.Errorf("expected ErrTooManyRetries, got: %v", err)
+	}
+	if callCount != 121 {
+		t.Errorf("expected %d exec calls, got: %d", 121, callCount)
+	}
+}
+
+func TestSystemRunner_WaitUntilContainerExecIsSuccessful_SlowCommandCountsTowardsTimeout(t *testing.T) {
+	clock := &mockTime{}
+	var callCount int
+	commands := &mockCommands{
+		execShellCommand: func(cmd string) system.RunnableCommand {
+			callCount++
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					clock.Sleep(20 * time.Second)
+					return fmt.Errorf("always fails")
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         clock,
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd", time.Minute)
+
+	if !errors.Is(err, ErrTooManyRetries) {
+		t.Errorf("expected ErrTooManyRetries, got: %v", err)
+	}
+	// The calls end at 20s, 41s and 62s, so the deadline has passed after the third one
+	if callCount != 3 {
+		t.Errorf("expected %d exec calls, got: %d", 3, callCount)
+	}
+	if elapsed := clock.Now().Sub(time.Time{}); elapsed > time.Minute+20*time.Second {
+		t.Errorf("expected to give up shortly after the timeout, waited %s", elapsed)
+	}
+}
+
+// newContainerInspectRunner returns a runner whose docker container inspect calls return each result in turn,
+// repeating the last one, and a pointer to the commands that were run
+func newContainerInspectRunner(results ...func(stdout io.Writer) error) (*SystemRunner, *[]string) {
+	var capturedCmds []string
+	commands := &mockCommands{
+		execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+			result := results[min(len(capturedCmds), len(results)-1)]
+			capturedCmds = append(capturedCmds, cmd)
+			return &mockRunnableCommand{
+				runFunc: func() error { return result(stdout) },
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+	return runner, &capturedCmds
+}
+
+// inspectStatus returns a docker container inspect result that writes status
+func inspectStatus(status string) func(stdout io.Writer) error {
+	return func(stdout io.Writer) error {
+		_, err := io.WriteString(stdout, status+"\n")
+		return err
+	}
+}
+
+func TestSystemRunner_WaitUntilContainerHealthy_BecomesHealthy(t *testing.T) {
+	runner, capturedCmds := newContainerInspectRunner(
+		func(stdout io.Writer) error { return errors.New("no such container") },
+		inspectStatus("starting"),
+		inspectStatus("healthy"),
+	)
+
+	err := runner.WaitUntilContainerHealthy("test-container")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(*capturedCmds) != 3 {
+		t.Errorf("expected 3 inspect calls, got: %d", len(*capturedCmds))
+	}
+	expectedCmd := "docker container inspect -f '{{if .State.Health}}{{.State.Health.Status}}{{end}}' test-container"
+	if (*capturedCmds)[0] != expectedCmd {
+		t.Errorf("expected command %q, got %q", expectedCmd, (*capturedCmds)[0])
+	}
+}
+
+func TestSystemRunner_WaitUntilContainerHealthy_StaysUnhealthy(t *testing.T) {
+	runner, capturedCmds := newContainerInspectRunner(inspectStatus("unhealthy"))
+
+	err := runner.WaitUntilContainerHealthy("test-container")
+
+	if !errors.Is(err, ErrTooManyRetries) {
+		t.Fatalf("expected ErrTooManyRetries, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), `"unhealthy"`) {
+		t.Errorf("expected error to contain the last status, got: %v", err)
+	}
+	if len(*capturedCmds) != containerWaitMaxRetries {
+		t.Errorf("expected %d inspect calls, got: %d", containerWaitMaxRetries, len(*capturedCmds))
+	}
+}
+
+func TestSystemRunner_WaitUntilContainerHealthy_NoHealthCheck(t *testing.T) {
+	runner, capturedCmds := newContainerInspectRunner(inspectStatus(""))
+
+	err := runner.WaitUntilContainerHealthy("test-container")
+
+	if !errors.Is(err, ErrNoHealthCheck) {
+		t.Fatalf("expected ErrNoHealthCheck, got: %v", err)
+	}
+	if len(*capturedCmds) != 1 {
+		t.Errorf("expected 1 inspect call, got: %d", len(*capturedCmds))
+	}
+}
+
+func TestSystemRunner_WaitUntilContainerExecIsSuccessful_ExecutesCorrectCommand(t *testing.T) {
+	var capturedCmd string
+	commands := &mockCommands{
+		execShellCommand: func(cmd string) system.RunnableCommand {
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					capturedCmd = cmd
+					return nil // Success on the first try
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	err := runner.WaitUntilContainerExecIsSuccessful("test-container", "test-cmd", 0)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "docker container exec test-container test-cmd"
+	if capturedCmd != expectedCmd {
+		t.Errorf("wrong command issued %q, expected %q", capturedCmd, expectedCmd)
+	}
+}
+
+func TestSystemRunner_ComposePs_ParsesOutput(t *testing.T) {
+	var capturedCmd string
+	commands := &mockCommands{
+		execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+			capturedCmd = cmd
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					_, err := io.WriteString(stdout, "immich running 0 healthy\npaperless exited 1\n\n")
+					return err
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	statuses, err := runner.ComposePs([]string{"immich", "paperless"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "docker compose ps --all --format '{{.Service}} {{.State}} {{.ExitCode}} {{.Health}}' immich paperless"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command %q, got %q", expectedCmd, capturedCmd)
+	}
+	expected := []ServiceStatus{
+		{Service: "immich", State: "running", Health: "healthy"},
+		{Service: "paperless", State: "exited", ExitCode: 1},
+	}
+	if diff := cmp.Diff(expected, statuses); diff != "" {
+		t.Errorf("statuses mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSystemRunner_ComposePs_CommandFails(t *testing.T) {
+	expectedErr := errors.New("ps failed")
+	commands := &mockCommands{
+		execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					return expectedErr
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	_, err := runner.ComposePs([]string{})
+
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got: %v", expectedErr, err)
+	}
+}
+
+// newComposePsSequenceRunner returns a runner whose docker compose ps calls write each output in turn, repeating the
+// last one, and a pointer to the number of calls
+func newComposePsSequenceRunner(outputs ...string) (*SystemRunner, *int) {
+	var callCount int
+	commands := &mockCommands{
+		execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+			output := outputs[min(callCount, len(outputs)-1)]
+			callCount++
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					_, err := io.WriteString(stdout, output)
+					return err
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+	return runner, &callCount
+}
+
+func TestSystemRunner_WaitUntilServicesAreHealthy_SucceedsAfterRetries(t *testing.T) {
+	runner, callCount := newComposePsSequenceRunner(
+		"immich running 0 starting\n",
+		"immich running 0 starting\nredis running 0\n",
+		"immich running 0 healthy\nredis running 0\n",
+	)
+
+	err := runner.WaitUntilServicesAreHealthy([]string{"immich", "redis"}, time.Minute)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if *callCount != 3 {
+		t.Errorf("expected 3 ps calls, got: %d", *callCount)
+	}
+}
+
+func TestSystemRunner_WaitUntilServicesAreHealthy_TimesOut(t *testing.T) {
+	runner, callCount := newComposePsSequenceRunner("immich running 0 unhealthy\nredis exited 137\n")
+
+	err := runner.WaitUntilServicesAreHealthy([]string{"immich", "redis"}, 10*time.Second)
+
+	if !errors.Is(err, ErrTooManyRetries) {
+		t.Fatalf("expected ErrTooManyRetries, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "[immich redis]") {
+		t.Errorf("expected error to contain the unhealthy services, got: %v", err)
+	}
+	expectedCalls := int(10 * time.Second / serviceHealthPollInterval)
+	if *callCount != expectedCalls {
+		t.Errorf("expected %d ps calls, got: %d", expectedCalls, *callCount)
+	}
+}
+
+func TestSystemRunner_WaitUntilServicesAreHealthy_ChecksOnceWithShortTimeout(t *testing.T) {
+	runner, callCount := newComposePsSequenceRunner("")
+
+	err := runner.WaitUntilServicesAreHealthy([]string{"immich"}, time.Millisecond)
+
+	if !errors.Is(err, ErrTooManyRetries) {
+		t.Fatalf("expected ErrTooManyRetries, got: %v", err)
+	}
+	if *callCount != 1 {
+		t.Errorf("expected 1 ps call, got: %d", *callCount)
+	}
+}
+
+func TestUnhealthyServices(t *testing.T) {
+	statuses := []ServiceStatus{
+		{Service: "no-healthcheck", State: "running"},
+		{Service: "healthy", State: "running", Health: "healthy"},
+		{Service: "starting", State: "running", Health: "starting"},
+		{Service: "exited", State: "exited", ExitCode: 1},
+		{Service: "completed", State: "exited", ExitCode: 0},
+	}
+
+	unhealthy := unhealthyServices(statuses, []string{"healthy", "completed", "missing"})
+
+	if diff := cmp.Diff([]string{"starting", "exited", "missing"}, unhealthy); diff != "" {
+		t.Errorf("unhealthy services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSystemRunner_ComposeServices_ParsesOutput(t *testing.T) {
+	var capturedCmd string
+	commands := &mockCommands{
+		execShellCommandStdout: func(cmd string, stdout io.Writer) system.RunnableCommand {
+			capturedCmd = cmd
+			return &mockRunnableCommand{
+				runFunc: func() error {
+					_, err := io.WriteString(stdout, "adguard\ntraefik\nimmich\n")
+					return err
+				},
+			}
+		},
+	}
+	runner := &SystemRunner{
+		commands:                     commands,
+		files:                        &mockFiles{},
+		time:                         &mockTime{},
+		env:                          &mockEnv{},
+		buildDockerComposeCommandStr: mockBuildDockerComposeCommandStr,
+	}
+
+	services, err := runner.ComposeServices()
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "docker compose config --services"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command %q, got %q", expectedCmd, capturedCmd)
+	}
+	if diff := cmp.Diff([]string{"adguard", "traefik", "immich"}, services); diff != "" {
+		t.Errorf("services mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSystemRunner_ComposeStart_InjectsProvidedUserIDs(t *testing.T) {
+	var capturedCmd string
+	runner := (&SystemRunner{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				capturedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		files: &mockFiles{},
+		time:  &mockTime{},
+		env:   &mockEnv{},
+	}).WithUserIDs(&mockUserIDs{uid: 1026, gid: 100})
+
+	err := runner.ComposeStart([]string{"immich"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "HOMELAB_GENERAL_UID=1026 HOMELAB_GENERAL_GID=100 docker compose up -d immich"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command to be %q, got %q", expectedCmd, capturedCmd)
+	}
+}
+
+func TestSystemRunner_ComposeStart_InjectsRootUserIDs(t *testing.T) {
+	var capturedCmd string
+	runner := (&SystemRunner{
+		commands: &mockCommands{
+			execShellCommand: func(cmd string) system.RunnableCommand {
+				capturedCmd = cmd
+				return &mockRunnableCommand{}
+			},
+		},
+		files: &mockFiles{},
+		time:  &mockTime{},
+		env:   &mockEnv{},
+	}).WithUserIDs(&mockUserIDs{uid: 0, gid: 0})
+
+	err := runner.ComposeStop([]string{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedCmd := "HOMELAB_GENERAL_UID=0 HOMELAB_GENERAL_GID=0 docker compose stop"
+	if capturedCmd != expectedCmd {
+		t.Errorf("expected command to be %q, got %q", expectedCmd, capturedCmd)
 	}
 }
@@ -2,7 +2,11 @@ package dotenv
 
 import (
 	"errors"
+	"io/fs"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
 	"sync"
 
 	"github.com/spf13/viper"
@@ -10,7 +14,9 @@ import (
 
 var (
 	viperInstance *viper.Viper
-	mu            sync.RWMutex
+	// loadedFiles are the paths of the .env files that viperInstance was loaded from, in the order they were merged
+	loadedFiles []string
+	mu          sync.RWMutex
 )
 
 // See the following:
@@ -25,21 +31,77 @@ func GetViper() *viper.Viper {
 	return viperInstance
 }
 
+// LoadedFiles returns the paths of the .env files loaded by LoadDotEnv or LoadDotEnvFiles, in the order they were
+// merged. Returns nil if no .env file was loaded.
+func LoadedFiles() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Clone(loadedFiles)
+}
+
+// userConfigDirName is the directory inside the user's config directory where the fallback .env file is searched
+const userConfigDirName = "auto-homelab"
+
 // LoadDotEnv loads the .env file from the current working directory.
 // It uses Viper to read the .env file and stores the configuration in a package-level Viper instance.
+// If the current working directory has no .env file, the one in the user's config directory
+// ($XDG_CONFIG_HOME/auto-homelab/.env, usually ~/.config/auto-homelab/.env) is loaded instead.
 // If the .env file is not found, it logs an info message and continues without error.
 // If the .env file is found and loaded successfully, it logs an info message.
 // Any other errors during loading are logged as warnings.
 func LoadDotEnv() {
+	paths := []string{"."}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(configDir, userConfigDirName))
+	} else {
+		slog.Debug("Could not find the user's config directory, not searching a .env file in it", "error", err)
+	}
+	loadDotEnvFrom(paths...)
+}
+
+// LoadDotEnvFiles loads the .env files at paths in order, replacing the Viper instance loaded by LoadDotEnv. The
+// values of a file override the ones of the files before it, so that a base file can be followed by the overrides of
+// a host. Files that don't exist are skipped, and any other error while loading a file is logged as a warning.
+func LoadDotEnvFiles(paths ...string) {
+	v := viper.New()
+	v.SetConfigType("env")
+
+	var loaded []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			slog.Info(".env file not found, skipping it", "file", path)
+			continue
+		}
+		v.SetConfigFile(path)
+		if err := v.MergeInConfig(); err != nil {
+			slog.Warn("Error loading .env file, skipping it", "file", path, "error", err.Error())
+			continue
+		}
+		loaded = append(loaded, path)
+	}
+
+	mu.Lock()
+	viperInstance = v
+	loadedFiles = loaded
+	mu.Unlock()
+
+	slog.Info("Loaded .env files", "files", loaded)
+}
+
+// loadDotEnvFrom loads the .env file of the first of paths that has one
+func loadDotEnvFrom(paths ...string) {
 	v := viper.New()
 
 	v.SetConfigName(".env")
 	v.SetConfigType("env")
-	v.AddConfigPath(".")
+	for _, path := range paths {
+		v.AddConfigPath(path)
+	}
 
 	if err := v.ReadInConfig(); err != nil {
-		if errors.Is(err, &viper.ConfigFileNotFoundError{}) {
-			slog.Info(".env file not found in current directory, continuing without it")
+		var notFoundErr viper.ConfigFileNotFoundError
+		if errors.As(err, &notFoundErr) {
+			slog.Info(".env file not found, continuing without it", "searchedPaths", paths)
 			return
 		}
 		slog.Warn("Error loading .env file, continuing without it", "error", err.Error())
@@ -48,9 +110,10 @@ func LoadDotEnv() {
 
 	mu.Lock()
 	viperInstance = v
+	loadedFiles = []string{v.ConfigFileUsed()}
 	mu.Unlock()
 
 	// TODO this slog Info call is using a different format than the rest of the app
-	slog.Info("Successfully loaded .env file from current directory",
+	slog.Info("Successfully loaded .env file",
 		"file", v.ConfigFileUsed())
 }
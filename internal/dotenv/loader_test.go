@@ -0,0 +1,156 @@
+package dotenv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeDotenv writes a .env file with content into dir
+func writeDotenv(t *testing.T, dir string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+}
+
+// resetViper forgets the Viper instance loaded by a test
+func resetViper(t *testing.T) {
+	t.Cleanup(func() {
+		mu.Lock()
+		viperInstance = nil
+		loadedFiles = nil
+		mu.Unlock()
+	})
+}
+
+func TestLoadDotEnvFrom_WorkingDirectoryTakesPrecedence(t *testing.T) {
+	resetViper(t)
+	cwd, fallback := t.TempDir(), t.TempDir()
+	writeDotenv(t, cwd, "HOMELAB_SOURCE=cwd\n")
+	writeDotenv(t, fallback, "HOMELAB_SOURCE=fallback\n")
+
+	loadDotEnvFrom(cwd, fallback)
+
+	v := GetViper()
+	if v == nil {
+		t.Fatal("expected the .env file to be loaded")
+	}
+	if value := v.GetString("HOMELAB_SOURCE"); value != "cwd" {
+		t.Errorf("expected %q, got %q", "cwd", value)
+	}
+	if expected := filepath.Join(cwd, ".env"); v.ConfigFileUsed() != expected {
+		t.Errorf("expected file %q, got %q", expected, v.ConfigFileUsed())
+	}
+	if diff := cmp.Diff([]string{filepath.Join(cwd, ".env")}, LoadedFiles()); diff != "" {
+		t.Errorf("loaded files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadDotEnvFrom_FallbackWhenWorkingDirectoryHasNone(t *testing.T) {
+	resetViper(t)
+	cwd, fallback := t.TempDir(), t.TempDir()
+	writeDotenv(t, fallback, "HOMELAB_SOURCE=fallback\n")
+
+	loadDotEnvFrom(cwd, fallback)
+
+	v := GetViper()
+	if v == nil {
+		t.Fatal("expected the fallback .env file to be loaded")
+	}
+	if value := v.GetString("HOMELAB_SOURCE"); value != "fallback" {
+		t.Errorf("expected %q, got %q", "fallback", value)
+	}
+}
+
+func TestLoadDotEnvFrom_NoFile(t *testing.T) {
+	resetViper(t)
+
+	loadDotEnvFrom(t.TempDir(), t.TempDir())
+
+	if GetViper() != nil {
+		t.Error("expected no .env file to be loaded")
+	}
+	if LoadedFiles() != nil {
+		t.Errorf("expected no loaded files, got %q", LoadedFiles())
+	}
+}
+
+// writeEnvFile writes a .env-style file named name with content into dir, and returns its path
+func writeEnvFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadDotEnvFiles_LaterFilesOverrideEarlierOnes(t *testing.T) {
+	resetViper(t)
+	dir := t.TempDir()
+	base := writeEnvFile(t, dir, "base.env", "HOMELAB_SOURCE=base\nHOMELAB_BASE_ONLY=base\nHOMELAB_OVERRIDDEN=base\n")
+	host := writeEnvFile(t, dir, "host.env", "HOMELAB_SOURCE=host\nHOMELAB_HOST_ONLY=host\n")
+	local := writeEnvFile(t, dir, "local.env", "HOMELAB_OVERRIDDEN=local\n")
+
+	LoadDotEnvFiles(base, host, local)
+
+	if diff := cmp.Diff([]string{base, host, local}, LoadedFiles()); diff != "" {
+		t.Errorf("loaded files mismatch (-want +got):\n%s", diff)
+	}
+
+	v := GetViper()
+	if v == nil {
+		t.Fatal("expected the .env files to be loaded")
+	}
+	expected := map[string]string{
+		"HOMELAB_SOURCE":     "host",
+		"HOMELAB_BASE_ONLY":  "base",
+		"HOMELAB_HOST_ONLY":  "host",
+		"HOMELAB_OVERRIDDEN": "local",
+	}
+	for key, value := range expected {
+		if got := v.GetString(key); got != value {
+			t.Errorf("expected %s=%q, got %q", key, value, got)
+		}
+	}
+}
+
+func TestLoadDotEnvFiles_SkipsMissingFiles(t *testing.T) {
+	resetViper(t)
+	dir := t.TempDir()
+	base := writeEnvFile(t, dir, "base.env", "HOMELAB_SOURCE=base\n")
+
+	LoadDotEnvFiles(filepath.Join(dir, "missing.env"), base, filepath.Join(dir, "host.env"))
+
+	v := GetViper()
+	if v == nil {
+		t.Fatal("expected the existing .env file to be loaded")
+	}
+	if value := v.GetString("HOMELAB_SOURCE"); value != "base" {
+		t.Errorf("expected %q, got %q", "base", value)
+	}
+	if diff := cmp.Diff([]string{base}, LoadedFiles()); diff != "" {
+		t.Errorf("loaded files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadDotEnvFiles_ReplacesDefaultDotenv(t *testing.T) {
+	resetViper(t)
+	cwd, dir := t.TempDir(), t.TempDir()
+	writeDotenv(t, cwd, "HOMELAB_SOURCE=cwd\nHOMELAB_DEFAULT_ONLY=cwd\n")
+	host := writeEnvFile(t, dir, "host.env", "HOMELAB_SOURCE=host\n")
+	loadDotEnvFrom(cwd)
+
+	LoadDotEnvFiles(host)
+
+	v := GetViper()
+	if value := v.GetString("HOMELAB_SOURCE"); value != "host" {
+		t.Errorf("expected %q, got %q", "host", value)
+	}
+	if v.IsSet("HOMELAB_DEFAULT_ONLY") {
+		t.Error("expected the variables of the default .env file not to be loaded")
+	}
+}
@@ -0,0 +1,191 @@
+package dotenv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrDotenvRead        = errors.New("failed to read .env")
+	ErrInvalidDotenvLine = errors.New("invalid .env line")
+)
+
+// exportPrefix is the prefix that some .env files use so that they can also be sourced by a shell
+const exportPrefix = "export "
+
+// Entry is a single line of a .env file. Assignments have a Key, while comments and blank lines don't
+type Entry struct {
+	// Key is the name of the assigned variable, or an empty string for comments and blank lines
+	Key string
+	// Value is the unquoted value of the assigned variable
+	Value string
+	// Comment is the text after the "#" of a comment line, or of the inline comment of an assignment
+	Comment string
+	// Export is true when the assignment is prefixed by "export "
+	Export bool
+	// line is the line exactly as it was read, without its line ending
+	line string
+	// prefix is the text of an assignment line before its key, which holds its indentation and "export " prefix
+	prefix string
+	// inlineComment is the raw inline comment of an assignment, including the whitespace that precedes it
+	inlineComment string
+}
+
+// IsAssignment returns whether the entry assigns a value to a variable
+func (e Entry) IsAssignment() bool {
+	return e.Key != ""
+}
+
+// Dotenv is a parsed .env file, which keeps its lines in order so that comments and formatting are preserved
+type Dotenv struct {
+	Entries []Entry
+	// trailingNewline is true when the last line ends with a line ending
+	trailingNewline bool
+}
+
+// Parse reads a .env file. Comments and blank lines are kept as entries. Values can be unquoted, single quoted (taken
+// literally) or double quoted (where escaped double quotes are unescaped), and unquoted values can be followed by an
+// inline comment. Lines that are neither comments, blank lines nor KEY=value assignments are rejected
+func Parse(r io.Reader) (*Dotenv, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDotenvRead, err)
+	}
+
+	content := string(data)
+	dotenv := &Dotenv{Entries: []Entry{}, trailingNewline: strings.HasSuffix(content, "\n")}
+	if content == "" {
+		return dotenv, nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		entry, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w (line %d): %w", ErrInvalidDotenvLine, i+1, err)
+		}
+		dotenv.Entries = append(dotenv.Entries, entry)
+	}
+
+	return dotenv, nil
+}
+
+// DuplicateKeys returns the keys that are assigned more than once, in the order of their first assignment. Only the
+// last assignment of a duplicate key takes effect, which is rarely what whoever added the first one intended
+func (d *Dotenv) DuplicateKeys() []string {
+	counts := make(map[string]int)
+	var keys []string
+	for _, entry := range d.Entries {
+		if !entry.IsAssignment() {
+			continue
+		}
+		counts[entry.Key]++
+		if counts[entry.Key] == 1 {
+			keys = append(keys, entry.Key)
+		}
+	}
+
+	var duplicates []string
+	for _, key := range keys {
+		if counts[key] > 1 {
+			duplicates = append(duplicates, key)
+		}
+	}
+	return duplicates
+}
+
+// parseLine parses a single line of a .env file, which must not contain its line ending
+func parseLine(line string) (Entry, error) {
+	entry := Entry{line: line}
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return entry, nil
+	}
+	if comment, found := strings.CutPrefix(trimmed, "#"); found {
+		entry.Comment = strings.TrimSpace(comment)
+		return entry, nil
+	}
+
+	if rest, found := strings.CutPrefix(trimmed, exportPrefix); found {
+		entry.Export = true
+		trimmed = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+	entry.prefix = line[:strings.Index(line, trimmed)]
+
+	key, rawValue, found := strings.Cut(trimmed, "=")
+	key = strings.TrimSpace(key)
+	if !found {
+		return Entry{}, fmt.Errorf("missing \"=\" in %q", line)
+	}
+	if key == "" || strings.ContainsFunc(key, unicode.IsSpace) {
+		return Entry{}, fmt.Errorf("invalid key %q", key)
+	}
+
+	value, inlineComment, err := parseValue(rawValue)
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid value of %q: %w", key, err)
+	}
+	entry.Key = key
+	entry.Value = value
+	entry.inlineComment = inlineComment
+	entry.Comment = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(inlineComment), "#"))
+	return entry, nil
+}
+
+// parseValue reverses the quoting of a raw value, and returns it together with its raw inline comment, including the
+// whitespace that precedes it. A "#" only starts a comment when it is outside quotes and preceded by whitespace
+func parseValue(rawValue string) (value string, inlineComment string, err error) {
+	rawValue = strings.TrimSpace(rawValue)
+	if rawValue == "" {
+		return "", "", nil
+	}
+
+	var rest string
+	switch quote := rawValue[0]; quote {
+	case '"':
+		var unescaped strings.Builder
+		closed := false
+		i := 1
+		for ; i < len(rawValue) && !closed; i++ {
+			switch {
+			case rawValue[i] == '\\' && i+1 < len(rawValue) && rawValue[i+1] == '"':
+				// Only double quotes are escaped, so any other backslash is kept
+				i++
+				unescaped.WriteByte('"')
+			case rawValue[i] == '"':
+				closed = true
+			default:
+				unescaped.WriteByte(rawValue[i])
+			}
+		}
+		if !closed {
+			return "", "", errors.New("missing closing double quote")
+		}
+		value, rest = unescaped.String(), rawValue[i:]
+	case '\'':
+		end := strings.IndexByte(rawValue[1:], '\'')
+		if end < 0 {
+			return "", "", errors.New("missing closing single quote")
+		}
+		value, rest = rawValue[1:end+1], rawValue[end+2:]
+	default:
+		value, rest = rawValue, ""
+		for i := 1; i < len(rawValue); i++ {
+			if rawValue[i] == '#' && unicode.IsSpace(rune(rawValue[i-1])) {
+				value = strings.TrimSpace(rawValue[:i])
+				rest = rawValue[len(value):]
+				break
+			}
+		}
+	}
+
+	if trimmedRest := strings.TrimSpace(rest); trimmedRest == "" {
+		return value, "", nil
+	} else if !strings.HasPrefix(trimmedRest, "#") {
+		return "", "", fmt.Errorf("unexpected text after the value: %q", trimmedRest)
+	}
+	return value, rest, nil
+}
@@ -0,0 +1,114 @@
+package dotenv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParse_RepresentativeFile(t *testing.T) {
+	content := `# Database configuration
+HOMELAB_DB_HOST=127.0.0.1
+
+  # Indented comment
+HOMELAB_DB_PASSWORD="p@ss \"word\" # not a comment" # the password
+export HOMELAB_DB_NAME='literal \n value'
+HOMELAB_DB_USER=admin # inline comment
+HOMELAB_DB_PORT = 5432
+HOMELAB_EMPTY=
+HOMELAB_HASH=abc#def
+`
+
+	dotenv, err := Parse(strings.NewReader(content))
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := []Entry{
+		{Comment: "Database configuration"},
+		{Key: "HOMELAB_DB_HOST", Value: "127.0.0.1"},
+		{},
+		{Comment: "Indented comment"},
+		{Key: "HOMELAB_DB_PASSWORD", Value: `p@ss "word" # not a comment`, Comment: "the password"},
+		{Key: "HOMELAB_DB_NAME", Value: `literal \n value`, Export: true},
+		{Key: "HOMELAB_DB_USER", Value: "admin", Comment: "inline comment"},
+		{Key: "HOMELAB_DB_PORT", Value: "5432"},
+		{Key: "HOMELAB_EMPTY", Value: ""},
+		{Key: "HOMELAB_HASH", Value: "abc#def"},
+	}
+	if diff := cmp.Diff(expected, dotenv.Entries, cmpopts.IgnoreUnexported(Entry{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParse_EmptyFile(t *testing.T) {
+	dotenv, err := Parse(strings.NewReader(""))
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(dotenv.Entries) != 0 {
+		t.Errorf("expected no entries, got: %v", dotenv.Entries)
+	}
+}
+
+func TestParse_InvalidLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "missing equals sign", content: "HOMELAB_VAR\n"},
+		{name: "empty key", content: "=value\n"},
+		{name: "key with spaces", content: "HOMELAB VAR=value\n"},
+		{name: "unclosed double quote", content: "HOMELAB_VAR=\"value\n"},
+		{name: "unclosed single quote", content: "HOMELAB_VAR='value\n"},
+		{name: "text after quoted value", content: "HOMELAB_VAR=\"value\" extra\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader("# comment\n" + tc.content))
+
+			if !errors.Is(err, ErrInvalidDotenvLine) {
+				t.Fatalf("expected ErrInvalidDotenvLine, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), "line 2") {
+				t.Errorf("expected error to contain the line number, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestDotenv_DuplicateKeys(t *testing.T) {
+	content := `HOMELAB_A=1
+HOMELAB_B=2
+# HOMELAB_C=commented
+HOMELAB_C=3
+export HOMELAB_A=4
+HOMELAB_B=5
+HOMELAB_A=6
+`
+	dotenv, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	duplicates := dotenv.DuplicateKeys()
+
+	if diff := cmp.Diff([]string{"HOMELAB_A", "HOMELAB_B"}, duplicates); diff != "" {
+		t.Errorf("duplicates mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDotenv_DuplicateKeys_None(t *testing.T) {
+	dotenv, err := Parse(strings.NewReader("HOMELAB_A=1\n# HOMELAB_A=2\nHOMELAB_B=3\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if duplicates := dotenv.DuplicateKeys(); len(duplicates) != 0 {
+		t.Errorf("expected no duplicates, got: %v", duplicates)
+	}
+}
@@ -0,0 +1,70 @@
+package dotenv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrDotenvWrite = errors.New("failed to write .env")
+
+// Get returns the value of a variable, and whether it was found. If the variable is assigned more than once, the
+// last assignment wins, as it does when the file is loaded
+func (d *Dotenv) Get(key string) (string, bool) {
+	value, found := "", false
+	for _, entry := range d.Entries {
+		if entry.Key == key {
+			value, found = entry.Value, true
+		}
+	}
+	return value, found
+}
+
+// Set changes the value of a variable. Every assignment of the variable is rewritten as KEY="value", keeping its
+// indentation, its "export " prefix and its inline comment, and every other line is left untouched. If the variable
+// is not assigned, the assignment is added at the end of the file
+func (d *Dotenv) Set(key string, value string) {
+	found := false
+	for i, entry := range d.Entries {
+		if entry.Key != key {
+			continue
+		}
+		entry.Value = value
+		entry.line = entry.prefix + formatAssignment(key, value) + entry.inlineComment
+		d.Entries[i] = entry
+		found = true
+	}
+	if found {
+		return
+	}
+
+	if len(d.Entries) == 0 {
+		d.trailingNewline = true
+	}
+	d.Entries = append(d.Entries, Entry{Key: key, Value: value, line: formatAssignment(key, value)})
+}
+
+// Write writes the .env file. The lines that were not changed since the file was parsed are written exactly as they
+// were read
+func (d *Dotenv) Write(w io.Writer) error {
+	lines := make([]string, 0, len(d.Entries))
+	for _, entry := range d.Entries {
+		lines = append(lines, entry.line)
+	}
+	content := strings.Join(lines, "\n")
+	if d.trailingNewline {
+		content += "\n"
+	}
+
+	if _, err := io.WriteString(w, content); err != nil {
+		return fmt.Errorf("%w: %w", ErrDotenvWrite, err)
+	}
+	return nil
+}
+
+// formatAssignment formats a variable as KEY="value", escaping the double quotes of the value in the same way as
+// format.TextFormatter.FormatDotenvKeyValue
+func formatAssignment(key string, value string) string {
+	return fmt.Sprintf(`%s="%s"`, key, strings.ReplaceAll(value, `"`, `\"`))
+}
@@ -0,0 +1,130 @@
+package dotenv
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// dotenvContent contains comments, blank lines, quotes and unusual spacing, which must all survive a round trip
+var dotenvContent = `# Database configuration
+HOMELAB_DB_HOST=127.0.0.1
+
+  # Indented comment
+HOMELAB_DB_PASSWORD="p@ss \"word\""   # the password
+export HOMELAB_DB_NAME='homelab'
+HOMELAB_DB_PORT = 5432
+`
+
+func TestDotenv_Write_UnchangedIsByteIdentical(t *testing.T) {
+	for _, content := range []string{dotenvContent, strings.TrimSuffix(dotenvContent, "\n"), ""} {
+		dotenv, err := Parse(strings.NewReader(content))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		var out bytes.Buffer
+
+		err = dotenv.Write(&out)
+
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if out.String() != content {
+			t.Errorf("expected %q, got %q", content, out.String())
+		}
+	}
+}
+
+func TestDotenv_Set_OnlyChangesTargetedLine(t *testing.T) {
+	dotenv, err := Parse(strings.NewReader(dotenvContent))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var out bytes.Buffer
+
+	dotenv.Set("HOMELAB_DB_PASSWORD", `new "secret"`)
+	dotenv.Set("HOMELAB_DB_NAME", "other")
+	err = dotenv.Write(&out)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := `# Database configuration
+HOMELAB_DB_HOST=127.0.0.1
+
+  # Indented comment
+HOMELAB_DB_PASSWORD="new \"secret\""   # the password
+export HOMELAB_DB_NAME="other"
+HOMELAB_DB_PORT = 5432
+`
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestDotenv_Set_AppendsMissingKey(t *testing.T) {
+	dotenv, err := Parse(strings.NewReader("HOMELAB_A=a\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var out bytes.Buffer
+
+	dotenv.Set("HOMELAB_B", "b")
+	err = dotenv.Write(&out)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := "HOMELAB_A=a\nHOMELAB_B=\"b\"\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestDotenv_Get(t *testing.T) {
+	dotenv, err := Parse(strings.NewReader("HOMELAB_A=first\n# HOMELAB_B=commented\nHOMELAB_A=\"last\"\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	value, found := dotenv.Get("HOMELAB_A")
+	if !found || value != "last" {
+		t.Errorf("expected (%q, true), got (%q, %v)", "last", value, found)
+	}
+	if _, found := dotenv.Get("HOMELAB_B"); found {
+		t.Error("expected commented variable not to be found")
+	}
+}
+
+func TestDotenv_Get_ReturnsSetValue(t *testing.T) {
+	dotenv, err := Parse(strings.NewReader("HOMELAB_A=a\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	dotenv.Set("HOMELAB_A", "changed")
+
+	if value, _ := dotenv.Get("HOMELAB_A"); value != "changed" {
+		t.Errorf("expected %q, got %q", "changed", value)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestDotenv_Write_Error(t *testing.T) {
+	dotenv, err := Parse(strings.NewReader("HOMELAB_A=a\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	err = dotenv.Write(failingWriter{})
+
+	if !errors.Is(err, ErrDotenvWrite) {
+		t.Errorf("expected ErrDotenvWrite, got: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package format
+
+import "strings"
+
+// RedactedValue replaces the values of sensitive variables when they are shown to the user
+const RedactedValue = "<redacted>"
+
+// sensitiveNameParts are the parts of the names of the variables whose values must never be shown to the user
+var sensitiveNameParts = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY"}
+
+// IsSensitiveName returns whether the name of a variable tells that its value is a secret, such as a password or a key
+func IsSensitiveName(name string) bool {
+	upperName := strings.ToUpper(name)
+	for _, part := range sensitiveNameParts {
+		if strings.Contains(upperName, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactValue returns RedactedValue if the variable is sensitive, and its value otherwise. Empty values are kept, so
+// that it is still visible that a secret has not been set
+func RedactValue(name string, value string) string {
+	if value == "" || !IsSensitiveName(name) {
+		return value
+	}
+	return RedactedValue
+}
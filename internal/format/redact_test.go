@@ -0,0 +1,26 @@
+package format
+
+import "testing"
+
+func TestRedactValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "HOMELAB_ADGUARD_PASSWORD", value: "s3cret", expected: RedactedValue},
+		{name: "HOMELAB_BACKUP_B2_APPLICATION_KEY", value: "app-key", expected: RedactedValue},
+		{name: "HOMELAB_PAPERLESS_SECRET_KEY", value: "abc", expected: RedactedValue},
+		{name: "HOMELAB_IMMICH_API_TOKEN", value: "abc", expected: RedactedValue},
+		{name: "homelab_db_password", value: "abc", expected: RedactedValue},
+		{name: "HOMELAB_BACKUP_RESTIC_PASSWORD", value: "", expected: ""},
+		{name: "HOMELAB_GENERAL_DOMAIN", value: "home.example.com", expected: "home.example.com"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RedactValue(tc.name, tc.value); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package format
+
+import "fmt"
+
+// sizeUnits are the binary units that sizes are formatted with, each 1024 times the previous one
+var sizeUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+
+// FormatSize formats a size in bytes with the largest binary unit that keeps it at least 1, such as "1.5 GiB". Sizes
+// in bytes have no decimals
+func FormatSize(bytes int64) string {
+	if bytes < 1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	size := float64(bytes)
+	unit := 0
+	for size >= 1024 && unit < len(sizeUnits)-1 {
+		size /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", size, sizeUnits[unit])
+}
@@ -0,0 +1,22 @@
+package format
+
+import "testing"
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{bytes: 0, expected: "0 B"},
+		{bytes: 1023, expected: "1023 B"},
+		{bytes: 1024, expected: "1.0 KiB"},
+		{bytes: 1536, expected: "1.5 KiB"},
+		{bytes: 5 * 1024 * 1024, expected: "5.0 MiB"},
+		{bytes: 3 << 40, expected: "3.0 TiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.bytes); got != tt.expected {
+			t.Errorf("FormatSize(%d): expected %q, got %q", tt.bytes, tt.expected, got)
+		}
+	}
+}
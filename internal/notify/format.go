@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidNotifyFormat = errors.New("invalid notification format")
+)
+
+// formatEnvVar is the environment variable with the format of the notifications. When it is not set, the plain
+// webhook format is used
+const formatEnvVar = "HOMELAB_NOTIFY_FORMAT"
+
+// Formats of the notifications
+const (
+	FormatWebhook = "webhook"
+	FormatNtfy    = "ntfy"
+	FormatDiscord = "discord"
+)
+
+// Payload is the body of the request that sends a notification, with its headers
+type Payload struct {
+	ContentType string
+	Headers     map[string]string
+	Body        []byte
+}
+
+// Formatter builds the payload of a notification in the format that a receiver understands
+type Formatter interface {
+	Format(event Event) (Payload, error)
+}
+
+// NewFormatter returns the formatter of a format. The name of the format is case-insensitive
+func NewFormatter(format string) (Formatter, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatWebhook:
+		return &WebhookFormatter{}, nil
+	case FormatNtfy:
+		return &NtfyFormatter{}, nil
+	case FormatDiscord:
+		return &DiscordFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("%w %q: must be %s, %s or %s", ErrInvalidNotifyFormat, format, FormatWebhook, FormatNtfy, FormatDiscord)
+	}
+}
+
+// WebhookFormatter sends the event as it is, as JSON
+type WebhookFormatter struct{}
+
+func (*WebhookFormatter) Format(event Event) (Payload, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return Payload{}, err
+	}
+	return Payload{ContentType: "application/json", Body: body}, nil
+}
+
+// NtfyFormatter sends the message as the body and the title in a header, which is how ntfy topics are published to.
+// Failures have a high priority, so that they stand out on phones
+type NtfyFormatter struct{}
+
+func (*NtfyFormatter) Format(event Event) (Payload, error) {
+	headers := map[string]string{
+		"Title":    event.Title,
+		"Tags":     "white_check_mark",
+		"Priority": "default",
+	}
+	if !event.Success {
+		headers["Tags"] = "rotating_light"
+		headers["Priority"] = "high"
+	}
+	return Payload{ContentType: "text/plain; charset=utf-8", Headers: headers, Body: []byte(event.Message)}, nil
+}
+
+// Colors of the Discord embeds
+const (
+	discordColorSuccess = 0x2ecc71
+	discordColorFailure = 0xe74c3c
+)
+
+// discordMessage is the body of a Discord webhook that sends a single embed
+type discordMessage struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       int    `json:"color"`
+}
+
+// DiscordFormatter sends the event as an embed of a Discord webhook, green for successes and red for failures
+type DiscordFormatter struct{}
+
+func (*DiscordFormatter) Format(event Event) (Payload, error) {
+	color := discordColorSuccess
+	if !event.Success {
+		color = discordColorFailure
+	}
+	body, err := json.Marshal(discordMessage{
+		Embeds: []discordEmbed{{Title: event.Title, Description: event.Message, Color: color}},
+	})
+	if err != nil {
+		return Payload{}, err
+	}
+	return Payload{ContentType: "application/json", Body: body}, nil
+}
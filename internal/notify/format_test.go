@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	successEvent = Event{Name: "backup", Title: "Backup completed", Message: "The cloud backup completed", Success: true}
+	failureEvent = Event{Name: "backup", Title: "Backup failed", Message: "restic command failed", Success: false}
+)
+
+// decodeJSON decodes the body of a payload into a generic value, to compare its shape without depending on the order
+// of the fields
+func decodeJSON(t *testing.T, body []byte) any {
+	t.Helper()
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("expected a JSON body, got %q: %v", body, err)
+	}
+	return decoded
+}
+
+func TestWebhookFormatter_Format(t *testing.T) {
+	tests := []struct {
+		event    Event
+		expected any
+	}{
+		{
+			event: successEvent,
+			expected: map[string]any{
+				"event": "backup", "title": "Backup completed", "message": "The cloud backup completed", "success": true,
+			},
+		},
+		{
+			event: failureEvent,
+			expected: map[string]any{
+				"event": "backup", "title": "Backup failed", "message": "restic command failed", "success": false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		payload, err := (&WebhookFormatter{}).Format(tt.event)
+
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if payload.ContentType != "application/json" {
+			t.Errorf("expected content type %q, got %q", "application/json", payload.ContentType)
+		}
+		if diff := cmp.Diff(tt.expected, decodeJSON(t, payload.Body)); diff != "" {
+			t.Errorf("body mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestNtfyFormatter_Format(t *testing.T) {
+	tests := []struct {
+		event           Event
+		expectedHeaders map[string]string
+	}{
+		{
+			event:           successEvent,
+			expectedHeaders: map[string]string{"Title": "Backup completed", "Tags": "white_check_mark", "Priority": "default"},
+		},
+		{
+			event:           failureEvent,
+			expectedHeaders: map[string]string{"Title": "Backup failed", "Tags": "rotating_light", "Priority": "high"},
+		},
+	}
+	for _, tt := range tests {
+		payload, err := (&NtfyFormatter{}).Format(tt.event)
+
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if diff := cmp.Diff(tt.expectedHeaders, payload.Headers); diff != "" {
+			t.Errorf("headers mismatch (-want +got):\n%s", diff)
+		}
+		if string(payload.Body) != tt.event.Message {
+			t.Errorf("expected body %q, got %q", tt.event.Message, payload.Body)
+		}
+	}
+}
+
+func TestDiscordFormatter_Format(t *testing.T) {
+	tests := []struct {
+		event    Event
+		expected any
+	}{
+		{
+			event: successEvent,
+			expected: map[string]any{"embeds": []any{map[string]any{
+				"title": "Backup completed", "description": "The cloud backup completed", "color": float64(discordColorSuccess),
+			}}},
+		},
+		{
+			event: failureEvent,
+			expected: map[string]any{"embeds": []any{map[string]any{
+				"title": "Backup failed", "description": "restic command failed", "color": float64(discordColorFailure),
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		payload, err := (&DiscordFormatter{}).Format(tt.event)
+
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if payload.ContentType != "application/json" {
+			t.Errorf("expected content type %q, got %q", "application/json", payload.ContentType)
+		}
+		if diff := cmp.Diff(tt.expected, decodeJSON(t, payload.Body)); diff != "" {
+			t.Errorf("body mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestNewFormatter(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected Formatter
+	}{
+		{format: "webhook", expected: &WebhookFormatter{}},
+		{format: "NTFY", expected: &NtfyFormatter{}},
+		{format: " discord ", expected: &DiscordFormatter{}},
+	}
+	for _, tt := range tests {
+		formatter, err := NewFormatter(tt.format)
+
+		if err != nil {
+			t.Fatalf("expected no error for %q, got: %v", tt.format, err)
+		}
+		if diff := cmp.Diff(tt.expected, formatter); diff != "" {
+			t.Errorf("formatter mismatch for %q (-want +got):\n%s", tt.format, diff)
+		}
+	}
+}
+
+func TestNewFormatter_Invalid(t *testing.T) {
+	_, err := NewFormatter("slack")
+
+	if !errors.Is(err, ErrInvalidNotifyFormat) {
+		t.Errorf("expected ErrInvalidNotifyFormat, got: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davidsilvasanmartin/auto-homelab/internal/system"
+)
+
+var (
+	ErrNotifyEndpointNotSet = errors.New("notification endpoint not set")
+	ErrNotificationFailed   = errors.New("failed to send notification")
+)
+
+// webhookURLEnvVar is the environment variable with the URL that notifications are posted to
+const webhookURLEnvVar = "HOMELAB_NOTIFY_WEBHOOK_URL"
+
+// webhookTimeout is the maximum time that sending a notification can take
+const webhookTimeout = 10 * time.Second
+
+// webhookResponseBodyLimit is the number of bytes of the response body included in the error of a failed notification
+const webhookResponseBodyLimit = 512
+
+// Event is something that happened in the homelab that users are notified about, such as a backup that failed
+type Event struct {
+	// Name identifies the kind of event, such as "backup" or "test"
+	Name    string `json:"event"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+}
+
+// TestEvent is the sample event sent to check that notifications are configured correctly
+func TestEvent() Event {
+	return Event{
+		Name:    "test",
+		Title:   "auto-homelab test notification",
+		Message: "Notifications are configured correctly",
+		Success: true,
+	}
+}
+
+// BackupEvent is the event sent when a backup finishes, such as the "local" or "cloud" backup. The backup failed if err
+// is not nil, and err is then the message of the event
+func BackupEvent(kind string, err error) Event {
+	if err != nil {
+		return Event{
+			Name:    "backup",
+			Title:   fmt.Sprintf("auto-homelab %s backup failed", kind),
+			Message: err.Error(),
+			Success: false,
+		}
+	}
+	return Event{
+		Name:    "backup",
+		Title:   fmt.Sprintf("auto-homelab %s backup completed", kind),
+		Message: fmt.Sprintf("The %s backup completed successfully", kind),
+		Success: true,
+	}
+}
+
+// WebhookNotifier sends events to a webhook URL with a POST request, in the format of its formatter
+type WebhookNotifier struct {
+	client    *http.Client
+	formatter Formatter
+	url       string
+}
+
+// NewWebhookNotifier creates a notifier that posts events to url, formatted by formatter
+func NewWebhookNotifier(url string, formatter Formatter) *WebhookNotifier {
+	return &WebhookNotifier{
+		client:    &http.Client{Timeout: webhookTimeout},
+		formatter: formatter,
+		url:       url,
+	}
+}
+
+// LoadWebhookNotifier creates a notifier from HOMELAB_NOTIFY_WEBHOOK_URL and HOMELAB_NOTIFY_FORMAT. It returns
+// ErrNotifyEndpointNotSet when the URL is not set or is empty
+func LoadWebhookNotifier(env system.Env) (*WebhookNotifier, error) {
+	url, exists := env.GetEnv(webhookURLEnvVar)
+	if !exists || strings.TrimSpace(url) == "" {
+		return nil, fmt.Errorf("%w: set %s to the URL that notifications are sent to", ErrNotifyEndpointNotSet, webhookURLEnvVar)
+	}
+	format, exists := env.GetEnv(formatEnvVar)
+	if !exists || strings.TrimSpace(format) == "" {
+		format = FormatWebhook
+	}
+	formatter, err := NewFormatter(format)
+	if err != nil {
+		return nil, err
+	}
+	return NewWebhookNotifier(strings.TrimSpace(url), formatter), nil
+}
+
+// Send posts the event to the webhook. Any response with a status code other than 2xx is an error, which includes the
+// beginning of the response body to help finding out what the receiver did not like
+func (n *WebhookNotifier) Send(event Event) error {
+	payload, err := n.formatter.Format(event)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotificationFailed, err)
+	}
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(payload.Body))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotificationFailed, err)
+	}
+	req.Header.Set("Content-Type", payload.ContentType)
+	for name, value := range payload.Headers {
+		req.Header.Set(name, value)
+	}
+
+	slog.Debug("Sending notification", "event", event.Name)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotificationFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseBodyLimit))
+		return fmt.Errorf("%w: status %s: %s", ErrNotificationFailed, resp.Status, strings.TrimSpace(string(body)))
+	}
+	slog.Debug("Notification sent", "event", event.Name, "status", resp.Status)
+	return nil
+}
@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWebhookNotifier_Send_PostsEventAsJSON(t *testing.T) {
+	var method, contentType string
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("expected a JSON body, got: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	notifier := NewWebhookNotifier(server.URL, &WebhookFormatter{})
+
+	err := notifier.Send(TestEvent())
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if method != http.MethodPost {
+		t.Errorf("expected method %q, got %q", http.MethodPost, method)
+	}
+	if contentType != "application/json" {
+		t.Errorf("expected content type %q, got %q", "application/json", contentType)
+	}
+	if diff := cmp.Diff(TestEvent(), received); diff != "" {
+		t.Errorf("event mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWebhookNotifier_Send_SetsFormatterHeaders(t *testing.T) {
+	var title, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		title = r.Header.Get("Title")
+		contentType = r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+	notifier := NewWebhookNotifier(server.URL, &NtfyFormatter{})
+
+	err := notifier.Send(TestEvent())
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if title != TestEvent().Title || body != TestEvent().Message {
+		t.Errorf("expected title %q and body %q, got %q and %q", TestEvent().Title, TestEvent().Message, title, body)
+	}
+	if contentType != "text/plain; charset=utf-8" {
+		t.Errorf("expected content type %q, got %q", "text/plain; charset=utf-8", contentType)
+	}
+}
+
+func TestWebhookNotifier_Send_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	notifier := NewWebhookNotifier(server.URL, &WebhookFormatter{})
+
+	err := notifier.Send(TestEvent())
+
+	if !errors.Is(err, ErrNotificationFailed) {
+		t.Fatalf("expected ErrNotificationFailed, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid token") {
+		t.Errorf("expected error to contain the status and the response body, got: %v", err)
+	}
+}
+
+func TestWebhookNotifier_Send_UnreachableEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+	notifier := NewWebhookNotifier(url, &WebhookFormatter{})
+
+	err := notifier.Send(TestEvent())
+
+	if !errors.Is(err, ErrNotificationFailed) {
+		t.Errorf("expected ErrNotificationFailed, got: %v", err)
+	}
+}
+
+func TestBackupEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected Event
+	}{
+		{
+			name:     "success",
+			err:      nil,
+			expected: Event{Name: "backup", Title: "auto-homelab local backup completed", Message: "The local backup completed successfully", Success: true},
+		},
+		{
+			name:     "failure",
+			err:      errors.New("restic command failed"),
+			expected: Event{Name: "backup", Title: "auto-homelab local backup failed", Message: "restic command failed", Success: false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, BackupEvent("local", tt.err)); diff != "" {
+				t.Errorf("event mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
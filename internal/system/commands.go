@@ -1,6 +1,10 @@
 package system
 
-import "log/slog"
+import (
+	"context"
+	"io"
+	"log/slog"
+)
 
 type Commands interface {
 	// ExecCommand executes a system terminal command
@@ -8,6 +12,19 @@ type Commands interface {
 	// ExecShellCommand executes a full shell command. The full command must be passed as a
 	// string rather than as a slice of its arguments
 	ExecShellCommand(command string) RunnableCommand
+	// ExecShellCommandContext is like ExecShellCommand, but the command is interrupted when the context is done
+	ExecShellCommandContext(ctx context.Context, command string) RunnableCommand
+	// ExecShellCommandWithStderr is like ExecShellCommand, but the standard error of the command is written
+	// into stderr instead of the system's os.Stderr, so that the caller can capture it
+	ExecShellCommandWithStderr(command string, stderr io.Writer) RunnableCommand
+	// ExecShellCommandWithStdout is like ExecShellCommand, but the standard output of the command is written
+	// into stdout instead of the system's os.Stdout, so that the caller can capture it
+	ExecShellCommandWithStdout(command string, stdout io.Writer) RunnableCommand
+	// ExecShellCommandContextWithStdout is like ExecShellCommandWithStdout, but the command is interrupted when the
+	// context is done
+	ExecShellCommandContextWithStdout(ctx context.Context, command string, stdout io.Writer) RunnableCommand
+	// LookPath searches for an executable in the directories of the PATH environment variable
+	LookPath(name string) (string, error)
 }
 
 // DefaultCommands is the default implementation of the Commands interface
@@ -35,3 +52,27 @@ func (s *DefaultCommands) ExecCommand(name string, arg ...string) RunnableComman
 func (s *DefaultCommands) ExecShellCommand(command string) RunnableCommand {
 	return s.ExecCommand("sh", "-c", command)
 }
+
+func (s *DefaultCommands) ExecShellCommandContext(ctx context.Context, command string) RunnableCommand {
+	slog.Debug("Executing command", "command", "sh", "arg", []string{"-c", command})
+	return s.stdlib.ExecCommandContext(ctx, "sh", "-c", command)
+}
+
+func (s *DefaultCommands) ExecShellCommandWithStderr(command string, stderr io.Writer) RunnableCommand {
+	slog.Debug("Executing command", "command", "sh", "arg", []string{"-c", command})
+	return s.stdlib.ExecCommandWithStderr(stderr, "sh", "-c", command)
+}
+
+func (s *DefaultCommands) ExecShellCommandWithStdout(command string, stdout io.Writer) RunnableCommand {
+	slog.Debug("Executing command", "command", "sh", "arg", []string{"-c", command})
+	return s.stdlib.ExecCommandWithStdout(stdout, "sh", "-c", command)
+}
+
+func (s *DefaultCommands) ExecShellCommandContextWithStdout(ctx context.Context, command string, stdout io.Writer) RunnableCommand {
+	slog.Debug("Executing command", "command", "sh", "arg", []string{"-c", command})
+	return s.stdlib.ExecCommandContextWithStdout(ctx, stdout, "sh", "-c", command)
+}
+
+func (s *DefaultCommands) LookPath(name string) (string, error) {
+	return s.stdlib.ExecLookPath(name)
+}
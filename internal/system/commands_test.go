@@ -1,6 +1,9 @@
 package system
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -106,6 +109,164 @@ func TestExecShellCommand_EmptyString(t *testing.T) {
 	}
 }
 
+// TestExecShellCommandContext tests that ExecShellCommandContext passes the context to the stdlib
+func TestExecShellCommandContext(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	var capturedCtx context.Context
+	var capturedName string
+	var capturedArgs []string
+	std := &mockStdlib{
+		execCommandContext: func(ctx context.Context, name string, arg ...string) RunnableCommand {
+			capturedCtx = ctx
+			capturedName = name
+			capturedArgs = arg
+			return &mockRunnableCommand{}
+		},
+	}
+	commands := &DefaultCommands{stdlib: std}
+
+	cmd := commands.ExecShellCommandContext(ctx, "docker compose logs -f")
+
+	if capturedCtx != ctx {
+		t.Errorf("expected context to be passed through")
+	}
+	if capturedName != "sh" {
+		t.Errorf("expected command name %q, got %q", "sh", capturedName)
+	}
+	expectedArgs := []string{"-c", "docker compose logs -f"}
+	if diff := cmp.Diff(expectedArgs, capturedArgs); diff != "" {
+		t.Errorf("args mismatch (-want +got):\n%s", diff)
+	}
+	if cmd == nil {
+		t.Fatal("expected non-nil command")
+	}
+}
+
+// TestExecShellCommandWithStderr tests that ExecShellCommandWithStderr passes the stderr writer to the stdlib
+func TestExecShellCommandWithStderr(t *testing.T) {
+	var capturedStderr io.Writer
+	var capturedName string
+	var capturedArgs []string
+	std := &mockStdlib{
+		execCommandStderr: func(stderr io.Writer, name string, arg ...string) RunnableCommand {
+			capturedStderr = stderr
+			capturedName = name
+			capturedArgs = arg
+			return &mockRunnableCommand{}
+		},
+	}
+	commands := &DefaultCommands{stdlib: std}
+	stderr := &bytes.Buffer{}
+
+	cmd := commands.ExecShellCommandWithStderr("docker compose config -q", stderr)
+
+	if capturedStderr != stderr {
+		t.Errorf("expected stderr writer to be passed through")
+	}
+	if capturedName != "sh" {
+		t.Errorf("expected command name %q, got %q", "sh", capturedName)
+	}
+	expectedArgs := []string{"-c", "docker compose config -q"}
+	if diff := cmp.Diff(expectedArgs, capturedArgs); diff != "" {
+		t.Errorf("args mismatch (-want +got):\n%s", diff)
+	}
+	if cmd == nil {
+		t.Fatal("expected non-nil command")
+	}
+}
+
+// TestExecShellCommandWithStdout tests that ExecShellCommandWithStdout passes the stdout writer to the stdlib
+func TestExecShellCommandWithStdout(t *testing.T) {
+	var capturedStdout io.Writer
+	var capturedName string
+	var capturedArgs []string
+	std := &mockStdlib{
+		execCommandStdout: func(stdout io.Writer, name string, arg ...string) RunnableCommand {
+			capturedStdout = stdout
+			capturedName = name
+			capturedArgs = arg
+			return &mockRunnableCommand{}
+		},
+	}
+	commands := &DefaultCommands{stdlib: std}
+	stdout := &bytes.Buffer{}
+
+	cmd := commands.ExecShellCommandWithStdout("docker compose ps", stdout)
+
+	if capturedStdout != stdout {
+		t.Errorf("expected stdout writer to be passed through")
+	}
+	if capturedName != "sh" {
+		t.Errorf("expected command name %q, got %q", "sh", capturedName)
+	}
+	expectedArgs := []string{"-c", "docker compose ps"}
+	if diff := cmp.Diff(expectedArgs, capturedArgs); diff != "" {
+		t.Errorf("args mismatch (-want +got):\n%s", diff)
+	}
+	if cmd == nil {
+		t.Fatal("expected non-nil command")
+	}
+}
+
+// TestExecShellCommandContextWithStdout tests that ExecShellCommandContextWithStdout passes the context and the stdout
+// writer to the stdlib
+func TestExecShellCommandContextWithStdout(t *testing.T) {
+	var capturedCtx context.Context
+	var capturedStdout io.Writer
+	var capturedArgs []string
+	std := &mockStdlib{
+		execCommandCtxOut: func(ctx context.Context, stdout io.Writer, name string, arg ...string) RunnableCommand {
+			capturedCtx = ctx
+			capturedStdout = stdout
+			capturedArgs = arg
+			return &mockRunnableCommand{}
+		},
+	}
+	commands := &DefaultCommands{stdlib: std}
+	ctx := context.Background()
+	stdout := &bytes.Buffer{}
+
+	cmd := commands.ExecShellCommandContextWithStdout(ctx, "restic backup /data --json", stdout)
+
+	if capturedCtx != ctx {
+		t.Errorf("expected context to be passed through")
+	}
+	if capturedStdout != stdout {
+		t.Errorf("expected stdout writer to be passed through")
+	}
+	expectedArgs := []string{"-c", "restic backup /data --json"}
+	if diff := cmp.Diff(expectedArgs, capturedArgs); diff != "" {
+		t.Errorf("args mismatch (-want +got):\n%s", diff)
+	}
+	if cmd == nil {
+		t.Fatal("expected non-nil command")
+	}
+}
+
+func TestLookPath(t *testing.T) {
+	var capturedFile string
+	std := &mockStdlib{
+		execLookPath: func(file string) (string, error) {
+			capturedFile = file
+			return "/usr/bin/rsync", nil
+		},
+	}
+	commands := &DefaultCommands{stdlib: std}
+
+	path, err := commands.LookPath("rsync")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedFile != "rsync" {
+		t.Errorf("expected to look for %q, got %q", "rsync", capturedFile)
+	}
+	if path != "/usr/bin/rsync" {
+		t.Errorf("expected path %q, got %q", "/usr/bin/rsync", path)
+	}
+}
+
 // TestNewDefaultCommands tests that the constructor creates proper defaults
 func TestNewDefaultCommands(t *testing.T) {
 	commands := NewDefaultCommands()
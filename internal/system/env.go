@@ -3,6 +3,10 @@ package system
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/davidsilvasanmartin/auto-homelab/internal/dotenv"
 	"github.com/spf13/viper"
@@ -14,20 +18,40 @@ type Env interface {
 	// GetRequiredEnv returns (value, true) if an environment variable with name varName exists,
 	// and ("", false) if it does not exist
 	GetRequiredEnv(varName string) (string, error)
+	// GetBoolEnv gets an environment variable as a boolean. Accepts yes/no, true/false and 1/0 (case-insensitive).
+	// The second value returned is false if the variable does not exist or its value can't be parsed
+	GetBoolEnv(varName string) (value bool, ok bool)
+	// GetIntEnv gets an environment variable as an integer. The bool returned indicates whether the variable exists.
+	// If the variable exists but its value is not an integer, an error is returned
+	GetIntEnv(varName string) (value int, exists bool, err error)
+	// GetAllEnv returns all the environment variables that have been loaded, keyed by their upper-cased name
+	GetAllEnv() map[string]string
+	// ListByPrefix returns the variables that have been loaded whose name starts with prefix, keyed by their upper-cased
+	// name. Like GetEnv, it does not read the process environment
+	ListByPrefix(prefix string) map[string]string
 }
 
 var (
 	ErrRequiredEnvNotFound = errors.New("missing required environment variable")
+	ErrInvalidIntEnv       = errors.New("environment variable is not a valid integer")
 )
 
+// DefaultPlaceholders are the values that .env templates commonly leave in variables that still have to be filled in.
+// NewDefaultEnv treats them as missing
+var DefaultPlaceholders = []string{"", "CHANGEME", "changeme"}
+
 type DefaultEnv struct {
 	// ViperConfig provides access to the Viper configuration loaded from .env file
 	ViperConfig func() *viper.Viper
+	// Placeholders are the values that make GetRequiredEnv treat a variable as missing, because they mean that the
+	// variable has not been filled in yet. Values are compared after trimming their whitespace
+	Placeholders []string
 }
 
 func NewDefaultEnv() *DefaultEnv {
 	return &DefaultEnv{
-		ViperConfig: dotenv.GetViper,
+		ViperConfig:  dotenv.GetViper,
+		Placeholders: DefaultPlaceholders,
 	}
 }
 
@@ -41,10 +65,74 @@ func (d *DefaultEnv) GetEnv(varName string) (string, bool) {
 	return "", false
 }
 
+// WithPlaceholders makes GetRequiredEnv treat the variables whose value is one of placeholders as missing, instead of
+// DefaultPlaceholders. For example, WithPlaceholders() accepts any value
+func (d *DefaultEnv) WithPlaceholders(placeholders ...string) *DefaultEnv {
+	d.Placeholders = placeholders
+	return d
+}
+
 func (d *DefaultEnv) GetRequiredEnv(varName string) (string, error) {
 	value, exists := d.GetEnv(varName)
 	if !exists {
 		return "", fmt.Errorf("%w: %q", ErrRequiredEnvNotFound, varName)
 	}
+	if slices.Contains(d.Placeholders, strings.TrimSpace(value)) {
+		return "", fmt.Errorf("%w: %q has the placeholder value %q", ErrRequiredEnvNotFound, varName, value)
+	}
 	return value, nil
 }
+
+func (d *DefaultEnv) GetBoolEnv(varName string) (bool, bool) {
+	value, exists := d.GetEnv(varName)
+	if !exists {
+		return false, false
+	}
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "yes", "true", "1":
+		return true, true
+	case "no", "false", "0":
+		return false, true
+	default:
+		slog.Warn("Ignoring environment variable with an invalid boolean value", "varName", varName, "value", value)
+		return false, false
+	}
+}
+
+func (d *DefaultEnv) GetIntEnv(varName string) (int, bool, error) {
+	value, exists := d.GetEnv(varName)
+	if !exists {
+		return 0, false, nil
+	}
+	intValue, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, true, fmt.Errorf("%w %q: %w", ErrInvalidIntEnv, varName, err)
+	}
+	return intValue, true, nil
+}
+
+func (d *DefaultEnv) GetAllEnv() map[string]string {
+	if d.ViperConfig == nil {
+		panic("viper config should be defined")
+	}
+	all := make(map[string]string)
+	v := d.ViperConfig()
+	if v == nil {
+		return all
+	}
+	// Viper lower-cases all keys, but environment variables in this project are always upper-cased
+	for _, key := range v.AllKeys() {
+		all[strings.ToUpper(key)] = v.GetString(key)
+	}
+	return all
+}
+
+func (d *DefaultEnv) ListByPrefix(prefix string) map[string]string {
+	vars := make(map[string]string)
+	for name, value := range d.GetAllEnv() {
+		if strings.HasPrefix(name, prefix) {
+			vars[name] = value
+		}
+	}
+	return vars
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/spf13/viper"
 )
 
@@ -115,3 +116,257 @@ func TestDefaultEnv_GetRequiredEnv_EmptyValue(t *testing.T) {
 		t.Errorf("expected empty value, got %q", value)
 	}
 }
+
+func TestDefaultEnv_GetRequiredEnv_PlaceholderIsMissing(t *testing.T) {
+	for _, value := range []string{"", "CHANGEME", "changeme", " CHANGEME "} {
+		t.Run(value, func(t *testing.T) {
+			varName := "DB_PASSWORD"
+			v := viper.New()
+			v.Set(varName, value)
+			env := (&DefaultEnv{
+				ViperConfig: func() *viper.Viper { return v },
+			}).WithPlaceholders(DefaultPlaceholders...)
+
+			_, err := env.GetRequiredEnv(varName)
+
+			if !errors.Is(err, ErrRequiredEnvNotFound) {
+				t.Errorf("expected ErrRequiredEnvNotFound, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestDefaultEnv_GetRequiredEnv_RealValuePassesWithPlaceholders(t *testing.T) {
+	varName := "DB_PASSWORD"
+	v := viper.New()
+	v.Set(varName, "changeme-not")
+	env := (&DefaultEnv{
+		ViperConfig: func() *viper.Viper { return v },
+	}).WithPlaceholders(DefaultPlaceholders...)
+
+	value, err := env.GetRequiredEnv(varName)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if value != "changeme-not" {
+		t.Errorf("expected value %q, got %q", "changeme-not", value)
+	}
+}
+
+func TestDefaultEnv_GetRequiredEnv_CustomPlaceholders(t *testing.T) {
+	varName := "DB_PASSWORD"
+	v := viper.New()
+	v.Set(varName, "TODO")
+	env := (&DefaultEnv{
+		ViperConfig: func() *viper.Viper { return v },
+	}).WithPlaceholders("TODO")
+
+	_, err := env.GetRequiredEnv(varName)
+
+	if !errors.Is(err, ErrRequiredEnvNotFound) {
+		t.Errorf("expected ErrRequiredEnvNotFound, got: %v", err)
+	}
+}
+
+func TestNewDefaultEnv_TreatsDefaultPlaceholdersAsMissing(t *testing.T) {
+	varName := "DB_PASSWORD"
+	v := viper.New()
+	v.Set(varName, "CHANGEME")
+	env := NewDefaultEnv()
+	env.ViperConfig = func() *viper.Viper { return v }
+
+	_, err := env.GetRequiredEnv(varName)
+
+	if !errors.Is(err, ErrRequiredEnvNotFound) {
+		t.Errorf("expected ErrRequiredEnvNotFound, got: %v", err)
+	}
+}
+
+func TestDefaultEnv_GetAllEnv_ReturnsUpperCasedKeys(t *testing.T) {
+	v := viper.New()
+	v.Set("DB_URL", "localhost:5432/db")
+	v.Set("DB_USER", "admin")
+	env := &DefaultEnv{
+		ViperConfig: func() *viper.Viper { return v },
+	}
+
+	all := env.GetAllEnv()
+
+	expected := map[string]string{
+		"DB_URL":  "localhost:5432/db",
+		"DB_USER": "admin",
+	}
+	if diff := cmp.Diff(expected, all); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultEnv_GetAllEnv_NilViperReturnsEmptyMap(t *testing.T) {
+	env := &DefaultEnv{
+		ViperConfig: func() *viper.Viper { return nil },
+	}
+
+	all := env.GetAllEnv()
+
+	if len(all) != 0 {
+		t.Errorf("expected empty map, got %v", all)
+	}
+}
+
+func TestDefaultEnv_GetBoolEnv_AcceptedTokens(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{"yes", true},
+		{"no", false},
+		{"true", true},
+		{"false", false},
+		{"1", true},
+		{"0", false},
+		{"YES", true},
+		{" False ", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			varName := "BOOL_VAR"
+			v := viper.New()
+			v.Set(varName, tt.value)
+			env := &DefaultEnv{
+				ViperConfig: func() *viper.Viper { return v },
+			}
+
+			value, ok := env.GetBoolEnv(varName)
+
+			if !ok {
+				t.Fatalf("expected ok to be true for %q, got false", tt.value)
+			}
+			if value != tt.expected {
+				t.Errorf("expected %v for %q, got %v", tt.expected, tt.value, value)
+			}
+		})
+	}
+}
+
+func TestDefaultEnv_GetBoolEnv_UnparseableValue(t *testing.T) {
+	varName := "BOOL_VAR"
+	v := viper.New()
+	v.Set(varName, "maybe")
+	env := &DefaultEnv{
+		ViperConfig: func() *viper.Viper { return v },
+	}
+
+	value, ok := env.GetBoolEnv(varName)
+
+	if ok {
+		t.Errorf("expected ok to be false for an unparseable value, got true")
+	}
+	if value {
+		t.Errorf("expected value to be false for an unparseable value, got true")
+	}
+}
+
+func TestDefaultEnv_GetBoolEnv_VariableNotSet(t *testing.T) {
+	env := &DefaultEnv{
+		ViperConfig: func() *viper.Viper { return viper.New() },
+	}
+
+	_, ok := env.GetBoolEnv("NONEXISTENT_VAR")
+
+	if ok {
+		t.Errorf("expected ok to be false when the variable is not set, got true")
+	}
+}
+
+func TestDefaultEnv_GetIntEnv_ValidInt(t *testing.T) {
+	varName := "INT_VAR"
+	v := viper.New()
+	v.Set(varName, "30")
+	env := &DefaultEnv{
+		ViperConfig: func() *viper.Viper { return v },
+	}
+
+	value, exists, err := env.GetIntEnv(varName)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !exists {
+		t.Errorf("expected exists to be true, got false")
+	}
+	if value != 30 {
+		t.Errorf("expected value %d, got %d", 30, value)
+	}
+}
+
+func TestDefaultEnv_GetIntEnv_NonNumericValue(t *testing.T) {
+	varName := "INT_VAR"
+	v := viper.New()
+	v.Set(varName, "thirty")
+	env := &DefaultEnv{
+		ViperConfig: func() *viper.Viper { return v },
+	}
+
+	_, exists, err := env.GetIntEnv(varName)
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrInvalidIntEnv) {
+		t.Errorf("expected ErrInvalidIntEnv, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), varName) {
+		t.Errorf("expected error message to contain var name %q, got: %s", varName, err.Error())
+	}
+	if !exists {
+		t.Errorf("expected exists to be true, got false")
+	}
+}
+
+func TestDefaultEnv_GetIntEnv_VariableNotSet(t *testing.T) {
+	env := &DefaultEnv{
+		ViperConfig: func() *viper.Viper { return viper.New() },
+	}
+
+	value, exists, err := env.GetIntEnv("NONEXISTENT_VAR")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exists {
+		t.Errorf("expected exists to be false, got true")
+	}
+	if value != 0 {
+		t.Errorf("expected value 0, got %d", value)
+	}
+}
+
+func TestDefaultEnv_ListByPrefix_ReturnsLoadedVarsLikeGetEnv(t *testing.T) {
+	t.Setenv("HOMELAB_GENERAL_TIMEZONE", "UTC")
+	t.Setenv("HOMELAB_EXTRA", "a=b")
+	v := viper.New()
+	v.Set("HOMELAB_GENERAL_DOMAIN", "home.example.com")
+	v.Set("HOMELAB_GENERAL_TIMEZONE", "Europe/Madrid")
+	v.Set("OTHER_VAR", "other")
+	env := &DefaultEnv{
+		ViperConfig: func() *viper.Viper { return v },
+	}
+
+	vars := env.ListByPrefix("HOMELAB_")
+
+	// The process environment is ignored, as GetEnv ignores it
+	expected := map[string]string{
+		"HOMELAB_GENERAL_DOMAIN":   "home.example.com",
+		"HOMELAB_GENERAL_TIMEZONE": "Europe/Madrid",
+	}
+	for name, value := range vars {
+		if got, _ := env.GetEnv(name); got != value {
+			t.Errorf("expected %s to be %q as GetEnv returns, got %q", name, got, value)
+		}
+	}
+	if diff := cmp.Diff(expected, vars); diff != "" {
+		t.Errorf("vars mismatch (-want +got):\n%s", diff)
+	}
+}
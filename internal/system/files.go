@@ -3,6 +3,8 @@ package system
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -20,17 +22,39 @@ type FilesHandler interface {
 	EmptyDir(path string) error
 	// CopyDir copies a directory, from srcPath into dstPath
 	CopyDir(srcPath string, dstPath string) error
+	// CopyDirWithOptions copies a directory like CopyDir, changing how unreadable files and symbolic links are copied.
+	// It returns the paths of the skipped entries
+	CopyDirWithOptions(srcPath string, dstPath string, opts CopyDirOptions) ([]string, error)
 	// Getwd gets the current working directory
 	Getwd() (dir string, err error)
 	// WriteFile writes the content to a file
 	WriteFile(path string, data []byte) error
+	// WriteSecretFile writes the content to a file that only its owner can read and write, such as a .env file. An
+	// existing file gets these permissions too
+	WriteSecretFile(path string, data []byte) error
+	// WriteNewFile writes the content to a file that must not exist yet, so that an existing file is never overwritten
+	WriteNewFile(path string, data []byte) error
+	// CreateNewFile creates a file that must not exist yet and opens it for writing, such as a log file that is written
+	// to while the program runs
+	CreateNewFile(path string) (io.WriteCloser, error)
+	// ReadFile reads the content of a file
+	ReadFile(path string) ([]byte, error)
 	// GetAbsPath gets the absolute path from a relative (or absolute) path and cleans it
 	GetAbsPath(path string) (string, error)
+	// ListFiles returns the information, such as the modification time, of the regular files inside a directory. It is
+	// not recursive
+	ListFiles(path string) ([]os.FileInfo, error)
+	// RemoveFile removes a single file
+	RemoveFile(path string) error
+	// DirSize returns the total size in bytes of the regular files inside a directory and its subdirectories
+	DirSize(path string) (int64, error)
 }
 
 const (
 	defaultDirPerms  os.FileMode = 0o755
 	defaultFilePerms os.FileMode = 0o644
+	// secretFilePerms are the permissions of the files with secrets, such as passwords, which only their owner can read
+	secretFilePerms os.FileMode = 0o600
 )
 
 var (
@@ -43,7 +67,13 @@ var (
 	ErrFailedToCopyDir      = errors.New("failed to copy directory")
 	ErrFailedToCheckPath    = errors.New("failed to check file or directory at path")
 	ErrFailedToWriteFile    = errors.New("failed to write file")
+	ErrFileAlreadyExists    = errors.New("file already exists")
+	ErrFailedToReadFile     = errors.New("failed to read file")
 	ErrFailedToGetAbsPath   = errors.New("failed to get abs path")
+	ErrFailedToListDir      = errors.New("failed to list directory")
+	ErrFailedToRemoveFile   = errors.New("failed to remove file")
+	ErrSymlinkLoop          = errors.New("symbolic link points to a directory that contains it")
+	ErrSpecialFile          = errors.New("cannot copy special file")
 )
 
 type DefaultFilesHandler struct {
@@ -150,6 +180,153 @@ func (d *DefaultFilesHandler) CopyDir(srcPath string, dstPath string) error {
 	return nil
 }
 
+// CopyDirOptions changes how CopyDirWithOptions copies a directory
+type CopyDirOptions struct {
+	// SkipUnreadable logs and skips the files and directories that cannot be read because of their permissions,
+	// instead of failing the whole copy. Special files such as sockets and named pipes are skipped too
+	SkipUnreadable bool
+	// FollowSymlinks copies the files and directories that symbolic links point to, instead of the links. A link that
+	// points to nothing is copied as a link
+	FollowSymlinks bool
+}
+
+// CopyDirWithOptions copies a directory natively, without cp. Like cp -r, when dstPath is an existing directory the
+// source directory itself is copied into it. It returns the paths of the entries that were skipped, which can only
+// happen with CopyDirOptions.SkipUnreadable
+func (d *DefaultFilesHandler) CopyDirWithOptions(srcPath string, dstPath string, opts CopyDirOptions) ([]string, error) {
+	cleanSrcPath := filepath.Clean(srcPath)
+	cleanDstPath := filepath.Clean(dstPath)
+	slog.Debug("Copying directory", "srcPath", cleanSrcPath, "dstPath", cleanDstPath,
+		"skipUnreadable", opts.SkipUnreadable, "followSymlinks", opts.FollowSymlinks)
+
+	targetPath := cleanDstPath
+	if stat, err := d.stdlib.Stat(cleanDstPath); err == nil && stat.IsDir() {
+		targetPath = filepath.Join(cleanDstPath, filepath.Base(cleanSrcPath))
+	}
+	c := &dirCopy{files: d, opts: opts}
+	srcInfo, err := d.stdlib.Stat(cleanSrcPath)
+	if err == nil {
+		err = c.copyDir(cleanSrcPath, targetPath, srcInfo)
+	}
+	if err != nil {
+		return c.skipped, fmt.Errorf("%w (%q to %q): %w", ErrFailedToCopyDir, cleanSrcPath, cleanDstPath, err)
+	}
+
+	if len(c.skipped) > 0 {
+		slog.Warn("Skipped unreadable files while copying directory", "srcPath", cleanSrcPath, "skipped", len(c.skipped))
+	}
+	slog.Debug("Successfully copied directory", "srcPath", cleanSrcPath, "dstPath", cleanDstPath)
+	return c.skipped, nil
+}
+
+// dirCopy holds the state of a CopyDirWithOptions call
+type dirCopy struct {
+	files *DefaultFilesHandler
+	opts  CopyDirOptions
+	// ancestors are the directories being copied, from the source directory to the current one. When symbolic links
+	// are followed, a link to one of them would make the copy endless
+	ancestors []os.FileInfo
+	skipped   []string
+}
+
+// skip records an entry that cannot be copied, or returns err if entries must not be skipped
+func (c *dirCopy) skip(path string, err error) error {
+	if !c.opts.SkipUnreadable {
+		return err
+	}
+	slog.Warn("Skipping unreadable file", "path", path, "error", err)
+	c.skipped = append(c.skipped, path)
+	return nil
+}
+
+// copyDir copies the content of srcPath, described by srcInfo, into dstPath
+func (c *dirCopy) copyDir(srcPath string, dstPath string, srcInfo os.FileInfo) error {
+	for _, ancestor := range c.ancestors {
+		if os.SameFile(ancestor, srcInfo) {
+			return fmt.Errorf("%w: %q", ErrSymlinkLoop, srcPath)
+		}
+	}
+	entries, err := c.files.stdlib.ReadDir(srcPath)
+	if err != nil && errors.Is(err, fs.ErrPermission) {
+		return c.skip(srcPath, err)
+	} else if err != nil {
+		return err
+	}
+	if err := c.files.stdlib.MkdirAll(dstPath, defaultDirPerms); err != nil {
+		return err
+	}
+
+	c.ancestors = append(c.ancestors, srcInfo)
+	defer func() { c.ancestors = c.ancestors[:len(c.ancestors)-1] }()
+	for _, entry := range entries {
+		entrySrcPath := filepath.Join(srcPath, entry.Name())
+		entryDstPath := filepath.Join(dstPath, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 && c.opts.FollowSymlinks {
+			targetInfo, err := c.files.stdlib.Stat(entrySrcPath)
+			if err != nil && errors.Is(err, fs.ErrNotExist) {
+				slog.Warn("Copying dangling symbolic link as a link", "path", entrySrcPath)
+			} else if err != nil {
+				return err
+			} else {
+				info = targetInfo
+			}
+		}
+		if err := c.copyEntry(entrySrcPath, entryDstPath, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyEntry copies a single entry of a directory, described by info
+func (c *dirCopy) copyEntry(srcPath string, dstPath string, info os.FileInfo) error {
+	switch {
+	case info.IsDir():
+		return c.copyDir(srcPath, dstPath, info)
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := c.files.stdlib.Readlink(srcPath)
+		if err != nil {
+			return err
+		}
+		return c.files.stdlib.Symlink(target, dstPath)
+	case info.Mode().IsRegular():
+		err := c.files.copyFile(srcPath, dstPath, info.Mode().Perm())
+		if err != nil && errors.Is(err, fs.ErrPermission) {
+			return c.skip(srcPath, err)
+		}
+		return err
+	default:
+		return c.skip(srcPath, fmt.Errorf("%w: %s", ErrSpecialFile, info.Mode().Type()))
+	}
+}
+
+// copyFile copies a regular file. Only the errors of opening srcPath can be permission errors of the source file, so
+// errors writing dstPath are wrapped to tell them apart
+func (d *DefaultFilesHandler) copyFile(srcPath string, dstPath string, perm os.FileMode) error {
+	src, err := d.stdlib.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := d.stdlib.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("%w %q: %v", ErrFailedToWriteFile, dstPath, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("%w %q: %v", ErrFailedToWriteFile, dstPath, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrFailedToWriteFile, dstPath, err)
+	}
+	return nil
+}
+
 func (d *DefaultFilesHandler) Getwd() (dir string, err error) {
 	return d.stdlib.Getwd()
 }
@@ -161,6 +338,53 @@ func (d *DefaultFilesHandler) WriteFile(path string, data []byte) error {
 	return nil
 }
 
+func (d *DefaultFilesHandler) WriteSecretFile(path string, data []byte) error {
+	// os.WriteFile keeps the permissions of an existing file, so they are restricted before its content is replaced
+	if err := d.stdlib.Chmod(path, secretFilePerms); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w %q: %w", ErrFailedToWriteFile, path, err)
+	}
+	if err := d.stdlib.WriteFile(path, data, secretFilePerms); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrFailedToWriteFile, path, err)
+	}
+	return nil
+}
+
+// WriteNewFile creates the file exclusively, which is atomic: if another process creates the same file first, this
+// method fails with ErrFileAlreadyExists instead of overwriting it
+func (d *DefaultFilesHandler) WriteNewFile(path string, data []byte) error {
+	file, err := d.CreateNewFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("%w %q: %w", ErrFailedToWriteFile, path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrFailedToWriteFile, path, err)
+	}
+	return nil
+}
+
+// CreateNewFile creates the file exclusively, like WriteNewFile. The caller must close it
+func (d *DefaultFilesHandler) CreateNewFile(path string) (io.WriteCloser, error) {
+	file, err := d.stdlib.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, defaultFilePerms)
+	if err != nil && errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w: %q", ErrFileAlreadyExists, path)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrFailedToWriteFile, path, err)
+	}
+	return file, nil
+}
+
+func (d *DefaultFilesHandler) ReadFile(path string) ([]byte, error) {
+	data, err := d.stdlib.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrFailedToReadFile, path, err)
+	}
+	return data, nil
+}
+
 func (d *DefaultFilesHandler) GetAbsPath(path string) (string, error) {
 	absPath, err := d.stdlib.FilepathAbs(path)
 	if err != nil {
@@ -168,3 +392,62 @@ func (d *DefaultFilesHandler) GetAbsPath(path string) (string, error) {
 	}
 	return absPath, nil
 }
+
+// ListFiles skips directories, symbolic links and any other entry that isn't a regular file, so that callers can't
+// follow them by mistake
+func (d *DefaultFilesHandler) ListFiles(path string) ([]os.FileInfo, error) {
+	cleanPath := filepath.Clean(path)
+	entries, err := d.stdlib.ReadDir(cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrFailedToListDir, cleanPath, err)
+	}
+	var infos []os.FileInfo
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrFailedToCheckPath, filepath.Join(cleanPath, entry.Name()), err)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// DirSize does not follow symbolic links, like CopyDir, so that the size is the one that a backup of the directory has
+func (d *DefaultFilesHandler) DirSize(path string) (int64, error) {
+	cleanPath := filepath.Clean(path)
+	entries, err := d.stdlib.ReadDir(cleanPath)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q: %w", ErrFailedToListDir, cleanPath, err)
+	}
+	var size int64
+	for _, entry := range entries {
+		entryPath := filepath.Join(cleanPath, entry.Name())
+		if entry.IsDir() {
+			dirSize, err := d.DirSize(entryPath)
+			if err != nil {
+				return 0, err
+			}
+			size += dirSize
+			continue
+		}
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return 0, fmt.Errorf("%w %q: %w", ErrFailedToCheckPath, entryPath, err)
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+func (d *DefaultFilesHandler) RemoveFile(path string) error {
+	if err := d.stdlib.Remove(path); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrFailedToRemoveFile, path, err)
+	}
+	return nil
+}
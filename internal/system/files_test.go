@@ -2,7 +2,12 @@ package system
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -579,6 +584,276 @@ func TestDefaultFilesHandler_CopyDir_CommandError(t *testing.T) {
 	}
 }
 
+// unreadableStdlib uses the real file system, but fails to open or list the unreadable paths with a permission error.
+// Permissions cannot be used for this because the tests may run as root, who can read every file
+type unreadableStdlib struct {
+	*goStdlib
+	unreadable []string
+}
+
+func (u *unreadableStdlib) Open(name string) (io.ReadCloser, error) {
+	if slices.Contains(u.unreadable, name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return u.goStdlib.Open(name)
+}
+
+func (u *unreadableStdlib) ReadDir(name string) ([]os.DirEntry, error) {
+	if slices.Contains(u.unreadable, name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return u.goStdlib.ReadDir(name)
+}
+
+// writeTree creates the files of a tree of directories inside root, mapping each relative path to its content
+func writeTree(t *testing.T, root string, tree map[string]string) {
+	t.Helper()
+	for path, content := range tree {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDefaultFilesHandler_CopyDirWithOptions_SkipsUnreadableEntries(t *testing.T) {
+	srcPath := filepath.Join(t.TempDir(), "data")
+	dstPath := t.TempDir()
+	writeTree(t, srcPath, map[string]string{
+		"readable.txt":        "readable",
+		"secret.txt":          "secret",
+		"nested/readable.txt": "nested",
+		"private/file.txt":    "private",
+	})
+	if err := os.Symlink("readable.txt", filepath.Join(srcPath, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	unreadable := []string{filepath.Join(srcPath, "private"), filepath.Join(srcPath, "secret.txt")}
+	files := &DefaultFilesHandler{stdlib: &unreadableStdlib{goStdlib: newGoStdlib(), unreadable: unreadable}}
+
+	skipped, err := files.CopyDirWithOptions(srcPath, dstPath, CopyDirOptions{SkipUnreadable: true})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if diff := cmp.Diff(unreadable, skipped); diff != "" {
+		t.Errorf("skipped mismatch (-want +got):\n%s", diff)
+	}
+	copiedPath := filepath.Join(dstPath, "data")
+	for path, expected := range map[string]string{"readable.txt": "readable", "nested/readable.txt": "nested", "link.txt": "readable"} {
+		content, err := os.ReadFile(filepath.Join(copiedPath, path))
+		if err != nil {
+			t.Errorf("expected %q to be copied, got %v", path, err)
+		} else if string(content) != expected {
+			t.Errorf("expected %q to contain %q, got %q", path, expected, content)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(copiedPath, "link.txt")); err != nil || target != "readable.txt" {
+		t.Errorf("expected link.txt to be a link to %q, got %q (%v)", "readable.txt", target, err)
+	}
+	for _, path := range []string{"secret.txt", "private"} {
+		if _, err := os.Lstat(filepath.Join(copiedPath, path)); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected %q not to be copied, got %v", path, err)
+		}
+	}
+}
+
+func TestDefaultFilesHandler_CopyDirWithOptions_UnreadableFailsWithoutSkip(t *testing.T) {
+	srcPath := filepath.Join(t.TempDir(), "data")
+	writeTree(t, srcPath, map[string]string{"secret.txt": "secret"})
+	unreadable := []string{filepath.Join(srcPath, "secret.txt")}
+	files := &DefaultFilesHandler{stdlib: &unreadableStdlib{goStdlib: newGoStdlib(), unreadable: unreadable}}
+
+	skipped, err := files.CopyDirWithOptions(srcPath, t.TempDir(), CopyDirOptions{})
+
+	if !errors.Is(err, ErrFailedToCopyDir) || !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected ErrFailedToCopyDir wrapping a permission error, got %v", err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("expected nothing to be skipped, got %v", skipped)
+	}
+}
+
+// writeSymlinkTree creates a source directory with a link to a file and a link to a directory, both outside of it
+func writeSymlinkTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"data/readable.txt":    "readable",
+		"outside/file.txt":     "outside file",
+		"outside/dir/file.txt": "outside dir",
+	})
+	srcPath := filepath.Join(root, "data")
+	if err := os.Symlink(filepath.Join(root, "outside", "file.txt"), filepath.Join(srcPath, "file-link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "outside", "dir"), filepath.Join(srcPath, "dir-link")); err != nil {
+		t.Fatal(err)
+	}
+	return srcPath
+}
+
+func TestDefaultFilesHandler_CopyDirWithOptions_CopiesSymlinksAsLinks(t *testing.T) {
+	srcPath := writeSymlinkTree(t)
+	dstPath := t.TempDir()
+	files := &DefaultFilesHandler{stdlib: newGoStdlib()}
+
+	_, err := files.CopyDirWithOptions(srcPath, dstPath, CopyDirOptions{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, name := range []string{"file-link", "dir-link"} {
+		info, err := os.Lstat(filepath.Join(dstPath, "data", name))
+		if err != nil {
+			t.Fatalf("expected %q to be copied, got %v", name, err)
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			t.Errorf("expected %q to be a symbolic link, got mode %v", name, info.Mode())
+		}
+	}
+}
+
+func TestDefaultFilesHandler_CopyDirWithOptions_FollowsSymlinks(t *testing.T) {
+	srcPath := writeSymlinkTree(t)
+	dstPath := t.TempDir()
+	files := &DefaultFilesHandler{stdlib: newGoStdlib()}
+
+	_, err := files.CopyDirWithOptions(srcPath, dstPath, CopyDirOptions{FollowSymlinks: true})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for path, expected := range map[string]string{"file-link": "outside file", "dir-link/file.txt": "outside dir"} {
+		copiedPath := filepath.Join(dstPath, "data", path)
+		info, err := os.Lstat(copiedPath)
+		if err != nil {
+			t.Fatalf("expected %q to be copied, got %v", path, err)
+		}
+		if !info.Mode().IsRegular() {
+			t.Errorf("expected %q to be a regular file, got mode %v", path, info.Mode())
+		}
+		if content, _ := os.ReadFile(copiedPath); string(content) != expected {
+			t.Errorf("expected %q to contain %q, got %q", path, expected, content)
+		}
+	}
+	if info, err := os.Lstat(filepath.Join(dstPath, "data", "dir-link")); err != nil || !info.IsDir() {
+		t.Errorf("expected dir-link to be a directory, got %v", err)
+	}
+}
+
+func TestDefaultFilesHandler_CopyDirWithOptions_FollowSymlinksCopiesDanglingLink(t *testing.T) {
+	srcPath := filepath.Join(t.TempDir(), "data")
+	writeTree(t, srcPath, map[string]string{"readable.txt": "readable"})
+	if err := os.Symlink("missing.txt", filepath.Join(srcPath, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	dstPath := t.TempDir()
+	files := &DefaultFilesHandler{stdlib: newGoStdlib()}
+
+	_, err := files.CopyDirWithOptions(srcPath, dstPath, CopyDirOptions{FollowSymlinks: true})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(dstPath, "data", "dangling")); err != nil || target != "missing.txt" {
+		t.Errorf("expected dangling to be a link to %q, got %q (%v)", "missing.txt", target, err)
+	}
+}
+
+func TestDefaultFilesHandler_CopyDirWithOptions_FollowSymlinksLoop(t *testing.T) {
+	srcPath := filepath.Join(t.TempDir(), "data")
+	writeTree(t, srcPath, map[string]string{"nested/readable.txt": "readable"})
+	if err := os.Symlink(srcPath, filepath.Join(srcPath, "nested", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	files := &DefaultFilesHandler{stdlib: newGoStdlib()}
+
+	_, err := files.CopyDirWithOptions(srcPath, t.TempDir(), CopyDirOptions{FollowSymlinks: true})
+
+	if !errors.Is(err, ErrSymlinkLoop) {
+		t.Errorf("expected ErrSymlinkLoop, got %v", err)
+	}
+}
+
+func TestDefaultFilesHandler_CopyDirWithOptions_FilePermissions(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read files without read permission")
+	}
+	srcPath := filepath.Join(t.TempDir(), "data")
+	dstPath := t.TempDir()
+	writeTree(t, srcPath, map[string]string{"readable.txt": "readable", "secret.txt": "secret"})
+	secretPath := filepath.Join(srcPath, "secret.txt")
+	if err := os.Chmod(secretPath, 0o000); err != nil {
+		t.Fatal(err)
+	}
+	files := &DefaultFilesHandler{stdlib: newGoStdlib()}
+
+	skipped, err := files.CopyDirWithOptions(srcPath, dstPath, CopyDirOptions{SkipUnreadable: true})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if diff := cmp.Diff([]string{secretPath}, skipped); diff != "" {
+		t.Errorf("skipped mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(dstPath, "data", "readable.txt")); err != nil {
+		t.Errorf("expected readable.txt to be copied, got %v", err)
+	}
+}
+
+func TestDefaultFilesHandler_CopyDirWithOptions_OtherErrorFails(t *testing.T) {
+	expectedErr := errors.New("disk failure")
+	std := &mockStdlib{
+		stat: func(name string) (os.FileInfo, error) {
+			if name == "/src" {
+				return &mockFileInfo{name: "src", isDir: true}, nil
+			}
+			return nil, os.ErrNotExist
+		},
+		readDir: func(name string) ([]os.DirEntry, error) {
+			return nil, expectedErr
+		},
+	}
+	files := &DefaultFilesHandler{stdlib: std}
+
+	_, err := files.CopyDirWithOptions("/src", "/dst", CopyDirOptions{SkipUnreadable: true})
+
+	if !errors.Is(err, ErrFailedToCopyDir) {
+		t.Errorf("expected ErrFailedToCopyDir, got %v", err)
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap %v, got %v", expectedErr, err)
+	}
+}
+
+func TestDefaultFilesHandler_CopyDirWithOptions_WriteErrorIsNotSkipped(t *testing.T) {
+	srcPath := filepath.Join(t.TempDir(), "data")
+	writeTree(t, srcPath, map[string]string{"readable.txt": "readable"})
+	files := &DefaultFilesHandler{stdlib: &failingOpenFileStdlib{goStdlib: newGoStdlib()}}
+
+	skipped, err := files.CopyDirWithOptions(srcPath, t.TempDir(), CopyDirOptions{SkipUnreadable: true})
+
+	if !errors.Is(err, ErrFailedToWriteFile) {
+		t.Errorf("expected ErrFailedToWriteFile, got %v", err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("expected nothing to be skipped, got %v", skipped)
+	}
+}
+
+// failingOpenFileStdlib uses the real file system, but fails to create files with a permission error
+type failingOpenFileStdlib struct {
+	*goStdlib
+}
+
+func (f *failingOpenFileStdlib) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+}
+
 func TestDefaultFilesHandler_Getwd(t *testing.T) {
 	wd := "/User/root"
 	files := &DefaultFilesHandler{
@@ -632,6 +907,76 @@ func TestDefaultFilesHandler_WriteFile_Success(t *testing.T) {
 	}
 }
 
+func TestDefaultFilesHandler_WriteSecretFile_RestrictsPermissions(t *testing.T) {
+	var calls []string
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			chmod: func(name string, mode os.FileMode) error {
+				calls = append(calls, fmt.Sprintf("chmod %s %o", name, mode))
+				return nil
+			},
+			writeFile: func(name string, data []byte, perm os.FileMode) error {
+				calls = append(calls, fmt.Sprintf("write %s %o", name, perm))
+				return nil
+			},
+		},
+	}
+
+	err := files.WriteSecretFile("/homelab/.env", []byte("KEY=value\n"))
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// The existing file is restricted before the secrets are written into it
+	expected := []string{"chmod /homelab/.env 600", "write /homelab/.env 600"}
+	if diff := cmp.Diff(expected, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultFilesHandler_WriteSecretFile_NewFile(t *testing.T) {
+	var written bool
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			chmod: func(name string, mode os.FileMode) error {
+				return &os.PathError{Op: "chmod", Path: name, Err: os.ErrNotExist}
+			},
+			writeFile: func(name string, data []byte, perm os.FileMode) error {
+				written = true
+				return nil
+			},
+		},
+	}
+
+	err := files.WriteSecretFile("/homelab/.env.bak", []byte("KEY=value\n"))
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !written {
+		t.Error("expected the file to be written")
+	}
+}
+
+func TestDefaultFilesHandler_WriteSecretFile_RealFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("OLD=value\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if err := NewDefaultFilesHandler().WriteSecretFile(path, []byte("NEW=value\n")); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("expected permissions 600, got %o", perm)
+	}
+}
+
 func TestDefaultFilesHandler_WriteFile_Failure(t *testing.T) {
 	expectedErr := errors.New("insufficient permissions")
 	files := &DefaultFilesHandler{
@@ -659,6 +1004,169 @@ func TestDefaultFilesHandler_WriteFile_Failure(t *testing.T) {
 	}
 }
 
+func TestDefaultFilesHandler_WriteNewFile_Success(t *testing.T) {
+	var capturedPath string
+	var capturedFlag int
+	var capturedPerm os.FileMode
+	file := &mockWriteCloser{}
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			openFile: func(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+				capturedPath = name
+				capturedFlag = flag
+				capturedPerm = perm
+				return file, nil
+			},
+		},
+	}
+	path := "/User/root/file.txt"
+	data := []byte("KEY=value\n")
+
+	err := files.WriteNewFile(path, data)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedPath != path {
+		t.Errorf("expected path to be %q, got %q", path, capturedPath)
+	}
+	if capturedFlag&os.O_EXCL == 0 || capturedFlag&os.O_CREATE == 0 {
+		t.Errorf("expected the file to be created exclusively, got flags %b", capturedFlag)
+	}
+	if capturedPerm != defaultFilePerms {
+		t.Errorf("expected perms to be %v, got %v", defaultFilePerms, capturedPerm)
+	}
+	if diff := cmp.Diff(data, file.written); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+	if !file.closed {
+		t.Error("expected file to be closed")
+	}
+}
+
+func TestDefaultFilesHandler_WriteNewFile_AlreadyExists(t *testing.T) {
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			openFile: func(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+				return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+			},
+		},
+	}
+
+	err := files.WriteNewFile("/User/root/file.txt", []byte{'Q'})
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrFileAlreadyExists) {
+		t.Errorf("expected ErrFileAlreadyExists, got: %v", err)
+	}
+}
+
+func TestDefaultFilesHandler_CreateNewFile_ReturnsOpenFile(t *testing.T) {
+	var capturedFlag int
+	file := &mockWriteCloser{}
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			openFile: func(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+				capturedFlag = flag
+				return file, nil
+			},
+		},
+	}
+
+	created, err := files.CreateNewFile("/User/root/run.log")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if created != file {
+		t.Errorf("expected the opened file to be returned")
+	}
+	if file.closed {
+		t.Errorf("expected the file to be left open")
+	}
+	if capturedFlag&os.O_EXCL == 0 {
+		t.Errorf("expected the file to be created exclusively, got flag %d", capturedFlag)
+	}
+}
+
+func TestDefaultFilesHandler_WriteNewFile_WriteError(t *testing.T) {
+	expectedErr := errors.New("disk full")
+	file := &mockWriteCloser{writeErr: expectedErr}
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			openFile: func(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+				return file, nil
+			},
+		},
+	}
+
+	err := files.WriteNewFile("/User/root/file.txt", []byte{'Q'})
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrFailedToWriteFile) {
+		t.Errorf("expected ErrFailedToWriteFile, got: %v", err)
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to be %v, got: %v", expectedErr, err)
+	}
+	if !file.closed {
+		t.Error("expected file to be closed")
+	}
+}
+
+func TestDefaultFilesHandler_ReadFile_Success(t *testing.T) {
+	var capturedPath string
+	data := []byte("KEY=value\n")
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			readFile: func(name string) ([]byte, error) {
+				capturedPath = name
+				return data, nil
+			},
+		},
+	}
+	path := "/User/root/.env"
+
+	got, err := files.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedPath != path {
+		t.Errorf("expected path to be %q, got %q", path, capturedPath)
+	}
+	if diff := cmp.Diff(data, got); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultFilesHandler_ReadFile_Failure(t *testing.T) {
+	expectedErr := errors.New("file does not exist")
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			readFile: func(name string) ([]byte, error) {
+				return nil, expectedErr
+			},
+		},
+	}
+
+	_, err := files.ReadFile("/User/root/.env")
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, ErrFailedToReadFile) {
+		t.Errorf("expected ErrFailedToReadFile, got: %v", err)
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap expectedErr, got: %v", err)
+	}
+}
+
 func TestDefaultFilesHandler_GetAbsPath_Success(t *testing.T) {
 	var capturedPath string
 	inputPath := "./file.txt"
@@ -711,3 +1219,131 @@ func TestDefaultFilesHandler_GetAbsPath_Failure(t *testing.T) {
 		t.Errorf("expected error message to contain input path %q, got: %q", inputPath, err.Error())
 	}
 }
+
+func TestDefaultFilesHandler_ListFiles_OnlyRegularFiles(t *testing.T) {
+	var capturedPath string
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			readDir: func(name string) ([]os.DirEntry, error) {
+				capturedPath = name
+				return []os.DirEntry{
+					fs.FileInfoToDirEntry(&mockFileInfo{name: "immich.sql"}),
+					fs.FileInfoToDirEntry(&mockFileInfo{name: "library", mode: os.ModeDir, isDir: true}),
+					fs.FileInfoToDirEntry(&mockFileInfo{name: "latest.sql", mode: os.ModeSymlink}),
+					fs.FileInfoToDirEntry(&mockFileInfo{name: "volume.tar.gz"}),
+				}, nil
+			},
+		},
+	}
+
+	infos, err := files.ListFiles("/backup/dumps/")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedPath != "/backup/dumps" {
+		t.Errorf("expected path to be %q, got %q", "/backup/dumps", capturedPath)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	if diff := cmp.Diff([]string{"immich.sql", "volume.tar.gz"}, names); diff != "" {
+		t.Errorf("files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultFilesHandler_ListFiles_Failure(t *testing.T) {
+	expectedErr := errors.New("permission denied")
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			readDir: func(name string) ([]os.DirEntry, error) {
+				return nil, expectedErr
+			},
+		},
+	}
+
+	_, err := files.ListFiles("/backup/dumps")
+
+	if !errors.Is(err, ErrFailedToListDir) {
+		t.Errorf("expected ErrFailedToListDir, got: %v", err)
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap expectedErr, got: %v", err)
+	}
+}
+
+func TestDefaultFilesHandler_DirSize(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a.txt":            "12345",
+		"nested/b.txt":     "1234567890",
+		"nested/deep/c.md": "123",
+		"empty/.keep":      "",
+	})
+	// Links are not followed, so the target is only counted once
+	if err := os.Symlink(filepath.Join(root, "a.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	files := &DefaultFilesHandler{stdlib: newGoStdlib()}
+
+	size, err := files.DirSize(root)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if size != 18 {
+		t.Errorf("expected size %d, got %d", 18, size)
+	}
+}
+
+func TestDefaultFilesHandler_DirSize_Failure(t *testing.T) {
+	files := &DefaultFilesHandler{stdlib: newGoStdlib()}
+
+	_, err := files.DirSize(filepath.Join(t.TempDir(), "missing"))
+
+	if !errors.Is(err, ErrFailedToListDir) {
+		t.Errorf("expected ErrFailedToListDir, got %v", err)
+	}
+}
+
+func TestDefaultFilesHandler_RemoveFile_Success(t *testing.T) {
+	var capturedPath string
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			remove: func(name string) error {
+				capturedPath = name
+				return nil
+			},
+		},
+	}
+
+	err := files.RemoveFile("/backup/dumps/immich.sql")
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if capturedPath != "/backup/dumps/immich.sql" {
+		t.Errorf("expected path to be %q, got %q", "/backup/dumps/immich.sql", capturedPath)
+	}
+}
+
+func TestDefaultFilesHandler_RemoveFile_Failure(t *testing.T) {
+	expectedErr := errors.New("permission denied")
+	files := &DefaultFilesHandler{
+		stdlib: &mockStdlib{
+			remove: func(name string) error {
+				return expectedErr
+			},
+		},
+	}
+
+	err := files.RemoveFile("/backup/dumps/immich.sql")
+
+	if !errors.Is(err, ErrFailedToRemoveFile) {
+		t.Errorf("expected ErrFailedToRemoveFile, got: %v", err)
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to wrap expectedErr, got: %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package system
 
 import (
+	"context"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -19,18 +21,46 @@ type stdlib interface {
 	Stat(name string) (os.FileInfo, error)
 	// ExecCommand wraps exec.Cmd
 	ExecCommand(name string, arg ...string) RunnableCommand
+	// ExecCommandContext wraps exec.CommandContext
+	ExecCommandContext(ctx context.Context, name string, arg ...string) RunnableCommand
+	// ExecCommandWithStderr wraps exec.Cmd, writing the command's standard error into stderr
+	ExecCommandWithStderr(stderr io.Writer, name string, arg ...string) RunnableCommand
+	// ExecCommandWithStdout wraps exec.Cmd, writing the command's standard output into stdout
+	ExecCommandWithStdout(stdout io.Writer, name string, arg ...string) RunnableCommand
+	// ExecCommandContextWithStdout wraps exec.CommandContext, writing the command's standard output into stdout
+	ExecCommandContextWithStdout(ctx context.Context, stdout io.Writer, name string, arg ...string) RunnableCommand
 	// ExecLookPath wraps exec.LookPath
 	ExecLookPath(file string) (string, error)
 	// MkdirAll wraps os.MkdirAll
 	MkdirAll(path string, perm os.FileMode) error
 	// RemoveAll wraps os.RemoveAll
 	RemoveAll(path string) error
+	// Remove wraps os.Remove
+	Remove(name string) error
+	// ReadDir wraps os.ReadDir
+	ReadDir(name string) ([]os.DirEntry, error)
 	// Sleep wraps time.Sleep
 	Sleep(d time.Duration)
+	// Now wraps time.Now
+	Now() time.Time
+	// After wraps time.After
+	After(d time.Duration) <-chan time.Time
+	// Open wraps os.Open
+	Open(name string) (io.ReadCloser, error)
+	// OpenFile wraps os.OpenFile
+	OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error)
 	// WriteFile wraps os.WriteFile
 	WriteFile(name string, data []byte, perm os.FileMode) error
+	// Chmod wraps os.Chmod
+	Chmod(name string, mode os.FileMode) error
+	// ReadFile wraps os.ReadFile
+	ReadFile(name string) ([]byte, error)
 	// FilepathAbs wraps filepath.Abs
 	FilepathAbs(path string) (string, error)
+	// Readlink wraps os.Readlink
+	Readlink(name string) (string, error)
+	// Symlink wraps os.Symlink
+	Symlink(oldname string, newname string) error
 }
 
 // goStdlib implements stdlib by using the real go's std
@@ -59,6 +89,45 @@ func (*goStdlib) ExecCommand(name string, arg ...string) RunnableCommand {
 	return cmd
 }
 
+// ExecCommandContext is like ExecCommand, but the command is interrupted (rather than killed) when the context is
+// done. This gives commands such as `docker compose logs -f` the chance to exit cleanly
+func (s *goStdlib) ExecCommandContext(ctx context.Context, name string, arg ...string) RunnableCommand {
+	return s.ExecCommandContextWithStdout(ctx, os.Stdout, name, arg...)
+}
+
+// ExecCommandContextWithStdout is like ExecCommandContext, but the standard output of the command is written into
+// stdout
+func (*goStdlib) ExecCommandContextWithStdout(ctx context.Context, stdout io.Writer, name string, arg ...string) RunnableCommand {
+	cmd := exec.CommandContext(ctx, name, arg...)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = "."
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	// If the command does not exit after being interrupted, it is killed
+	cmd.WaitDelay = 5 * time.Second
+	return cmd
+}
+
+// ExecCommandWithStderr is like ExecCommand, but the standard error of the command is written into stderr
+func (*goStdlib) ExecCommandWithStderr(stderr io.Writer, name string, arg ...string) RunnableCommand {
+	cmd := exec.Command(name, arg...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = stderr
+	cmd.Dir = "."
+	return cmd
+}
+
+// ExecCommandWithStdout is like ExecCommand, but the standard output of the command is written into stdout
+func (*goStdlib) ExecCommandWithStdout(stdout io.Writer, name string, arg ...string) RunnableCommand {
+	cmd := exec.Command(name, arg...)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = "."
+	return cmd
+}
+
 func (*goStdlib) ExecLookPath(file string) (string, error) {
 	return exec.LookPath(file)
 }
@@ -67,12 +136,42 @@ func (*goStdlib) MkdirAll(path string, perm os.FileMode) error { return os.Mkdir
 
 func (*goStdlib) RemoveAll(path string) error { return os.RemoveAll(path) }
 
+func (*goStdlib) Remove(name string) error { return os.Remove(name) }
+
+func (*goStdlib) ReadDir(name string) ([]os.DirEntry, error) { return os.ReadDir(name) }
+
 func (*goStdlib) Sleep(d time.Duration) { time.Sleep(d) }
 
+func (*goStdlib) Now() time.Time { return time.Now() }
+
+func (*goStdlib) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (*goStdlib) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (*goStdlib) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
 func (*goStdlib) WriteFile(name string, data []byte, perm os.FileMode) error {
 	return os.WriteFile(name, data, perm)
 }
 
+func (*goStdlib) Chmod(name string, mode os.FileMode) error { return os.Chmod(name, mode) }
+
+func (*goStdlib) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
 func (*goStdlib) FilepathAbs(path string) (string, error) {
 	return filepath.Abs(path)
 }
+
+func (*goStdlib) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+func (*goStdlib) Symlink(oldname string, newname string) error {
+	return os.Symlink(oldname, newname)
+}
@@ -1,6 +1,8 @@
 package system
 
 import (
+	"context"
+	"io"
 	"os"
 	"time"
 )
@@ -17,6 +19,25 @@ func (m *mockRunnableCommand) Run() error {
 	return nil
 }
 
+// mockWriteCloser is a mock implementation of io.WriteCloser that keeps the written data
+type mockWriteCloser struct {
+	written  []byte
+	writeErr error
+	closed   bool
+}
+
+func (m *mockWriteCloser) Write(p []byte) (int, error) {
+	if m.writeErr != nil {
+		return 0, m.writeErr
+	}
+	m.written = append(m.written, p...)
+	return len(p), nil
+}
+func (m *mockWriteCloser) Close() error {
+	m.closed = true
+	return nil
+}
+
 // mockFileInfo is a mock implementation of os.FileInfo for testing
 type mockFileInfo struct {
 	name    string
@@ -35,15 +56,29 @@ func (m *mockFileInfo) Sys() interface{}   { return nil }
 
 // mockStdlib is a mock implementation of the stdlib interface
 type mockStdlib struct {
-	getwd        func() (string, error)
-	stat         func(name string) (os.FileInfo, error)
-	execCommand  func(name string, arg ...string) RunnableCommand
-	execLookPath func(file string) (string, error)
-	mkdirAll     func(path string, mode os.FileMode) error
-	removeAll    func(path string) error
-	sleep        func(d time.Duration)
-	writeFile    func(name string, data []byte, perm os.FileMode) error
-	filepathAbs  func(path string) (string, error)
+	getwd              func() (string, error)
+	stat               func(name string) (os.FileInfo, error)
+	execCommand        func(name string, arg ...string) RunnableCommand
+	execCommandContext func(ctx context.Context, name string, arg ...string) RunnableCommand
+	execCommandStderr  func(stderr io.Writer, name string, arg ...string) RunnableCommand
+	execCommandStdout  func(stdout io.Writer, name string, arg ...string) RunnableCommand
+	execCommandCtxOut  func(ctx context.Context, stdout io.Writer, name string, arg ...string) RunnableCommand
+	execLookPath       func(file string) (string, error)
+	mkdirAll           func(path string, mode os.FileMode) error
+	removeAll          func(path string) error
+	remove             func(name string) error
+	readDir            func(name string) ([]os.DirEntry, error)
+	sleep              func(d time.Duration)
+	now                func() time.Time
+	after              func(d time.Duration) <-chan time.Time
+	open               func(name string) (io.ReadCloser, error)
+	openFile           func(name string, flag int, perm os.FileMode) (io.WriteCloser, error)
+	writeFile          func(name string, data []byte, perm os.FileMode) error
+	chmod              func(name string, mode os.FileMode) error
+	readFile           func(name string) ([]byte, error)
+	filepathAbs        func(path string) (string, error)
+	readlink           func(name string) (string, error)
+	symlink            func(oldname string, newname string) error
 }
 
 func (m *mockStdlib) Getwd() (string, error) {
@@ -64,6 +99,30 @@ func (m *mockStdlib) ExecCommand(name string, arg ...string) RunnableCommand {
 	}
 	return nil
 }
+func (m *mockStdlib) ExecCommandContext(ctx context.Context, name string, arg ...string) RunnableCommand {
+	if m.execCommandContext != nil {
+		return m.execCommandContext(ctx, name, arg...)
+	}
+	return nil
+}
+func (m *mockStdlib) ExecCommandWithStderr(stderr io.Writer, name string, arg ...string) RunnableCommand {
+	if m.execCommandStderr != nil {
+		return m.execCommandStderr(stderr, name, arg...)
+	}
+	return nil
+}
+func (m *mockStdlib) ExecCommandWithStdout(stdout io.Writer, name string, arg ...string) RunnableCommand {
+	if m.execCommandStdout != nil {
+		return m.execCommandStdout(stdout, name, arg...)
+	}
+	return nil
+}
+func (m *mockStdlib) ExecCommandContextWithStdout(ctx context.Context, stdout io.Writer, name string, arg ...string) RunnableCommand {
+	if m.execCommandCtxOut != nil {
+		return m.execCommandCtxOut(ctx, stdout, name, arg...)
+	}
+	return nil
+}
 func (m *mockStdlib) ExecLookPath(file string) (string, error) {
 	if m.execLookPath != nil {
 		return m.execLookPath(file)
@@ -82,20 +141,80 @@ func (m *mockStdlib) RemoveAll(path string) error {
 	}
 	return nil
 }
+func (m *mockStdlib) Remove(name string) error {
+	if m.remove != nil {
+		return m.remove(name)
+	}
+	return nil
+}
+func (m *mockStdlib) ReadDir(name string) ([]os.DirEntry, error) {
+	if m.readDir != nil {
+		return m.readDir(name)
+	}
+	return nil, nil
+}
 func (m *mockStdlib) Sleep(d time.Duration) {
 	if m.sleep != nil {
 		m.sleep(d)
 	}
 }
+func (m *mockStdlib) Now() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Time{}
+}
+func (m *mockStdlib) After(d time.Duration) <-chan time.Time {
+	if m.after != nil {
+		return m.after(d)
+	}
+	return nil
+}
+func (m *mockStdlib) Open(name string) (io.ReadCloser, error) {
+	if m.open != nil {
+		return m.open(name)
+	}
+	return nil, nil
+}
+func (m *mockStdlib) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	if m.openFile != nil {
+		return m.openFile(name, flag, perm)
+	}
+	return nil, nil
+}
 func (m *mockStdlib) WriteFile(name string, data []byte, perm os.FileMode) error {
 	if m.writeFile != nil {
 		return m.writeFile(name, data, perm)
 	}
 	return nil
 }
+func (m *mockStdlib) Chmod(name string, mode os.FileMode) error {
+	if m.chmod != nil {
+		return m.chmod(name, mode)
+	}
+	return nil
+}
+func (m *mockStdlib) ReadFile(name string) ([]byte, error) {
+	if m.readFile != nil {
+		return m.readFile(name)
+	}
+	return nil, nil
+}
 func (m *mockStdlib) FilepathAbs(path string) (string, error) {
 	if m.filepathAbs != nil {
 		return m.filepathAbs(path)
 	}
 	return "", nil
 }
+func (m *mockStdlib) Readlink(name string) (string, error) {
+	if m.readlink != nil {
+		return m.readlink(name)
+	}
+	return "", nil
+}
+func (m *mockStdlib) Symlink(oldname string, newname string) error {
+	if m.symlink != nil {
+		return m.symlink(oldname, newname)
+	}
+	return nil
+}
@@ -7,6 +7,10 @@ import (
 type Time interface {
 	// Sleep pauses the execution of the program for a certain amount of time
 	Sleep(d time.Duration)
+	// Now returns the current local time
+	Now() time.Time
+	// After returns a channel that receives the current time once the duration has elapsed
+	After(d time.Duration) <-chan time.Time
 }
 
 type DefaultTime struct {
@@ -22,3 +26,11 @@ func NewDefaultTime() *DefaultTime {
 func (t *DefaultTime) Sleep(d time.Duration) {
 	t.stdlib.Sleep(d)
 }
+
+func (t *DefaultTime) Now() time.Time {
+	return t.stdlib.Now()
+}
+
+func (t *DefaultTime) After(d time.Duration) <-chan time.Time {
+	return t.stdlib.After(d)
+}
@@ -21,3 +21,40 @@ func TestDefaultTime_Sleep_CallsStdlibSleep(t *testing.T) {
 		t.Errorf("expected sleep to have been called with duration %v, got: %v", expectedDuration, capturedDuration)
 	}
 }
+
+func TestDefaultTime_Now_ReturnsStdlibNow(t *testing.T) {
+	expectedTime := time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+	std := &mockStdlib{
+		now: func() time.Time {
+			return expectedTime
+		},
+	}
+	dt := &DefaultTime{stdlib: std}
+
+	now := dt.Now()
+
+	if !now.Equal(expectedTime) {
+		t.Errorf("expected now to be %v, got: %v", expectedTime, now)
+	}
+}
+
+func TestDefaultTime_After_ReturnsStdlibAfter(t *testing.T) {
+	var capturedDuration time.Duration
+	expectedChan := make(chan time.Time)
+	std := &mockStdlib{
+		after: func(d time.Duration) <-chan time.Time {
+			capturedDuration = d
+			return expectedChan
+		},
+	}
+	dt := &DefaultTime{stdlib: std}
+
+	got := dt.After(30 * time.Second)
+
+	if got != (<-chan time.Time)(expectedChan) {
+		t.Error("expected the channel returned by stdlib After")
+	}
+	if capturedDuration != 30*time.Second {
+		t.Errorf("expected after to have been called with duration %v, got: %v", 30*time.Second, capturedDuration)
+	}
+}
@@ -40,9 +40,8 @@ func main() {
 	if err := requireCommand("docker"); err != nil {
 		exitWithCommandMissingError("docker")
 	}
-	if err := requireCommand("restic"); err != nil {
-		exitWithCommandMissingError("restic")
-	}
+	// restic is only checked by the cloud backup commands, because its binary can be overridden with
+	// HOMELAB_RESTIC_BINARY
 	if err := requireCommand("sh"); err != nil {
 		exitWithCommandMissingError("sh")
 	}
@@ -53,9 +52,9 @@ func main() {
 		exitWithCommandMissingError("cp")
 	}
 	if err := cmd.Execute(); err != nil {
-		// Cobra already prints the error; ensure non-zero exit for failure cases
+		// Execute already prints the error; ensure non-zero exit for failure cases
 		slog.Error("Command execution failed", "error", err.Error())
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
 
@@ -66,5 +65,5 @@ func requireCommand(command string) error {
 
 func exitWithCommandMissingError(command string) {
 	slog.Error("A command was not found. Is it installed in PATH?", "command", command)
-	os.Exit(2)
+	os.Exit(cmd.ExitCodeMissingDependency)
 }